@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// command is a CLI subcommand such as "id-100 geonames import"
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"gdpr":     {usage: gdprUsage, run: runGDPR},
+	"geonames": {usage: "geonames import --file DE.txt [flags]", run: runGeonames},
+	"tokens":   {usage: tokensUsage, run: runTokens},
+	"webhooks": {usage: "webhooks receive [--addr :8090] [--secret SECRET]", run: runWebhooks},
+}
+
+// runSubcommand executes the subcommand named by args[0].
+// It returns false when no subcommand was given and the web server should start.
+func runSubcommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			printUsage()
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		printUsage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	return true
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  id-100                 start the web server")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  id-100 %s\n", cmd.usage)
+	}
+}
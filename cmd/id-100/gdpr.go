@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"id-100/internal/config"
+	"id-100/internal/database"
+	"id-100/internal/gdpr"
+	"id-100/internal/models"
+	"id-100/internal/utils"
+)
+
+const gdprUsage = `gdpr find|export|erase [--name NAME] [--session TOKEN_ID:SESSION] [--email ADDRESS] [--player ID] [flags]`
+
+// runGDPR implements "id-100 gdpr find|export|erase" for data subject requests
+func runGDPR(args []string) error {
+	if len(args) == 0 || (args[0] != "find" && args[0] != "export" && args[0] != "erase") {
+		return errors.New("usage: id-100 " + gdprUsage)
+	}
+	action := args[0]
+
+	config.LoadEnv()
+
+	fs := flag.NewFlagSet("gdpr "+action, flag.ExitOnError)
+	name := fs.String("name", "", "player name as typed at a bag (case-insensitive)")
+	session := fs.String("session", "", "a single bag session as TOKEN_ID:SESSION")
+	email := fs.String("email", "", "email address of bag requests, player accounts and sent emails")
+	player := fs.String("player", "", "player account id")
+	reference := fs.String("reference", "", "reference stored in the audit log, e.g. a ticket number")
+	out := fs.String("out", "", "export: ZIP file to write (default id-100_auskunft_<time>.zip)")
+	yes := fs.Bool("yes", false, "erase: confirm the erasure")
+	fs.Parse(args[1:])
+
+	q, err := gdpr.ParseQuery(*name, *session, *email, *player)
+	if err != nil {
+		fs.Usage()
+		return err
+	}
+
+	database.Init()
+	defer database.Close()
+
+	ctx := context.Background()
+	actor := "cli:" + os.Getenv("USER")
+
+	switch action {
+	case "find":
+		d, err := gdpr.Find(ctx, q)
+		if err != nil {
+			return err
+		}
+		printSubjectData(os.Stdout, d)
+		return nil
+
+	case "export":
+		path := *out
+		if path == "" {
+			path = gdpr.ArchiveName(time.Now())
+		}
+		d, err := gdpr.Export(ctx, q, actor, *reference)
+		if err != nil {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = gdpr.WriteArchive(f, d, q, func(imageURL string) (io.ReadCloser, error) {
+			body, err := utils.DownloadImageFromS3(ctx, imageURL)
+			if err != nil {
+				log.Printf("Image of %s not exported: %v", imageURL, err)
+			}
+			return body, err
+		})
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		log.Printf("Exported to %s", path)
+		return nil
+	}
+
+	d, err := gdpr.Find(ctx, q)
+	if err != nil {
+		return err
+	}
+	printSubjectData(os.Stdout, d)
+	if !*yes {
+		return errors.New("nothing erased; run again with --yes to erase the records above")
+	}
+
+	res, err := gdpr.Erase(ctx, q, actor, *reference)
+	if err != nil {
+		return err
+	}
+	kinds := make([]string, 0, len(res.Counts))
+	for kind := range res.Counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	fmt.Println("\nErased:")
+	for _, kind := range kinds {
+		fmt.Printf("  %-16s %d\n", kind, res.Counts[kind])
+	}
+	if !res.Verified {
+		return fmt.Errorf("erasure not verified (audit #%d): records left %v, images left %d", res.AuditID, res.Remaining, res.ImagesRemaining)
+	}
+	log.Printf("Erasure verified (audit #%d)", res.AuditID)
+	return nil
+}
+
+// printSubjectData lists the records found per kind
+func printSubjectData(w io.Writer, d *models.SubjectData) {
+	counts := gdpr.Counts(d)
+	for _, kind := range gdpr.Kinds {
+		fmt.Fprintf(w, "%-16s %d\n", kind, counts[kind])
+	}
+	for _, s := range d.Sessions {
+		fmt.Fprintf(w, "session %d:%d  %s #%d  %q %s\n", s.TokenID, s.SessionNumber, s.BagName, s.SessionNumber, s.PlayerName, s.StartedAt.Format("2006-01-02"))
+	}
+	for _, c := range d.Contributions {
+		fmt.Fprintf(w, "contribution %d  ID %03d  %q %s\n", c.ID, c.DeriveNumber, c.UserName, c.CreatedAt.Format("2006-01-02"))
+	}
+	for _, br := range d.BagRequests {
+		fmt.Fprintf(w, "bag request %d  %s  %s\n", br.ID, br.Email, br.Status)
+	}
+	for _, p := range d.Players {
+		fmt.Fprintf(w, "player %d  %s\n", p.ID, p.Email)
+	}
+	for _, wd := range d.WebhookDeliveries {
+		fmt.Fprintf(w, "webhook delivery %d  %s  %s\n", wd.ID, wd.Event, wd.Status)
+	}
+}
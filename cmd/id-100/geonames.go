@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"id-100/internal/config"
+	"id-100/internal/database"
+	"id-100/internal/geonames"
+	"id-100/internal/meili"
+	"id-100/internal/repository"
+)
+
+// runGeonames implements "id-100 geonames import"
+func runGeonames(args []string) error {
+	if len(args) == 0 || args[0] != "import" {
+		return errors.New("usage: id-100 geonames import --file DE.txt [flags]")
+	}
+
+	config.LoadEnv()
+
+	fs := flag.NewFlagSet("geonames import", flag.ExitOnError)
+	file := fs.String("file", "", "path to an extracted GeoNames dump, e.g. DE.txt (required)")
+	features := fs.String("features", strings.Join(geonames.DefaultFeatureCodes, ","), "comma-separated feature codes to import")
+	countries := fs.String("countries", "", "comma-separated ISO country codes to import (default: all in file)")
+	minPopulation := fs.Int64("min-population", 0, "skip places with a smaller population")
+	target := fs.String("target", "meilisearch", "where to load cities: meilisearch, postgres or both (comma-separated)")
+	index := fs.String("index", geonames.DefaultIndex, "Meilisearch index name")
+	meiliURL := fs.String("meili-url", config.GetGeocodingURL(), "Meilisearch URL")
+	batchSize := fs.Int("batch-size", 5000, "documents per Meilisearch/Postgres batch")
+	fs.Parse(args[1:])
+
+	if *file == "" {
+		fs.Usage()
+		return errors.New("--file is required")
+	}
+
+	toMeili, toPostgres := false, false
+	for _, t := range strings.Split(*target, ",") {
+		switch strings.TrimSpace(t) {
+		case "meilisearch":
+			toMeili = true
+		case "postgres":
+			toPostgres = true
+		default:
+			return fmt.Errorf("unknown target %q", t)
+		}
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cities, err := geonames.Parse(f, geonames.Filter{
+		FeatureCodes:  geonames.SplitList(*features),
+		Countries:     geonames.SplitList(*countries),
+		MinPopulation: *minPopulation,
+	})
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", *file, err)
+	}
+	log.Printf("Parsed %d cities from %s", len(cities), *file)
+
+	ctx := context.Background()
+
+	if toPostgres {
+		database.Init()
+		defer database.Close()
+
+		for start := 0; start < len(cities); start += *batchSize {
+			end := min(start+*batchSize, len(cities))
+			if err := repository.UpsertCities(ctx, cities[start:end]); err != nil {
+				return fmt.Errorf("postgres: %w", err)
+			}
+		}
+		log.Printf("Upserted %d cities into Postgres", len(cities))
+	}
+
+	if toMeili {
+		client := meili.NewClient(*meiliURL, config.GetMeiliMasterKey())
+		if err := geonames.ImportToMeilisearch(ctx, client, *index, cities, *batchSize); err != nil {
+			return fmt.Errorf("meilisearch: %w", err)
+		}
+		log.Printf("Imported %d cities into Meilisearch index %q", len(cities), *index)
+	}
+
+	return nil
+}
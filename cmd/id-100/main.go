@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
 	"time"
 
 	"github.com/getsentry/sentry-go"
@@ -9,16 +11,31 @@ import (
 	"github.com/labstack/echo/v5"
 	"github.com/labstack/echo/v5/middleware"
 
+	"id-100/internal/bagrequest"
+	"id-100/internal/citysearch"
 	"id-100/internal/config"
+	"id-100/internal/consent"
 	"id-100/internal/database"
+	"id-100/internal/email"
+	"id-100/internal/feed"
 	"id-100/internal/handlers"
+	"id-100/internal/handlers/api"
+	"id-100/internal/jobs"
 	appMiddleware "id-100/internal/middleware"
+	"id-100/internal/repository"
 	appSentry "id-100/internal/sentry"
 	"id-100/internal/templates"
+	"id-100/internal/uploads"
 	"id-100/internal/version"
+	"id-100/internal/webhooks"
 )
 
 func main() {
+	// Run a CLI subcommand instead of the server when one is given (e.g. "geonames import")
+	if runSubcommand(os.Args[1:]) {
+		return
+	}
+
 	// Load configuration (validates and caches config values)
 	cfg := config.Load()
 
@@ -41,8 +58,59 @@ func main() {
 	// Initialize session store
 	appMiddleware.InitSessionStore(cfg.SessionSecret, cfg.IsProduction)
 
+	// Bag request opt-in links are signed with the session secret
+	bagrequest.Init(cfg.SessionSecret)
+	if !email.Enabled() {
+		log.Printf("WARNING: SMTP_HOST is not set; bag requests are refused until opt-in emails can be sent")
+	}
+
+	// Consent records store client IPs hashed with the session secret
+	consent.Init(cfg.SessionSecret)
+
+	// City search proxies Meilisearch server-side and falls back to Postgres
+	citysearch.Init(config.GetGeocodingURL(), config.GetMeiliSearchKey(), repository.SearchCities)
+
+	// Background maintenance jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	jobs.Every(jobsCtx, "purge idempotency keys", time.Hour, func(ctx context.Context) error {
+		_, err := repository.PurgeIdempotencyKeys(ctx)
+		return err
+	})
+	jobs.Every(jobsCtx, "clean up stale tus uploads", 30*time.Minute, func(ctx context.Context) error {
+		return uploads.CleanupStaleTus(ctx, 24*time.Hour)
+	})
+	jobs.Every(jobsCtx, "deliver webhooks", 10*time.Second, webhooks.ProcessDue)
+	jobs.Every(jobsCtx, "purge webhook deliveries", 24*time.Hour, func(ctx context.Context) error {
+		_, err := repository.PurgeWebhookDeliveries(ctx, time.Now().Add(-webhooks.DeliveryRetention))
+		return err
+	})
+	jobs.Every(jobsCtx, "purge unconfirmed bag requests", time.Hour, func(ctx context.Context) error {
+		_, err := repository.PurgeUnconfirmedBagRequests(ctx, time.Now().Add(-bagrequest.ConfirmationTTL))
+		return err
+	})
+	jobs.Every(jobsCtx, "purge player login links", time.Hour, func(ctx context.Context) error {
+		_, err := repository.PurgePlayerLoginLinks(ctx, time.Now())
+		return err
+	})
+	jobs.Every(jobsCtx, "send emails", 30*time.Second, email.ProcessOutbox)
+	jobs.Every(jobsCtx, "queue admin digest", time.Hour, email.QueueAdminDigest)
+	jobs.Every(jobsCtx, "purge sent emails", 24*time.Hour, func(ctx context.Context) error {
+		_, err := repository.PurgeEmails(ctx, time.Now().Add(-email.Retention))
+		return err
+	})
+	jobs.Every(jobsCtx, "clean up expired direct uploads", 30*time.Minute, func(ctx context.Context) error {
+		return uploads.CleanupExpiredDirect(ctx, 2*time.Hour)
+	})
+
+	// Live contribution feed: one LISTEN connection per instance fans out to SSE clients
+	feed.Listen(jobsCtx, database.DB, feed.Default, api.LoadFeedContribution)
+
 	e := echo.New()
 
+	// Behind Traefik the client address is in X-Forwarded-For (used for rate limiting)
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+
 	e.Use(middleware.RequestLogger())
 	e.Use(middleware.Recover())
 
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"id-100/internal/config"
+	"id-100/internal/database"
+	"id-100/internal/tokenbatch"
+)
+
+const tokensUsage = `tokens create-batch (--pattern "Werkzeug {n}" --count 30 | --csv bags.csv) [flags]`
+
+// runTokens implements "id-100 tokens create-batch"
+func runTokens(args []string) error {
+	if len(args) == 0 || args[0] != "create-batch" {
+		return errors.New("usage: id-100 " + tokensUsage)
+	}
+
+	config.LoadEnv()
+
+	fs := flag.NewFlagSet("tokens create-batch", flag.ExitOnError)
+	pattern := fs.String("pattern", "", "bag name pattern, {n} is replaced by the running number")
+	start := fs.Int("start", 1, "first number for --pattern")
+	count := fs.Int("count", 0, "number of bags to create with --pattern")
+	csvFile := fs.String("csv", "", "CSV with bag names and optional quotas instead of --pattern")
+	maxUploads := fs.Int("max-uploads", tokenbatch.DefaultMaxUploads, "upload quota per bag (CSV rows may override it)")
+	group := fs.String("group", "", "group label, e.g. the workshop name")
+	expires := fs.String("expires", "", "optional expiry, a date (valid through that day) or RFC 3339 time")
+	format := fs.String("format", "csv", "output format: csv or json")
+	out := fs.String("out", "", "write the export to this file instead of stdout")
+	fs.Parse(args[1:])
+
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	var (
+		entries []tokenbatch.Entry
+		err     error
+	)
+	switch {
+	case *csvFile != "" && *pattern != "":
+		return errors.New("use either --pattern or --csv")
+	case *csvFile != "":
+		f, ferr := os.Open(*csvFile)
+		if ferr != nil {
+			return ferr
+		}
+		entries, err = tokenbatch.ParseCSV(f, *maxUploads)
+		f.Close()
+	case *pattern != "":
+		entries, err = tokenbatch.FromPattern(*pattern, *start, *count, *maxUploads)
+	default:
+		fs.Usage()
+		return errors.New("--pattern or --csv is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	label, err := tokenbatch.NormalizeGroup(*group)
+	if err != nil {
+		return err
+	}
+	expiresAt, err := tokenbatch.ParseExpiry(*expires, time.Now(), time.Local)
+	if err != nil {
+		return err
+	}
+
+	database.Init()
+	defer database.Close()
+
+	tokens, err := tokenbatch.Create(context.Background(), entries, label, expiresAt, config.GetBaseURL())
+	if err != nil {
+		return err
+	}
+	log.Printf("Created %d tokens", len(tokens))
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(tokens)
+	}
+	return tokenbatch.WriteCSV(w, tokens)
+}
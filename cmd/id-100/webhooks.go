@@ -0,0 +1,26 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"id-100/internal/webhooks"
+)
+
+// runWebhooks implements "id-100 webhooks receive", a local receiver for testing subscriptions
+func runWebhooks(args []string) error {
+	if len(args) == 0 || args[0] != "receive" {
+		return errors.New("usage: id-100 webhooks receive [--addr :8090] [--secret SECRET]")
+	}
+
+	fs := flag.NewFlagSet("webhooks receive", flag.ExitOnError)
+	addr := fs.String("addr", ":8090", "address to listen on")
+	secret := fs.String("secret", "", "webhook secret for signature checks (empty: do not check)")
+	fs.Parse(args[1:])
+
+	log.Printf("Receiving webhooks on %s", *addr)
+	return http.ListenAndServe(*addr, &webhooks.Receiver{Secret: *secret, Out: os.Stdout})
+}
@@ -0,0 +1,90 @@
+package attribution
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"id-100/internal/journey"
+)
+
+// Players choose at name entry how their name appears next to their
+// contributions. The choice is stored per session (token_sessions.name_display)
+// and applied when contributions.user_name is written, so every public page,
+// feed and API shows the same name. Admins keep seeing the typed name.
+
+// Style is a display preference for a player name
+type Style string
+
+const (
+	Full         Style = "full"          // "Anna Maria Schmidt"
+	FirstInitial Style = "first_initial" // "Anna S."
+	Initials     Style = "initials"      // "A. M. S."
+	Anonymous    Style = "anonymous"     // "Anonym"
+)
+
+// Default keeps names as typed, like before players could choose
+const Default = Full
+
+// AnonymousName is shown for anonymous contributions and empty names
+const AnonymousName = "Anonym"
+
+// Styles lists all styles in the order the name form offers them
+var Styles = []Style{Full, FirstInitial, Initials, Anonymous}
+
+// Parse validates a submitted style; an empty value is the default
+func Parse(s string) (Style, bool) {
+	if s == "" {
+		return Default, true
+	}
+	for _, style := range Styles {
+		if string(style) == s {
+			return style, true
+		}
+	}
+	return "", false
+}
+
+// Label is the German name of a style for forms and the admin
+func (s Style) Label() string {
+	switch s {
+	case FirstInitial:
+		return "Vorname und Initial"
+	case Initials:
+		return "Nur Initialen"
+	case Anonymous:
+		return "Anonym"
+	}
+	return "Voller Name"
+}
+
+// Apply formats a typed player name in this style
+func (s Style) Apply(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return AnonymousName
+	}
+	switch s {
+	case FirstInitial:
+		return journey.Pseudonym(name)
+	case Initials:
+		parts := strings.Fields(name)
+		for i, p := range parts {
+			r, _ := utf8.DecodeRuneInString(p)
+			parts[i] = string(unicode.ToUpper(r)) + "."
+		}
+		return strings.Join(parts, " ")
+	case Anonymous:
+		return AnonymousName
+	}
+	return name
+}
+
+// Name is the public name of a session: a name the player chose in their
+// account wins over the typed name in the session's style
+func Name(typed, publicName string, s Style) string {
+	if publicName != "" {
+		return publicName
+	}
+	return s.Apply(typed)
+}
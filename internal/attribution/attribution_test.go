@@ -0,0 +1,48 @@
+package attribution
+
+import "testing"
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		style Style
+		in    string
+		want  string
+	}{
+		{Full, "  Anna   Maria Schmidt ", "Anna Maria Schmidt"},
+		{FirstInitial, "Anna Maria Schmidt", "Anna S."},
+		{FirstInitial, "Anna", "Anna"},
+		{Initials, "Anna Maria schmidt", "A. M. S."},
+		{Initials, "jörg", "J."},
+		{Anonymous, "Anna Schmidt", "Anonym"},
+		{Full, "", "Anonym"},
+		{Initials, "   ", "Anonym"},
+	}
+	for _, tt := range tests {
+		if got := tt.style.Apply(tt.in); got != tt.want {
+			t.Errorf("%s.Apply(%q) = %q, want %q", tt.style, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, s := range Styles {
+		if got, ok := Parse(string(s)); !ok || got != s {
+			t.Errorf("Parse(%q) = %q, %v", s, got, ok)
+		}
+	}
+	if got, ok := Parse(""); !ok || got != Default {
+		t.Errorf("Parse(\"\") = %q, %v; want the default", got, ok)
+	}
+	if _, ok := Parse("nickname"); ok {
+		t.Error("Parse accepted an unknown style")
+	}
+}
+
+func TestName(t *testing.T) {
+	if got := Name("Anna Schmidt", "", Initials); got != "A. S." {
+		t.Errorf("Name without public name = %q", got)
+	}
+	if got := Name("Anna Schmidt", "Mia K.", Anonymous); got != "Mia K." {
+		t.Errorf("Name with public name = %q, want the public name", got)
+	}
+}
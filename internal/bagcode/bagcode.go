@@ -0,0 +1,59 @@
+package bagcode
+
+import (
+	"strings"
+)
+
+// Short bag codes like "K7P-4QX" stand in for the 40-character upload token in
+// QR codes and can be typed by hand. Codes are generated by the database
+// (generate_bag_code) and stored as six uppercase characters without hyphen.
+
+const (
+	// Length is the number of characters of a code without hyphen
+	Length = 6
+	// Alphabet is Crockford's base32: no I, L, O or U
+	Alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// typos maps characters that are easily confused when reading a sticker
+var typos = map[rune]rune{'O': '0', 'I': '1', 'L': '1'}
+
+// Normalize turns user input such as "k7p 4qx" or "K7P-4QX" into the stored
+// form and reports whether it is a well-formed code
+func Normalize(input string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(input) {
+		switch {
+		case r == '-' || r == ' ' || r == '\t':
+			continue
+		case typos[r] != 0:
+			r = typos[r]
+		}
+		if !strings.ContainsRune(Alphabet, r) {
+			return "", false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() != Length {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// Format inserts the hyphen for display, "K7P4QX" becomes "K7P-4QX"
+func Format(code string) string {
+	if len(code) != Length {
+		return code
+	}
+	return code[:3] + "-" + code[3:]
+}
+
+// Path is the short link path of a code
+func Path(code string) string {
+	return "/b/" + Format(code)
+}
+
+// URL is the absolute short link encoded in a bag's QR code
+func URL(baseURL, code string) string {
+	return baseURL + Path(code)
+}
@@ -0,0 +1,39 @@
+package bagcode
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"K7P-4QX", "K7P4QX", true},
+		{"k7p4qx", "K7P4QX", true},
+		{" k7p 4qx ", "K7P4QX", true},
+		{"O1L-I00", "011100", true}, // misread characters
+		{"K7P-4QU", "", false},      // U is not in the alphabet
+		{"K7P-4Q", "", false},
+		{"K7P-4QXX", "", false},
+		{"K7P_4QX", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Normalize(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Normalize(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if got := Format("K7P4QX"); got != "K7P-4QX" {
+		t.Errorf("Format = %q", got)
+	}
+	if got := Format("ABC"); got != "ABC" {
+		t.Errorf("Format of short input = %q", got)
+	}
+	if got := URL("https://example.org", "K7P4QX"); got != "https://example.org/b/K7P-4QX" {
+		t.Errorf("URL = %q", got)
+	}
+}
@@ -0,0 +1,51 @@
+package bagrequest
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxAddressFieldLength limits each postal address field
+const maxAddressFieldLength = 200
+
+var (
+	// ErrIncompleteAddress is returned if only some address fields are filled in
+	ErrIncompleteAddress = errors.New("incomplete postal address")
+	// ErrAddressConsent is returned if an address is given without consent to store it
+	ErrAddressConsent = errors.New("postal address given without consent")
+)
+
+// Address is the optional postal address a requester can leave for shipping
+type Address struct {
+	Name       string
+	Street     string
+	PostalCode string
+	City       string
+}
+
+// IsEmpty reports whether no address field is filled in
+func (a Address) IsEmpty() bool {
+	return a.Name == "" && a.Street == "" && a.PostalCode == "" && a.City == ""
+}
+
+// NormalizeAddress trims the fields and checks that the address is either empty
+// or complete, and that the requester consented to storing it
+func NormalizeAddress(a Address, consent bool) (Address, error) {
+	fields := []*string{&a.Name, &a.Street, &a.PostalCode, &a.City}
+	for _, f := range fields {
+		*f = strings.Join(strings.Fields(*f), " ")
+	}
+	if a.IsEmpty() {
+		return a, nil
+	}
+	for _, f := range fields {
+		if *f == "" || utf8.RuneCountInString(*f) > maxAddressFieldLength {
+			return Address{}, ErrIncompleteAddress
+		}
+	}
+	if !consent {
+		return Address{}, ErrAddressConsent
+	}
+	return a, nil
+}
@@ -0,0 +1,105 @@
+package bagrequest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Double opt-in for bag requests. A request is stored unconfirmed and only becomes
+// visible to admins once the requester opens the signed link from the opt-in email.
+
+const (
+	// ConfirmationTTL is how long a confirmation link is valid; unconfirmed
+	// requests are purged afterwards
+	ConfirmationTTL = 48 * time.Hour
+	// MaxPerAddress is how many requests one address may submit within AddressWindow
+	MaxPerAddress = 3
+	// AddressWindow is the period MaxPerAddress applies to
+	AddressWindow = 24 * time.Hour
+	// maxEmailLength is the maximum length of a forward-path address (RFC 5321)
+	maxEmailLength = 254
+	// tokenPurpose separates confirmation signatures from other uses of the secret
+	tokenPurpose = "bag-request-confirm"
+)
+
+var (
+	// ErrInvalidEmail is returned for addresses that cannot receive the opt-in email
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrInvalidToken is returned for malformed or forged confirmation tokens
+	ErrInvalidToken = errors.New("invalid confirmation token")
+	// ErrTokenExpired is returned for correctly signed but expired tokens
+	ErrTokenExpired = errors.New("confirmation token expired")
+)
+
+var secret []byte
+
+// Init sets the secret confirmation tokens are signed with (the session secret)
+func Init(key string) {
+	secret = []byte(key)
+}
+
+// NormalizeEmail validates a submitted address and returns it trimmed with a
+// lowercase domain. Display names ("Mia <mia@example.org>") are rejected.
+func NormalizeEmail(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || len(raw) > maxEmailLength {
+		return "", ErrInvalidEmail
+	}
+	addr, err := mail.ParseAddress(raw)
+	if err != nil || addr.Name != "" || addr.Address != raw {
+		return "", ErrInvalidEmail
+	}
+	local, domain, ok := strings.Cut(addr.Address, "@")
+	if !ok || local == "" || strings.ContainsAny(addr.Address, " \t\r\n") {
+		return "", ErrInvalidEmail
+	}
+	// Require a dotted domain: intranet hosts and IP literals cannot be sent to
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, "[") ||
+		strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return "", ErrInvalidEmail
+	}
+	return local + "@" + strings.ToLower(domain), nil
+}
+
+// ConfirmationToken returns the signed token for the confirmation link of a request:
+// "<id>.<expiry unix>.<signature>"
+func ConfirmationToken(requestID int, expiresAt time.Time) string {
+	payload := strconv.Itoa(requestID) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + sign(payload)
+}
+
+// ParseConfirmationToken verifies a token and returns the request ID it confirms
+func ParseConfirmationToken(token string, now time.Time) (int, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, ErrInvalidToken
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(payload))) {
+		return 0, ErrInvalidToken
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || id < 1 {
+		return 0, ErrInvalidToken
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	if now.After(time.Unix(expires, 0)) {
+		return id, ErrTokenExpired
+	}
+	return id, nil
+}
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(tokenPurpose + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,76 @@
+package bagrequest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	valid := map[string]string{
+		"mia@example.org":         "mia@example.org",
+		"  Mia@Example.ORG ":      "Mia@example.org",
+		"mia.m+werkzeug@mail.de":  "mia.m+werkzeug@mail.de",
+		"m@sub.domain.example.de": "m@sub.domain.example.de",
+	}
+	for in, want := range valid {
+		got, err := NormalizeEmail(in)
+		if err != nil || got != want {
+			t.Errorf("NormalizeEmail(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"@",
+		"mia",
+		"mia@localhost",
+		"mia@[127.0.0.1]",
+		"mia@example..org",
+		"mia@.example.org",
+		"Mia <mia@example.org>",
+		"mia@example.org, tom@example.org",
+		"mia@example.org\r\nBcc: x@example.org",
+		strings.Repeat("a", 250) + "@example.org",
+	}
+	for _, in := range invalid {
+		if got, err := NormalizeEmail(in); !errors.Is(err, ErrInvalidEmail) {
+			t.Errorf("NormalizeEmail(%q) = %q, %v; want ErrInvalidEmail", in, got, err)
+		}
+	}
+}
+
+func TestConfirmationToken(t *testing.T) {
+	Init("test-secret")
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	token := ConfirmationToken(42, now.Add(ConfirmationTTL))
+
+	id, err := ParseConfirmationToken(token, now)
+	if err != nil || id != 42 {
+		t.Fatalf("ParseConfirmationToken = %d, %v; want 42", id, err)
+	}
+
+	if _, err := ParseConfirmationToken(token, now.Add(ConfirmationTTL+time.Second)); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expired token: err = %v, want ErrTokenExpired", err)
+	}
+
+	// Changing the ID or expiry invalidates the signature
+	parts := strings.Split(token, ".")
+	for _, forged := range []string{
+		"43." + parts[1] + "." + parts[2],
+		parts[0] + ".9999999999." + parts[2],
+		parts[0] + "." + parts[1],
+		"",
+		"a.b.c",
+	} {
+		if _, err := ParseConfirmationToken(forged, now); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("ParseConfirmationToken(%q) err = %v, want ErrInvalidToken", forged, err)
+		}
+	}
+
+	Init("other-secret")
+	if _, err := ParseConfirmationToken(token, now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token signed with another secret: err = %v, want ErrInvalidToken", err)
+	}
+}
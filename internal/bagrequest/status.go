@@ -0,0 +1,58 @@
+package bagrequest
+
+// Fulfilment pipeline of a bag request:
+// new → confirmed → token_assigned → shipped → returned / lost
+const (
+	StatusNew           = "new"
+	StatusConfirmed     = "confirmed"
+	StatusTokenAssigned = "token_assigned"
+	StatusShipped       = "shipped"
+	StatusReturned      = "returned"
+	StatusLost          = "lost"
+)
+
+// Statuses lists all statuses in pipeline order
+var Statuses = []string{StatusNew, StatusConfirmed, StatusTokenAssigned, StatusShipped, StatusReturned, StatusLost}
+
+// AdminStatuses are the statuses visible in the admin dashboard (everything after the opt-in)
+var AdminStatuses = Statuses[1:]
+
+// StatusLabels are the German display names of the statuses
+var StatusLabels = map[string]string{
+	StatusNew:           "Unbestätigt",
+	StatusConfirmed:     "Bestätigt",
+	StatusTokenAssigned: "Token zugewiesen",
+	StatusShipped:       "Versendet",
+	StatusReturned:      "Zurück",
+	StatusLost:          "Verloren",
+}
+
+// transitions lists the statuses an admin may move a request to from each status.
+// new → confirmed only happens through the opt-in link, confirmed → token_assigned
+// only by creating the token.
+var transitions = map[string][]string{
+	StatusTokenAssigned: {StatusShipped},
+	StatusShipped:       {StatusReturned, StatusLost},
+	StatusLost:          {StatusReturned},
+}
+
+// IsValidStatus reports whether s is a known status
+func IsValidStatus(s string) bool {
+	_, ok := StatusLabels[s]
+	return ok
+}
+
+// CanTransition reports whether an admin may move a request from one status to another
+func CanTransition(from, to string) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStatuses returns the statuses an admin may move a request to from status
+func NextStatuses(status string) []string {
+	return transitions[status]
+}
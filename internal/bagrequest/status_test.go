@@ -0,0 +1,61 @@
+package bagrequest
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusTokenAssigned, StatusShipped, true},
+		{StatusShipped, StatusReturned, true},
+		{StatusShipped, StatusLost, true},
+		{StatusLost, StatusReturned, true},
+		// opt-in and token creation have their own endpoints
+		{StatusNew, StatusConfirmed, false},
+		{StatusConfirmed, StatusTokenAssigned, false},
+		{StatusConfirmed, StatusShipped, false},
+		{StatusReturned, StatusShipped, false},
+		{StatusShipped, StatusConfirmed, false},
+		{"unknown", StatusShipped, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestStatusLabels(t *testing.T) {
+	for _, s := range Statuses {
+		if !IsValidStatus(s) || StatusLabels[s] == "" {
+			t.Errorf("status %q has no label", s)
+		}
+	}
+	if IsValidStatus("handled") {
+		t.Error("IsValidStatus(\"handled\") = true")
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	a, err := NormalizeAddress(Address{}, false)
+	if err != nil || !a.IsEmpty() {
+		t.Errorf("empty address = %+v, %v", a, err)
+	}
+
+	full := Address{Name: " Mia  Muster ", Street: "Hauptstr. 1", PostalCode: "34117", City: "Kassel"}
+	a, err = NormalizeAddress(full, true)
+	if err != nil || a.Name != "Mia Muster" {
+		t.Errorf("full address = %+v, %v", a, err)
+	}
+
+	if _, err := NormalizeAddress(full, false); !errors.Is(err, ErrAddressConsent) {
+		t.Errorf("address without consent: err = %v, want ErrAddressConsent", err)
+	}
+	if _, err := NormalizeAddress(Address{Name: "Mia", City: "Kassel"}, true); !errors.Is(err, ErrIncompleteAddress) {
+		t.Errorf("partial address: err = %v, want ErrIncompleteAddress", err)
+	}
+}
@@ -0,0 +1,150 @@
+package citysearch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"id-100/internal/meili"
+	"id-100/internal/models"
+)
+
+const (
+	// MinQueryLength is the shortest query that is forwarded to a backend
+	MinQueryLength = 2
+	// MaxQueryLength caps queries so cache keys and SQL parameters stay small
+	MaxQueryLength = 100
+	// DefaultLimit is the number of hits returned when the caller does not ask for more
+	DefaultLimit = 10
+	// MaxLimit is the largest number of hits a caller may request
+	MaxLimit = 20
+
+	cacheSize      = 512
+	cacheTTL       = 10 * time.Minute
+	meiliTimeout   = 2 * time.Second
+	indexName      = "cities"
+	sourceMeili    = "meilisearch"
+	sourcePostgres = "postgres"
+)
+
+// Hit is a single city suggestion returned to the browser
+type Hit struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Lat        float64 `json:"lat"`
+	Lon        float64 `json:"lon"`
+	Type       string  `json:"type"`
+	Population int64   `json:"population"`
+}
+
+// Result is the response of a city search
+type Result struct {
+	Hits   []Hit  `json:"hits"`
+	Source string `json:"source"`
+}
+
+// FallbackFunc searches an alternative store when Meilisearch is unavailable
+type FallbackFunc func(ctx context.Context, query string, limit int) ([]models.City, error)
+
+// Service answers city searches from Meilisearch with a cache and a fallback store
+type Service struct {
+	meili    *meili.Client
+	index    string
+	fallback FallbackFunc
+	cache    *lruCache[Result]
+}
+
+// Default is the service used by the HTTP handler; set up via Init
+var Default *Service
+
+// Init configures the default service
+func Init(meiliURL, apiKey string, fallback FallbackFunc) {
+	Default = New(meili.NewClient(meiliURL, apiKey), fallback)
+}
+
+// New creates a search service backed by the given Meilisearch client
+func New(client *meili.Client, fallback FallbackFunc) *Service {
+	return &Service{
+		meili:    client,
+		index:    indexName,
+		fallback: fallback,
+		cache:    newLRUCache[Result](cacheSize, cacheTTL),
+	}
+}
+
+// NormalizeQuery trims and length-limits a query; ok is false when it is too short to search
+func NormalizeQuery(q string) (string, bool) {
+	q = strings.TrimSpace(q)
+	if runes := []rune(q); len(runes) > MaxQueryLength {
+		q = string(runes[:MaxQueryLength])
+	}
+	return q, len([]rune(q)) >= MinQueryLength
+}
+
+// Search returns up to limit cities matching q
+func (s *Service) Search(ctx context.Context, q string, limit int) (Result, error) {
+	if limit <= 0 || limit > MaxLimit {
+		limit = DefaultLimit
+	}
+	key := fmt.Sprintf("%d:%s", limit, strings.ToLower(q))
+	if res, ok := s.cache.Get(key); ok {
+		return res, nil
+	}
+
+	res, err := s.searchMeili(ctx, q, limit)
+	if err != nil {
+		log.Printf("City search: Meilisearch unavailable, using fallback: %v", err)
+		res, err = s.searchFallback(ctx, q, limit)
+		if err != nil {
+			return Result{}, err
+		}
+		// Do not cache degraded results, so recovery is picked up on the next request
+		return res, nil
+	}
+
+	s.cache.Add(key, res)
+	return res, nil
+}
+
+func (s *Service) searchMeili(ctx context.Context, q string, limit int) (Result, error) {
+	if s.meili == nil {
+		return Result{}, fmt.Errorf("meilisearch not configured")
+	}
+	ctx, cancel := context.WithTimeout(ctx, meiliTimeout)
+	defer cancel()
+
+	hits := []Hit{}
+	err := s.meili.Search(ctx, s.index, meili.SearchRequest{
+		Q:                    q,
+		Limit:                limit,
+		AttributesToRetrieve: []string{"id", "name", "lat", "lon", "type", "population"},
+	}, &hits)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Hits: hits, Source: sourceMeili}, nil
+}
+
+func (s *Service) searchFallback(ctx context.Context, q string, limit int) (Result, error) {
+	if s.fallback == nil {
+		return Result{}, fmt.Errorf("no fallback configured")
+	}
+	cities, err := s.fallback(ctx, q, limit)
+	if err != nil {
+		return Result{}, err
+	}
+	hits := make([]Hit, 0, len(cities))
+	for _, c := range cities {
+		hits = append(hits, Hit{
+			ID:         fmt.Sprintf("%d", c.ID),
+			Name:       c.Name,
+			Lat:        c.Lat,
+			Lon:        c.Lon,
+			Type:       c.Type,
+			Population: c.Population,
+		})
+	}
+	return Result{Hits: hits, Source: sourcePostgres}, nil
+}
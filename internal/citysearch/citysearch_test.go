@@ -0,0 +1,101 @@
+package citysearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"id-100/internal/meili"
+	"id-100/internal/models"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{"  Berlin ", "Berlin", true},
+		{"B", "B", false},
+		{"   ", "", false},
+		{"Kö", "Kö", true},
+		{strings.Repeat("x", 150), strings.Repeat("x", MaxQueryLength), true},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizeQuery(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("NormalizeQuery(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestSearchUsesMeilisearchAndCaches(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path != "/indexes/cities/search" || r.Header.Get("Authorization") != "Bearer search-key" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"hits":[{"id":"2950159","name":"Berlin","lat":52.5,"lon":13.4,"type":"capital","population":3426354}]}`))
+	}))
+	defer srv.Close()
+
+	svc := New(meili.NewClient(srv.URL, "search-key"), nil)
+
+	for i := 0; i < 3; i++ {
+		res, err := svc.Search(context.Background(), "Berl", 5)
+		if err != nil {
+			t.Fatalf("Search returned error: %v", err)
+		}
+		if res.Source != "meilisearch" || len(res.Hits) != 1 || res.Hits[0].Name != "Berlin" {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+	}
+	// Case-insensitive cache key
+	svc.Search(context.Background(), "berl", 5)
+
+	if calls != 1 {
+		t.Errorf("expected 1 Meilisearch call thanks to the cache, got %d", calls)
+	}
+}
+
+func TestSearchFallsBackWhenMeilisearchDown(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var fallbackCalls int
+	fallback := func(ctx context.Context, q string, limit int) ([]models.City, error) {
+		fallbackCalls++
+		return []models.City{{ID: 2873891, Name: "Kassel", Type: "town", Population: 194501}}, nil
+	}
+	svc := New(meili.NewClient(srv.URL, ""), fallback)
+
+	for i := 0; i < 2; i++ {
+		res, err := svc.Search(context.Background(), "Kass", 0)
+		if err != nil {
+			t.Fatalf("Search returned error: %v", err)
+		}
+		if res.Source != "postgres" || len(res.Hits) != 1 || res.Hits[0].ID != "2873891" {
+			t.Fatalf("unexpected fallback result: %+v", res)
+		}
+	}
+	if fallbackCalls != 2 {
+		t.Errorf("fallback results should not be cached, got %d calls", fallbackCalls)
+	}
+}
+
+func TestSearchFallbackError(t *testing.T) {
+	svc := New(nil, func(ctx context.Context, q string, limit int) ([]models.City, error) {
+		return nil, errors.New("db down")
+	})
+	if _, err := svc.Search(context.Background(), "Kassel", 5); err == nil {
+		t.Fatal("expected error when both backends fail")
+	}
+}
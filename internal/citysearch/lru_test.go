@@ -0,0 +1,64 @@
+package citysearch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRUCache[int](2, time.Minute)
+	c.Add("a", 1)
+	c.Add("b", 2)
+
+	// Touch "a" so "b" becomes the eviction candidate
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Errorf("Get(c) = %v, %v; want 3, true", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+}
+
+func TestLRUCacheUpdateExisting(t *testing.T) {
+	c := newLRUCache[string](2, time.Minute)
+	c.Add("a", "old")
+	c.Add("a", "new")
+
+	if v, _ := c.Get("a"); v != "new" {
+		t.Errorf("Get(a) = %q, want %q", v, "new")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.Len())
+	}
+}
+
+func TestLRUCacheExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := newLRUCache[int](4, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Add("a", 1)
+	now = now.Add(30 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("entry should still be valid before TTL")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("entry should expire after TTL")
+	}
+	if c.Len() != 0 {
+		t.Errorf("expired entry should be removed, Len() = %d", c.Len())
+	}
+}
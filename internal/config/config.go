@@ -19,9 +19,14 @@ type Config struct {
 	SentryDSN     string // SentryDSN is the Data Source Name for Sentry error tracking
 }
 
+// LoadEnv loads variables from a local .env file if present
+func LoadEnv() {
+	godotenv.Load()
+}
+
 // Load loads configuration from environment variables
 func Load() *Config {
-	godotenv.Load()
+	LoadEnv()
 
 	isProduction := IsProduction()
 
@@ -7,7 +7,8 @@ const (
 	DefaultGeocodingURL = "http://localhost:8081"
 )
 
-// GetGeocodingURL returns the Meilisearch/geocoding API URL from environment or default
+// GetGeocodingURL returns the Meilisearch/geocoding API URL from environment or default.
+// It is only used server-side; browsers query /api/cities instead.
 func GetGeocodingURL() string {
 	geocodingURL := os.Getenv("GEOCODING_API_URL")
 	if geocodingURL == "" {
@@ -21,7 +22,7 @@ func GetMeiliMasterKey() string {
 	return os.Getenv("MEILI_MASTER_KEY")
 }
 
-// GetMeiliSearchKey returns the Meilisearch search key (for frontend/read-only access)
+// GetMeiliSearchKey returns the read-only Meilisearch search key used by /api/cities
 func GetMeiliSearchKey() string {
 	return os.Getenv("MEILI_SEARCH_KEY")
 }
@@ -0,0 +1,49 @@
+package config
+
+import "os"
+
+const (
+	// DefaultSMTPPort is the submission port used if SMTP_PORT is not set
+	DefaultSMTPPort = "587"
+	// DefaultMailFrom is the sender address used if MAIL_FROM is not set
+	DefaultMailFrom = "ID-100 <noreply@localhost>"
+)
+
+// GetSMTPHost returns the SMTP server host. Email is disabled if it is empty.
+func GetSMTPHost() string {
+	return os.Getenv("SMTP_HOST")
+}
+
+// GetSMTPPort returns the SMTP server port from environment or default
+func GetSMTPPort() string {
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = DefaultSMTPPort
+	}
+	return port
+}
+
+// GetSMTPUsername returns the SMTP login; no authentication is attempted if it is empty
+func GetSMTPUsername() string {
+	return os.Getenv("SMTP_USERNAME")
+}
+
+// GetSMTPPassword returns the SMTP password
+func GetSMTPPassword() string {
+	return os.Getenv("SMTP_PASSWORD")
+}
+
+// GetMailFrom returns the sender address for outgoing email
+func GetMailFrom() string {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = DefaultMailFrom
+	}
+	return from
+}
+
+// GetAdminEmail returns the address that receives the bag request digest.
+// The digest is not sent if it is empty.
+func GetAdminEmail() string {
+	return os.Getenv("ADMIN_EMAIL")
+}
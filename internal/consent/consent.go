@@ -0,0 +1,51 @@
+package consent
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Players agree to the Datenschutzerklärung when they enter their name. Each
+// agreement is stored with the policy version and the exact texts shown, so we
+// can prove what a session consented to. Sessions whose latest consent is for
+// an older version are asked again before they can upload.
+
+// PolicyVersion is the version of the Datenschutzerklärung. Bump it whenever
+// datenschutz.html changes in substance.
+const PolicyVersion = "2026-10-18"
+
+// Text is the consent checkbox shown with the name form and for re-consent
+const Text = "Ich bestätige, dass ich nur Bilder hochlade, die keine erkennbaren Personen ohne Einwilligung zeigen, und stimme der Datenschutzerklärung zu."
+
+// Sources of a consent record
+const (
+	SourceWeb = "web" // name form or re-consent on /upload
+	SourceAPI = "api" // participant API
+)
+
+// ipPurpose separates IP hashes from other uses of the secret
+const ipPurpose = "consent-ip:"
+
+var secret []byte
+
+// Init sets the secret IP addresses are hashed with (the session secret)
+func Init(key string) {
+	secret = []byte(key)
+}
+
+// Texts returns the consent texts shown for the current policy version
+func Texts() []string {
+	return []string{Text}
+}
+
+// HashIP returns a keyed hash of a client IP. Without the secret it cannot be
+// reversed by trying all addresses; an empty IP hashes to "".
+func HashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ipPurpose + ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,32 @@
+package consent
+
+import "testing"
+
+func TestHashIP(t *testing.T) {
+	Init("secret-a")
+	a := HashIP("203.0.113.7")
+	if a == "" || a == "203.0.113.7" {
+		t.Fatalf("HashIP = %q", a)
+	}
+	if HashIP("203.0.113.7") != a {
+		t.Error("HashIP is not deterministic")
+	}
+	if HashIP("203.0.113.8") == a {
+		t.Error("different IPs share a hash")
+	}
+	if HashIP("") != "" {
+		t.Error("empty IP should hash to an empty string")
+	}
+
+	Init("secret-b")
+	if HashIP("203.0.113.7") == a {
+		t.Error("hash does not depend on the secret")
+	}
+}
+
+func TestTexts(t *testing.T) {
+	texts := Texts()
+	if len(texts) == 0 || texts[0] != Text {
+		t.Errorf("Texts() = %q", texts)
+	}
+}
@@ -0,0 +1,20 @@
+-- Migration: 003_create_cities.sql
+-- Description: Stores GeoNames populated places imported via `id-100 geonames import`
+
+-- Table: cities
+-- One row per GeoNames feature, keyed by the geonameid so re-imports update in place
+CREATE TABLE IF NOT EXISTS cities (
+    id BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    ascii_name TEXT NOT NULL DEFAULT '',
+    country_code TEXT NOT NULL DEFAULT '',
+    feature_code TEXT NOT NULL DEFAULT '',
+    type TEXT NOT NULL DEFAULT 'city',
+    lat DOUBLE PRECISION NOT NULL DEFAULT 0,
+    lon DOUBLE PRECISION NOT NULL DEFAULT 0,
+    population BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cities_country_code ON cities(country_code);
+CREATE INDEX IF NOT EXISTS idx_cities_population ON cities(population DESC);
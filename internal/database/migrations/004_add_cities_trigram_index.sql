@@ -0,0 +1,6 @@
+-- Migration: 004_add_cities_trigram_index.sql
+-- Description: Trigram index so /api/cities can fall back to Postgres when Meilisearch is down
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_cities_name_trgm ON cities USING GIN (lower(name) gin_trgm_ops);
@@ -0,0 +1,43 @@
+-- Migration: 005_add_search_vectors.sql
+-- Description: Full-text search vectors for /suche, kept up to date by triggers
+
+-- Deriven: title and description, German stemming
+ALTER TABLE deriven ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION deriven_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('german', COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('german', COALESCE(NEW.description, '')), 'B');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_deriven_search_vector ON deriven;
+CREATE TRIGGER trg_deriven_search_vector
+    BEFORE INSERT OR UPDATE OF title, description ON deriven
+    FOR EACH ROW EXECUTE FUNCTION deriven_search_vector_update();
+
+-- Contributions: comment text with German stemming, city name unstemmed ('simple')
+ALTER TABLE contributions ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION contributions_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('simple', COALESCE(NEW.user_city, '')), 'A') ||
+        setweight(to_tsvector('german', COALESCE(NEW.user_comment, '')), 'B');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_contributions_search_vector ON contributions;
+CREATE TRIGGER trg_contributions_search_vector
+    BEFORE INSERT OR UPDATE OF user_city, user_comment ON contributions
+    FOR EACH ROW EXECUTE FUNCTION contributions_search_vector_update();
+
+-- Backfill existing rows (fires the triggers above)
+UPDATE deriven SET title = title;
+UPDATE contributions SET user_comment = user_comment;
+
+CREATE INDEX IF NOT EXISTS idx_deriven_search_vector ON deriven USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_contributions_search_vector ON contributions USING GIN (search_vector);
@@ -0,0 +1,13 @@
+-- Migration: 006_create_upload_idempotency_keys.sql
+-- Description: Idempotency keys for the participant upload API so retried uploads return the original contribution
+
+CREATE TABLE IF NOT EXISTS upload_idempotency_keys (
+    token_id INTEGER NOT NULL REFERENCES upload_tokens(id) ON DELETE CASCADE,
+    idempotency_key TEXT NOT NULL,
+    -- NULL while the upload is still being processed
+    contribution_id INTEGER REFERENCES contributions(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (token_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_idempotency_keys_created_at ON upload_idempotency_keys(created_at);
@@ -0,0 +1,29 @@
+-- Migration: 007_create_tus_uploads.sql
+-- Description: State of resumable (tus) uploads; chunk bytes live in object storage under tus/<id>/
+
+CREATE TABLE IF NOT EXISTS tus_uploads (
+    id TEXT PRIMARY KEY,
+    token_id INTEGER NOT NULL REFERENCES upload_tokens(id) ON DELETE CASCADE,
+    session_number INTEGER NOT NULL,
+    derive_number INTEGER NOT NULL,
+    user_comment TEXT NOT NULL DEFAULT '',
+    upload_length BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    contribution_id INTEGER REFERENCES contributions(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_tus_uploads_token ON tus_uploads(token_id, session_number);
+CREATE INDEX IF NOT EXISTS idx_tus_uploads_updated_at ON tus_uploads(updated_at) WHERE completed_at IS NULL;
+
+-- Table: tus_upload_chunks
+-- One stored object per accepted PATCH, concatenated in offset order on completion
+CREATE TABLE IF NOT EXISTS tus_upload_chunks (
+    upload_id TEXT NOT NULL REFERENCES tus_uploads(id) ON DELETE CASCADE,
+    chunk_offset BIGINT NOT NULL,
+    size BIGINT NOT NULL,
+    object_key TEXT NOT NULL,
+    PRIMARY KEY (upload_id, chunk_offset)
+);
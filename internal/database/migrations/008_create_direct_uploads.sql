@@ -0,0 +1,18 @@
+-- Migration: 008_create_direct_uploads.sql
+-- Description: Presigned direct-to-storage uploads waiting for finalisation
+
+CREATE TABLE IF NOT EXISTS direct_uploads (
+    id TEXT PRIMARY KEY,
+    token_id INTEGER NOT NULL REFERENCES upload_tokens(id) ON DELETE CASCADE,
+    session_number INTEGER NOT NULL,
+    derive_number INTEGER NOT NULL,
+    object_key TEXT NOT NULL UNIQUE,
+    content_type TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    contribution_id INTEGER REFERENCES contributions(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finalized_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_direct_uploads_token ON direct_uploads(token_id) WHERE finalized_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_direct_uploads_expires_at ON direct_uploads(expires_at) WHERE finalized_at IS NULL;
@@ -0,0 +1,26 @@
+-- Migration: 009_add_contribution_notify.sql
+-- Description: NOTIFY on channel 'contributions' whenever a contribution is published or removed (live feed)
+
+CREATE OR REPLACE FUNCTION contributions_notify() RETURNS trigger AS $$
+DECLARE
+    row contributions%ROWTYPE;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        row := OLD;
+    ELSE
+        row := NEW;
+    END IF;
+    PERFORM pg_notify('contributions', json_build_object(
+        'op', lower(TG_OP),
+        'id', row.id,
+        'derive_number', (SELECT number FROM deriven WHERE id = row.derive_id),
+        'city', COALESCE(row.user_city, '')
+    )::text);
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_contributions_notify ON contributions;
+CREATE TRIGGER trg_contributions_notify
+    AFTER INSERT OR DELETE ON contributions
+    FOR EACH ROW EXECUTE FUNCTION contributions_notify();
@@ -0,0 +1,12 @@
+-- Migration: 012_add_bag_request_opt_in.sql
+-- Description: Double opt-in for bag requests; only confirmed requests are shown to admins
+
+ALTER TABLE bag_requests ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMPTZ;
+
+-- Requests made before the opt-in existed count as confirmed
+UPDATE bag_requests SET confirmed_at = created_at WHERE confirmed_at IS NULL;
+
+-- Per-address rate limit
+CREATE INDEX IF NOT EXISTS idx_bag_requests_email_created_at ON bag_requests(LOWER(email), created_at DESC);
+-- Purge of expired unconfirmed requests
+CREATE INDEX IF NOT EXISTS idx_bag_requests_unconfirmed ON bag_requests(created_at) WHERE confirmed_at IS NULL;
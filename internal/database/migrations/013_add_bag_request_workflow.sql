@@ -0,0 +1,33 @@
+-- Migration: 013_add_bag_request_workflow.sql
+-- Description: Fulfilment pipeline for bag requests (replaces the handled flag), admin notes,
+-- optional postal address and the upload token created for the request
+
+ALTER TABLE bag_requests
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'new',
+    ADD COLUMN IF NOT EXISTS admin_notes TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS shipping_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS shipping_street TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS shipping_postal_code TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS shipping_city TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS address_consent_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS token_id INTEGER REFERENCES upload_tokens(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS token_assigned_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS shipped_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS returned_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS lost_at TIMESTAMPTZ;
+
+-- Handled requests were shipped; the exact time is unknown
+UPDATE bag_requests SET status = CASE
+    WHEN handled THEN 'shipped'
+    WHEN confirmed_at IS NOT NULL THEN 'confirmed'
+    ELSE 'new'
+END;
+
+ALTER TABLE bag_requests ADD CONSTRAINT bag_requests_status_check
+    CHECK (status IN ('new', 'confirmed', 'token_assigned', 'shipped', 'returned', 'lost'));
+
+DROP INDEX IF EXISTS idx_bag_requests_handled;
+ALTER TABLE bag_requests DROP COLUMN IF EXISTS handled;
+
+CREATE INDEX IF NOT EXISTS idx_bag_requests_status ON bag_requests(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_bag_requests_token_id ON bag_requests(token_id);
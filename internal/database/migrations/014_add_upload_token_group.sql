@@ -0,0 +1,6 @@
+-- Migration: 014_add_upload_token_group.sql
+-- Description: Optional group label for tokens created in one batch, e.g. per workshop
+
+ALTER TABLE upload_tokens ADD COLUMN IF NOT EXISTS group_label TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_upload_tokens_group_label ON upload_tokens(group_label) WHERE group_label <> '';
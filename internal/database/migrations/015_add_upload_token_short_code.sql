@@ -0,0 +1,37 @@
+-- Migration: 015_add_upload_token_short_code.sql
+-- Description: Short human-typeable bag codes (e.g. K7P-4QX) for QR codes and manual entry
+
+-- Six characters of Crockford base32 (no I, L, O, U), stored without the hyphen.
+-- The randomness comes from gen_random_uuid(), which uses a secure generator.
+CREATE OR REPLACE FUNCTION generate_bag_code() RETURNS TEXT AS $$
+DECLARE
+    alphabet CONSTANT TEXT := '0123456789ABCDEFGHJKMNPQRSTVWXYZ';
+    bytes BYTEA;
+    code TEXT;
+BEGIN
+    LOOP
+        bytes := uuid_send(gen_random_uuid());
+        code := '';
+        FOR i IN 0..5 LOOP
+            code := code || substr(alphabet, 1 + (get_byte(bytes, i) & 31), 1);
+        END LOOP;
+        EXIT WHEN NOT EXISTS (SELECT 1 FROM upload_tokens WHERE short_code = code);
+    END LOOP;
+    RETURN code;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+ALTER TABLE upload_tokens ADD COLUMN IF NOT EXISTS short_code TEXT;
+
+DO $$
+DECLARE
+    t RECORD;
+BEGIN
+    FOR t IN SELECT id FROM upload_tokens WHERE short_code IS NULL ORDER BY id LOOP
+        UPDATE upload_tokens SET short_code = generate_bag_code() WHERE id = t.id;
+    END LOOP;
+END $$;
+
+ALTER TABLE upload_tokens ALTER COLUMN short_code SET DEFAULT generate_bag_code();
+ALTER TABLE upload_tokens ALTER COLUMN short_code SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_upload_tokens_short_code ON upload_tokens(short_code);
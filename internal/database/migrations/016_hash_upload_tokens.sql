@@ -0,0 +1,21 @@
+-- Migration: 016_hash_upload_tokens.sql
+-- Description: Store upload tokens as SHA-256 hashes instead of plain text, add optional expiry and rotation time
+
+ALTER TABLE upload_tokens ADD COLUMN IF NOT EXISTS token_hash TEXT;
+ALTER TABLE upload_tokens ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE upload_tokens ADD COLUMN IF NOT EXISTS token_rotated_at TIMESTAMPTZ;
+
+-- Hex-encoded SHA-256 of the token, matching utils.HashToken
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'upload_tokens' AND column_name = 'token') THEN
+        UPDATE upload_tokens SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex') WHERE token_hash IS NULL;
+    END IF;
+END $$;
+
+ALTER TABLE upload_tokens ALTER COLUMN token_hash SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_upload_tokens_token_hash ON upload_tokens(token_hash);
+
+-- The plain token is only shown once when it is created or rotated
+DROP INDEX IF EXISTS idx_upload_tokens_token;
+ALTER TABLE upload_tokens DROP COLUMN IF EXISTS token;
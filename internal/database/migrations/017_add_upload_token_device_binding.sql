@@ -0,0 +1,9 @@
+-- Migration: 017_add_upload_token_device_binding.sql
+-- Description: Bind a running bag session to the device that started it and record handover requests
+
+-- Device ID from the session cookie of the device that set the player name
+ALTER TABLE upload_tokens ADD COLUMN IF NOT EXISTS session_device TEXT;
+
+-- A pending request of another device to take the bag over
+ALTER TABLE upload_tokens ADD COLUMN IF NOT EXISTS handover_device TEXT;
+ALTER TABLE upload_tokens ADD COLUMN IF NOT EXISTS handover_requested_at TIMESTAMPTZ;
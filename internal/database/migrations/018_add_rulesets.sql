@@ -0,0 +1,20 @@
+-- Migration: 018_add_rulesets.sql
+-- Description: Named rulesets for cooldown, upload quotas, session time limits and allowed derives, assignable per bag
+
+-- Limits of 0 and an empty derive list mean "no restriction"
+CREATE TABLE IF NOT EXISTS rulesets (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    cooldown_seconds INTEGER NOT NULL DEFAULT 5 CHECK (cooldown_seconds >= 0),
+    session_uploads INTEGER NOT NULL DEFAULT 0 CHECK (session_uploads >= 0),
+    lifetime_uploads INTEGER NOT NULL DEFAULT 0 CHECK (lifetime_uploads >= 0),
+    session_minutes INTEGER NOT NULL DEFAULT 0 CHECK (session_minutes >= 0),
+    idle_minutes INTEGER NOT NULL DEFAULT 0 CHECK (idle_minutes >= 0),
+    allowed_derives INTEGER[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Bags without a ruleset use the built-in defaults
+ALTER TABLE upload_tokens ADD COLUMN IF NOT EXISTS ruleset_id INTEGER REFERENCES rulesets(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_upload_tokens_ruleset ON upload_tokens(ruleset_id);
@@ -0,0 +1,62 @@
+-- Migration: 019_create_token_sessions.sql
+-- Description: History of bag sessions (player, city, start and end), kept in sync with upload_tokens by a trigger
+
+CREATE TABLE IF NOT EXISTS token_sessions (
+    token_id INTEGER NOT NULL REFERENCES upload_tokens(id) ON DELETE CASCADE,
+    session_number INTEGER NOT NULL,
+    player_name TEXT NOT NULL DEFAULT '',
+    player_city TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMPTZ,
+    PRIMARY KEY (token_id, session_number)
+);
+
+-- A new session number ends the previous session; setting a player starts
+-- (or renames) the current one. Covers every code path that resets, ends,
+-- hands over or assigns a bag.
+CREATE OR REPLACE FUNCTION upload_tokens_track_session() RETURNS trigger AS $$
+BEGIN
+    IF NEW.total_sessions IS DISTINCT FROM OLD.total_sessions THEN
+        UPDATE token_sessions SET ended_at = NOW()
+        WHERE token_id = OLD.id AND session_number = OLD.total_sessions AND ended_at IS NULL;
+    END IF;
+
+    IF COALESCE(NEW.current_player, '') <> '' AND (
+        NEW.current_player IS DISTINCT FROM OLD.current_player
+        OR NEW.current_player_city IS DISTINCT FROM OLD.current_player_city
+        OR NEW.total_sessions IS DISTINCT FROM OLD.total_sessions
+    ) THEN
+        INSERT INTO token_sessions (token_id, session_number, player_name, player_city, started_at)
+        VALUES (NEW.id, NEW.total_sessions, NEW.current_player, COALESCE(NEW.current_player_city, ''),
+                COALESCE(NEW.session_started_at, NOW()))
+        ON CONFLICT (token_id, session_number) DO UPDATE
+            SET player_name = EXCLUDED.player_name,
+                player_city = EXCLUDED.player_city,
+                ended_at = NULL;
+    END IF;
+
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_upload_tokens_track_session ON upload_tokens;
+CREATE TRIGGER trg_upload_tokens_track_session
+    AFTER UPDATE ON upload_tokens
+    FOR EACH ROW EXECUTE FUNCTION upload_tokens_track_session();
+
+-- Backfill: running sessions first, then past sessions from the upload log
+-- (start and end approximated by the first and last upload)
+INSERT INTO token_sessions (token_id, session_number, player_name, player_city, started_at)
+SELECT id, total_sessions, current_player, COALESCE(current_player_city, ''), COALESCE(session_started_at, created_at)
+FROM upload_tokens
+WHERE COALESCE(current_player, '') <> ''
+ON CONFLICT DO NOTHING;
+
+INSERT INTO token_sessions (token_id, session_number, player_name, player_city, started_at, ended_at)
+SELECT l.token_id, l.session_number, MAX(l.player_name), COALESCE(MAX(c.user_city), ''), COALESCE(MIN(l.uploaded_at), NOW()),
+       CASE WHEN l.session_number <> t.total_sessions THEN MAX(l.uploaded_at) END
+FROM upload_logs l
+JOIN upload_tokens t ON t.id = l.token_id
+LEFT JOIN contributions c ON c.id = l.contribution_id
+GROUP BY l.token_id, l.session_number, t.total_sessions
+ON CONFLICT DO NOTHING;
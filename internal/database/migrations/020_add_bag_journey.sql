@@ -0,0 +1,39 @@
+-- Migration: 020_add_bag_journey.sql
+-- Description: Public journey slug per bag (/werkzeug/<slug>) and per-session opt-out from the public journey
+
+-- Ten characters of lowercase Crockford base32, like generate_bag_code
+CREATE OR REPLACE FUNCTION generate_journey_slug() RETURNS TEXT AS $$
+DECLARE
+    alphabet CONSTANT TEXT := '0123456789abcdefghjkmnpqrstvwxyz';
+    bytes BYTEA;
+    slug TEXT;
+BEGIN
+    LOOP
+        bytes := uuid_send(gen_random_uuid());
+        slug := '';
+        FOR i IN 0..9 LOOP
+            slug := slug || substr(alphabet, 1 + (get_byte(bytes, i) & 31), 1);
+        END LOOP;
+        EXIT WHEN NOT EXISTS (SELECT 1 FROM upload_tokens WHERE journey_slug = slug);
+    END LOOP;
+    RETURN slug;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+ALTER TABLE upload_tokens ADD COLUMN IF NOT EXISTS journey_slug TEXT;
+
+DO $$
+DECLARE
+    t RECORD;
+BEGIN
+    FOR t IN SELECT id FROM upload_tokens WHERE journey_slug IS NULL ORDER BY id LOOP
+        UPDATE upload_tokens SET journey_slug = generate_journey_slug() WHERE id = t.id;
+    END LOOP;
+END $$;
+
+ALTER TABLE upload_tokens ALTER COLUMN journey_slug SET DEFAULT generate_journey_slug();
+ALTER TABLE upload_tokens ALTER COLUMN journey_slug SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_upload_tokens_journey_slug ON upload_tokens(journey_slug);
+
+-- Players can keep their session out of the public journey
+ALTER TABLE token_sessions ADD COLUMN IF NOT EXISTS journey_hidden BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+-- Migration: 021_add_session_recaps.sql
+-- Description: Permanent, unguessable recap link for sessions ended by the player
+
+ALTER TABLE token_sessions ADD COLUMN IF NOT EXISTS recap_key TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_token_sessions_recap_key ON token_sessions(recap_key);
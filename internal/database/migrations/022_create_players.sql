@@ -0,0 +1,34 @@
+-- Migration: 022_create_players.sql
+-- Description: Optional player accounts (recovery code or email magic link) linking sessions across bags
+
+CREATE TABLE IF NOT EXISTS players (
+    id SERIAL PRIMARY KEY,
+    recovery_code_hash TEXT NOT NULL UNIQUE,
+    email TEXT,
+    -- Name shown publicly instead of the name typed in at each bag; NULL keeps those
+    public_name TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_players_email ON players (LOWER(email)) WHERE email IS NOT NULL;
+
+-- Single-use magic links; only the hash of the emailed token is stored. A
+-- link requested while logged in adds the address to that player.
+CREATE TABLE IF NOT EXISTS player_login_links (
+    token_hash TEXT PRIMARY KEY,
+    email TEXT NOT NULL,
+    player_id INTEGER REFERENCES players(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_player_login_links_email ON player_login_links (LOWER(email), created_at);
+
+-- Sessions a player linked to their account. public_name overrides
+-- player_name on public pages; admins keep seeing player_name.
+ALTER TABLE token_sessions ADD COLUMN IF NOT EXISTS player_id INTEGER REFERENCES players(id) ON DELETE SET NULL;
+ALTER TABLE token_sessions ADD COLUMN IF NOT EXISTS public_name TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_token_sessions_player ON token_sessions (player_id) WHERE player_id IS NOT NULL;
@@ -0,0 +1,6 @@
+-- Migration: 023_add_session_name_display.sql
+-- Description: Per-session display preference for the player name next to contributions
+
+-- 'full' keeps names as typed, which is how existing contributions are shown
+ALTER TABLE token_sessions ADD COLUMN IF NOT EXISTS name_display TEXT NOT NULL DEFAULT 'full'
+    CHECK (name_display IN ('full', 'first_initial', 'initials', 'anonymous'));
@@ -0,0 +1,19 @@
+-- Migration: 024_create_consents.sql
+-- Description: Versioned consent records for the privacy policy agreement at name entry
+
+CREATE TABLE IF NOT EXISTS consents (
+    id SERIAL PRIMARY KEY,
+    -- Kept when a bag is deleted: the record is the proof of consent
+    token_id INTEGER REFERENCES upload_tokens(id) ON DELETE SET NULL,
+    session_number INTEGER NOT NULL,
+    policy_version TEXT NOT NULL,
+    -- The exact consent texts shown
+    texts TEXT[] NOT NULL,
+    source TEXT NOT NULL CHECK (source IN ('web', 'api')),
+    -- Keyed hash of the client IP (consent.HashIP)
+    ip_hash TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_consents_session ON consents (token_id, session_number, policy_version);
+CREATE INDEX IF NOT EXISTS idx_consents_created_at ON consents (created_at);
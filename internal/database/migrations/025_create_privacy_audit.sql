@@ -0,0 +1,22 @@
+-- Migration: 025_create_privacy_audit.sql
+-- Description: Audit log of data subject access (export) and erasure requests
+
+CREATE TABLE IF NOT EXISTS privacy_audit (
+    id SERIAL PRIMARY KEY,
+    action TEXT NOT NULL CHECK (action IN ('export', 'erase')),
+    -- Who ran the request, e.g. "admin:alice" or "cli:root"
+    actor TEXT NOT NULL,
+    -- Free text such as a ticket number linking to the request itself
+    reference TEXT NOT NULL DEFAULT '',
+    -- Kinds of criteria used (name, session, email, player); the values are
+    -- personal data and are never stored here
+    criteria TEXT[] NOT NULL,
+    -- Number of records per kind found (export) or erased (erase)
+    counts JSONB NOT NULL DEFAULT '{}',
+    -- Erasure only: whether a search afterwards found nothing left
+    verified BOOLEAN,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_privacy_audit_created_at ON privacy_audit (created_at DESC);
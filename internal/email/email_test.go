@@ -0,0 +1,268 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"id-100/internal/models"
+)
+
+var testFrom = &mail.Address{Name: "ID-100", Address: "noreply@id-100.example"}
+
+func TestBuildMultipart(t *testing.T) {
+	raw, err := Build(testFrom, Message{
+		To:      "mia@example.org",
+		Subject: "Dein Werkzeug ist unterwegs",
+		Text:    "Grüße\nID-100",
+		HTML:    "<p>Grüße</p>",
+	}, time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Dein Werkzeug ist unterwegs" {
+		t.Errorf("Subject = %q, %v", subject, err)
+	}
+	if got := msg.Header.Get("To"); got != "<mia@example.org>" {
+		t.Errorf("To = %q", got)
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@id-100.example>") {
+		t.Errorf("Message-ID = %q", msg.Header.Get("Message-ID"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "Grüße\r\nID-100"},
+		{"text/html; charset=utf-8", "<p>Grüße</p>"},
+	}
+	for _, w := range want {
+		part, err := mr.NextRawPart()
+		if err != nil {
+			t.Fatalf("NextRawPart: %v", err)
+		}
+		if got := part.Header.Get("Content-Type"); got != w.contentType {
+			t.Errorf("part Content-Type = %q, want %q", got, w.contentType)
+		}
+		body, _ := io.ReadAll(quotedprintable.NewReader(part))
+		if string(body) != w.body {
+			t.Errorf("part body = %q, want %q", body, w.body)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("expected two parts, got more (%v)", err)
+	}
+}
+
+func TestBuildPlainText(t *testing.T) {
+	raw, err := Build(testFrom, Message{To: "mia@example.org", Subject: "Hallo", Text: "nur Text"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := msg.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestBuildRejectsInvalidRecipient(t *testing.T) {
+	for _, to := range []string{"", "keine-adresse", "a@b.de\r\nBcc: x@y.de"} {
+		if _, err := Build(testFrom, Message{To: to, Text: "x"}, time.Now()); err == nil {
+			t.Errorf("Build(To: %q) should fail", to)
+		}
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	created := time.Date(2026, 5, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		data        interface{}
+		wantSubject string
+		wantText    string
+	}{
+		{
+			TemplateBagRequestConfirmation,
+			BagRequestConfirmation{
+				BaseURL:    "https://id-100.example",
+				RequestID:  7,
+				CreatedAt:  created,
+				ConfirmURL: "https://id-100.example/werkzeug-anfordern/bestaetigen?token=7.1.abc",
+				ExpiresAt:  created.Add(48 * time.Hour),
+			},
+			"Bitte bestätige deine Anfrage für ein Werkzeug",
+			"https://id-100.example/werkzeug-anfordern/bestaetigen?token=7.1.abc\n\nDer Link ist bis 03.05.2026 12:30 Uhr gültig.",
+		},
+		{
+			TemplateBagShipped,
+			BagShipped{BaseURL: "https://id-100.example", RequestID: 7},
+			"Dein Werkzeug ist unterwegs",
+			"https://id-100.example/leitfaden",
+		},
+		{
+			TemplateAdminDigest,
+			AdminDigest{BaseURL: "https://id-100.example", Since: created, Requests: []models.BagRequest{
+				{ID: 1, Email: "a@example.org", CreatedAt: created},
+				{ID: 2, Email: "b@example.org", CreatedAt: created},
+			}},
+			"ID-100: 2 neue Werkzeug-Anfragen",
+			"- 01.05.2026 12:30  b@example.org (Nr. 2)",
+		},
+		{
+			TemplatePlayerLogin,
+			PlayerLogin{
+				BaseURL:   "https://id-100.example",
+				LoginURL:  "https://id-100.example/spieler/anmelden/abc",
+				ExpiresAt: created.Add(30 * time.Minute),
+			},
+			"Dein Anmeldelink für ID-100",
+			"https://id-100.example/spieler/anmelden/abc\n\nDer Link ist bis 01.05.2026 13:00 Uhr gültig",
+		},
+	}
+	for _, tt := range tests {
+		m, err := Render(tt.name, "mia@example.org", tt.data)
+		if err != nil {
+			t.Errorf("Render(%s): %v", tt.name, err)
+			continue
+		}
+		if m.Subject != tt.wantSubject {
+			t.Errorf("%s subject = %q, want %q", tt.name, m.Subject, tt.wantSubject)
+		}
+		if !strings.Contains(m.Text, tt.wantText) {
+			t.Errorf("%s text does not contain %q:\n%s", tt.name, tt.wantText, m.Text)
+		}
+		if !strings.HasPrefix(m.HTML, "<!DOCTYPE html>") || !strings.Contains(m.HTML, "<title>"+tt.wantSubject) {
+			t.Errorf("%s html not wrapped in layout:\n%s", tt.name, m.HTML)
+		}
+	}
+}
+
+func TestRenderEscapesHTML(t *testing.T) {
+	m, err := Render(TemplateAdminDigest, "admin@example.org", AdminDigest{
+		Requests: []models.BagRequest{{ID: 1, Email: `<script>@example.org`}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(m.HTML, "<script>") {
+		t.Error("email address was not escaped in HTML body")
+	}
+	if m.Subject != "ID-100: 1 neue Werkzeug-Anfrage" {
+		t.Errorf("singular subject = %q", m.Subject)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{5, 16 * time.Minute},
+		{20, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// fakeSMTP accepts a single session like a local mail catcher and returns the received DATA
+func fakeSMTP(t *testing.T) (host, port string, received <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	out := make(chan string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+
+		reply("220 localhost ESMTP test")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"), strings.HasPrefix(cmd, "RCPT TO:"):
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				out <- data.String()
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	host, port, _ = net.SplitHostPort(ln.Addr().String())
+	return host, port, out
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	host, port, received := fakeSMTP(t)
+	s := &SMTPSender{Host: host, Port: port, From: testFrom}
+
+	err := s.Send(context.Background(), Message{To: "mia@example.org", Subject: "Hallo", Text: "Hallo Mia", HTML: "<p>Hallo Mia</p>"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	select {
+	case data := <-received:
+		if !strings.Contains(data, "To: <mia@example.org>\r\n") || !strings.Contains(data, "Hallo Mia") {
+			t.Errorf("unexpected message:\n%s", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+}
@@ -0,0 +1,161 @@
+package email
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"id-100/internal/config"
+	"id-100/internal/models"
+	"id-100/internal/repository"
+)
+
+const (
+	// MaxAttempts is how often an email is tried before it is marked failed
+	MaxAttempts = 8
+	// baseBackoff is the delay after the first failed attempt; it doubles per attempt
+	baseBackoff = time.Minute
+	// maxBackoff caps the delay between attempts
+	maxBackoff = 6 * time.Hour
+	// batchSize is the number of emails one worker run sends
+	batchSize = 20
+	// claimLease keeps a claimed email away from other instances while it is sent
+	claimLease = 2 * time.Minute
+	// maxErrorLength keeps stored SMTP errors short
+	maxErrorLength = 500
+	// DigestInterval is how often the admin digest of new bag requests is sent
+	DigestInterval = 24 * time.Hour
+	// Retention is how long sent and failed emails stay in the outbox
+	Retention = 30 * 24 * time.Hour
+)
+
+// Enabled reports whether SMTP is configured. Without it nothing is queued.
+func Enabled() bool {
+	return config.GetSMTPHost() != ""
+}
+
+// Enqueue renders a template and stores it in the outbox for the background sender
+func Enqueue(ctx context.Context, name, to string, data interface{}) error {
+	return enqueue(ctx, name, to, data, time.Time{})
+}
+
+func enqueue(ctx context.Context, name, to string, data interface{}, createdAt time.Time) error {
+	if !Enabled() {
+		return nil
+	}
+	m, err := Render(name, to, data)
+	if err != nil {
+		return err
+	}
+	_, err = repository.EnqueueEmail(ctx, models.OutboxEmail{
+		Template:  name,
+		To:        m.To,
+		Subject:   m.Subject,
+		TextBody:  m.Text,
+		HTMLBody:  m.HTML,
+		CreatedAt: createdAt,
+	})
+	return err
+}
+
+// Backoff returns the delay before the next attempt after attempts failed attempts
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+// ProcessOutbox sends all due emails, rescheduling failures with exponential backoff
+func ProcessOutbox(ctx context.Context) error {
+	if !Enabled() {
+		return nil
+	}
+	sender, err := NewSMTPSenderFromEnv()
+	if err != nil {
+		return err
+	}
+	for {
+		due, err := repository.ClaimDueEmails(ctx, batchSize, claimLease)
+		if err != nil {
+			return err
+		}
+		for _, m := range due {
+			sendErr := sender.Send(ctx, Message{To: m.To, Subject: m.Subject, Text: m.TextBody, HTML: m.HTMLBody})
+			if sendErr == nil {
+				err = repository.MarkEmailSent(ctx, m.ID)
+			} else {
+				log.Printf("Failed to send email %d (%s): %v", m.ID, m.Template, sendErr)
+				var next *time.Time
+				if attempts := m.Attempts + 1; attempts < MaxAttempts {
+					t := time.Now().Add(Backoff(attempts))
+					next = &t
+				}
+				err = repository.MarkEmailAttemptFailed(ctx, m.ID, truncate(sendErr.Error()), next)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if len(due) < batchSize {
+			return nil
+		}
+	}
+}
+
+// QueueAdminDigest queues a digest of the bag requests confirmed since the previous
+// digest to ADMIN_EMAIL, at most once per DigestInterval. Nothing is sent if there
+// are no new requests. The digest is
+// stored with the end of its window as created_at, so the next one starts exactly there.
+func QueueAdminDigest(ctx context.Context) error {
+	to := config.GetAdminEmail()
+	if to == "" || !Enabled() {
+		return nil
+	}
+	now := time.Now()
+	since := now.Add(-DigestInterval)
+	last, err := repository.GetLastEmailCreatedAt(ctx, TemplateAdminDigest)
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		if now.Sub(*last) < DigestInterval {
+			return nil
+		}
+		since = *last
+	}
+
+	requests, err := repository.GetBagRequestsConfirmedBetween(ctx, since, now)
+	if err != nil {
+		return err
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+	return enqueue(ctx, TemplateAdminDigest, to, AdminDigest{
+		BaseURL:  strings.TrimRight(config.GetBaseURL(), "/"),
+		Since:    since,
+		Requests: requests,
+	}, now)
+}
+
+// truncate cuts s to maxErrorLength bytes at a rune boundary
+func truncate(s string) string {
+	if len(s) <= maxErrorLength {
+		return s
+	}
+	cut := maxErrorLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
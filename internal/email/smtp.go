@@ -0,0 +1,101 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"id-100/internal/config"
+)
+
+// sendTimeout bounds a single SMTP conversation
+const sendTimeout = 30 * time.Second
+
+// SMTPSender delivers messages to an SMTP server. STARTTLS is used when the
+// server offers it; authentication only if a username is set.
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     *mail.Address
+}
+
+// NewSMTPSenderFromEnv configures a sender from SMTP_* and MAIL_FROM
+func NewSMTPSenderFromEnv() (*SMTPSender, error) {
+	from, err := mail.ParseAddress(config.GetMailFrom())
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAIL_FROM: %w", err)
+	}
+	return &SMTPSender{
+		Host:     config.GetSMTPHost(),
+		Port:     config.GetSMTPPort(),
+		Username: config.GetSMTPUsername(),
+		Password: config.GetSMTPPassword(),
+		From:     from,
+	}, nil
+}
+
+// Send delivers m in a single SMTP session
+func (s *SMTPSender) Send(ctx context.Context, m Message) error {
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", m.To, err)
+	}
+	raw, err := Build(s.From, m, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(s.Host, s.Port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if s.Username != "" {
+		// net/smtp refuses PLAIN auth over unencrypted connections to remote hosts
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+	if err := c.Mail(s.From.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
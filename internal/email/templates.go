@@ -0,0 +1,86 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"id-100/internal/models"
+)
+
+//go:embed templates/*.html templates/*.txt
+var templateFS embed.FS
+
+// Template names; each has a <name>.txt (defining "subject" and the text body)
+// and a <name>.html (defining "content" for layout.html)
+const (
+	TemplateBagRequestConfirmation = "bag_request_confirmation"
+	TemplateBagShipped             = "bag_shipped"
+	TemplateAdminDigest            = "admin_digest"
+	TemplatePlayerLogin            = "player_login"
+)
+
+// BagRequestConfirmation is the double opt-in email sent right after the bag request
+type BagRequestConfirmation struct {
+	BaseURL    string
+	RequestID  int
+	CreatedAt  time.Time
+	ConfirmURL string
+	ExpiresAt  time.Time
+}
+
+// BagShipped is sent to a requester when an admin marks the request shipped
+type BagShipped struct {
+	BaseURL   string
+	RequestID int
+}
+
+// AdminDigest lists bag requests received since the previous digest
+type AdminDigest struct {
+	BaseURL  string
+	Since    time.Time
+	Requests []models.BagRequest
+}
+
+// PlayerLogin is the magic link to sign in to (or add an address to) a player account
+type PlayerLogin struct {
+	BaseURL   string
+	LoginURL  string
+	ExpiresAt time.Time
+	AddEmail  bool // requested by a signed-in player to add this address
+}
+
+// Render renders the named template for recipient to
+func Render(name, to string, data interface{}) (Message, error) {
+	txt, err := texttemplate.ParseFS(templateFS, "templates/"+name+".txt")
+	if err != nil {
+		return Message{}, err
+	}
+	var subject, text bytes.Buffer
+	if err := txt.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := txt.Execute(&text, data); err != nil {
+		return Message{}, fmt.Errorf("render %s text: %w", name, err)
+	}
+
+	html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")
+	if err != nil {
+		return Message{}, err
+	}
+	var body bytes.Buffer
+	if err := html.ExecuteTemplate(&body, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("render %s html: %w", name, err)
+	}
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    body.String(),
+	}, nil
+}
@@ -0,0 +1,117 @@
+package feed
+
+import (
+	"strings"
+	"sync"
+
+	"id-100/internal/models"
+)
+
+// Live contribution feed. A single listener per instance receives Postgres
+// notifications and fans them out to the SSE subscribers of that instance, so
+// every instance sees every contribution no matter which one stored it.
+
+const (
+	// OpCreated is sent when a contribution was published
+	OpCreated = "created"
+	// OpDeleted is sent when a contribution was removed
+	OpDeleted = "deleted"
+
+	// subscriberBuffer is how many events a slow subscriber may lag behind before events are dropped
+	subscriberBuffer = 32
+)
+
+// Event is a change on the contributions table. Contribution is only set for OpCreated.
+type Event struct {
+	Op           string
+	ID           int
+	DeriveNumber int
+	City         string
+	Contribution *models.APIContribution
+}
+
+// Filter narrows the events a subscriber receives; zero values match everything
+type Filter struct {
+	City         string
+	DeriveNumber int
+}
+
+// Matches reports whether ev passes the filter. Cities compare case-insensitively.
+func (f Filter) Matches(ev Event) bool {
+	if f.DeriveNumber > 0 && ev.DeriveNumber != f.DeriveNumber {
+		return false
+	}
+	if f.City != "" && !strings.EqualFold(strings.TrimSpace(ev.City), strings.TrimSpace(f.City)) {
+		return false
+	}
+	return true
+}
+
+// Subscription receives matching events on C until it is cancelled
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	filter Filter
+	hub    *Hub
+}
+
+// Cancel removes the subscription from its hub and closes C
+func (s *Subscription) Cancel() {
+	s.hub.unsubscribe(s)
+}
+
+// Hub fans events out to subscribers
+type Hub struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// Default is the hub used by the HTTP handlers; fed by Listen
+var Default = NewHub()
+
+// NewHub creates an empty hub
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe registers a subscriber for events matching f
+func (h *Hub) Subscribe(f Filter) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	s := &Subscription{C: ch, ch: ch, filter: f, hub: h}
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	return s
+}
+
+func (h *Hub) unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.ch)
+	}
+}
+
+// Publish delivers ev to all matching subscribers. It never blocks: a subscriber
+// whose buffer is full misses the event rather than stalling everyone else.
+func (h *Hub) Publish(ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		if !s.filter.Matches(ev) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+		}
+	}
+}
+
+// Len returns the number of active subscribers
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
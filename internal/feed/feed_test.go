@@ -0,0 +1,126 @@
+package feed
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"id-100/internal/models"
+)
+
+func TestFilterMatches(t *testing.T) {
+	ev := Event{Op: OpCreated, ID: 1, DeriveNumber: 7, City: "Kassel"}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty filter", Filter{}, true},
+		{"same derive", Filter{DeriveNumber: 7}, true},
+		{"other derive", Filter{DeriveNumber: 8}, false},
+		{"city case-insensitive", Filter{City: "kassel "}, true},
+		{"other city", Filter{City: "Berlin"}, false},
+		{"both match", Filter{City: "Kassel", DeriveNumber: 7}, true},
+		{"city matches, derive not", Filter{City: "Kassel", DeriveNumber: 1}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Matches(ev); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHubPublishesToMatchingSubscribers(t *testing.T) {
+	h := NewHub()
+	all := h.Subscribe(Filter{})
+	kassel := h.Subscribe(Filter{City: "Kassel"})
+	defer all.Cancel()
+	defer kassel.Cancel()
+
+	h.Publish(Event{Op: OpCreated, ID: 1, City: "Berlin"})
+	h.Publish(Event{Op: OpCreated, ID: 2, City: "Kassel"})
+
+	if ev := <-all.C; ev.ID != 1 {
+		t.Errorf("first event for unfiltered subscriber = %d, want 1", ev.ID)
+	}
+	if ev := <-all.C; ev.ID != 2 {
+		t.Errorf("second event for unfiltered subscriber = %d, want 2", ev.ID)
+	}
+	if ev := <-kassel.C; ev.ID != 2 {
+		t.Errorf("event for Kassel subscriber = %d, want 2", ev.ID)
+	}
+	select {
+	case ev := <-kassel.C:
+		t.Errorf("unexpected event for Kassel subscriber: %+v", ev)
+	default:
+	}
+}
+
+func TestHubDropsEventsForSlowSubscribers(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe(Filter{})
+	defer s.Cancel()
+
+	for i := 0; i < subscriberBuffer+10; i++ {
+		h.Publish(Event{Op: OpCreated, ID: i})
+	}
+	if len(s.C) != subscriberBuffer {
+		t.Errorf("buffered events = %d, want %d", len(s.C), subscriberBuffer)
+	}
+}
+
+func TestSubscriptionCancel(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe(Filter{})
+	s.Cancel()
+	s.Cancel() // second cancel is a no-op
+
+	if _, ok := <-s.C; ok {
+		t.Error("channel should be closed after Cancel")
+	}
+	if h.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", h.Len())
+	}
+	h.Publish(Event{Op: OpCreated, ID: 1}) // must not panic on closed channel
+}
+
+func TestParseNotification(t *testing.T) {
+	ev, err := ParseNotification(`{"op":"insert","id":42,"derive_number":3,"city":"Kassel"}`)
+	if err != nil {
+		t.Fatalf("ParseNotification returned error: %v", err)
+	}
+	if ev.Op != OpCreated || ev.ID != 42 || ev.DeriveNumber != 3 || ev.City != "Kassel" {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+
+	ev, err = ParseNotification(`{"op":"delete","id":42,"derive_number":null,"city":""}`)
+	if err != nil || ev.Op != OpDeleted || ev.DeriveNumber != 0 {
+		t.Errorf("delete notification = %+v, %v", ev, err)
+	}
+
+	for _, payload := range []string{`not json`, `{"op":"update","id":1}`} {
+		if _, err := ParseNotification(payload); err == nil {
+			t.Errorf("ParseNotification(%q) should fail", payload)
+		}
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	var buf bytes.Buffer
+	ct := &models.APIContribution{ID: 42, DeriveNumber: 3, ImageURL: "https://example.org/a.webp", UserName: "Mia", CreatedAt: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	if err := WriteEvent(&buf, Event{Op: OpCreated, ID: 42, Contribution: ct}); err != nil {
+		t.Fatal(err)
+	}
+	want := "id: 42\nevent: contribution\ndata: {\"id\":42,\"derive_number\":3,\"image_url\":\"https://example.org/a.webp\",\"user_name\":\"Mia\",\"created_at\":\"2026-05-01T12:00:00Z\"}\n\n"
+	if buf.String() != want {
+		t.Errorf("created event =\n%q\nwant\n%q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteEvent(&buf, Event{Op: OpDeleted, ID: 42}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "event: removed\ndata: {\"id\":42}\n\n"; buf.String() != want {
+		t.Errorf("removed event = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,107 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"id-100/internal/models"
+)
+
+// Channel is the Postgres notification channel written by the contributions trigger
+const Channel = "contributions"
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// notification is the JSON payload sent by the contributions_notify trigger
+type notification struct {
+	Op           string `json:"op"`
+	ID           int    `json:"id"`
+	DeriveNumber int    `json:"derive_number"`
+	City         string `json:"city"`
+}
+
+// LoadFunc fetches a published contribution by ID
+type LoadFunc func(ctx context.Context, id int) (*models.APIContribution, error)
+
+// ParseNotification decodes a trigger payload into an event (without the contribution itself)
+func ParseNotification(payload string) (Event, error) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		return Event{}, fmt.Errorf("invalid feed notification %q: %w", payload, err)
+	}
+	switch n.Op {
+	case "insert":
+		n.Op = OpCreated
+	case "delete":
+		n.Op = OpDeleted
+	default:
+		return Event{}, fmt.Errorf("unknown feed operation %q", n.Op)
+	}
+	return Event{Op: n.Op, ID: n.ID, DeriveNumber: n.DeriveNumber, City: n.City}, nil
+}
+
+// Listen holds a dedicated connection that LISTENs on Channel and publishes
+// every notification to hub until ctx is done, reconnecting with backoff.
+func Listen(ctx context.Context, pool *pgxpool.Pool, hub *Hub, load LoadFunc) {
+	go func() {
+		delay := minReconnectDelay
+		for {
+			err := listenOnce(ctx, pool, hub, load, func() { delay = minReconnectDelay })
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Contribution feed listener stopped, reconnecting in %s: %v", delay, err)
+			sentry.CaptureException(err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, maxReconnectDelay)
+		}
+	}()
+}
+
+func listenOnce(ctx context.Context, pool *pgxpool.Pool, hub *Hub, load LoadFunc, connected func()) error {
+	pooled, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection carries LISTEN state, so it must not go back into the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+	connected()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		ev, err := ParseNotification(n.Payload)
+		if err != nil {
+			log.Printf("Skipping feed notification: %v", err)
+			continue
+		}
+		if ev.Op == OpCreated {
+			ev.Contribution, err = load(ctx, ev.ID)
+			if err != nil {
+				log.Printf("Failed to load contribution %d for feed: %v", ev.ID, err)
+				continue
+			}
+		}
+		hub.Publish(ev)
+	}
+}
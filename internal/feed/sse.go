@@ -0,0 +1,44 @@
+package feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"id-100/internal/models"
+)
+
+// Server-Sent Events encoding. Published contributions carry their ID as event ID
+// so browsers resume via Last-Event-ID; removals do not move that position.
+
+const (
+	// EventContribution is the SSE event name of a published contribution
+	EventContribution = "contribution"
+	// EventRemoved is the SSE event name of a deleted contribution
+	EventRemoved = "removed"
+)
+
+// WriteContribution writes a published contribution as an SSE event
+func WriteContribution(w io.Writer, ct *models.APIContribution) error {
+	data, err := json.Marshal(ct)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ct.ID, EventContribution, data)
+	return err
+}
+
+// WriteEvent writes ev as an SSE event
+func WriteEvent(w io.Writer, ev Event) error {
+	if ev.Op == OpCreated && ev.Contribution != nil {
+		return WriteContribution(w, ev.Contribution)
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: {\"id\":%d}\n\n", EventRemoved, ev.ID)
+	return err
+}
+
+// WriteComment writes an SSE comment line, used as heartbeat to keep proxies from closing the stream
+func WriteComment(w io.Writer, text string) error {
+	_, err := fmt.Fprintf(w, ": %s\n\n", text)
+	return err
+}
@@ -0,0 +1,134 @@
+package gdpr
+
+import (
+	"context"
+	"fmt"
+
+	"id-100/internal/models"
+	"id-100/internal/repository"
+	"id-100/internal/utils"
+	"id-100/internal/webhooks"
+)
+
+// ErasureResult reports what an erasure deleted and whether a search
+// afterwards found anything left
+type ErasureResult struct {
+	Counts          map[string]int `json:"counts"`
+	Remaining       map[string]int `json:"remaining"`
+	ImagesRemaining int            `json:"images_remaining"`
+	Verified        bool           `json:"verified"`
+	AuditID         int            `json:"audit_id"`
+}
+
+// Find returns everything stored about the subject of q
+func Find(ctx context.Context, q models.SubjectQuery) (*models.SubjectData, error) {
+	return repository.FindSubjectData(ctx, q)
+}
+
+// Export returns everything stored about the subject of q for an archive
+// and records the export in the audit log first, so no data leaves without
+// an audit record. Callers write the archive with WriteArchive.
+func Export(ctx context.Context, q models.SubjectQuery, actor, reference string) (*models.SubjectData, error) {
+	d, err := repository.FindSubjectData(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	audit := &models.PrivacyAudit{
+		Action:    ActionExport,
+		Actor:     actor,
+		Reference: reference,
+		Criteria:  Criteria(q),
+		Counts:    Counts(d),
+	}
+	if err := repository.RecordPrivacyAudit(ctx, audit); err != nil {
+		return nil, fmt.Errorf("record audit: %w", err)
+	}
+	return d, nil
+}
+
+// Erase deletes everything stored about the subject of q and verifies that a
+// second search finds nothing and no image is left in storage. Stored objects
+// go first: if deleting one fails, the rows stay and the erasure can simply
+// be run again. Subscribers get contribution.deleted for every contribution.
+// The audit record is written in every case.
+func Erase(ctx context.Context, q models.SubjectQuery, actor, reference string) (*ErasureResult, error) {
+	audit := &models.PrivacyAudit{
+		Action:    ActionErase,
+		Actor:     actor,
+		Reference: reference,
+		Criteria:  Criteria(q),
+		Counts:    map[string]int{},
+	}
+	res, err := erase(ctx, q, audit)
+	if err != nil {
+		audit.Error = err.Error()
+	}
+	if auditErr := repository.RecordPrivacyAudit(ctx, audit); auditErr != nil && err == nil {
+		err = fmt.Errorf("record audit: %w", auditErr)
+	}
+	if res != nil {
+		res.AuditID = audit.ID
+	}
+	return res, err
+}
+
+func erase(ctx context.Context, q models.SubjectQuery, audit *models.PrivacyAudit) (*ErasureResult, error) {
+	d, err := repository.FindSubjectData(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]string, 0, len(d.Contributions))
+	for _, c := range d.Contributions {
+		if c.ImageURL != "" {
+			images = append(images, c.ImageURL)
+		}
+	}
+	for _, key := range append(images, d.StagedObjects...) {
+		if err := utils.DeleteFromS3(ctx, key); err != nil && !utils.IsS3NotFound(err) {
+			return nil, fmt.Errorf("delete stored object: %w", err)
+		}
+	}
+
+	counts, err := repository.EraseSubjectData(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	counts["images"] = len(images)
+	audit.Counts = counts
+
+	tokens := map[int]int{}
+	for _, l := range d.UploadLogs {
+		if l.ContributionID != nil {
+			tokens[*l.ContributionID] = l.TokenID
+		}
+	}
+	for _, c := range d.Contributions {
+		webhooks.Emit(ctx, webhooks.EventContributionDeleted, webhooks.ContributionDeleted{
+			ID:        c.ID,
+			TokenID:   tokens[c.ID],
+			DeletedBy: "admin",
+		})
+	}
+
+	after, err := repository.FindSubjectData(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("verify: %w", err)
+	}
+	res := &ErasureResult{Counts: counts, Remaining: Remaining(after)}
+	for _, imageURL := range images {
+		body, err := utils.DownloadImageFromS3(ctx, imageURL)
+		if err == nil {
+			body.Close()
+		}
+		if !utils.IsS3NotFound(err) {
+			res.ImagesRemaining++
+		}
+	}
+	res.Verified = len(res.Remaining) == 0 && res.ImagesRemaining == 0
+	audit.Verified = res.Verified
+	if !res.Verified {
+		audit.Error = fmt.Sprintf("not verified: %v records and %d images left", res.Remaining, res.ImagesRemaining)
+	}
+	return res, nil
+}
@@ -0,0 +1,195 @@
+package gdpr
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"id-100/internal/bagrequest"
+	"id-100/internal/models"
+	"id-100/internal/recap"
+)
+
+// Data subject access and erasure requests. Admins (privacy tab) and
+// operators (id-100 gdpr) search by player name, bag session, email address
+// or player account, export everything found as a ZIP and erase it. Every
+// export and erasure leaves an audit record that names the kinds of criteria
+// used but never their values.
+
+const (
+	ActionExport = "export"
+	ActionErase  = "erase"
+)
+
+// Kinds of criteria, stored in the audit record instead of their values
+const (
+	CriterionName    = "name"
+	CriterionSession = "session"
+	CriterionEmail   = "email"
+	CriterionPlayer  = "player"
+)
+
+var (
+	ErrEmptyQuery     = errors.New("no search criteria given")
+	ErrInvalidSession = errors.New("session must be TOKEN_ID:SESSION, e.g. 12:3")
+	ErrInvalidEmail   = errors.New("invalid email address")
+	ErrInvalidPlayer  = errors.New("player must be a player account id")
+)
+
+// Kinds lists the kinds of records in the order they are shown
+var Kinds = []string{"sessions", "contributions", "upload_logs", "bag_requests", "players", "consents", "emails", "webhook_deliveries"}
+
+// Labels are the German names of record kinds, including those only counted on erasure
+var Labels = map[string]string{
+	"sessions":           "Sessions",
+	"contributions":      "Beiträge",
+	"upload_logs":        "Upload-Logs",
+	"bag_requests":       "Werkzeug-Anfragen",
+	"players":            "Spielerkonten",
+	"consents":           "Einwilligungen",
+	"emails":             "E-Mails",
+	"webhook_deliveries": "Webhook-Zustellungen",
+	"images":             "Bilder",
+	"staged_uploads":     "Unfertige Uploads",
+	"login_links":        "Anmeldelinks",
+}
+
+// ParseQuery validates search input as typed into the admin form or passed
+// to the CLI. Empty fields are ignored; at least one must be set.
+func ParseQuery(name, session, email, player string) (models.SubjectQuery, error) {
+	var q models.SubjectQuery
+	q.Name = strings.Join(strings.Fields(name), " ")
+
+	if session = strings.TrimSpace(session); session != "" {
+		token, number, ok := strings.Cut(session, ":")
+		t, err1 := strconv.Atoi(strings.TrimSpace(token))
+		n, err2 := strconv.Atoi(strings.TrimSpace(number))
+		if !ok || err1 != nil || err2 != nil || t < 1 || n < 0 {
+			return q, ErrInvalidSession
+		}
+		q.TokenID, q.SessionNumber = t, n
+	}
+
+	if strings.TrimSpace(email) != "" {
+		addr, err := bagrequest.NormalizeEmail(email)
+		if err != nil {
+			return q, ErrInvalidEmail
+		}
+		q.Email = addr
+	}
+
+	if player = strings.TrimSpace(player); player != "" {
+		id, err := strconv.Atoi(player)
+		if err != nil || id < 1 {
+			return q, ErrInvalidPlayer
+		}
+		q.PlayerID = id
+	}
+
+	if len(Criteria(q)) == 0 {
+		return q, ErrEmptyQuery
+	}
+	return q, nil
+}
+
+// Criteria returns the kinds of criteria set in q
+func Criteria(q models.SubjectQuery) []string {
+	kinds := []string{}
+	if q.Name != "" {
+		kinds = append(kinds, CriterionName)
+	}
+	if q.TokenID > 0 {
+		kinds = append(kinds, CriterionSession)
+	}
+	if q.Email != "" {
+		kinds = append(kinds, CriterionEmail)
+	}
+	if q.PlayerID > 0 {
+		kinds = append(kinds, CriterionPlayer)
+	}
+	return kinds
+}
+
+// Counts returns the number of records per kind
+func Counts(d *models.SubjectData) map[string]int {
+	return map[string]int{
+		"sessions":           len(d.Sessions),
+		"contributions":      len(d.Contributions),
+		"upload_logs":        len(d.UploadLogs),
+		"bag_requests":       len(d.BagRequests),
+		"players":            len(d.Players),
+		"consents":           len(d.Consents),
+		"emails":             len(d.Emails),
+		"webhook_deliveries": len(d.WebhookDeliveries),
+	}
+}
+
+// Remaining returns the kinds of erasable records still found, leaving out
+// consent records, which are kept as proof of consent
+func Remaining(d *models.SubjectData) map[string]int {
+	left := map[string]int{}
+	for kind, n := range Counts(d) {
+		if n > 0 && kind != "consents" {
+			left[kind] = n
+		}
+	}
+	return left
+}
+
+// archive is the content of daten.json
+type archive struct {
+	ExportedAt    time.Time           `json:"exported_at"`
+	Query         models.SubjectQuery `json:"query"`
+	Data          *models.SubjectData `json:"data"`
+	MissingImages []int               `json:"missing_images"` // contribution ids whose image could not be read
+}
+
+// ImageName is the path of a contribution's image inside the archive
+func ImageName(c models.SubjectContribution) string {
+	return fmt.Sprintf("bilder/%d_ID-%03d%s", c.ID, c.DeriveNumber, recap.ImageExt(c.ImageURL))
+}
+
+// WriteArchive writes a ZIP with the images of all contributions and
+// daten.json with every record found. Images that cannot be opened are listed
+// in daten.json; errors writing to w abort.
+func WriteArchive(w io.Writer, d *models.SubjectData, q models.SubjectQuery, open recap.OpenFunc) error {
+	zw := zip.NewWriter(w)
+	a := archive{ExportedAt: time.Now().UTC(), Query: q, Data: d, MissingImages: []int{}}
+
+	for _, c := range d.Contributions {
+		body, err := open(c.ImageURL)
+		if err != nil {
+			a.MissingImages = append(a.MissingImages, c.ID)
+			continue
+		}
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: ImageName(c), Method: zip.Store, Modified: c.CreatedAt})
+		if err == nil {
+			_, err = io.Copy(f, body)
+		}
+		body.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "daten.json", Method: zip.Deflate, Modified: a.ExportedAt})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(a); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ArchiveName is the download name of an export
+func ArchiveName(t time.Time) string {
+	return "id-100_auskunft_" + t.Format("20060102-150405") + ".zip"
+}
@@ -0,0 +1,142 @@
+package gdpr
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"id-100/internal/models"
+)
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery("  Anna   Schmidt ", "12:3", " Mia@Example.ORG ", "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.SubjectQuery{Name: "Anna Schmidt", TokenID: 12, SessionNumber: 3, Email: "Mia@example.org", PlayerID: 7}
+	if q != want {
+		t.Errorf("ParseQuery = %+v, want %+v", q, want)
+	}
+
+	tests := []struct {
+		name, session, email, player string
+		err                          error
+	}{
+		{"", "", "", "", ErrEmptyQuery},
+		{"  ", "", "", "", ErrEmptyQuery},
+		{"", "12", "", "", ErrInvalidSession},
+		{"", "x:3", "", "", ErrInvalidSession},
+		{"", "0:3", "", "", ErrInvalidSession},
+		{"", "", "mia", "", ErrInvalidEmail},
+		{"", "", "Mia <mia@example.org>", "", ErrInvalidEmail},
+		{"", "", "", "abc", ErrInvalidPlayer},
+		{"", "", "", "0", ErrInvalidPlayer},
+	}
+	for _, tt := range tests {
+		if _, err := ParseQuery(tt.name, tt.session, tt.email, tt.player); !errors.Is(err, tt.err) {
+			t.Errorf("ParseQuery(%q, %q, %q, %q) error = %v, want %v", tt.name, tt.session, tt.email, tt.player, err, tt.err)
+		}
+	}
+}
+
+func TestCriteria(t *testing.T) {
+	if got := Criteria(models.SubjectQuery{}); got == nil || len(got) != 0 {
+		t.Errorf("Criteria of an empty query = %#v, want an empty slice", got)
+	}
+	got := Criteria(models.SubjectQuery{Name: "Anna", Email: "a@example.org", TokenID: 1})
+	if !reflect.DeepEqual(got, []string{CriterionName, CriterionSession, CriterionEmail}) {
+		t.Errorf("Criteria = %v", got)
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	d := &models.SubjectData{
+		Consents:          []models.Consent{{ID: 1}},
+		UploadLogs:        []models.SubjectUploadLog{{ID: 4}, {ID: 5}},
+		BagRequests:       []models.BagRequest{},
+		WebhookDeliveries: []models.SubjectWebhookDelivery{{ID: 9}},
+	}
+	if got := Remaining(d); !reflect.DeepEqual(got, map[string]int{"upload_logs": 2, "webhook_deliveries": 1}) {
+		t.Errorf("Remaining = %v; consents must not count", got)
+	}
+	for _, kind := range Kinds {
+		if Labels[kind] == "" {
+			t.Errorf("no label for %q", kind)
+		}
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	created := time.Date(2026, 5, 1, 14, 3, 0, 0, time.UTC)
+	d := &models.SubjectData{
+		Contributions: []models.SubjectContribution{
+			{ID: 41, DeriveNumber: 7, ImageURL: "http://minio/b/derive_7_1.jpg", UserName: "Anna S.", CreatedAt: created},
+			{ID: 42, DeriveNumber: 9, ImageURL: "http://minio/b/gone.webp", CreatedAt: created},
+		},
+		BagRequests: []models.BagRequest{{ID: 3, Email: "anna@example.org"}},
+		WebhookDeliveries: []models.SubjectWebhookDelivery{
+			{ID: 5, Event: "session.started", Payload: json.RawMessage(`{"event":"session.started","data":{"player_name":"Anna Schmidt"}}`)},
+		},
+	}
+	open := func(url string) (io.ReadCloser, error) {
+		if strings.Contains(url, "gone") {
+			return nil, errors.New("not found")
+		}
+		return io.NopCloser(strings.NewReader("image:" + url)), nil
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, d, models.SubjectQuery{Name: "Anna"}, open); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		files[f.Name] = f
+	}
+	if !reflect.DeepEqual(names, []string{"bilder/41_ID-007.jpg", "daten.json"}) {
+		t.Fatalf("archive entries = %v", names)
+	}
+
+	rc, err := files["daten.json"].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	var got struct {
+		Query         models.SubjectQuery `json:"query"`
+		Data          models.SubjectData  `json:"data"`
+		MissingImages []int               `json:"missing_images"`
+	}
+	if err := json.NewDecoder(rc).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Query.Name != "Anna" || len(got.Data.Contributions) != 2 || got.Data.BagRequests[0].Email != "anna@example.org" {
+		t.Errorf("daten.json = %+v", got)
+	}
+	var payload struct {
+		Data struct {
+			PlayerName string `json:"player_name"`
+		} `json:"data"`
+	}
+	if len(got.Data.WebhookDeliveries) != 1 {
+		t.Fatalf("webhook deliveries in daten.json = %+v", got.Data.WebhookDeliveries)
+	}
+	if err := json.Unmarshal(got.Data.WebhookDeliveries[0].Payload, &payload); err != nil || payload.Data.PlayerName != "Anna Schmidt" {
+		t.Errorf("webhook payload in daten.json = %s (%v), want it as JSON", got.Data.WebhookDeliveries[0].Payload, err)
+	}
+	if !reflect.DeepEqual(got.MissingImages, []int{42}) {
+		t.Errorf("missing images = %v, want [42]", got.MissingImages)
+	}
+}
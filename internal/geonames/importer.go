@@ -0,0 +1,87 @@
+package geonames
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"id-100/internal/meili"
+	"id-100/internal/models"
+)
+
+// DefaultIndex is the Meilisearch index used by the city autocomplete
+const DefaultIndex = "cities"
+
+// indexSettings mirrors the settings the former shell loader applied to the cities index
+var indexSettings = map[string]interface{}{
+	"searchableAttributes": []string{"name", "ascii_name"},
+	"rankingRules":         []string{"words", "typo", "proximity", "attribute", "sort", "exactness", "population:desc"},
+	"typoTolerance": map[string]interface{}{
+		"enabled":             true,
+		"minWordSizeForTypos": map[string]int{"oneTypo": 4, "twoTypos": 8},
+	},
+}
+
+// ImportToMeilisearch creates/configures the index and loads cities in batches.
+// Documents are keyed by geonameid, so running the import twice replaces rather than duplicates.
+func ImportToMeilisearch(ctx context.Context, client *meili.Client, index string, cities []models.City, batchSize int) error {
+	if batchSize <= 0 {
+		batchSize = len(cities)
+	}
+
+	uid, err := client.CreateIndex(ctx, index, "id")
+	if err != nil {
+		return fmt.Errorf("create index: %w", err)
+	}
+	if ts, err := client.WaitForTask(ctx, uid); err != nil {
+		return fmt.Errorf("create index: %w", err)
+	} else if ts.Status != "succeeded" {
+		// A re-import hits index_already_exists, which is fine
+		if ts.Error == nil || ts.Error.Code != "index_already_exists" {
+			return taskError("create index", ts)
+		}
+	}
+
+	uid, err = client.UpdateSettings(ctx, index, indexSettings)
+	if err != nil {
+		return fmt.Errorf("update settings: %w", err)
+	}
+	if err := waitSucceeded(ctx, client, uid, "update settings"); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(cities); start += batchSize {
+		end := start + batchSize
+		if end > len(cities) {
+			end = len(cities)
+		}
+		uid, err := client.AddDocuments(ctx, index, cities[start:end])
+		if err != nil {
+			return fmt.Errorf("add documents: %w", err)
+		}
+		if err := waitSucceeded(ctx, client, uid, "add documents"); err != nil {
+			return err
+		}
+		log.Printf("Indexed %d/%d cities", end, len(cities))
+	}
+
+	return nil
+}
+
+func waitSucceeded(ctx context.Context, client *meili.Client, uid int64, op string) error {
+	ts, err := client.WaitForTask(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if ts.Status != "succeeded" {
+		return taskError(op, ts)
+	}
+	return nil
+}
+
+func taskError(op string, ts *meili.TaskStatus) error {
+	if ts.Error != nil {
+		return fmt.Errorf("%s: task %d %s: %s (%s)", op, ts.UID, ts.Status, ts.Error.Message, ts.Error.Code)
+	}
+	return fmt.Errorf("%s: task %d %s", op, ts.UID, ts.Status)
+}
@@ -0,0 +1,139 @@
+package geonames
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"id-100/internal/meili"
+	"id-100/internal/models"
+)
+
+// fakeMeili emulates the task API: every write enqueues a task that is
+// "processing" on the first poll and finished on the second.
+type fakeMeili struct {
+	mu          sync.Mutex
+	nextUID     int64
+	polls       map[int64]int
+	indexExists bool
+	failed      map[int64]string
+	docs        map[string]models.City
+}
+
+func newFakeMeili() *fakeMeili {
+	return &fakeMeili{polls: map[int64]int{}, failed: map[int64]string{}, docs: map[string]models.City{}}
+}
+
+func (f *fakeMeili) enqueue(w http.ResponseWriter, failCode string) {
+	f.nextUID++
+	if failCode != "" {
+		f.failed[f.nextUID] = failCode
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]int64{"taskUid": f.nextUID})
+}
+
+func (f *fakeMeili) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/indexes":
+		if f.indexExists {
+			f.enqueue(w, "index_already_exists")
+			return
+		}
+		f.indexExists = true
+		f.enqueue(w, "")
+	case r.Method == http.MethodPatch && r.URL.Path == "/indexes/cities/settings":
+		f.enqueue(w, "")
+	case r.Method == http.MethodPost && r.URL.Path == "/indexes/cities/documents":
+		var batch []map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&batch)
+		for _, doc := range batch {
+			id, _ := doc["id"].(string)
+			name, _ := doc["name"].(string)
+			f.docs[id] = models.City{Name: name}
+		}
+		f.enqueue(w, "")
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/tasks/"):
+		var uid int64
+		json.Unmarshal([]byte(strings.TrimPrefix(r.URL.Path, "/tasks/")), &uid)
+		f.polls[uid]++
+		status := map[string]interface{}{"uid": uid, "status": "processing"}
+		if f.polls[uid] > 1 {
+			status["status"] = "succeeded"
+			if code, ok := f.failed[uid]; ok {
+				status["status"] = "failed"
+				status["error"] = map[string]string{"code": code, "message": code}
+			}
+		}
+		json.NewEncoder(w).Encode(status)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestImportToMeilisearchIsIdempotent(t *testing.T) {
+	fake := newFakeMeili()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client := meili.NewClient(srv.URL, "secret")
+	client.PollInterval = time.Millisecond
+
+	cities := []models.City{
+		{ID: 1, Name: "Berlin"},
+		{ID: 2, Name: "Hamburg"},
+		{ID: 3, Name: "Kassel"},
+	}
+
+	for run := 1; run <= 2; run++ {
+		if err := ImportToMeilisearch(context.Background(), client, "cities", cities, 2); err != nil {
+			t.Fatalf("run %d: ImportToMeilisearch returned error: %v", run, err)
+		}
+	}
+
+	if len(fake.docs) != 3 {
+		t.Errorf("expected 3 documents after two imports, got %d", len(fake.docs))
+	}
+	if fake.docs["2"].Name != "Hamburg" {
+		t.Errorf("documents should be keyed by string geonameid, got %v", fake.docs)
+	}
+	for uid, n := range fake.polls {
+		if n < 2 {
+			t.Errorf("task %d was polled %d times, expected polling until finished", uid, n)
+		}
+	}
+}
+
+func TestImportToMeilisearchFailedTask(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/tasks/") {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"uid": 1, "status": "failed",
+				"error": map[string]string{"code": "invalid_api_key", "message": "bad key"},
+			})
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"taskUid":1}`))
+	}))
+	defer srv.Close()
+
+	client := meili.NewClient(srv.URL, "")
+	err := ImportToMeilisearch(context.Background(), client, "cities", []models.City{{ID: 1, Name: "Berlin"}}, 0)
+	if err == nil || !strings.Contains(err.Error(), "invalid_api_key") {
+		t.Fatalf("expected failed task error, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package geonames
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"id-100/internal/models"
+)
+
+// DefaultFeatureCodes are the populated place codes imported when no filter is given
+var DefaultFeatureCodes = []string{"PPL", "PPLA", "PPLA2", "PPLA3", "PPLA4", "PPLC"}
+
+// Column indexes of the GeoNames "geoname" dump format (tab separated, 19 columns)
+const (
+	colGeonameID   = 0
+	colName        = 1
+	colASCIIName   = 2
+	colLatitude    = 4
+	colLongitude   = 5
+	colFeatureCode = 7
+	colCountryCode = 8
+	colPopulation  = 14
+	minColumns     = 15
+)
+
+// Filter restricts which GeoNames rows are imported
+type Filter struct {
+	FeatureCodes  []string // defaults to DefaultFeatureCodes when empty
+	Countries     []string // ISO 3166 alpha-2 codes; empty means all countries
+	MinPopulation int64
+}
+
+// CityType maps a GeoNames feature code to the type stored in the search index
+func CityType(featureCode string) string {
+	switch featureCode {
+	case "PPLC":
+		return "capital"
+	case "PPLA":
+		return "major_city"
+	case "PPLA2":
+		return "town"
+	default:
+		return "city"
+	}
+}
+
+// Parse reads a GeoNames dump (e.g. DE.txt) and returns all rows matching the filter
+func Parse(r io.Reader, f Filter) ([]models.City, error) {
+	features := toSet(f.FeatureCodes, DefaultFeatureCodes)
+	countries := toSet(f.Countries, nil)
+
+	scanner := bufio.NewScanner(r)
+	// alternatenames can get long for big cities, so allow lines up to 1 MiB
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var cities []models.City
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cols := strings.Split(line, "\t")
+		if len(cols) < minColumns {
+			return nil, fmt.Errorf("line %d: expected at least %d columns, got %d", lineNo, minColumns, len(cols))
+		}
+
+		if !features[cols[colFeatureCode]] {
+			continue
+		}
+		if len(countries) > 0 && !countries[strings.ToUpper(cols[colCountryCode])] {
+			continue
+		}
+
+		var population int64
+		if p := strings.TrimSpace(cols[colPopulation]); p != "" {
+			n, err := strconv.ParseInt(p, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid population %q: %w", lineNo, p, err)
+			}
+			population = n
+		}
+		if population < f.MinPopulation {
+			continue
+		}
+
+		id, err := strconv.ParseInt(cols[colGeonameID], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid geonameid %q: %w", lineNo, cols[colGeonameID], err)
+		}
+		lat, err := strconv.ParseFloat(cols[colLatitude], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid latitude %q: %w", lineNo, cols[colLatitude], err)
+		}
+		lon, err := strconv.ParseFloat(cols[colLongitude], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid longitude %q: %w", lineNo, cols[colLongitude], err)
+		}
+
+		cities = append(cities, models.City{
+			ID:          id,
+			Name:        cols[colName],
+			ASCIIName:   cols[colASCIIName],
+			CountryCode: cols[colCountryCode],
+			FeatureCode: cols[colFeatureCode],
+			Type:        CityType(cols[colFeatureCode]),
+			Lat:         lat,
+			Lon:         lon,
+			Population:  population,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return cities, nil
+}
+
+// SplitList splits a comma separated flag value into trimmed, upper-cased entries
+func SplitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.ToUpper(strings.TrimSpace(part)); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func toSet(values, fallback []string) map[string]bool {
+	if len(values) == 0 {
+		values = fallback
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToUpper(v)] = true
+	}
+	return set
+}
@@ -0,0 +1,124 @@
+package geonames
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func parseFixture(t *testing.T, f Filter) map[string]int64 {
+	t.Helper()
+	file, err := os.Open("testdata/DE_sample.txt")
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer file.Close()
+
+	cities, err := Parse(file, f)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	byName := make(map[string]int64, len(cities))
+	for _, c := range cities {
+		byName[c.Name] = c.Population
+	}
+	return byName
+}
+
+func TestParseDefaultFilter(t *testing.T) {
+	got := parseFixture(t, Filter{})
+
+	for _, name := range []string{"Berlin", "Hamburg", "Wittenberg", "Kassel", "Kleindorf", "Linz"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("expected %s to be imported", name)
+		}
+	}
+	// Mountains and city sections are not populated places we import
+	for _, name := range []string{"Zugspitze", "Berlin-Mitte"} {
+		if _, ok := got[name]; ok {
+			t.Errorf("did not expect %s to be imported", name)
+		}
+	}
+	if got["Kleindorf"] != 0 {
+		t.Errorf("empty population should parse as 0, got %d", got["Kleindorf"])
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"country", Filter{Countries: []string{"at"}}, []string{"Linz"}},
+		{"feature codes", Filter{FeatureCodes: []string{"PPLC", "PPLA"}}, []string{"Berlin", "Hamburg", "Linz"}},
+		{"min population", Filter{Countries: []string{"DE"}, MinPopulation: 1000000}, []string{"Berlin", "Hamburg"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseFixture(t, tt.filter)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d cities %v, want %v", len(got), got, tt.want)
+			}
+			for _, name := range tt.want {
+				if _, ok := got[name]; !ok {
+					t.Errorf("expected %s in result %v", name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	file, err := os.Open("testdata/DE_sample.txt")
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer file.Close()
+
+	cities, err := Parse(file, Filter{FeatureCodes: []string{"PPLC"}})
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(cities) != 1 {
+		t.Fatalf("expected 1 city, got %d", len(cities))
+	}
+	c := cities[0]
+	if c.ID != 2950159 || c.Type != "capital" || c.CountryCode != "DE" || c.Lat != 52.52437 || c.Lon != 13.41053 {
+		t.Errorf("unexpected city: %+v", c)
+	}
+}
+
+func TestParseMalformedLine(t *testing.T) {
+	_, err := Parse(strings.NewReader("123\tonly\tthree\n"), Filter{})
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("expected line-numbered error, got %v", err)
+	}
+}
+
+func TestCityType(t *testing.T) {
+	tests := map[string]string{
+		"PPLC":  "capital",
+		"PPLA":  "major_city",
+		"PPLA2": "town",
+		"PPLA3": "city",
+		"PPL":   "city",
+	}
+	for code, want := range tests {
+		if got := CityType(code); got != want {
+			t.Errorf("CityType(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := SplitList(" de, at ,,ch")
+	want := []string{"DE", "AT", "CH"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("SplitList = %v, want %v", got, want)
+	}
+	if SplitList("") != nil {
+		t.Error("SplitList(\"\") should be nil")
+	}
+}
@@ -0,0 +1,128 @@
+package admin
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/templates"
+	"id-100/internal/uploads"
+	"id-100/internal/utils"
+)
+
+// AdminBagDetailHandler shows a bag with the history of all its sessions
+func AdminBagDetailHandler(c *echo.Context) error {
+	tokenID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Invalid token ID")
+	}
+
+	ctx := c.Request().Context()
+	token, err := repository.GetTokenInfo(ctx, tokenID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.String(http.StatusNotFound, "Token not found")
+	}
+	if err != nil {
+		log.Printf("Failed to fetch token %d: %v", tokenID, err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Database error")
+	}
+
+	sessions, err := repository.ListBagSessions(ctx, tokenID, token.TotalSessions)
+	if err != nil {
+		log.Printf("Failed to fetch sessions of token %d: %v", tokenID, err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Database error")
+	}
+
+	uploadCount, points := 0, 0
+	for i := range sessions {
+		uploadCount += len(sessions[i].Uploads)
+		points += sessions[i].Points
+		for j := range sessions[i].Uploads {
+			sessions[i].Uploads[j].ImageURL = utils.EnsureFullImageURL(sessions[i].Uploads[j].ImageURL)
+		}
+	}
+
+	// Only the last ended session can be reopened, and only while the next one is still empty
+	reopenable := -1
+	if token.CurrentPlayer == "" && token.TotalUploads == 0 {
+		reopenable = token.TotalSessions - 1
+	}
+
+	return c.Render(http.StatusOK, "layout", templates.MergeTemplateData(map[string]interface{}{
+		"Title":            "Werkzeug " + token.BagName,
+		"ContentTemplate":  "admin_bag.content",
+		"AdditionalCSS":    "admin.styles.css",
+		"Token":            token,
+		"Sessions":         sessions,
+		"UploadCount":      uploadCount,
+		"Points":           points,
+		"ReopenableNumber": strconv.Itoa(reopenable),
+		"CurrentPath":      c.Request().URL.Path,
+		"CurrentYear":      time.Now().Year(),
+	}))
+}
+
+// parseSessionParams reads :id and :session of the session routes
+func parseSessionParams(c *echo.Context) (tokenID, sessionNumber int, err error) {
+	if tokenID, err = strconv.Atoi(c.Param("id")); err != nil {
+		return 0, 0, err
+	}
+	sessionNumber, err = strconv.Atoi(c.Param("session"))
+	return tokenID, sessionNumber, err
+}
+
+// AdminReopenSessionHandler continues the last ended session of a bag, e.g.
+// when a player ended it by mistake
+func AdminReopenSessionHandler(c *echo.Context) error {
+	tokenID, sessionNumber, err := parseSessionParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+
+	ok, err := repository.ReopenSession(c.Request().Context(), tokenID, sessionNumber)
+	if err != nil {
+		log.Printf("Failed to reopen session %d of token %d: %v", sessionNumber, tokenID, err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+	if !ok {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "Nur die zuletzt beendete Session kann fortgesetzt werden, solange die aktuelle noch leer ist",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"status":  "success",
+		"message": "Session #" + strconv.Itoa(sessionNumber) + " läuft wieder",
+	})
+}
+
+// AdminDeleteSessionUploadsHandler deletes all contributions of one bag session
+func AdminDeleteSessionUploadsHandler(c *echo.Context) error {
+	tokenID, sessionNumber, err := parseSessionParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+
+	deleted, err := uploads.DeleteSession(c.Request().Context(), tokenID, sessionNumber)
+	if err != nil {
+		log.Printf("Failed to delete uploads of session %d of token %d: %v", sessionNumber, tokenID, err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"deleted": deleted,
+		"message": strconv.Itoa(deleted) + " Beiträge gelöscht",
+	})
+}
@@ -1,35 +1,233 @@
 package admin
 
 import (
-	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
 	"log"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v5"
 
+	"id-100/internal/bagcode"
+	"id-100/internal/bagrequest"
+	"id-100/internal/config"
+	"id-100/internal/email"
 	"id-100/internal/repository"
 	"id-100/internal/sentryhelper"
+	"id-100/internal/utils"
+	"id-100/internal/webhooks"
 )
 
-// AdminBagRequestCompleteHandler marks a bag request as handled
-func AdminBagRequestCompleteHandler(c *echo.Context) error {
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
+// maxAdminNotesLength limits the admin notes of a bag request
+const maxAdminNotesLength = 2000
+
+// AdminBagRequestStatusHandler moves a bag request along the fulfilment pipeline
+func AdminBagRequestStatusHandler(c *echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
 	}
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := c.Bind(&req); err != nil || !bagrequest.IsValidStatus(req.Status) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid status"})
+	}
 
-	rowsAffected, err := repository.MarkBagRequestHandled(context.Background(), id)
+	ctx := c.Request().Context()
+	br, err := repository.GetBagRequest(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	}
 	if err != nil {
-		log.Printf("Failed to mark bag_request handled: %v", err)
+		log.Printf("Failed to load bag_request %d: %v", id, err)
 		sentryhelper.CaptureException(c, err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
 	}
+	if !bagrequest.CanTransition(br.Status, req.Status) {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": fmt.Sprintf("Status %q kann nicht auf %q gesetzt werden",
+				bagrequest.StatusLabels[br.Status], bagrequest.StatusLabels[req.Status]),
+		})
+	}
 
-	if rowsAffected == 0 {
+	err = repository.SetBagRequestStatus(ctx, id, br.Status, req.Status)
+	if errors.Is(err, repository.ErrBagRequestStatusChanged) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Status wurde inzwischen geändert, bitte neu laden"})
+	}
+	if err != nil {
+		log.Printf("Failed to set bag_request %d status: %v", id, err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+
+	if req.Status == bagrequest.StatusShipped {
+		if err := email.Enqueue(ctx, email.TemplateBagShipped, br.Email, email.BagShipped{
+			BaseURL:   strings.TrimRight(config.GetBaseURL(), "/"),
+			RequestID: br.ID,
+		}); err != nil {
+			log.Printf("Failed to queue shipping email for bag_request %d: %v", br.ID, err)
+			sentryhelper.CaptureException(c, err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": req.Status})
+}
+
+// AdminBagRequestTokenHandler creates the upload token for a confirmed bag request
+func AdminBagRequestTokenHandler(c *echo.Context, baseURL string) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+	var req struct {
+		BagName    string `json:"bag_name"`
+		MaxUploads int    `json:"max_uploads"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	req.BagName = strings.TrimSpace(req.BagName)
+	if req.BagName == "" {
+		req.BagName = fmt.Sprintf("Anfrage #%d", id)
+	}
+	if req.MaxUploads <= 0 {
+		req.MaxUploads = 100 // Default
+	}
+
+	token, err := utils.GenerateSecureToken(40)
+	if err != nil {
+		log.Printf("Failed to generate token: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+
+	tokenID, shortCode, err := repository.CreateTokenForBagRequest(c.Request().Context(), id, token, req.BagName, req.MaxUploads)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	case errors.Is(err, repository.ErrBagRequestStatusChanged):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Nur bestätigte Anfragen bekommen einen Token"})
+	case err != nil:
+		log.Printf("Failed to create token for bag_request %d: %v", id, err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
 	}
 
+	webhooks.Emit(c.Request().Context(), webhooks.EventTokenCreated, webhooks.TokenCreated{
+		TokenID:    tokenID,
+		BagName:    req.BagName,
+		MaxUploads: req.MaxUploads,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":     bagrequest.StatusTokenAssigned,
+		"token_id":   tokenID,
+		"bag_name":   req.BagName,
+		"code":       bagcode.Format(shortCode),
+		"upload_url": bagcode.URL(baseURL, shortCode),
+		"qr_url":     fmt.Sprintf("%s/admin/tokens/%d/qr", baseURL, tokenID),
+	})
+}
+
+// AdminBagRequestNotesHandler saves the admin notes of a bag request
+func AdminBagRequestNotesHandler(c *echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+	var req struct {
+		Notes string `json:"notes"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	notes := strings.TrimSpace(req.Notes)
+	if utf8.RuneCountInString(notes) > maxAdminNotesLength {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Notizen dürfen höchstens %d Zeichen lang sein", maxAdminNotesLength)})
+	}
+
+	rowsAffected, err := repository.UpdateBagRequestNotes(c.Request().Context(), id, notes)
+	if err != nil {
+		log.Printf("Failed to update bag_request %d notes: %v", id, err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+	if rowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	}
 	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
 }
+
+// AdminBagRequestExportHandler exports bag requests as CSV, by default the shipping
+// list (token assigned, not yet shipped). ?status=all exports every confirmed request.
+func AdminBagRequestExportHandler(c *echo.Context) error {
+	status := c.QueryParam("status")
+	switch {
+	case status == "":
+		status = bagrequest.StatusTokenAssigned
+	case status == "all":
+		status = ""
+	case !bagrequest.IsValidStatus(status) || status == bagrequest.StatusNew:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid status"})
+	}
+
+	requests, err := repository.GetBagRequests(c.Request().Context(), status, 0)
+	if err != nil {
+		log.Printf("Failed to export bag requests: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+
+	name := status
+	if name == "" {
+		name = "alle"
+	}
+	filename := fmt.Sprintf("werkzeug-anfragen-%s-%s.csv", name, time.Now().Format("2006-01-02"))
+	c.Response().Header().Set("Content-Type", "text/csv; charset=utf-8")
+	c.Response().Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Response().WriteHeader(http.StatusOK)
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04")
+	}
+
+	w := csv.NewWriter(c.Response())
+	w.Write([]string{"ID", "E-Mail", "Name", "Straße", "PLZ", "Ort", "Werkzeug", "Token-ID", "Status",
+		"Bestätigt", "Token zugewiesen", "Versendet", "Zurück", "Verloren", "Notizen"})
+	for _, br := range requests {
+		tokenID := ""
+		if br.TokenID != nil {
+			tokenID = strconv.Itoa(*br.TokenID)
+		}
+		w.Write([]string{
+			strconv.Itoa(br.ID),
+			utils.CSVSafe(br.Email),
+			utils.CSVSafe(br.ShippingName),
+			utils.CSVSafe(br.ShippingStreet),
+			utils.CSVSafe(br.ShippingPostalCode),
+			utils.CSVSafe(br.ShippingCity),
+			utils.CSVSafe(br.TokenBagName),
+			tokenID,
+			bagrequest.StatusLabels[br.Status],
+			formatTime(br.ConfirmedAt),
+			formatTime(br.TokenAssignedAt),
+			formatTime(br.ShippedAt),
+			formatTime(br.ReturnedAt),
+			formatTime(br.LostAt),
+			utils.CSVSafe(br.AdminNotes),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
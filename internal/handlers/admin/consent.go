@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/consent"
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/utils"
+)
+
+// AdminConsentExportHandler exports all consent records as CSV or JSON
+func AdminConsentExportHandler(c *echo.Context, format string) error {
+	records, err := repository.ListConsents(c.Request().Context())
+	if err != nil {
+		log.Printf("Failed to export consents: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+
+	filename := fmt.Sprintf("einwilligungen-%s.%s", time.Now().Format("2006-01-02"), format)
+	c.Response().Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	if format == "json" {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"policy_version": consent.PolicyVersion,
+			"count":          len(records),
+			"consents":       records,
+		})
+	}
+
+	c.Response().Header().Set("Content-Type", "text/csv; charset=utf-8")
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(c.Response())
+	w.Write([]string{"ID", "Zeitpunkt", "Token-ID", "Werkzeug", "Session", "Version", "Quelle", "IP-Hash", "Texte"})
+	for _, r := range records {
+		tokenID := ""
+		if r.TokenID != nil {
+			tokenID = strconv.Itoa(*r.TokenID)
+		}
+		w.Write([]string{
+			strconv.Itoa(r.ID),
+			r.CreatedAt.Format(time.RFC3339),
+			tokenID,
+			utils.CSVSafe(r.BagName),
+			strconv.Itoa(r.SessionNumber),
+			utils.CSVSafe(r.PolicyVersion),
+			r.Source,
+			r.IPHash,
+			utils.CSVSafe(strings.Join(r.Texts, "\n")),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Printf("Failed to write consent export: %v", err)
+	}
+	return nil
+}
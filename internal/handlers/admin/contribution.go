@@ -11,6 +11,7 @@ import (
 	"id-100/internal/repository"
 	"id-100/internal/sentryhelper"
 	"id-100/internal/utils"
+	"id-100/internal/webhooks"
 )
 
 // AdminDeleteContributionHandler deletes a contribution from the admin panel
@@ -65,6 +66,12 @@ func AdminDeleteContributionHandler(c *echo.Context) error {
 		}
 	}
 
+	webhooks.Emit(ctx, webhooks.EventContributionDeleted, webhooks.ContributionDeleted{
+		ID:        contributionID,
+		TokenID:   tokenID,
+		DeletedBy: "admin",
+	})
+
 	return c.JSON(http.StatusOK, map[string]string{
 		"status":  "success",
 		"message": "Contribution deleted successfully",
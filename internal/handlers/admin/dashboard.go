@@ -9,11 +9,15 @@ import (
 	"github.com/getsentry/sentry-go"
 	"github.com/labstack/echo/v5"
 
+	"id-100/internal/bagrequest"
+	"id-100/internal/gdpr"
 	"id-100/internal/models"
 	"id-100/internal/repository"
+	"id-100/internal/rules"
 	"id-100/internal/sentryhelper"
 	"id-100/internal/templates"
 	"id-100/internal/utils"
+	"id-100/internal/webhooks"
 )
 
 // AdminDashboardHandler shows the admin dashboard
@@ -40,16 +44,24 @@ func AdminDashboardHandler(c *echo.Context) error {
 	// Fetch bag requests (with optional status filter)
 	status := c.QueryParam("bag_status")
 	tab := c.QueryParam("tab")
-	if tab != "tokens" && tab != "requests" && tab != "contribs" {
+	if tab != "tokens" && tab != "requests" && tab != "contribs" && tab != "rules" && tab != "webhooks" && tab != "privacy" {
 		tab = "tokens"
 	}
 
+	if status == "all" || !bagrequest.IsValidStatus(status) || status == bagrequest.StatusNew {
+		status = ""
+	}
+
 	// Get counts for filter badges
-	openCount, handledCount, err := repository.GetBagRequestCounts(context.Background())
+	bagCounts, err := repository.GetBagRequestCounts(context.Background())
 	if err != nil {
 		log.Printf("Failed to fetch bag request counts: %v", err)
 		sentryhelper.CaptureError(c, err, sentry.LevelWarning)
-		openCount, handledCount = 0, 0
+		bagCounts = map[string]int{}
+	}
+	bagTotal := 0
+	for _, s := range bagrequest.AdminStatuses {
+		bagTotal += bagCounts[s]
 	}
 
 	// Get bag requests
@@ -60,17 +72,101 @@ func AdminDashboardHandler(c *echo.Context) error {
 		bagRequests = []models.BagRequest{}
 	}
 
+	// Group labels of batch-created tokens for the export links
+	tokenGroups := []string{}
+	if tab == "tokens" {
+		if tokenGroups, err = repository.GetTokenGroups(context.Background()); err != nil {
+			log.Printf("Failed to fetch token groups: %v", err)
+			sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+			tokenGroups = []string{}
+		}
+	}
+
+	// Rulesets are edited on their tab and assigned on the token cards
+	rulesetList := []rules.Ruleset{}
+	if tab == "tokens" || tab == "rules" {
+		if rulesetList, err = repository.ListRulesets(context.Background()); err != nil {
+			log.Printf("Failed to fetch rulesets: %v", err)
+			sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+			rulesetList = []rules.Ruleset{}
+		}
+	}
+
+	// Webhook subscriptions and delivery log are only needed on their tab
+	webhookList := []models.Webhook{}
+	deliveries := []models.WebhookDelivery{}
+	if tab == "webhooks" {
+		if webhookList, err = repository.ListWebhooks(context.Background()); err != nil {
+			log.Printf("Failed to fetch webhooks: %v", err)
+			sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+			webhookList = []models.Webhook{}
+		}
+		if deliveries, err = repository.ListWebhookDeliveries(context.Background(), 50); err != nil {
+			log.Printf("Failed to fetch webhook deliveries: %v", err)
+			sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+			deliveries = []models.WebhookDelivery{}
+		}
+	}
+
+	// Consent records per privacy policy version for the privacy tab
+	consentCounts := []models.ConsentVersionCount{}
+	if tab == "privacy" {
+		if consentCounts, err = repository.CountConsentsByVersion(context.Background()); err != nil {
+			log.Printf("Failed to count consents: %v", err)
+			sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+			consentCounts = []models.ConsentVersionCount{}
+		}
+	}
+
+	// Data subject search and the audit log of exports and erasures
+	var subject *models.SubjectData
+	var subjectQuery models.SubjectQuery
+	subjectError := ""
+	audits := []models.PrivacyAudit{}
+	if tab == "privacy" {
+		if subjectSearched(c) {
+			if subjectQuery, err = subjectQueryParams(c); err != nil {
+				subjectError = err.Error()
+			} else if subject, err = gdpr.Find(context.Background(), subjectQuery); err != nil {
+				log.Printf("Failed to search subject data: %v", err)
+				sentryhelper.CaptureException(c, err)
+				subjectError = "Suche fehlgeschlagen"
+			}
+		}
+		if audits, err = repository.ListPrivacyAudits(context.Background(), 20); err != nil {
+			log.Printf("Failed to fetch privacy audits: %v", err)
+			sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+			audits = []models.PrivacyAudit{}
+		}
+	}
+
 	return c.Render(http.StatusOK, "layout", templates.MergeTemplateData(map[string]interface{}{
 		"Title":           "Admin Dashboard",
 		"ContentTemplate": "admin_dashboard.content",
 		"AdditionalCSS":   "admin.styles.css",
 		"Tokens":          tokens,
+		"TokenGroups":     tokenGroups,
 		"RecentContribs":  recentContribs,
 		"BagRequests":     bagRequests,
 		"BagStatus":       status,
-		"OpenCount":       openCount,
-		"HandledCount":    handledCount,
+		"BagStatuses":     bagrequest.AdminStatuses,
+		"BagStatusLabels": bagrequest.StatusLabels,
+		"BagCounts":       bagCounts,
+		"BagTotal":        bagTotal,
 		"Tab":             tab,
+		"Rulesets":        rulesetList,
+		"RulesetDefault":  rules.Default,
+		"Webhooks":        webhookList,
+		"WebhookEvents":   webhooks.Events,
+		"Deliveries":      deliveries,
+		"ConsentCounts":   consentCounts,
+		"Subject":         subject,
+		"SubjectCounts":   subjectCounts(subject),
+		"SubjectKinds":    gdpr.Kinds,
+		"SubjectLabels":   gdpr.Labels,
+		"SubjectError":    subjectError,
+		"SubjectSearch":   subjectSearchValues(c),
+		"PrivacyAudits":   audits,
 		"CurrentPath":     c.Request().URL.Path,
 		"CurrentYear":     time.Now().Year(),
 	}))
@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/gdpr"
+	"id-100/internal/models"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/utils"
+)
+
+// adminActor names the signed-in admin in privacy audit records
+func adminActor(c *echo.Context) string {
+	username, _, _ := c.Request().BasicAuth()
+	return "admin:" + username
+}
+
+// subjectQueryParams parses the subject search of the privacy tab from the query string
+func subjectQueryParams(c *echo.Context) (models.SubjectQuery, error) {
+	return gdpr.ParseQuery(c.QueryParam("name"), c.QueryParam("session"), c.QueryParam("email"), c.QueryParam("player"))
+}
+
+// AdminPrivacyExportHandler streams a ZIP with everything stored about a data
+// subject (access request). The export is recorded in the audit log.
+func AdminPrivacyExportHandler(c *echo.Context) error {
+	q, err := subjectQueryParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	d, err := gdpr.Export(ctx, q, adminActor(c), strings.TrimSpace(c.QueryParam("reference")))
+	if err != nil {
+		log.Printf("Failed to export subject data: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+
+	c.Response().Header().Set("Content-Type", "application/zip")
+	c.Response().Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": gdpr.ArchiveName(time.Now())}))
+	c.Response().WriteHeader(http.StatusOK)
+
+	err = gdpr.WriteArchive(c.Response(), d, q, func(imageURL string) (io.ReadCloser, error) {
+		body, err := utils.DownloadImageFromS3(ctx, imageURL)
+		if err != nil {
+			log.Printf("Subject export: %v", err)
+			sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+		}
+		return body, err
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		// Headers are sent already; the client gets a truncated archive
+		log.Printf("Failed to write subject export: %v", err)
+		sentryhelper.CaptureException(c, err)
+	}
+	return nil
+}
+
+// AdminPrivacyEraseHandler erases everything stored about a data subject and
+// reports whether a search afterwards came back empty
+func AdminPrivacyEraseHandler(c *echo.Context) error {
+	type EraseRequest struct {
+		Name      string `json:"name"`
+		Session   string `json:"session"`
+		Email     string `json:"email"`
+		Player    string `json:"player"`
+		Reference string `json:"reference"`
+	}
+
+	var req EraseRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	q, err := gdpr.ParseQuery(req.Name, req.Session, req.Email, req.Player)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	res, err := gdpr.Erase(c.Request().Context(), q, adminActor(c), strings.TrimSpace(req.Reference))
+	if err != nil {
+		log.Printf("Failed to erase subject data: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+
+	message := fmt.Sprintf("Daten gelöscht und geprüft (Protokoll #%d)", res.AuditID)
+	if !res.Verified {
+		log.Printf("Erasure %d not verified: %v records, %d images left", res.AuditID, res.Remaining, res.ImagesRemaining)
+		sentryhelper.CaptureError(c, errors.New("subject erasure not verified"), sentry.LevelWarning)
+		message = fmt.Sprintf("Gelöscht, aber bei der Prüfung noch Daten gefunden (Protokoll #%d). Bitte erneut löschen.", res.AuditID)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": message,
+		"result":  res,
+	})
+}
+
+// subjectSearched reports whether the privacy tab was opened with a subject search
+func subjectSearched(c *echo.Context) bool {
+	for _, p := range []string{"name", "session", "email", "player"} {
+		if strings.TrimSpace(c.QueryParam(p)) != "" {
+			return true
+		}
+	}
+	return c.QueryParam("search") != ""
+}
+
+// subjectSearchValues keeps the search form filled in and builds the export link
+func subjectSearchValues(c *echo.Context) map[string]string {
+	values := map[string]string{}
+	for _, p := range []string{"name", "session", "email", "player", "reference"} {
+		values[p] = strings.TrimSpace(c.QueryParam(p))
+	}
+	return values
+}
+
+// subjectCounts counts the records found, nil without a search
+func subjectCounts(d *models.SubjectData) map[string]int {
+	if d == nil {
+		return nil
+	}
+	return gdpr.Counts(d)
+}
@@ -1,15 +1,28 @@
 package admin
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image"
+	"image/png"
+	"log"
 	"mime"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/labstack/echo/v5"
 	qrcode "github.com/skip2/go-qrcode"
 
+	"id-100/internal/bagcode"
+	"id-100/internal/journey"
+	"id-100/internal/labels"
+	"id-100/internal/models"
 	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
 	"id-100/internal/utils"
 )
 
@@ -17,14 +30,14 @@ import (
 func AdminDownloadQRHandler(c *echo.Context, baseURL string) error {
 	tokenID := c.Param("id")
 
-	// Get token from database
-	token, bagName, err := repository.GetTokenByID(context.Background(), tokenID)
+	// Get the bag code from database
+	shortCode, bagName, err := repository.GetTokenShortCode(context.Background(), tokenID)
 	if err != nil {
 		return c.String(http.StatusNotFound, "Token not found")
 	}
 
-	// Generate upload URL
-	uploadURL := fmt.Sprintf("%s/upload?token=%s", baseURL, token)
+	// The short link keeps the QR code sparse and the token out of browser history
+	uploadURL := bagcode.URL(baseURL, shortCode)
 
 	// Check format parameter
 	format := c.QueryParam("format")
@@ -64,3 +77,120 @@ func AdminDownloadQRHandler(c *echo.Context, baseURL string) error {
 		return c.String(http.StatusBadRequest, "Invalid format. Use 'svg' or 'png'")
 	}
 }
+
+// labelLogo is the project logo printed on every label, loaded once from the static assets
+var labelLogo = sync.OnceValue(func() image.Image {
+	f, err := os.Open("web/static/assets/favicon/android-chrome-192x192.png")
+	if err != nil {
+		log.Printf("Label logo not available: %v", err)
+		return nil
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		log.Printf("Label logo not readable: %v", err)
+		return nil
+	}
+	return img
+})
+
+// AdminTokenLabelsHandler renders printable A4 label sheets as PDF for the
+// tokens given by ?ids=1,2,3 or ?group=, or for all tokens. The grid can be
+// changed with columns, rows, margin and bleed (in mm); marks=0 hides cut marks.
+func AdminTokenLabelsHandler(c *echo.Context, baseURL string) error {
+	layout := labels.DefaultLayout
+	intParam := func(name string, dst *int) error {
+		if v := c.QueryParam(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s", name)
+			}
+			*dst = n
+		}
+		return nil
+	}
+	floatParam := func(name string, dst *float64) error {
+		if v := c.QueryParam(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s", name)
+			}
+			*dst = f
+		}
+		return nil
+	}
+	for _, err := range []error{
+		intParam("columns", &layout.Columns),
+		intParam("rows", &layout.Rows),
+		floatParam("margin", &layout.MarginMM),
+		floatParam("bleed", &layout.BleedMM),
+	} {
+		if err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+	}
+	layout.CutMarks = c.QueryParam("marks") != "0"
+	if err := layout.Validate(); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	ctx := c.Request().Context()
+	var (
+		tokens []models.TokenInfo
+		err    error
+	)
+	if ids := c.QueryParam("ids"); ids != "" {
+		var list []int
+		for _, s := range strings.Split(ids, ",") {
+			id, convErr := strconv.Atoi(strings.TrimSpace(s))
+			if convErr != nil {
+				return c.String(http.StatusBadRequest, "invalid ids")
+			}
+			list = append(list, id)
+		}
+		if len(list) > labels.MaxLabels {
+			return c.String(http.StatusBadRequest, labels.ErrTooManyLabels.Error())
+		}
+		tokens, err = repository.GetTokensByIDs(ctx, list)
+	} else {
+		tokens, err = repository.GetTokensByGroup(ctx, c.QueryParam("group"))
+	}
+	if err != nil {
+		log.Printf("Failed to load tokens for labels: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Database error")
+	}
+	if len(tokens) == 0 {
+		return c.String(http.StatusNotFound, "No tokens found")
+	}
+	if len(tokens) > labels.MaxLabels {
+		return c.String(http.StatusBadRequest, labels.ErrTooManyLabels.Error())
+	}
+
+	host := strings.TrimPrefix(strings.TrimPrefix(baseURL, "https://"), "http://")
+	sheet := make([]labels.Label, len(tokens))
+	for i, t := range tokens {
+		sheet[i] = labels.Label{
+			BagName: t.BagName,
+			Code:    bagcode.Format(t.ShortCode),
+			URL:     bagcode.URL(baseURL, t.ShortCode),
+			Journey: "Reise: " + journey.URL(host, t.JourneySlug),
+		}
+	}
+
+	var buf bytes.Buffer
+	instructions := fmt.Sprintf("QR-Code scannen oder den Code auf %s/b eingeben. Namen eintragen, Fotos zu den Aufgaben hochladen und das Werkzeug danach weitergeben.", host)
+	if err := labels.Render(&buf, sheet, labels.Options{Layout: layout, Title: "ID-100", Instructions: instructions, Logo: labelLogo()}); err != nil {
+		log.Printf("Failed to render labels: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "PDF generation failed")
+	}
+
+	name := "alle"
+	if g := c.QueryParam("group"); g != "" {
+		name = utils.SanitizeFilename(g)
+	}
+	filename := fmt.Sprintf("etiketten_%s.pdf", name)
+	c.Response().Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename}))
+	return c.Blob(http.StatusOK, "application/pdf", buf.Bytes())
+}
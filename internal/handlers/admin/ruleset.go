@@ -0,0 +1,161 @@
+package admin
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/repository"
+	"id-100/internal/rules"
+	"id-100/internal/sentryhelper"
+)
+
+// rulesetRequest is the body of the ruleset editor; allowed derives are
+// entered as a list like "1-20, 35"
+type rulesetRequest struct {
+	Name            string `json:"name"`
+	CooldownSeconds int    `json:"cooldown_seconds"`
+	SessionUploads  int    `json:"session_uploads"`
+	LifetimeUploads int    `json:"lifetime_uploads"`
+	SessionMinutes  int    `json:"session_minutes"`
+	IdleMinutes     int    `json:"idle_minutes"`
+	AllowedDerives  string `json:"allowed_derives"`
+}
+
+// bindRuleset parses and validates a ruleset from the request body
+func bindRuleset(c *echo.Context) (rules.Ruleset, error) {
+	var req rulesetRequest
+	if err := c.Bind(&req); err != nil {
+		return rules.Ruleset{}, errors.New("Invalid request body")
+	}
+	derives, err := rules.ParseDerives(req.AllowedDerives)
+	if err != nil {
+		return rules.Ruleset{}, errors.New("Ungültige Aufgabenliste, z.B. \"1-20, 35\"")
+	}
+	r := rules.Ruleset{
+		Name:            req.Name,
+		CooldownSeconds: req.CooldownSeconds,
+		SessionUploads:  req.SessionUploads,
+		LifetimeUploads: req.LifetimeUploads,
+		SessionMinutes:  req.SessionMinutes,
+		IdleMinutes:     req.IdleMinutes,
+		AllowedDerives:  derives,
+	}
+	switch err := r.Validate(); {
+	case errors.Is(err, rules.ErrNameRequired):
+		return r, errors.New("Name erforderlich")
+	case err != nil:
+		return r, errors.New("Limits dürfen nicht negativ sein")
+	}
+	return r, nil
+}
+
+// isUniqueViolation reports whether err is a duplicate key error
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// AdminCreateRulesetHandler creates a named ruleset
+func AdminCreateRulesetHandler(c *echo.Context) error {
+	r, err := bindRuleset(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	id, err := repository.CreateRuleset(c.Request().Context(), r)
+	if isUniqueViolation(err) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Es gibt bereits ein Regelwerk mit diesem Namen"})
+	}
+	if err != nil {
+		log.Printf("Failed to create ruleset: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"status": "success", "id": id})
+}
+
+// AdminUpdateRulesetHandler replaces a ruleset; it applies to its bags immediately
+func AdminUpdateRulesetHandler(c *echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+	r, err := bindRuleset(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	r.ID = id
+
+	rows, err := repository.UpdateRuleset(c.Request().Context(), r)
+	if isUniqueViolation(err) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Es gibt bereits ein Regelwerk mit diesem Namen"})
+	}
+	if err != nil {
+		log.Printf("Failed to update ruleset: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+	if rows == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "success"})
+}
+
+// AdminDeleteRulesetHandler removes a ruleset; its bags fall back to the defaults
+func AdminDeleteRulesetHandler(c *echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+
+	rows, err := repository.DeleteRuleset(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Failed to delete ruleset: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+	if rows == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "success"})
+}
+
+// AdminTokenRulesetHandler assigns a ruleset to a token; a ruleset_id of 0
+// or null switches the bag back to the defaults
+func AdminTokenRulesetHandler(c *echo.Context) error {
+	tokenID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+
+	type AssignRequest struct {
+		RulesetID *int `json:"ruleset_id"`
+	}
+	var req AssignRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.RulesetID != nil && *req.RulesetID == 0 {
+		req.RulesetID = nil
+	}
+
+	rows, err := repository.SetTokenRuleset(c.Request().Context(), tokenID, req.RulesetID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Regelwerk nicht gefunden"})
+	}
+	if err != nil {
+		log.Printf("Failed to assign ruleset: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+	if rows == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Token not found"})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"status": "success", "ruleset_id": req.RulesetID})
+}
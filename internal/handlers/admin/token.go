@@ -2,21 +2,39 @@ package admin
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v5"
 
+	"id-100/internal/bagcode"
 	"id-100/internal/repository"
 	"id-100/internal/sentryhelper"
+	"id-100/internal/tokenbatch"
 	"id-100/internal/utils"
+	"id-100/internal/webhooks"
 )
 
-// AdminTokenResetHandler resets a token for the next player
+// AdminTokenResetHandler resets a token for the next player. Subscribers get
+// session.ended only if a session was running.
 func AdminTokenResetHandler(c *echo.Context) error {
 	tokenID := c.Param("id")
 
+	bagName, sessionNumber, player, err := repository.GetTokenSession(c.Request().Context(), tokenID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.String(http.StatusNotFound, "Token not found")
+	}
+	if err != nil {
+		log.Printf("Database error in AdminTokenResetHandler: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Database error")
+	}
+
 	rows, err := repository.ResetToken(context.Background(), tokenID)
 	if err != nil {
 		log.Printf("Database error in AdminTokenResetHandler: %v", err)
@@ -28,6 +46,17 @@ func AdminTokenResetHandler(c *echo.Context) error {
 		return c.String(http.StatusNotFound, "Token not found")
 	}
 
+	if player != "" {
+		id, _ := strconv.Atoi(tokenID)
+		webhooks.Emit(c.Request().Context(), webhooks.EventSessionEnded, webhooks.Session{
+			TokenID:       id,
+			BagName:       bagName,
+			SessionNumber: sessionNumber,
+			PlayerName:    webhooks.PublicPlayerName(c.Request().Context(), id, sessionNumber),
+			EndedBy:       "admin",
+		})
+	}
+
 	return c.JSON(http.StatusOK, map[string]string{
 		"status":  "success",
 		"message": "Werkzeug wurde zurückgesetzt und kann an den nächsten Spieler weitergegeben werden",
@@ -52,6 +81,28 @@ func AdminTokenDeactivateHandler(c *echo.Context) error {
 	})
 }
 
+// AdminTokenReleaseDeviceHandler lifts the device binding of a running session;
+// the next device that opens the bag continues it
+func AdminTokenReleaseDeviceHandler(c *echo.Context) error {
+	tokenID := c.Param("id")
+
+	rows, err := repository.ReleaseTokenDevice(c.Request().Context(), tokenID)
+	if err != nil {
+		log.Printf("Database error in AdminTokenReleaseDeviceHandler: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Database error")
+	}
+
+	if rows == 0 {
+		return c.String(http.StatusNotFound, "Token not found")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"status":  "success",
+		"message": "Gerät freigegeben. Das nächste Gerät, das den QR-Code öffnet, übernimmt die Session.",
+	})
+}
+
 // AdminTokenAssignHandler assigns a token to a specific player
 func AdminTokenAssignHandler(c *echo.Context) error {
 	tokenID := c.Param("id")
@@ -108,6 +159,7 @@ func AdminCreateTokenHandler(c *echo.Context, baseURL string) error {
 	type CreateRequest struct {
 		BagName    string `json:"bag_name"`
 		MaxUploads int    `json:"max_uploads"`
+		ExpiresAt  string `json:"expires_at"`
 	}
 
 	var req CreateRequest
@@ -127,6 +179,13 @@ func AdminCreateTokenHandler(c *echo.Context, baseURL string) error {
 		req.MaxUploads = 100 // Default
 	}
 
+	expiresAt, err := tokenbatch.ParseExpiry(req.ExpiresAt, time.Now(), time.Local)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
 	// Generate secure token
 	token, err := utils.GenerateSecureToken(40)
 	if err != nil {
@@ -138,7 +197,7 @@ func AdminCreateTokenHandler(c *echo.Context, baseURL string) error {
 	}
 
 	// Insert into database
-	tokenID, err := repository.CreateToken(context.Background(), token, req.BagName, req.MaxUploads)
+	tokenID, shortCode, err := repository.CreateToken(context.Background(), token, req.BagName, req.MaxUploads, expiresAt)
 	if err != nil {
 		log.Printf("Failed to create token: %v", err)
 		sentryhelper.CaptureException(c, err)
@@ -147,16 +206,101 @@ func AdminCreateTokenHandler(c *echo.Context, baseURL string) error {
 		})
 	}
 
-	// Generate upload URL
-	uploadURL := fmt.Sprintf("%s/upload?token=%s", baseURL, token)
+	webhooks.Emit(c.Request().Context(), webhooks.EventTokenCreated, webhooks.TokenCreated{
+		TokenID:    tokenID,
+		BagName:    req.BagName,
+		MaxUploads: req.MaxUploads,
+	})
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"status":     "success",
 		"token_id":   tokenID,
 		"token":      token,
+		"code":       bagcode.Format(shortCode),
 		"bag_name":   req.BagName,
-		"upload_url": uploadURL,
-		"qr_url":     fmt.Sprintf("%s/admin/tokens/%d/qr", baseURL, tokenID),
+		"expires_at": expiresAt,
+		"upload_url": bagcode.URL(baseURL, shortCode),
+		"qr_url":     tokenbatch.QRURL(baseURL, tokenID),
+	})
+}
+
+// AdminTokenRotateHandler gives a bag a new token and bag code. Printed QR
+// codes, short links and open browser sessions of the old token stop working;
+// the new token is only shown in this response.
+func AdminTokenRotateHandler(c *echo.Context, baseURL string) error {
+	tokenID := c.Param("id")
+
+	token, err := utils.GenerateSecureToken(40)
+	if err != nil {
+		log.Printf("Failed to generate token: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+
+	shortCode, bagName, err := repository.RotateToken(c.Request().Context(), tokenID, token)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Token not found",
+		})
+	}
+	if err != nil {
+		log.Printf("Failed to rotate token: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+
+	id, _ := strconv.Atoi(tokenID)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":     "success",
+		"token_id":   id,
+		"token":      token,
+		"code":       bagcode.Format(shortCode),
+		"bag_name":   bagName,
+		"upload_url": bagcode.URL(baseURL, shortCode),
+		"qr_url":     tokenbatch.QRURL(baseURL, id),
+	})
+}
+
+// AdminUpdateExpiryHandler sets the expiry of a token; an empty expires_at removes it
+func AdminUpdateExpiryHandler(c *echo.Context) error {
+	tokenID := c.Param("id")
+
+	type ExpiryRequest struct {
+		ExpiresAt string `json:"expires_at"`
+	}
+
+	var req ExpiryRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	expiresAt, err := tokenbatch.ParseExpiry(req.ExpiresAt, time.Now(), time.Local)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	rows, err := repository.UpdateTokenExpiry(c.Request().Context(), tokenID, expiresAt)
+	if err != nil {
+		log.Printf("Database error in AdminUpdateExpiryHandler: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Database error")
+	}
+
+	if rows == 0 {
+		return c.String(http.StatusNotFound, "Token not found")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":     "success",
+		"expires_at": expiresAt,
 	})
 }
 
@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/tokenbatch"
+)
+
+// AdminCreateTokenBatchHandler creates many tokens at once, either from a name
+// pattern (JSON) or from an uploaded CSV of bag names and quotas (multipart
+// form with a "file" field). Without a group label the batch gets a dated one
+// so it can be exported again later. An optional expires_at applies to all tokens.
+func AdminCreateTokenBatchHandler(c *echo.Context, baseURL string) error {
+	var (
+		entries []tokenbatch.Entry
+		group   string
+		expires string
+		err     error
+	)
+
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		group = c.FormValue("group")
+		expires = c.FormValue("expires_at")
+		maxUploads, _ := strconv.Atoi(c.FormValue("max_uploads"))
+
+		fh, ferr := c.FormFile("file")
+		if ferr != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "file is required"})
+		}
+		f, ferr := fh.Open()
+		if ferr != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "file is required"})
+		}
+		defer f.Close()
+
+		entries, err = tokenbatch.ParseCSV(f, maxUploads)
+	} else {
+		var req struct {
+			Pattern    string `json:"pattern"`
+			Start      int    `json:"start"`
+			Count      int    `json:"count"`
+			MaxUploads int    `json:"max_uploads"`
+			Group      string `json:"group"`
+			ExpiresAt  string `json:"expires_at"`
+		}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		}
+		if req.Start == 0 {
+			req.Start = 1
+		}
+		group = req.Group
+		expires = req.ExpiresAt
+		entries, err = tokenbatch.FromPattern(req.Pattern, req.Start, req.Count, req.MaxUploads)
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	group, err = tokenbatch.NormalizeGroup(group)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if group == "" {
+		group = "Stapel " + time.Now().Format("2006-01-02 15:04:05")
+	}
+	expiresAt, err := tokenbatch.ParseExpiry(expires, time.Now(), time.Local)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	tokens, err := tokenbatch.Create(c.Request().Context(), entries, group, expiresAt, baseURL)
+	if err != nil {
+		log.Printf("Failed to create token batch: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+
+	q := url.Values{"group": {group}}.Encode()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":      "success",
+		"group":       group,
+		"count":       len(tokens),
+		"tokens":      tokens,
+		"export_csv":  "/admin/tokens/export.csv?" + q,
+		"export_json": "/admin/tokens/export.json?" + q,
+	})
+}
+
+// AdminTokenExportHandler exports tokens with their upload URLs as CSV or JSON,
+// optionally limited to one group via ?group=
+func AdminTokenExportHandler(c *echo.Context, baseURL, format string) error {
+	group := c.QueryParam("group")
+
+	infos, err := repository.GetTokensByGroup(c.Request().Context(), group)
+	if err != nil {
+		log.Printf("Failed to export tokens: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+	tokens := tokenbatch.Export(infos, baseURL)
+
+	name := "alle"
+	if group != "" {
+		name = group
+	}
+	filename := fmt.Sprintf("tokens-%s-%s.%s", name, time.Now().Format("2006-01-02"), format)
+	c.Response().Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	if format == "json" {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"group":  group,
+			"count":  len(tokens),
+			"tokens": tokens,
+		})
+	}
+
+	c.Response().Header().Set("Content-Type", "text/csv; charset=utf-8")
+	c.Response().WriteHeader(http.StatusOK)
+	if err := tokenbatch.WriteCSV(c.Response(), tokens); err != nil {
+		log.Printf("Failed to write token export: %v", err)
+	}
+	return nil
+}
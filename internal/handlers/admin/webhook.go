@@ -0,0 +1,151 @@
+package admin
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/utils"
+	"id-100/internal/webhooks"
+)
+
+// AdminCreateWebhookHandler creates a webhook subscription and returns its signing secret
+func AdminCreateWebhookHandler(c *echo.Context) error {
+	type CreateRequest struct {
+		URL         string   `json:"url"`
+		Events      []string `json:"events"`
+		Description string   `json:"description"`
+	}
+
+	var req CreateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	req.URL = strings.TrimSpace(req.URL)
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "URL muss mit http:// oder https:// beginnen"})
+	}
+	if len(req.Events) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Mindestens ein Event auswählen"})
+	}
+	for _, e := range req.Events {
+		if !webhooks.IsValidEvent(e) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unbekanntes Event: " + e})
+		}
+	}
+
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		log.Printf("Failed to generate webhook secret: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+
+	id, err := repository.CreateWebhook(c.Request().Context(), req.URL, secret, req.Events, strings.TrimSpace(req.Description))
+	if err != nil {
+		log.Printf("Failed to create webhook: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"id":     id,
+		"secret": secret,
+	})
+}
+
+// AdminWebhookToggleHandler pauses or resumes a webhook subscription
+func AdminWebhookToggleHandler(c *echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+
+	type ToggleRequest struct {
+		Active bool `json:"active"`
+	}
+	var req ToggleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	rows, err := repository.SetWebhookActive(c.Request().Context(), id, req.Active)
+	if err != nil {
+		log.Printf("Failed to toggle webhook: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+	if rows == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"status": "ok", "active": req.Active})
+}
+
+// AdminDeleteWebhookHandler removes a webhook subscription and its delivery log
+func AdminDeleteWebhookHandler(c *echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+
+	rows, err := repository.DeleteWebhook(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Failed to delete webhook: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+	if rows == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// AdminWebhookTestHandler queues a ping event for one subscription
+func AdminWebhookTestHandler(c *echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+
+	queued, err := webhooks.Ping(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Failed to queue webhook ping: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+	if !queued {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+	return c.JSON(http.StatusOK, map[string]string{
+		"status":  "ok",
+		"message": "Test-Event wurde in die Warteschlange gestellt",
+	})
+}
+
+// AdminRetryWebhookDeliveryHandler queues a finished delivery for another attempt
+func AdminRetryWebhookDeliveryHandler(c *echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+
+	rows, err := repository.RetryWebhookDelivery(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Failed to retry webhook delivery: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "server error"})
+	}
+	if rows == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "not found or already queued"})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
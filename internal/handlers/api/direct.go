@@ -0,0 +1,262 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/consent"
+	"id-100/internal/middleware"
+	"id-100/internal/models"
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/tus"
+	"id-100/internal/uploads"
+	"id-100/internal/utils"
+)
+
+// Presigned direct-to-storage uploads. The client requests a presigned PUT/POST for a
+// staging key, sends the file straight to MinIO/S3 and then calls finalise, which
+// validates the object and creates the contribution under the token's quota rules.
+
+const (
+	// directUploadExpiry is how long presigned URLs stay valid
+	directUploadExpiry = 15 * time.Minute
+	// directUploadGrace allows finalising shortly after the URL expired (slow uploads)
+	directUploadGrace = time.Hour
+	// maxPendingDirectUploads caps open presigned uploads per token
+	maxPendingDirectUploads = 5
+)
+
+// presignRequest is the body of DirectUploadCreateHandler
+type presignRequest struct {
+	DeriveNumber int    `json:"derive_number"`
+	ContentType  string `json:"content_type"`
+}
+
+// DirectUploadCreateHandler presigns a staging upload for the current session
+func DirectUploadCreateHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+	tokenID, _ := c.Get("token_id").(int)
+	sessionNumber, _ := c.Get("session_number").(int)
+	remaining, _ := c.Get("uploads_remaining").(int)
+
+	if currentPlayer, _ := c.Get("current_player").(string); currentPlayer == "" {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "Bitte starte zuerst eine Sitzung mit deinem Namen",
+			"code":  "player_name_required",
+		})
+	}
+	consented, err := repository.HasConsent(ctx, tokenID, sessionNumber, consent.PolicyVersion)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !consented {
+		return consentRequired(c)
+	}
+
+	var req presignRequest
+	if err := c.Bind(&req); err != nil {
+		return apiError(c, http.StatusBadRequest, "Ungültige Anfrage")
+	}
+	req.ContentType = strings.ToLower(strings.TrimSpace(req.ContentType))
+	if _, ok := uploads.DirectUploadContentTypes[req.ContentType]; !ok {
+		return apiError(c, http.StatusUnsupportedMediaType, "Nur JPEG, PNG oder WebP erlaubt")
+	}
+	if req.DeriveNumber < 1 {
+		return apiError(c, http.StatusBadRequest, "Ungültige Aufgabennummer")
+	}
+	if !middleware.Ruleset(c).AllowsDerive(req.DeriveNumber) {
+		return apiError(c, http.StatusForbidden, deriveNotAllowedMessage)
+	}
+
+	exists, err := repository.DeriveExists(ctx, req.DeriveNumber)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !exists {
+		return apiError(c, http.StatusNotFound, "Aufgabe nicht gefunden")
+	}
+
+	pending, err := repository.CountPendingDirectUploads(ctx, tokenID)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if remaining-pending <= 0 {
+		return apiError(c, http.StatusForbidden, "Upload-Limit erreicht")
+	}
+	if pending >= maxPendingDirectUploads {
+		return apiError(c, http.StatusTooManyRequests, "Zu viele offene Uploads, bitte schließe zuerst die laufenden ab")
+	}
+
+	id, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return dbError(c, err)
+	}
+	key := uploads.DirectStagingKey(id, req.ContentType)
+
+	presigned, err := utils.PresignUpload(ctx, key, req.ContentType, tus.MaxSize, directUploadExpiry)
+	if err != nil {
+		log.Printf("Presigning upload failed: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return apiError(c, http.StatusInternalServerError, "Upload fehlgeschlagen")
+	}
+
+	err = repository.CreateDirectUpload(ctx, models.DirectUpload{
+		ID:            id,
+		TokenID:       tokenID,
+		SessionNumber: sessionNumber,
+		DeriveNumber:  req.DeriveNumber,
+		ObjectKey:     key,
+		ContentType:   req.ContentType,
+		ExpiresAt:     presigned.ExpiresAt,
+	})
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"id":           id,
+		"upload":       presigned,
+		"finalize_url": "/api/v1/direct-uploads/" + id + "/finalize",
+		"max_size":     tus.MaxSize,
+	})
+}
+
+// finalizeRequest is the optional body of DirectUploadFinalizeHandler
+type finalizeRequest struct {
+	Comment string `json:"comment"`
+}
+
+// DirectUploadFinalizeHandler turns a staged object into a contribution.
+// Repeating the call after success returns the same contribution.
+func DirectUploadFinalizeHandler(c *echo.Context) error {
+	// Finalisation must not be cut short by a client that gives up waiting
+	ctx := context.WithoutCancel(c.Request().Context())
+	tokenID, _ := c.Get("token_id").(int)
+	sessionNumber, _ := c.Get("session_number").(int)
+	currentPlayer, _ := c.Get("current_player").(string)
+	currentPlayerCity, _ := c.Get("current_player_city").(string)
+	remaining, _ := c.Get("uploads_remaining").(int)
+
+	u, err := repository.GetDirectUpload(ctx, c.Param("id"), tokenID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apiError(c, http.StatusNotFound, "Upload nicht gefunden")
+	}
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	if u.ContributionID != nil {
+		upload, err := repository.GetSessionUpload(ctx, *u.ContributionID, tokenID, u.SessionNumber)
+		if err != nil {
+			return dbError(c, err)
+		}
+		upload.ImageURL = utils.EnsureFullImageURL(upload.ImageURL)
+		return c.JSON(http.StatusOK, participantUploadResponse{Upload: *upload, UploadsRemaining: remaining})
+	}
+	if u.FinalizedAt != nil {
+		return apiError(c, http.StatusConflict, "Dieser Upload wird gerade verarbeitet")
+	}
+	if sessionNumber != u.SessionNumber || time.Now().After(u.ExpiresAt.Add(directUploadGrace)) {
+		if err := uploads.AbortDirect(ctx, u); err != nil {
+			log.Printf("Failed to abort direct upload: %v", err)
+		}
+		return apiError(c, http.StatusGone, "Upload abgelaufen")
+	}
+
+	var req finalizeRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return apiError(c, http.StatusBadRequest, "Ungültige Anfrage")
+		}
+	}
+
+	if remaining <= 0 {
+		if err := uploads.AbortDirect(ctx, u); err != nil {
+			log.Printf("Failed to abort direct upload over quota: %v", err)
+		}
+		return apiError(c, http.StatusForbidden, "Upload-Limit erreicht")
+	}
+	wait, err := cooldownRemaining(c, tokenID, sessionNumber)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if wait > 0 {
+		return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+			"error":             "Bitte warte zwischen Uploads",
+			"remaining_seconds": wait,
+		})
+	}
+
+	size, _, err := utils.HeadS3Object(ctx, u.ObjectKey)
+	if utils.IsS3NotFound(err) {
+		return apiError(c, http.StatusConflict, "Datei wurde noch nicht hochgeladen")
+	}
+	if err != nil {
+		log.Printf("Checking staged object failed: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return apiError(c, http.StatusInternalServerError, "Upload fehlgeschlagen")
+	}
+	if size <= 0 || size > tus.MaxSize {
+		if err := uploads.AbortDirect(ctx, u); err != nil {
+			log.Printf("Failed to abort oversized direct upload: %v", err)
+		}
+		return apiError(c, http.StatusRequestEntityTooLarge, "Datei zu groß")
+	}
+
+	claimed, err := repository.ClaimDirectUpload(ctx, u.ID)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !claimed {
+		return apiError(c, http.StatusConflict, "Dieser Upload wird gerade verarbeitet")
+	}
+
+	result, err := uploads.FinishDirect(ctx, u, uploads.Params{
+		TokenID:       tokenID,
+		SessionNumber: sessionNumber,
+		DeriveNumber:  u.DeriveNumber,
+		PlayerName:    currentPlayer,
+		PlayerCity:    currentPlayerCity,
+		Comment:       req.Comment,
+	})
+	if err != nil {
+		if errors.Is(err, uploads.ErrInvalidImage) || errors.Is(err, uploads.ErrDeriveNotFound) {
+			if abortErr := uploads.AbortDirect(ctx, u); abortErr != nil {
+				log.Printf("Failed to abort invalid direct upload: %v", abortErr)
+				sentryhelper.CaptureError(c, abortErr, sentry.LevelWarning)
+			}
+			if errors.Is(err, uploads.ErrDeriveNotFound) {
+				return apiError(c, http.StatusNotFound, "Aufgabe nicht gefunden")
+			}
+			return apiError(c, http.StatusUnprocessableEntity, "Ungültiges Bildformat")
+		}
+		if unclaimErr := repository.UnclaimDirectUpload(ctx, u.ID); unclaimErr != nil {
+			log.Printf("Failed to unclaim direct upload: %v", unclaimErr)
+		}
+		if errors.Is(err, uploads.ErrConsentRequired) {
+			return consentRequired(c)
+		}
+		log.Printf("Finishing direct upload %s failed: %v", u.ID, err)
+		sentryhelper.CaptureException(c, err)
+		return apiError(c, http.StatusInternalServerError, "Upload fehlgeschlagen")
+	}
+
+	upload, err := repository.GetSessionUpload(ctx, result.ContributionID, tokenID, sessionNumber)
+	if err != nil {
+		return dbError(c, err)
+	}
+	upload.ImageURL = utils.EnsureFullImageURL(upload.ImageURL)
+
+	sentryhelper.Logger(c).Info().Emitf("direct upload success: contribution=%d derive=%d token=%d player=%s", result.ContributionID, u.DeriveNumber, tokenID, currentPlayer)
+
+	return c.JSON(http.StatusCreated, participantUploadResponse{Upload: *upload, UploadsRemaining: remaining - 1})
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/feed"
+	"id-100/internal/models"
+	"id-100/internal/repository"
+	"id-100/internal/utils"
+)
+
+const (
+	// feedHeartbeat keeps idle streams alive through proxies (Traefik closes after 60s idle)
+	feedHeartbeat = 25 * time.Second
+	// feedReplayLimit caps how many missed contributions are replayed on reconnect
+	feedReplayLimit = 100
+	// feedRetry is the reconnect delay suggested to EventSource clients, in milliseconds
+	feedRetry = 5000
+)
+
+// LoadFeedContribution loads a published contribution for the live feed
+func LoadFeedContribution(ctx context.Context, id int) (*models.APIContribution, error) {
+	ct, err := repository.GetAPIContribution(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ct.ImageURL = utils.EnsureFullImageURL(ct.ImageURL)
+	return ct, nil
+}
+
+// ContributionStreamHandler streams new contributions as Server-Sent Events.
+// Optional filters: city, derive. Reconnecting clients get missed contributions
+// replayed from Last-Event-ID.
+func ContributionStreamHandler(c *echo.Context) error {
+	filter := feed.Filter{City: strings.TrimSpace(c.QueryParam("city"))}
+	if v := c.QueryParam("derive"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return apiError(c, http.StatusBadRequest, "Ungültiger Parameter derive")
+		}
+		filter.DeriveNumber = n
+	}
+
+	// Subscribe before replaying so nothing published in between is lost
+	sub := feed.Default.Subscribe(filter)
+	defer sub.Cancel()
+
+	ctx := c.Request().Context()
+	w := c.Response()
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("retry: " + strconv.Itoa(feedRetry) + "\n\n")); err != nil {
+		return nil
+	}
+
+	lastID := 0
+	if id, err := strconv.Atoi(c.Request().Header.Get("Last-Event-ID")); err == nil && id > 0 {
+		lastID = id
+		missed, err := repository.ListAPIContributionsAfterID(ctx, id, filter.City, filter.DeriveNumber, feedReplayLimit)
+		if err != nil {
+			log.Printf("Failed to replay contribution feed after %d: %v", id, err)
+		}
+		for i := range missed {
+			missed[i].ImageURL = utils.EnsureFullImageURL(missed[i].ImageURL)
+			if err := feed.WriteContribution(w, &missed[i]); err != nil {
+				return nil
+			}
+			lastID = missed[i].ID
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(feedHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if ev.Op == feed.OpCreated && ev.ID <= lastID {
+				// Already sent during replay
+				continue
+			}
+			err = feed.WriteEvent(w, ev)
+		case <-heartbeat.C:
+			err = feed.WriteComment(w, "ping")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			// Client went away
+			return nil
+		}
+	}
+}
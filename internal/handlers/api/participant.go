@@ -0,0 +1,530 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/attribution"
+	"id-100/internal/bagcode"
+	"id-100/internal/consent"
+	"id-100/internal/middleware"
+	"id-100/internal/models"
+	"id-100/internal/recap"
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/seo"
+	"id-100/internal/uploads"
+	"id-100/internal/utils"
+	"id-100/internal/webhooks"
+)
+
+// Participant API: JSON counterpart of the /upload pages for PWA and native clients.
+// Authenticated with the upload session cookie, which players get from their bag
+// code via ParticipantAuthHandler, or with the bag token as bearer credential
+// (middleware.BagToken).
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header
+const maxIdempotencyKeyLength = 255
+
+// deriveNotAllowedMessage answers uploads for derives outside the bag's ruleset
+const deriveNotAllowedMessage = "Diese Aufgabe ist für dieses Werkzeug nicht freigegeben"
+
+// consentRequired answers uploads of sessions that have not agreed to the
+// current privacy policy; clients ask again via POST /api/v1/participant/consent
+func consentRequired(c *echo.Context) error {
+	return c.JSON(http.StatusConflict, map[string]string{
+		"error": "Bitte stimme der aktuellen Datenschutzerklärung zu",
+		"code":  "consent_required",
+	})
+}
+
+// participantUploadResponse wraps an upload with the remaining quota
+type participantUploadResponse struct {
+	Upload           models.ParticipantUpload `json:"upload"`
+	UploadsRemaining int                      `json:"uploads_remaining"`
+}
+
+// cooldownRemaining returns the seconds until the next upload is allowed in this
+// session under the bag's ruleset
+func cooldownRemaining(c *echo.Context, tokenID, sessionNumber int) (int, error) {
+	lastUpload, err := repository.GetLastUploadTime(c.Request().Context(), tokenID, sessionNumber)
+	if err != nil {
+		return 0, err
+	}
+	if wait := middleware.Ruleset(c).CooldownRemaining(lastUpload, time.Now()); wait > 0 {
+		return int(wait.Seconds()) + 1, nil
+	}
+	return 0, nil
+}
+
+// participantAuthRequest is the body of ParticipantAuthHandler
+type participantAuthRequest struct {
+	Code string `json:"code"`
+}
+
+// ParticipantAuthHandler exchanges a bag code for the upload session cookie, like
+// the short link /b/:code does for browsers. The cookie carries the device ID, so
+// sessions started with it are bound to this client.
+func ParticipantAuthHandler(c *echo.Context) error {
+	var req participantAuthRequest
+	if err := c.Bind(&req); err != nil {
+		return apiError(c, http.StatusBadRequest, "Ungültige Anfrage")
+	}
+	code, ok := bagcode.Normalize(req.Code)
+	if !ok {
+		return apiError(c, http.StatusNotFound, "Code nicht gefunden")
+	}
+
+	tokenHash, err := repository.GetTokenHashByShortCode(c.Request().Context(), code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apiError(c, http.StatusNotFound, "Code nicht gefunden")
+	}
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	session, err := middleware.Store.Get(c.Request(), "id-100-session")
+	if err != nil {
+		log.Printf("Session error in ParticipantAuthHandler: %v", err)
+	}
+	middleware.SetSessionBag(session, tokenHash)
+	middleware.DeviceID(session)
+	if err := session.Save(c.Request(), c.Response()); err != nil {
+		log.Printf("Failed to save session in ParticipantAuthHandler: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return apiError(c, http.StatusInternalServerError, "Serverfehler")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ParticipantSessionHandler returns the status of the current bag session
+func ParticipantSessionHandler(c *echo.Context) error {
+	tokenID, _ := c.Get("token_id").(int)
+	sessionNumber, _ := c.Get("session_number").(int)
+	currentPlayer, _ := c.Get("current_player").(string)
+
+	status := models.ParticipantSession{
+		NeedsPlayerName: currentPlayer == "",
+		PlayerName:      currentPlayer,
+		SessionNumber:   sessionNumber,
+		PolicyVersion:   consent.PolicyVersion,
+		ConsentText:     consent.Text,
+	}
+	status.BagName, _ = c.Get("bag_name").(string)
+	status.PlayerCity, _ = c.Get("current_player_city").(string)
+	status.SessionStartedAt, _ = c.Get("session_started_at").(time.Time)
+	status.MaxUploads, _ = c.Get("max_uploads").(int)
+	status.TotalUploads, _ = c.Get("total_uploads").(int)
+	status.UploadsRemaining, _ = c.Get("uploads_remaining").(int)
+	status.AllowedDerives = middleware.Ruleset(c).AllowedDerives
+	if endsAt, ok := c.Get("session_ends_at").(time.Time); ok && currentPlayer != "" {
+		status.SessionEndsAt = &endsAt
+	}
+	if currentPlayer != "" {
+		style, publicName, err := repository.GetSessionAttribution(c.Request().Context(), tokenID, sessionNumber)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return dbError(c, err)
+		}
+		status.NameDisplay, status.PublicName = string(style), publicName
+
+		consented, err := repository.HasConsent(c.Request().Context(), tokenID, sessionNumber, consent.PolicyVersion)
+		if err != nil {
+			return dbError(c, err)
+		}
+		status.NeedsConsent = !consented
+	}
+
+	list, err := repository.ListSessionUploads(c.Request().Context(), tokenID, sessionNumber)
+	if err != nil {
+		return dbError(c, err)
+	}
+	status.SessionUploads = len(list)
+	for _, u := range list {
+		status.Points += u.Points
+	}
+
+	if status.CooldownSeconds, err = cooldownRemaining(c, tokenID, sessionNumber); err != nil {
+		return dbError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// startSessionRequest is the body of ParticipantStartSessionHandler
+type startSessionRequest struct {
+	PlayerName    string `json:"player_name"`
+	PlayerCity    string `json:"player_city"`
+	AgreePrivacy  bool   `json:"agree_privacy"`
+	PolicyVersion string `json:"policy_version"` // optional: the privacy policy version the client showed
+	HideJourney   bool   `json:"hide_journey"`   // keep the session out of the public bag journey
+	NameDisplay   string `json:"name_display"`   // how the name appears next to contributions
+}
+
+// privacyRequiredMessage answers requests without the privacy agreement
+const privacyRequiredMessage = "Bitte bestätige die Datenschutzerklärung und dass du keine erkennbaren Personen ohne Einwilligung hochlädst."
+
+// policyOutdated answers agreements to a privacy policy version that is no longer current
+func policyOutdated(c *echo.Context) error {
+	return c.JSON(http.StatusConflict, map[string]string{
+		"error":          "Die Datenschutzerklärung wurde aktualisiert, bitte lies sie erneut",
+		"code":           "policy_outdated",
+		"policy_version": consent.PolicyVersion,
+	})
+}
+
+// recordConsent stores the agreement of a session to the current privacy policy
+func recordConsent(c *echo.Context, tokenID, sessionNumber int) error {
+	return repository.RecordConsent(c.Request().Context(), tokenID, sessionNumber,
+		consent.PolicyVersion, consent.Texts(), consent.SourceAPI, consent.HashIP(c.RealIP()))
+}
+
+// ParticipantStartSessionHandler sets the player of a fresh bag session. The
+// session is bound to the device of the request (X-Device-ID header or browser
+// session), which is therefore required.
+func ParticipantStartSessionHandler(c *echo.Context) error {
+	if currentPlayer, _ := c.Get("current_player").(string); currentPlayer != "" {
+		return apiError(c, http.StatusConflict, "Es läuft bereits eine Sitzung mit diesem Werkzeug")
+	}
+	deviceID, _ := c.Get("device_id").(string)
+	if deviceID == "" {
+		return apiError(c, http.StatusBadRequest, "Geräte-ID fehlt (X-Device-ID)")
+	}
+
+	var req startSessionRequest
+	if err := c.Bind(&req); err != nil {
+		return apiError(c, http.StatusBadRequest, "Ungültige Anfrage")
+	}
+	req.PlayerName = strings.TrimSpace(req.PlayerName)
+	req.PlayerCity = strings.TrimSpace(req.PlayerCity)
+	if req.PlayerName == "" {
+		return apiError(c, http.StatusBadRequest, "Name erforderlich")
+	}
+	if !req.AgreePrivacy {
+		return apiError(c, http.StatusBadRequest, privacyRequiredMessage)
+	}
+	if req.PolicyVersion != "" && req.PolicyVersion != consent.PolicyVersion {
+		return policyOutdated(c)
+	}
+	nameDisplay, ok := attribution.Parse(req.NameDisplay)
+	if !ok {
+		return apiError(c, http.StatusBadRequest, "Ungültige Namensanzeige")
+	}
+
+	tokenID, _ := c.Get("token_id").(int)
+	if err := repository.UpdatePlayerNameAndCity(c.Request().Context(), req.PlayerName, req.PlayerCity, tokenID, deviceID); err != nil {
+		return dbError(c, err)
+	}
+
+	bagName, _ := c.Get("bag_name").(string)
+	sessionNumber, _ := c.Get("session_number").(int)
+	if req.HideJourney {
+		if err := repository.SetSessionJourneyHidden(c.Request().Context(), tokenID, sessionNumber, true); err != nil {
+			return dbError(c, err)
+		}
+	}
+	if _, err := repository.SetSessionNameDisplay(c.Request().Context(), tokenID, sessionNumber, nameDisplay); err != nil {
+		return dbError(c, err)
+	}
+	if err := recordConsent(c, tokenID, sessionNumber); err != nil {
+		return dbError(c, err)
+	}
+	webhooks.Emit(c.Request().Context(), webhooks.EventSessionStarted, webhooks.Session{
+		TokenID:       tokenID,
+		BagName:       bagName,
+		SessionNumber: sessionNumber,
+		PlayerName:    webhooks.PublicPlayerName(c.Request().Context(), tokenID, sessionNumber),
+		PlayerCity:    req.PlayerCity,
+	})
+
+	c.Set("current_player", req.PlayerName)
+	c.Set("current_player_city", req.PlayerCity)
+	c.Set("session_started_at", time.Now())
+	return ParticipantSessionHandler(c)
+}
+
+// consentRequest is the body of ParticipantConsentHandler
+type consentRequest struct {
+	AgreePrivacy  bool   `json:"agree_privacy"`
+	PolicyVersion string `json:"policy_version"` // optional, like in startSessionRequest
+}
+
+// ParticipantConsentHandler records that the running session agrees to the
+// current privacy policy, e.g. after it changed during the session
+func ParticipantConsentHandler(c *echo.Context) error {
+	if currentPlayer, _ := c.Get("current_player").(string); currentPlayer == "" {
+		return apiError(c, http.StatusConflict, "Bitte starte zuerst eine Sitzung")
+	}
+
+	var req consentRequest
+	if err := c.Bind(&req); err != nil {
+		return apiError(c, http.StatusBadRequest, "Ungültige Anfrage")
+	}
+	if !req.AgreePrivacy {
+		return apiError(c, http.StatusBadRequest, privacyRequiredMessage)
+	}
+	if req.PolicyVersion != "" && req.PolicyVersion != consent.PolicyVersion {
+		return policyOutdated(c)
+	}
+
+	tokenID, _ := c.Get("token_id").(int)
+	sessionNumber, _ := c.Get("session_number").(int)
+	if err := recordConsent(c, tokenID, sessionNumber); err != nil {
+		return dbError(c, err)
+	}
+	return ParticipantSessionHandler(c)
+}
+
+// nameDisplayRequest is the body of ParticipantNameDisplayHandler
+type nameDisplayRequest struct {
+	NameDisplay string `json:"name_display"`
+}
+
+// ParticipantNameDisplayHandler changes how the player's name appears next to
+// the contributions of the running session, including earlier ones
+func ParticipantNameDisplayHandler(c *echo.Context) error {
+	if currentPlayer, _ := c.Get("current_player").(string); currentPlayer == "" {
+		return apiError(c, http.StatusConflict, "Bitte starte zuerst eine Sitzung")
+	}
+
+	var req nameDisplayRequest
+	if err := c.Bind(&req); err != nil {
+		return apiError(c, http.StatusBadRequest, "Ungültige Anfrage")
+	}
+	nameDisplay, ok := attribution.Parse(req.NameDisplay)
+	if !ok || req.NameDisplay == "" {
+		return apiError(c, http.StatusBadRequest, "Ungültige Namensanzeige")
+	}
+
+	tokenID, _ := c.Get("token_id").(int)
+	sessionNumber, _ := c.Get("session_number").(int)
+	if _, err := repository.SetSessionNameDisplay(c.Request().Context(), tokenID, sessionNumber, nameDisplay); err != nil {
+		return dbError(c, err)
+	}
+	return ParticipantSessionHandler(c)
+}
+
+// ParticipantEndSessionHandler ends the session so the bag can be passed on
+func ParticipantEndSessionHandler(c *echo.Context) error {
+	currentPlayer, _ := c.Get("current_player").(string)
+	if currentPlayer == "" {
+		return apiError(c, http.StatusConflict, "Bitte starte zuerst eine Sitzung")
+	}
+	tokenID, _ := c.Get("token_id").(int)
+
+	rows, err := repository.ResetToken(c.Request().Context(), strconv.Itoa(tokenID))
+	if err != nil {
+		return dbError(c, err)
+	}
+	if rows == 0 {
+		return apiError(c, http.StatusNotFound, "Token nicht gefunden")
+	}
+
+	bagName, _ := c.Get("bag_name").(string)
+	sessionNumber, _ := c.Get("session_number").(int)
+	webhooks.Emit(c.Request().Context(), webhooks.EventSessionEnded, webhooks.Session{
+		TokenID:       tokenID,
+		BagName:       bagName,
+		SessionNumber: sessionNumber,
+		PlayerName:    webhooks.PublicPlayerName(c.Request().Context(), tokenID, sessionNumber),
+		EndedBy:       "player",
+	})
+
+	resp := map[string]string{
+		"status":  "success",
+		"message": "Session beendet. Das Werkzeug kann jetzt an den nächsten Spieler weitergegeben werden.",
+	}
+	if key, err := repository.CreateSessionRecap(c.Request().Context(), tokenID, sessionNumber); err == nil {
+		baseURL := seo.GetBaseURLFromRequest(c.Scheme(), c.Request().Host, c.Request().Header.Get("X-Forwarded-Host"))
+		resp["recap_url"] = baseURL + recap.Path(key)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("Failed to create recap for session %d of token %d: %v", sessionNumber, tokenID, err)
+		sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ParticipantUploadsHandler lists the uploads of the current session
+func ParticipantUploadsHandler(c *echo.Context) error {
+	tokenID, _ := c.Get("token_id").(int)
+	sessionNumber, _ := c.Get("session_number").(int)
+
+	list, err := repository.ListSessionUploads(c.Request().Context(), tokenID, sessionNumber)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if list == nil {
+		list = []models.ParticipantUpload{}
+	}
+	for i := range list {
+		list[i].ImageURL = utils.EnsureFullImageURL(list[i].ImageURL)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"data": list})
+}
+
+// ParticipantUploadHandler stores a multipart upload (derive_number, image, comment).
+// With an Idempotency-Key header, retries of a completed upload return the original
+// contribution with 200 and the header Idempotent-Replayed: true.
+func ParticipantUploadHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+	tokenID, _ := c.Get("token_id").(int)
+	sessionNumber, _ := c.Get("session_number").(int)
+	currentPlayer, _ := c.Get("current_player").(string)
+	currentPlayerCity, _ := c.Get("current_player_city").(string)
+	remaining, _ := c.Get("uploads_remaining").(int)
+
+	if currentPlayer == "" {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "Bitte starte zuerst eine Sitzung mit deinem Namen",
+			"code":  "player_name_required",
+		})
+	}
+	consented, err := repository.HasConsent(ctx, tokenID, sessionNumber, consent.PolicyVersion)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !consented {
+		return consentRequired(c)
+	}
+
+	key := strings.TrimSpace(c.Request().Header.Get("Idempotency-Key"))
+	if len(key) > maxIdempotencyKeyLength {
+		return apiError(c, http.StatusBadRequest, "Idempotency-Key zu lang")
+	}
+
+	if key != "" {
+		existingID, claimed, err := repository.ClaimIdempotencyKey(ctx, tokenID, key)
+		if err != nil {
+			return dbError(c, err)
+		}
+		if !claimed {
+			if existingID == nil {
+				return apiError(c, http.StatusConflict, "Dieser Upload wird gerade verarbeitet")
+			}
+			upload, err := repository.GetSessionUpload(ctx, *existingID, tokenID, sessionNumber)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return apiError(c, http.StatusUnprocessableEntity, "Idempotency-Key gehört zu einer anderen Sitzung")
+			}
+			if err != nil {
+				return dbError(c, err)
+			}
+			upload.ImageURL = utils.EnsureFullImageURL(upload.ImageURL)
+			c.Response().Header().Set("Idempotent-Replayed", "true")
+			return c.JSON(http.StatusOK, participantUploadResponse{Upload: *upload, UploadsRemaining: remaining})
+		}
+	}
+
+	// From here on a claimed key must be released if no contribution gets created
+	completed := false
+	defer func() {
+		if key != "" && !completed {
+			if err := repository.ReleaseIdempotencyKey(ctx, tokenID, key); err != nil {
+				log.Printf("Failed to release idempotency key: %v", err)
+				sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+			}
+		}
+	}()
+
+	if remaining <= 0 {
+		return apiError(c, http.StatusForbidden, "Upload-Limit erreicht")
+	}
+
+	wait, err := cooldownRemaining(c, tokenID, sessionNumber)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if wait > 0 {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(wait))
+		return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+			"error":             "Bitte warte zwischen Uploads",
+			"remaining_seconds": wait,
+		})
+	}
+
+	deriveNumber, err := strconv.Atoi(c.FormValue("derive_number"))
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, "Ungültige Aufgabennummer")
+	}
+	if !middleware.Ruleset(c).AllowsDerive(deriveNumber) {
+		return apiError(c, http.StatusForbidden, deriveNotAllowedMessage)
+	}
+
+	file, err := c.FormFile("image")
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, "Kein Bild gefunden")
+	}
+	src, err := file.Open()
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, "Datei konnte nicht geöffnet werden")
+	}
+	defer src.Close()
+
+	result, err := uploads.Store(ctx, src, uploads.Params{
+		TokenID:       tokenID,
+		SessionNumber: sessionNumber,
+		DeriveNumber:  deriveNumber,
+		PlayerName:    currentPlayer,
+		PlayerCity:    currentPlayerCity,
+		Comment:       c.FormValue("comment"),
+	})
+	switch {
+	case errors.Is(err, uploads.ErrInvalidImage):
+		return apiError(c, http.StatusBadRequest, "Ungültiges Bildformat")
+	case errors.Is(err, uploads.ErrDeriveNotFound):
+		return apiError(c, http.StatusNotFound, "Aufgabe nicht gefunden")
+	case errors.Is(err, uploads.ErrConsentRequired):
+		return consentRequired(c)
+	case err != nil:
+		log.Printf("Participant upload failed: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return apiError(c, http.StatusInternalServerError, "Upload fehlgeschlagen")
+	}
+
+	if key != "" {
+		if err := repository.CompleteIdempotencyKey(ctx, tokenID, key, result.ContributionID); err != nil {
+			log.Printf("Failed to complete idempotency key: %v", err)
+			sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+		}
+	}
+	completed = true
+
+	upload, err := repository.GetSessionUpload(ctx, result.ContributionID, tokenID, sessionNumber)
+	if err != nil {
+		return dbError(c, err)
+	}
+	upload.ImageURL = utils.EnsureFullImageURL(upload.ImageURL)
+
+	sentryhelper.Logger(c).Info().Emitf("api upload success: contribution=%d derive=%d token=%d player=%s", result.ContributionID, deriveNumber, tokenID, currentPlayer)
+
+	return c.JSON(http.StatusCreated, participantUploadResponse{Upload: *upload, UploadsRemaining: remaining - 1})
+}
+
+// ParticipantDeleteUploadHandler deletes an upload of the current session
+func ParticipantDeleteUploadHandler(c *echo.Context) error {
+	contributionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, "Ungültige Beitrags-ID")
+	}
+	tokenID, _ := c.Get("token_id").(int)
+	sessionNumber, _ := c.Get("session_number").(int)
+
+	err = uploads.DeleteOwn(c.Request().Context(), contributionID, tokenID, sessionNumber)
+	if errors.Is(err, uploads.ErrNotOwned) {
+		return apiError(c, http.StatusNotFound, "Beitrag nicht in dieser Sitzung gefunden")
+	}
+	if err != nil {
+		log.Printf("Participant delete failed: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return apiError(c, http.StatusInternalServerError, "Löschen fehlgeschlagen")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
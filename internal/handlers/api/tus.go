@@ -0,0 +1,310 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/consent"
+	"id-100/internal/middleware"
+	"id-100/internal/models"
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/tus"
+	"id-100/internal/uploads"
+	"id-100/internal/utils"
+)
+
+// Resumable uploads via tus 1.0 (core, creation and termination extensions).
+// Routes are authorised by the bag session (middleware.BagToken); each accepted
+// PATCH is stored as its own object and the chunks are stitched together and run
+// through the normal upload pipeline once the upload is complete.
+
+// TusBasePath is the creation URL; uploads live below it
+const TusBasePath = "/api/v1/tus"
+
+// TusResumable rejects requests for other protocol versions and sets the version header
+func TusResumable(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		c.Response().Header().Set("Tus-Resumable", tus.Version)
+		if c.Request().Method != http.MethodOptions && c.Request().Header.Get("Tus-Resumable") != tus.Version {
+			c.Response().Header().Set("Tus-Version", tus.Version)
+			return c.NoContent(http.StatusPreconditionFailed)
+		}
+		return next(c)
+	}
+}
+
+// TusOptionsHandler advertises the server capabilities
+func TusOptionsHandler(c *echo.Context) error {
+	h := c.Response().Header()
+	h.Set("Tus-Version", tus.Version)
+	h.Set("Tus-Extension", tus.Extensions)
+	h.Set("Tus-Max-Size", strconv.FormatInt(tus.MaxSize, 10))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// TusCreateHandler creates a new upload. Upload-Metadata must contain derive_number
+// and may contain comment.
+func TusCreateHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+	tokenID, _ := c.Get("token_id").(int)
+	sessionNumber, _ := c.Get("session_number").(int)
+	remaining, _ := c.Get("uploads_remaining").(int)
+
+	if currentPlayer, _ := c.Get("current_player").(string); currentPlayer == "" {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "Bitte starte zuerst eine Sitzung mit deinem Namen",
+			"code":  "player_name_required",
+		})
+	}
+	consented, err := repository.HasConsent(ctx, tokenID, sessionNumber, consent.PolicyVersion)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !consented {
+		return consentRequired(c)
+	}
+	if remaining <= 0 {
+		return apiError(c, http.StatusForbidden, "Upload-Limit erreicht")
+	}
+
+	if c.Request().Header.Get("Upload-Defer-Length") != "" {
+		return apiError(c, http.StatusBadRequest, "Upload-Defer-Length wird nicht unterstützt")
+	}
+	length, err := tus.ParseLength(c.Request().Header.Get("Upload-Length"))
+	if err != nil || length == 0 {
+		return apiError(c, http.StatusBadRequest, "Ungültige Upload-Length")
+	}
+	if length > tus.MaxSize {
+		return apiError(c, http.StatusRequestEntityTooLarge, "Datei zu groß")
+	}
+
+	meta, err := tus.ParseMetadata(c.Request().Header.Get("Upload-Metadata"))
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, "Ungültige Upload-Metadata")
+	}
+	deriveNumber, err := strconv.Atoi(meta["derive_number"])
+	if err != nil || deriveNumber < 1 {
+		return apiError(c, http.StatusBadRequest, "Ungültige Aufgabennummer")
+	}
+	if !middleware.Ruleset(c).AllowsDerive(deriveNumber) {
+		return apiError(c, http.StatusForbidden, deriveNotAllowedMessage)
+	}
+	exists, err := repository.DeriveExists(ctx, deriveNumber)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !exists {
+		return apiError(c, http.StatusNotFound, "Aufgabe nicht gefunden")
+	}
+
+	id, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	err = repository.CreateTusUpload(ctx, models.TusUpload{
+		ID:            id,
+		TokenID:       tokenID,
+		SessionNumber: sessionNumber,
+		DeriveNumber:  deriveNumber,
+		UserComment:   uploads.TrimComment(strings.TrimSpace(meta["comment"])),
+		Length:        length,
+	})
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	c.Response().Header().Set("Location", TusBasePath+"/"+id)
+	return c.NoContent(http.StatusCreated)
+}
+
+// loadTusUpload fetches the upload addressed by :id for the authenticated token
+func loadTusUpload(c *echo.Context) (*models.TusUpload, error) {
+	tokenID, _ := c.Get("token_id").(int)
+	return repository.GetTusUpload(c.Request().Context(), c.Param("id"), tokenID)
+}
+
+func setTusOffset(c *echo.Context, u *models.TusUpload) {
+	h := c.Response().Header()
+	h.Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	h.Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	h.Set("Cache-Control", "no-store")
+	if u.ContributionID != nil {
+		h.Set("X-Contribution-Id", strconv.Itoa(*u.ContributionID))
+	}
+}
+
+// TusHeadHandler reports the current offset of an upload
+func TusHeadHandler(c *echo.Context) error {
+	u, err := loadTusUpload(c)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		return dbError(c, err)
+	}
+	setTusOffset(c, u)
+	return c.NoContent(http.StatusOK)
+}
+
+// TusPatchHandler appends a chunk at Upload-Offset and finishes the upload once complete
+func TusPatchHandler(c *echo.Context) error {
+	// Storage and bookkeeping must survive a client dropping the connection mid-chunk
+	ctx := context.WithoutCancel(c.Request().Context())
+
+	if !strings.HasPrefix(c.Request().Header.Get("Content-Type"), tus.OffsetContentType) {
+		return c.NoContent(http.StatusUnsupportedMediaType)
+	}
+	offset, err := tus.ParseLength(c.Request().Header.Get("Upload-Offset"))
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, "Ungültiger Upload-Offset")
+	}
+
+	u, err := loadTusUpload(c)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		return dbError(c, err)
+	}
+	if u.CompletedAt != nil || offset != u.Offset {
+		setTusOffset(c, u)
+		return c.NoContent(http.StatusConflict)
+	}
+
+	// The bag was handed over since the upload started: it must not count for the next player
+	if sessionNumber, _ := c.Get("session_number").(int); sessionNumber != u.SessionNumber {
+		if err := uploads.AbortTus(ctx, u.ID); err != nil {
+			log.Printf("Failed to abort tus upload of ended session: %v", err)
+		}
+		return c.NoContent(http.StatusGone)
+	}
+
+	if u.Offset < u.Length {
+		limit := tus.ChunkLimit(u.Length, u.Offset)
+		data, readErr := io.ReadAll(io.LimitReader(c.Request().Body, limit))
+		if len(data) == 0 {
+			if readErr != nil {
+				return c.NoContent(http.StatusBadRequest)
+			}
+			setTusOffset(c, u)
+			return c.NoContent(http.StatusNoContent)
+		}
+
+		// Keep whatever arrived before an interrupted transfer so the client can resume from there
+		key := uploads.TusChunkKey(u.ID, u.Offset)
+		if err := utils.UploadToS3(ctx, key, data, "application/octet-stream"); err != nil {
+			log.Printf("Failed to store tus chunk: %v", err)
+			sentryhelper.CaptureException(c, err)
+			return apiError(c, http.StatusInternalServerError, "Upload fehlgeschlagen")
+		}
+		ok, err := repository.AppendTusChunk(ctx, u.ID, u.Offset, int64(len(data)), key)
+		if err != nil || !ok {
+			if delErr := utils.DeleteFromS3(ctx, key); delErr != nil {
+				log.Printf("Failed to delete rejected tus chunk: %v", delErr)
+			}
+			if err != nil {
+				return dbError(c, err)
+			}
+			return c.NoContent(http.StatusConflict)
+		}
+		u.Offset += int64(len(data))
+	}
+
+	if u.Offset < u.Length {
+		setTusOffset(c, u)
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	return finishTusUpload(ctx, c, u)
+}
+
+// finishTusUpload creates the contribution for a fully received upload
+func finishTusUpload(ctx context.Context, c *echo.Context, u *models.TusUpload) error {
+	tokenID, _ := c.Get("token_id").(int)
+	currentPlayer, _ := c.Get("current_player").(string)
+	currentPlayerCity, _ := c.Get("current_player_city").(string)
+	remaining, _ := c.Get("uploads_remaining").(int)
+
+	if remaining <= 0 {
+		if err := uploads.AbortTus(ctx, u.ID); err != nil {
+			log.Printf("Failed to abort tus upload over quota: %v", err)
+		}
+		return apiError(c, http.StatusForbidden, "Upload-Limit erreicht")
+	}
+	wait, err := cooldownRemaining(c, tokenID, u.SessionNumber)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if wait > 0 {
+		// Chunks are kept, so the final PATCH can be retried after the pause
+		setTusOffset(c, u)
+		c.Response().Header().Set("Retry-After", strconv.Itoa(wait))
+		return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+			"error":             "Bitte warte zwischen Uploads",
+			"remaining_seconds": wait,
+		})
+	}
+
+	result, err := uploads.FinishTus(ctx, u, uploads.Params{
+		TokenID:       tokenID,
+		SessionNumber: u.SessionNumber,
+		DeriveNumber:  u.DeriveNumber,
+		PlayerName:    currentPlayer,
+		PlayerCity:    currentPlayerCity,
+		Comment:       u.UserComment,
+	})
+	if errors.Is(err, uploads.ErrInvalidImage) || errors.Is(err, uploads.ErrDeriveNotFound) {
+		if abortErr := uploads.AbortTus(ctx, u.ID); abortErr != nil {
+			log.Printf("Failed to abort invalid tus upload: %v", abortErr)
+			sentryhelper.CaptureError(c, abortErr, sentry.LevelWarning)
+		}
+		if errors.Is(err, uploads.ErrDeriveNotFound) {
+			return apiError(c, http.StatusNotFound, "Aufgabe nicht gefunden")
+		}
+		return apiError(c, http.StatusUnprocessableEntity, "Ungültiges Bildformat")
+	}
+	if errors.Is(err, uploads.ErrConsentRequired) {
+		// Chunks are kept like below, so the upload finishes after the consent
+		setTusOffset(c, u)
+		return consentRequired(c)
+	}
+	if err != nil {
+		// Chunks are kept: a PATCH with Upload-Offset = Upload-Length retries the processing
+		log.Printf("Finishing tus upload %s failed: %v", u.ID, err)
+		sentryhelper.CaptureException(c, err)
+		setTusOffset(c, u)
+		return apiError(c, http.StatusInternalServerError, "Upload fehlgeschlagen")
+	}
+
+	sentryhelper.Logger(c).Info().Emitf("tus upload success: contribution=%d derive=%d token=%d player=%s", result.ContributionID, u.DeriveNumber, tokenID, currentPlayer)
+
+	u.ContributionID = &result.ContributionID
+	setTusOffset(c, u)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// TusDeleteHandler terminates an upload and removes its stored chunks
+func TusDeleteHandler(c *echo.Context) error {
+	u, err := loadTusUpload(c)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		return dbError(c, err)
+	}
+	if err := uploads.AbortTus(context.WithoutCancel(c.Request().Context()), u.ID); err != nil {
+		return dbError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/models"
+	"id-100/internal/pagination"
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/utils"
+)
+
+// Public read-only JSON API (v1). Documented in web/api/openapi.yaml.
+
+// listResponse is the envelope of every v1 list endpoint
+type listResponse struct {
+	Data       interface{} `json:"data"`
+	NextCursor *string     `json:"next_cursor"`
+}
+
+func apiError(c *echo.Context, status int, msg string) error {
+	return c.JSON(status, map[string]string{"error": msg})
+}
+
+func dbError(c *echo.Context, err error) error {
+	log.Printf("API v1 query error: %v", err)
+	sentryhelper.CaptureException(c, err)
+	return apiError(c, http.StatusInternalServerError, "Datenbankfehler")
+}
+
+// parseLimit reads the limit query parameter
+func parseLimit(c *echo.Context) int {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	return pagination.ClampLimit(limit)
+}
+
+// parseDate accepts RFC 3339 timestamps or plain dates (YYYY-MM-DD, UTC midnight)
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// DerivenHandler lists all deriven ordered by number
+func DerivenHandler(c *echo.Context) error {
+	cursor, err := pagination.Decode(c.QueryParam("cursor"))
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, "Ungültiger Cursor")
+	}
+	limit := parseLimit(c)
+
+	f := repository.APIDerivenFilter{City: c.QueryParam("city"), Limit: limit}
+	if cursor != nil {
+		f.AfterNumber = cursor.ID
+	}
+
+	list, err := repository.ListAPIDeriven(c.Request().Context(), f)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	resp := listResponse{Data: []models.APIDerive{}}
+	if len(list) > limit {
+		list = list[:limit]
+		next := pagination.Cursor{ID: list[limit-1].Number}.Encode()
+		resp.NextCursor = &next
+	}
+	for i := range list {
+		if list[i].LatestImageURL != "" {
+			list[i].LatestImageURL = utils.EnsureFullImageURL(list[i].LatestImageURL)
+		}
+	}
+	if len(list) > 0 {
+		resp.Data = list
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ContributionsHandler lists contributions newest first.
+// Filters: city, derive (number), since, until.
+func ContributionsHandler(c *echo.Context) error {
+	f, err := contributionFilter(c)
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, err.Error())
+	}
+	if d := c.QueryParam("derive"); d != "" {
+		f.DeriveNumber, err = strconv.Atoi(d)
+		if err != nil || f.DeriveNumber < 1 {
+			return apiError(c, http.StatusBadRequest, "Ungültige ID-Nummer")
+		}
+	}
+	return listContributions(c, f)
+}
+
+// DeriveContributionsHandler lists the contributions of a single derive
+func DeriveContributionsHandler(c *echo.Context) error {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil || number < 1 {
+		return apiError(c, http.StatusBadRequest, "Ungültige ID-Nummer")
+	}
+
+	exists, err := repository.DeriveExists(c.Request().Context(), number)
+	if err != nil {
+		return dbError(c, err)
+	}
+	if !exists {
+		return apiError(c, http.StatusNotFound, "ID nicht gefunden")
+	}
+
+	f, err := contributionFilter(c)
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, err.Error())
+	}
+	f.DeriveNumber = number
+	return listContributions(c, f)
+}
+
+// filterError is a client error with a user-facing message
+type filterError string
+
+func (e filterError) Error() string { return string(e) }
+
+// contributionFilter parses the shared query parameters of contribution lists
+func contributionFilter(c *echo.Context) (repository.APIContributionFilter, error) {
+	f := repository.APIContributionFilter{
+		City:  c.QueryParam("city"),
+		Limit: parseLimit(c),
+	}
+
+	cursor, err := pagination.Decode(c.QueryParam("cursor"))
+	if err != nil {
+		return f, filterError("Ungültiger Cursor")
+	}
+	f.After = cursor
+
+	if s := c.QueryParam("since"); s != "" {
+		if f.Since, err = parseDate(s); err != nil {
+			return f, filterError("Ungültiges Datum für since (RFC 3339 oder JJJJ-MM-TT)")
+		}
+	}
+	if s := c.QueryParam("until"); s != "" {
+		if f.Until, err = parseDate(s); err != nil {
+			return f, filterError("Ungültiges Datum für until (RFC 3339 oder JJJJ-MM-TT)")
+		}
+	}
+	return f, nil
+}
+
+func listContributions(c *echo.Context, f repository.APIContributionFilter) error {
+	list, err := repository.ListAPIContributions(c.Request().Context(), f)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	resp := listResponse{Data: []models.APIContribution{}}
+	if len(list) > f.Limit {
+		list = list[:f.Limit]
+		last := list[f.Limit-1]
+		next := pagination.Cursor{Time: last.CreatedAt, ID: last.ID}.Encode()
+		resp.NextCursor = &next
+	}
+	for i := range list {
+		list[i].ImageURL = utils.EnsureFullImageURL(list[i].ImageURL)
+	}
+	if len(list) > 0 {
+		resp.Data = list
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// CitiesHandler lists all cities with contributions ordered by name
+func CitiesHandler(c *echo.Context) error {
+	cursor, err := pagination.Decode(c.QueryParam("cursor"))
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, "Ungültiger Cursor")
+	}
+	limit := parseLimit(c)
+
+	after := ""
+	if cursor != nil {
+		after = cursor.Key
+	}
+
+	list, err := repository.ListAPICities(c.Request().Context(), after, limit)
+	if err != nil {
+		return dbError(c, err)
+	}
+
+	resp := listResponse{Data: []models.APICity{}}
+	if len(list) > limit {
+		list = list[:limit]
+		next := pagination.Cursor{Key: list[limit-1].Name}.Encode()
+		resp.NextCursor = &next
+	}
+	if len(list) > 0 {
+		resp.Data = list
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
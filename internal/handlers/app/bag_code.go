@@ -0,0 +1,80 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/bagcode"
+	"id-100/internal/middleware"
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/templates"
+	"id-100/internal/utils"
+)
+
+// renderBagCodeForm shows the form for typing a bag code by hand
+func renderBagCodeForm(c *echo.Context, status int, code, formError string) error {
+	return c.Render(status, "layout", templates.MergeTemplateData(map[string]interface{}{
+		"Title":           "Werkzeug-Code eingeben",
+		"ContentTemplate": "bag_code.content",
+		"Code":            code,
+		"FormError":       formError,
+		"CurrentPath":     c.Request().URL.Path,
+		"CurrentYear":     time.Now().Year(),
+		"FooterStats":     utils.GetFooterStats(),
+	}))
+}
+
+// BagCodeFormHandler shows the manual entry form for damaged or unreadable QR codes
+func BagCodeFormHandler(c *echo.Context) error {
+	return renderBagCodeForm(c, http.StatusOK, "", "")
+}
+
+// BagCodeSubmitHandler redirects a typed code to its short link
+func BagCodeSubmitHandler(c *echo.Context) error {
+	input := c.FormValue("code")
+	code, ok := bagcode.Normalize(input)
+	if !ok {
+		return renderBagCodeForm(c, http.StatusBadRequest, input, "Der Code besteht aus sechs Zeichen, z.B. K7P-4QX.")
+	}
+	return c.Redirect(http.StatusSeeOther, bagcode.Path(code))
+}
+
+// BagCodeHandler resolves a short link like /b/K7P-4QX to its upload token, stores
+// the token hash in the session and continues to /upload, so no token ever
+// appears in the address bar
+func BagCodeHandler(c *echo.Context) error {
+	input := c.Param("code")
+	code, ok := bagcode.Normalize(input)
+	if !ok {
+		return renderBagCodeForm(c, http.StatusNotFound, input, "Diesen Code gibt es nicht. Bitte prüfe die Schreibweise.")
+	}
+
+	tokenHash, err := repository.GetTokenHashByShortCode(c.Request().Context(), code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return renderBagCodeForm(c, http.StatusNotFound, bagcode.Format(code), "Diesen Code gibt es nicht. Bitte prüfe die Schreibweise.")
+	}
+	if err != nil {
+		log.Printf("Failed to resolve bag code: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+
+	session, err := middleware.Store.Get(c.Request(), "id-100-session")
+	if err != nil {
+		log.Printf("Session error in BagCodeHandler: %v", err)
+	}
+	middleware.SetSessionBag(session, tokenHash)
+	if err := session.Save(c.Request(), c.Response()); err != nil {
+		log.Printf("Failed to save session in BagCodeHandler: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/upload")
+}
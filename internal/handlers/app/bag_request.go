@@ -2,17 +2,26 @@ package app
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v5"
 
+	"id-100/internal/bagrequest"
+	"id-100/internal/config"
+	mail "id-100/internal/email"
+	"id-100/internal/models"
 	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
 	"id-100/internal/seo"
 	"id-100/internal/templates"
 	"id-100/internal/utils"
+	"id-100/internal/webhooks"
 )
 
 // RequestBagHandler displays the bag request form
@@ -42,10 +51,26 @@ func RequestBagHandler(c *echo.Context) error {
 	}))
 }
 
-// RequestBagPostHandler handles bag request submissions
+// bagRequestsUnavailable answers submissions while no opt-in email can be sent
+const bagRequestsUnavailable = "Werkzeug-Anfragen sind gerade nicht möglich, weil wir keine Bestätigungs-E-Mail senden können. Bitte versuche es später noch einmal."
+
+// RequestBagPostHandler handles bag request submissions. The request stays
+// unconfirmed until the link from the opt-in email is opened; without SMTP
+// submissions are refused instead of skipping the opt-in.
 func RequestBagPostHandler(c *echo.Context) error {
+	if !mail.Enabled() {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": bagRequestsUnavailable})
+	}
+
 	type payload struct {
-		Email string `json:"email"`
+		Email   string `json:"email"`
+		Website string `json:"website"` // honeypot, hidden from humans
+		// Optional postal address for shipping
+		Name           string `json:"name"`
+		Street         string `json:"street"`
+		PostalCode     string `json:"postal_code"`
+		City           string `json:"city"`
+		AddressConsent bool   `json:"address_consent"`
 	}
 	var p payload
 	if strings.Contains(c.Request().Header.Get("Content-Type"), "application/json") {
@@ -54,16 +79,117 @@ func RequestBagPostHandler(c *echo.Context) error {
 		}
 	} else {
 		p.Email = c.FormValue("email")
+		p.Website = c.FormValue("website")
+		p.Name = c.FormValue("name")
+		p.Street = c.FormValue("street")
+		p.PostalCode = c.FormValue("postal_code")
+		p.City = c.FormValue("city")
+		p.AddressConsent = c.FormValue("address_consent") != ""
+	}
+	if strings.TrimSpace(p.Website) != "" {
+		// Bots get the normal answer so they do not learn about the trap
+		return c.JSON(http.StatusOK, map[string]interface{}{"status": "ok", "confirmation_required": true})
 	}
-	email := strings.TrimSpace(p.Email)
-	if email == "" || !strings.Contains(email, "@") {
+	email, err := bagrequest.NormalizeEmail(p.Email)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Ungültige E-Mail"})
 	}
+	address, err := bagrequest.NormalizeAddress(bagrequest.Address{
+		Name:       p.Name,
+		Street:     p.Street,
+		PostalCode: p.PostalCode,
+		City:       p.City,
+	}, p.AddressConsent)
+	switch {
+	case errors.Is(err, bagrequest.ErrAddressConsent):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bitte stimme der Speicherung deiner Adresse zu oder lass die Adressfelder leer"})
+	case err != nil:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bitte gib die Adresse vollständig an oder lass die Adressfelder leer"})
+	}
 
-	err := repository.InsertBagRequest(context.Background(), email)
+	ctx := c.Request().Context()
+	recent, err := repository.CountBagRequestsSince(ctx, email, time.Now().Add(-bagrequest.AddressWindow))
+	if err != nil {
+		log.Printf("Failed to count bag requests: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Serverfehler"})
+	}
+	if recent >= bagrequest.MaxPerAddress {
+		return c.JSON(http.StatusTooManyRequests, map[string]string{
+			"error": "Für diese E-Mail gibt es heute schon mehrere Anfragen. Bitte schau in dein Postfach.",
+		})
+	}
+
+	id, createdAt, err := repository.InsertBagRequest(ctx, models.BagRequest{
+		Email:              email,
+		ShippingName:       address.Name,
+		ShippingStreet:     address.Street,
+		ShippingPostalCode: address.PostalCode,
+		ShippingCity:       address.City,
+	})
 	if err != nil {
 		log.Printf("Failed to insert bag request: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Serverfehler"})
+	}
+
+	baseURL := strings.TrimRight(config.GetBaseURL(), "/")
+	expiresAt := createdAt.Add(bagrequest.ConfirmationTTL)
+	if err := mail.Enqueue(ctx, mail.TemplateBagRequestConfirmation, email, mail.BagRequestConfirmation{
+		BaseURL:    baseURL,
+		RequestID:  id,
+		CreatedAt:  createdAt,
+		ConfirmURL: baseURL + "/werkzeug-anfordern/bestaetigen?token=" + url.QueryEscape(bagrequest.ConfirmationToken(id, expiresAt)),
+		ExpiresAt:  expiresAt,
+	}); err != nil {
+		log.Printf("Failed to queue confirmation email for bag_request %d: %v", id, err)
+		sentryhelper.CaptureException(c, err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Serverfehler"})
 	}
-	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+	return c.JSON(http.StatusOK, map[string]interface{}{"status": "ok", "confirmation_required": true})
+}
+
+// RequestBagConfirmHandler confirms a bag request from the opt-in link
+func RequestBagConfirmHandler(c *echo.Context) error {
+	status := "confirmed"
+	httpStatus := http.StatusOK
+
+	id, err := bagrequest.ParseConfirmationToken(c.QueryParam("token"), time.Now())
+	switch {
+	case errors.Is(err, bagrequest.ErrTokenExpired):
+		status, httpStatus = "expired", http.StatusGone
+	case err != nil:
+		status, httpStatus = "invalid", http.StatusBadRequest
+	default:
+		br, newlyConfirmed, err := repository.ConfirmBagRequest(c.Request().Context(), id)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			// Purged after expiry
+			status, httpStatus = "expired", http.StatusGone
+		case err != nil:
+			log.Printf("Failed to confirm bag request %d: %v", id, err)
+			sentryhelper.CaptureException(c, err)
+			return c.String(http.StatusInternalServerError, "Serverfehler")
+		case newlyConfirmed:
+			emitBagRequestCreated(c.Request().Context(), br.ID, br.Email, br.CreatedAt)
+		}
+	}
+
+	return c.Render(httpStatus, "layout", templates.MergeTemplateData(map[string]interface{}{
+		"Title":           "Werkzeug-Anfrage bestätigen",
+		"ContentTemplate": "request_bag_confirm.content",
+		"Status":          status,
+		"CurrentPath":     c.Request().URL.Path,
+		"CurrentYear":     time.Now().Year(),
+		"FooterStats":     utils.GetFooterStats(),
+	}))
+}
+
+// emitBagRequestCreated announces a request once it is confirmed
+func emitBagRequestCreated(ctx context.Context, id int, email string, createdAt time.Time) {
+	webhooks.Emit(ctx, webhooks.EventBagRequestCreated, webhooks.BagRequestCreated{
+		ID:        id,
+		Email:     email,
+		CreatedAt: createdAt,
+	})
 }
@@ -0,0 +1,30 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+)
+
+func TestRequestBagPostHandlerWithoutSMTP(t *testing.T) {
+	t.Setenv("SMTP_HOST", "")
+	e := echo.New()
+	e.POST("/werkzeug-anfordern", RequestBagPostHandler)
+
+	req := httptest.NewRequest(http.MethodPost, "/werkzeug-anfordern", strings.NewReader(`{"email":"anna@example.org"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] != bagRequestsUnavailable {
+		t.Errorf("body = %s, want the unavailable message", rec.Body.String())
+	}
+}
@@ -1,16 +1,15 @@
 package app
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
-	"github.com/getsentry/sentry-go"
 	"github.com/labstack/echo/v5"
 
-	"id-100/internal/repository"
 	"id-100/internal/sentryhelper"
-	"id-100/internal/utils"
+	"id-100/internal/uploads"
 )
 
 // UserDeleteContributionHandler allows users to delete their own contributions from the current session
@@ -29,52 +28,18 @@ func UserDeleteContributionHandler(c *echo.Context) error {
 
 	sessionNumber, _ := c.Get("session_number").(int)
 
-	ctx := c.Request().Context()
-
-	// Verify that this contribution belongs to the current user's session
-	imageURL, err := repository.GetContributionForDeletion(ctx, contributionID, tokenID, sessionNumber)
-	if err != nil {
-		log.Printf("Failed to fetch contribution or ownership mismatch: %v", err)
-		sentryhelper.CaptureException(c, err)
+	// Verify that this contribution belongs to the current user's session and delete it
+	err = uploads.DeleteOwn(c.Request().Context(), contributionID, tokenID, sessionNumber)
+	if errors.Is(err, uploads.ErrNotOwned) {
+		log.Printf("Contribution %d not found or ownership mismatch", contributionID)
 		return c.JSON(http.StatusForbidden, map[string]string{"error": "You can only delete your own uploads from this session"})
 	}
-
-	// Delete from upload_logs first
-	err = repository.DeleteUploadLog(ctx, contributionID)
-	if err != nil {
-		log.Printf("Failed to delete from upload_logs: %v", err)
-		sentryhelper.CaptureException(c, err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete upload log"})
-	}
-
-	// Delete from contributions table
-	rowsAffected, err := repository.DeleteContribution(ctx, contributionID)
 	if err != nil {
 		log.Printf("Failed to delete contribution: %v", err)
 		sentryhelper.CaptureException(c, err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete contribution"})
 	}
 
-	if rowsAffected == 0 {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Contribution not found"})
-	}
-
-	// Decrement the total_uploads counter
-	err = repository.DecrementTokenUploadCount(ctx, tokenID)
-	if err != nil {
-		log.Printf("Failed to decrement upload counter: %v", err)
-		sentryhelper.CaptureError(c, err, sentry.LevelWarning)
-	}
-
-	// Delete from S3 storage
-	if imageURL != "" {
-		s3Err := utils.DeleteFromS3(ctx, imageURL)
-		if s3Err != nil {
-			log.Printf("Failed to delete from S3 (continuing anyway): %v", s3Err)
-			sentryhelper.CaptureError(c, s3Err, sentry.LevelWarning)
-		}
-	}
-
 	return c.JSON(http.StatusOK, map[string]string{
 		"status":  "success",
 		"message": "Upload deleted successfully",
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/models"
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/seo"
+	"id-100/internal/templates"
+	"id-100/internal/utils"
+)
+
+const (
+	exhibitionRecent = 24
+	exhibitionRandom = 24
+)
+
+// ExhibitionHandler renders the full-screen kiosk for the exhibition. It starts with
+// recent and random contributions and adds new ones live from the contribution stream.
+// Optional filters: city, derive.
+func ExhibitionHandler(c *echo.Context) error {
+	ctx := c.Request().Context()
+	city := strings.TrimSpace(c.QueryParam("city"))
+	deriveNumber, _ := strconv.Atoi(c.QueryParam("derive"))
+	if deriveNumber < 0 {
+		deriveNumber = 0
+	}
+
+	recent, err := repository.ListRecentAPIContributions(ctx, city, deriveNumber, exhibitionRecent)
+	if err != nil {
+		log.Printf("Failed to load recent contributions for exhibition: %v", err)
+		sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+		recent = []models.APIContribution{}
+	}
+	exclude := make([]int, len(recent))
+	for i, ct := range recent {
+		exclude[i] = ct.ID
+	}
+	random, err := repository.ListRandomAPIContributions(ctx, city, deriveNumber, exhibitionRandom, exclude)
+	if err != nil {
+		log.Printf("Failed to load random contributions for exhibition: %v", err)
+		sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+		random = []models.APIContribution{}
+	}
+	for i := range recent {
+		recent[i].ImageURL = utils.EnsureFullImageURL(recent[i].ImageURL)
+	}
+	for i := range random {
+		random[i].ImageURL = utils.EnsureFullImageURL(random[i].ImageURL)
+	}
+
+	// The kiosk subscribes with the same filters it was opened with
+	streamURL := "/api/v1/contributions/stream"
+	filter := url.Values{}
+	if city != "" {
+		filter.Set("city", city)
+	}
+	if deriveNumber > 0 {
+		filter.Set("derive", strconv.Itoa(deriveNumber))
+	}
+	if len(filter) > 0 {
+		streamURL += "?" + filter.Encode()
+	}
+
+	baseURL := seo.GetBaseURLFromRequest(c.Scheme(), c.Request().Host, c.Request().Header.Get("X-Forwarded-Host"))
+	seoMeta := seo.NewBuilder(baseURL).ForPage("exhibition")
+
+	return c.Render(http.StatusOK, "kiosk", templates.MergeTemplateData(map[string]interface{}{
+		"Title":     seoMeta.Title,
+		"SEO":       seoMeta,
+		"Recent":    recent,
+		"Random":    random,
+		"StreamURL": streamURL,
+		"City":      city,
+		"Derive":    deriveNumber,
+	}))
+}
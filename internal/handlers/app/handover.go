@@ -0,0 +1,99 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/middleware"
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/templates"
+	"id-100/internal/utils"
+	"id-100/internal/webhooks"
+)
+
+// RequestHandoverHandler lets a device that scanned a bag in use ask the current
+// player to hand it over. It runs without TokenWithSession, which would only
+// show the "bag in use" page, and reads the token from the session.
+func RequestHandoverHandler(c *echo.Context) error {
+	session, err := middleware.Store.Get(c.Request(), "id-100-session")
+	if err != nil {
+		log.Printf("Session error in RequestHandoverHandler: %v", err)
+	}
+	tokenHash := middleware.SessionTokenHash(session)
+	if tokenHash == "" {
+		return c.Redirect(http.StatusSeeOther, "/upload")
+	}
+	deviceID := middleware.DeviceID(session)
+	if err := session.Save(c.Request(), c.Response()); err != nil {
+		log.Printf("Failed to save session in RequestHandoverHandler: %v", err)
+	}
+
+	if _, err := repository.RequestHandover(c.Request().Context(), tokenHash, deviceID); err != nil {
+		log.Printf("Failed to request handover: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	return c.Redirect(http.StatusSeeOther, "/upload")
+}
+
+// ApproveHandoverHandler ends the current player's session and passes the bag
+// to the device that asked for it
+func ApproveHandoverHandler(c *echo.Context) error {
+	tokenID, _ := c.Get("token_id").(int)
+
+	ok, err := repository.ApproveHandover(c.Request().Context(), tokenID, time.Now().Add(-middleware.HandoverRequestTTL))
+	if err != nil {
+		log.Printf("Failed to approve handover: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	if !ok {
+		// The request expired or was withdrawn in the meantime
+		return c.Redirect(http.StatusSeeOther, "/upload")
+	}
+
+	bagName, _ := c.Get("bag_name").(string)
+	sessionNumber, _ := c.Get("session_number").(int)
+	webhooks.Emit(c.Request().Context(), webhooks.EventSessionEnded, webhooks.Session{
+		TokenID:       tokenID,
+		BagName:       bagName,
+		SessionNumber: sessionNumber,
+		PlayerName:    webhooks.PublicPlayerName(c.Request().Context(), tokenID, sessionNumber),
+		EndedBy:       "handover",
+	})
+
+	session, err := middleware.Store.Get(c.Request(), "id-100-session")
+	if err == nil {
+		delete(session.Values, middleware.SessionKeyPlayerName)
+		delete(session.Values, middleware.SessionKeyPlayerCity)
+		if err := session.Save(c.Request(), c.Response()); err != nil {
+			log.Printf("Failed to save session in ApproveHandoverHandler: %v", err)
+		}
+	}
+
+	return c.Render(http.StatusOK, "layout", templates.MergeTemplateData(map[string]interface{}{
+		"Title":           "Werkzeug übergeben",
+		"ContentTemplate": "bag_in_use.content",
+		"BagName":         bagName,
+		"HandedOver":      true,
+		"CurrentPath":     c.Request().URL.Path,
+		"CurrentYear":     time.Now().Year(),
+		"FooterStats":     utils.GetFooterStats(),
+	}))
+}
+
+// DeclineHandoverHandler drops a pending handover request, the session goes on
+func DeclineHandoverHandler(c *echo.Context) error {
+	tokenID, _ := c.Get("token_id").(int)
+
+	if err := repository.DeclineHandover(c.Request().Context(), tokenID); err != nil {
+		log.Printf("Failed to decline handover: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	return c.Redirect(http.StatusSeeOther, "/upload")
+}
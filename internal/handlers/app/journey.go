@@ -0,0 +1,87 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/journey"
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/seo"
+	"id-100/internal/templates"
+	"id-100/internal/utils"
+)
+
+// JourneyHandler shows the public journey of a bag: its sessions as a
+// timeline with pseudonymised players, cities and contributions
+func JourneyHandler(c *echo.Context) error {
+	slug := strings.ToLower(c.Param("slug"))
+	if !journey.ValidSlug(slug) {
+		return c.String(http.StatusNotFound, "Werkzeug nicht gefunden")
+	}
+
+	ctx := c.Request().Context()
+	token, err := repository.GetTokenByJourneySlug(ctx, slug)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.String(http.StatusNotFound, "Werkzeug nicht gefunden")
+	}
+	if err != nil {
+		log.Printf("Failed to fetch bag journey %s: %v", slug, err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Datenbankfehler")
+	}
+
+	stages, err := repository.ListJourneyStages(ctx, token.ID)
+	if err != nil {
+		log.Printf("Failed to fetch journey stages of token %d: %v", token.ID, err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Datenbankfehler")
+	}
+
+	cities := map[string]bool{}
+	contributions := 0
+	ogImage := ""
+	for i := range stages {
+		if stages[i].City != "" {
+			cities[strings.ToLower(stages[i].City)] = true
+		}
+		contributions += len(stages[i].Contributions)
+		for j := range stages[i].Contributions {
+			stages[i].Contributions[j].ImageURL = utils.EnsureFullImageURL(stages[i].Contributions[j].ImageURL)
+			ogImage = stages[i].Contributions[j].ImageURL
+		}
+	}
+
+	baseURL := seo.GetBaseURLFromRequest(c.Scheme(), c.Request().Host, c.Request().Header.Get("X-Forwarded-Host"))
+	builder := seo.NewBuilder(baseURL)
+	seoMeta := builder.Default()
+	if ogImage == "" {
+		ogImage = seoMeta.ImageURL
+	}
+	seoMeta = builder.Custom(
+		"Die Reise von "+token.BagName+" | Innenstadt ID-100",
+		"Von Hand zu Hand durch die Stadt: alle Etappen von "+token.BagName+" bei der urbanen Stadtrallye.",
+		ogImage,
+		journey.URL(baseURL, slug),
+		"article",
+	)
+
+	return c.Render(http.StatusOK, "layout", templates.MergeTemplateData(map[string]interface{}{
+		"Title":             seoMeta.Title,
+		"SEO":               seoMeta,
+		"ContentTemplate":   "journey.content",
+		"Token":             token,
+		"Stages":            stages,
+		"CityCount":         len(cities),
+		"ContributionCount": contributions,
+		"CurrentPath":       c.Request().URL.Path,
+		"CurrentYear":       time.Now().Year(),
+		"FooterStats":       utils.GetFooterStats(),
+	}))
+}
@@ -0,0 +1,380 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/attribution"
+	"id-100/internal/bagrequest"
+	"id-100/internal/config"
+	mail "id-100/internal/email"
+	"id-100/internal/middleware"
+	"id-100/internal/models"
+	"id-100/internal/player"
+	"id-100/internal/recap"
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/templates"
+	"id-100/internal/utils"
+)
+
+// playerNotices are the messages shown after redirects back to /spieler
+var playerNotices = map[string]string{
+	"umbenannt":    "Dein Name wurde bei allen deinen Beiträgen geändert.",
+	"anonymisiert": "Deine Beiträge werden jetzt als „" + attribution.AnonymousName + "“ angezeigt.",
+	"verknuepft":   "Die Session gehört jetzt zu deinem Konto.",
+	"vergeben":     "Diese Session gehört bereits zu einem Spielerkonto.",
+	"anzeige":      "Deine Beiträge werden jetzt in der gewählten Form angezeigt.",
+	"abgemeldet":   "Du bist abgemeldet. Mit deinem Wiederherstellungscode kannst du dich jederzeit wieder anmelden.",
+}
+
+// renderPlayerPage renders /spieler; data adds to or overrides the defaults
+func renderPlayerPage(c *echo.Context, status int, data map[string]interface{}) error {
+	page := map[string]interface{}{
+		"Title":           "Mein Fortschritt | Innenstadt ID-100",
+		"NoIndex":         true,
+		"ContentTemplate": "player.content",
+		"EmailEnabled":    mail.Enabled(),
+		"NameStyles":      attribution.Styles,
+		"CurrentPath":     "/spieler",
+		"CurrentYear":     time.Now().Year(),
+		"FooterStats":     utils.GetFooterStats(),
+	}
+	for k, v := range data {
+		page[k] = v
+	}
+
+	if playerID, ok := middleware.PlayerID(c); ok {
+		p, err := repository.GetPlayer(c.Request().Context(), playerID)
+		if err == nil {
+			err = addPlayerProgress(c.Request().Context(), p, page)
+		}
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			// The account is gone; drop the stale cookie
+			middleware.SignOutPlayer(c)
+		case err != nil:
+			log.Printf("Failed to fetch progress of player %d: %v", playerID, err)
+			sentryhelper.CaptureException(c, err)
+			return c.String(http.StatusInternalServerError, "Datenbankfehler")
+		}
+	}
+	return c.Render(status, "layout", templates.MergeTemplateData(page))
+}
+
+// addPlayerProgress adds a player with their sessions and progress to the page data
+func addPlayerProgress(ctx context.Context, p *models.Player, page map[string]interface{}) error {
+	sessions, err := repository.ListPlayerSessions(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	contribs, err := repository.ListPlayerContributions(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	derives, err := repository.GetDerivenForUpload(ctx)
+	if err != nil {
+		return err
+	}
+	bags := map[int]bool{}
+	for _, s := range sessions {
+		bags[s.TokenID] = true
+	}
+	page["Player"] = p
+	page["Sessions"] = sessions
+	page["Bags"] = len(bags)
+	page["Progress"] = player.Progress(derives, contribs)
+	return nil
+}
+
+// linkCurrentBagSession links the bag session of this browser, if any, to a
+// player who just signed in
+func linkCurrentBagSession(c *echo.Context, playerID int) {
+	session, err := middleware.Store.Get(c.Request(), "id-100-session")
+	if err != nil {
+		return
+	}
+	tokenID, ok := middleware.GetSessionNumber(session.Values[middleware.SessionKeyTokenID])
+	if !ok {
+		return
+	}
+	sessionNumber, ok := middleware.GetSessionNumber(session.Values[middleware.SessionKeySessionNum])
+	if !ok {
+		return
+	}
+	if _, err := repository.LinkPlayerSession(c.Request().Context(), playerID, tokenID, sessionNumber); err != nil {
+		log.Printf("Failed to link session %d of token %d to player %d: %v", sessionNumber, tokenID, playerID, err)
+		sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+	}
+}
+
+// createPlayer creates an account with a new recovery code and signs it in.
+// It returns the formatted code, which is shown once and never stored.
+func createPlayer(c *echo.Context, email string) (string, error) {
+	code, err := player.NewRecoveryCode()
+	if err != nil {
+		return "", err
+	}
+	id, err := repository.CreatePlayer(c.Request().Context(), player.HashCode(code), email)
+	if err != nil {
+		return "", err
+	}
+	if err := middleware.SignInPlayer(c, id); err != nil {
+		return "", err
+	}
+	linkCurrentBagSession(c, id)
+	return player.FormatCode(code), nil
+}
+
+// PlayerHandler shows the progress of the signed-in player, or how to sign in
+func PlayerHandler(c *echo.Context) error {
+	return renderPlayerPage(c, http.StatusOK, map[string]interface{}{
+		"Notice": playerNotices[c.QueryParam("hinweis")],
+	})
+}
+
+// PlayerCreateHandler creates an account with a recovery code
+func PlayerCreateHandler(c *echo.Context) error {
+	code, err := createPlayer(c, "")
+	if err != nil {
+		log.Printf("Failed to create player: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	return renderPlayerPage(c, http.StatusOK, map[string]interface{}{"RecoveryCode": code})
+}
+
+// PlayerCodeLoginHandler signs in with a recovery code
+func PlayerCodeLoginHandler(c *echo.Context) error {
+	code, ok := player.NormalizeCode(c.FormValue("code"))
+	if !ok {
+		return renderPlayerPage(c, http.StatusBadRequest, map[string]interface{}{
+			"FormError": "Das ist kein gültiger Wiederherstellungscode. Er hat 16 Zeichen, z.B. K7P4-QX2M-9ABC-DEFG.",
+		})
+	}
+	id, err := repository.FindPlayerByCode(c.Request().Context(), player.HashCode(code))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return renderPlayerPage(c, http.StatusUnauthorized, map[string]interface{}{
+			"FormError": "Zu diesem Wiederherstellungscode gibt es kein Konto.",
+		})
+	}
+	if err == nil {
+		err = middleware.SignInPlayer(c, id)
+	}
+	if err != nil {
+		log.Printf("Failed to sign in player: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	linkCurrentBagSession(c, id)
+	return c.Redirect(http.StatusSeeOther, "/spieler")
+}
+
+// PlayerEmailLinkHandler sends a magic link to sign in, or to add the address
+// to the account of a signed-in player
+func PlayerEmailLinkHandler(c *echo.Context) error {
+	if !mail.Enabled() {
+		return renderPlayerPage(c, http.StatusServiceUnavailable, map[string]interface{}{
+			"FormError": "Die Anmeldung per E-Mail ist gerade nicht verfügbar. Bitte nutze deinen Wiederherstellungscode.",
+		})
+	}
+	email, err := bagrequest.NormalizeEmail(c.FormValue("email"))
+	if err != nil {
+		return renderPlayerPage(c, http.StatusBadRequest, map[string]interface{}{"FormError": "Ungültige E-Mail"})
+	}
+
+	ctx := c.Request().Context()
+	recent, err := repository.CountPlayerLoginLinksSince(ctx, email, time.Now().Add(-player.LinkTTL))
+	if err != nil {
+		log.Printf("Failed to count player login links: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	if recent >= player.MaxLinksPerAddress {
+		return renderPlayerPage(c, http.StatusTooManyRequests, map[string]interface{}{
+			"FormError": "Wir haben dir gerade schon mehrere Links geschickt. Bitte schau in dein Postfach.",
+		})
+	}
+
+	token, err := player.NewLinkToken()
+	if err != nil {
+		log.Printf("Failed to generate player login link: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	var playerID *int
+	if id, ok := middleware.PlayerID(c); ok {
+		playerID = &id
+	}
+	expiresAt := time.Now().Add(player.LinkTTL)
+	if err := repository.CreatePlayerLoginLink(ctx, utils.HashToken(token), email, playerID, expiresAt); err != nil {
+		log.Printf("Failed to store player login link: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	baseURL := strings.TrimRight(config.GetBaseURL(), "/")
+	if err := mail.Enqueue(ctx, mail.TemplatePlayerLogin, email, mail.PlayerLogin{
+		BaseURL:   baseURL,
+		LoginURL:  baseURL + player.LinkPath(token),
+		ExpiresAt: expiresAt,
+		AddEmail:  playerID != nil,
+	}); err != nil {
+		log.Printf("Failed to queue player login email: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	return renderPlayerPage(c, http.StatusOK, map[string]interface{}{
+		"Notice": "Wir haben dir einen Link an " + email + " geschickt. Er ist 30 Minuten gültig.",
+	})
+}
+
+// PlayerEmailLoginHandler opens a magic link. Unknown addresses get a new
+// account, which is shown its recovery code once.
+func PlayerEmailLoginHandler(c *echo.Context) error {
+	token := c.Param("token")
+	invalid := map[string]interface{}{
+		"FormError": "Dieser Link ist ungültig, abgelaufen oder wurde schon benutzt. Du kannst dir einen neuen schicken lassen.",
+	}
+	if !player.ValidLinkToken(token) {
+		return renderPlayerPage(c, http.StatusBadRequest, invalid)
+	}
+
+	ctx := c.Request().Context()
+	email, playerID, err := repository.UsePlayerLoginLink(ctx, utils.HashToken(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return renderPlayerPage(c, http.StatusGone, invalid)
+	}
+	if err != nil {
+		log.Printf("Failed to use player login link: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+
+	if playerID != nil {
+		err := repository.SetPlayerEmail(ctx, *playerID, email)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return renderPlayerPage(c, http.StatusConflict, map[string]interface{}{
+				"FormError": "Diese E-Mail gehört schon zu einem anderen Spielerkonto.",
+			})
+		}
+		if err == nil {
+			err = middleware.SignInPlayer(c, *playerID)
+		}
+		if err != nil {
+			log.Printf("Failed to add email to player %d: %v", *playerID, err)
+			sentryhelper.CaptureException(c, err)
+			return c.String(http.StatusInternalServerError, "Serverfehler")
+		}
+		return renderPlayerPage(c, http.StatusOK, map[string]interface{}{
+			"Notice": "Deine E-Mail ist bestätigt. Du kannst dich jetzt auch per Link anmelden.",
+		})
+	}
+
+	id, err := repository.FindPlayerByEmail(ctx, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		code, err := createPlayer(c, email)
+		if err != nil {
+			log.Printf("Failed to create player: %v", err)
+			sentryhelper.CaptureException(c, err)
+			return c.String(http.StatusInternalServerError, "Serverfehler")
+		}
+		return renderPlayerPage(c, http.StatusOK, map[string]interface{}{"RecoveryCode": code})
+	}
+	if err == nil {
+		err = middleware.SignInPlayer(c, id)
+	}
+	if err != nil {
+		log.Printf("Failed to sign in player: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	linkCurrentBagSession(c, id)
+	return c.Redirect(http.StatusSeeOther, "/spieler")
+}
+
+// PlayerLogoutHandler signs the player out of this browser
+func PlayerLogoutHandler(c *echo.Context) error {
+	if err := middleware.SignOutPlayer(c); err != nil {
+		log.Printf("Failed to sign out player: %v", err)
+	}
+	return c.Redirect(http.StatusSeeOther, "/spieler?hinweis=abgemeldet")
+}
+
+// PlayerRenameHandler changes the public name of all contributions of the
+// signed-in player; the anonymise button submits anonymous=1
+func PlayerRenameHandler(c *echo.Context) error {
+	playerID, ok := middleware.PlayerID(c)
+	if !ok {
+		return c.Redirect(http.StatusSeeOther, "/spieler")
+	}
+	name, notice := attribution.AnonymousName, "anonymisiert"
+	if c.FormValue("anonymous") == "" {
+		if name, ok = player.NormalizeName(c.FormValue("public_name")); !ok {
+			return renderPlayerPage(c, http.StatusBadRequest, map[string]interface{}{
+				"FormError": "Bitte gib einen Namen mit höchstens 40 Zeichen ein.",
+			})
+		}
+		notice = "umbenannt"
+	}
+	if _, err := repository.RenamePlayer(c.Request().Context(), playerID, name); err != nil {
+		log.Printf("Failed to rename player %d: %v", playerID, err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	return c.Redirect(http.StatusSeeOther, "/spieler?hinweis="+notice)
+}
+
+// PlayerNameDisplayHandler applies a display preference to all contributions of
+// the signed-in player instead of a chosen public name
+func PlayerNameDisplayHandler(c *echo.Context) error {
+	playerID, ok := middleware.PlayerID(c)
+	if !ok {
+		return c.Redirect(http.StatusSeeOther, "/spieler")
+	}
+	style, ok := attribution.Parse(c.FormValue("name_display"))
+	if !ok {
+		return c.String(http.StatusBadRequest, "Ungültige Namensanzeige")
+	}
+	if _, err := repository.SetPlayerNameDisplay(c.Request().Context(), playerID, style); err != nil {
+		log.Printf("Failed to set name display of player %d: %v", playerID, err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	return c.Redirect(http.StatusSeeOther, "/spieler?hinweis=anzeige")
+}
+
+// PlayerLinkRecapHandler adds a past session to the signed-in player's account
+// by its recap link
+func PlayerLinkRecapHandler(c *echo.Context) error {
+	key := c.Param("key")
+	if !recap.ValidKey(key) {
+		return c.String(http.StatusNotFound, "Rückblick nicht gefunden")
+	}
+	playerID, ok := middleware.PlayerID(c)
+	if !ok {
+		return c.Redirect(http.StatusSeeOther, "/spieler")
+	}
+	linked, err := repository.LinkPlayerSessionByRecap(c.Request().Context(), playerID, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.String(http.StatusNotFound, "Rückblick nicht gefunden")
+	}
+	if err != nil {
+		log.Printf("Failed to link recap session to player %d: %v", playerID, err)
+		sentryhelper.CaptureException(c, err)
+		return c.String(http.StatusInternalServerError, "Serverfehler")
+	}
+	if !linked {
+		return c.Redirect(http.StatusSeeOther, "/spieler?hinweis=vergeben")
+	}
+	return c.Redirect(http.StatusSeeOther, "/spieler?hinweis=verknuepft")
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/journey"
+	"id-100/internal/middleware"
+	"id-100/internal/models"
+	"id-100/internal/recap"
+	"id-100/internal/repository"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/seo"
+	"id-100/internal/templates"
+	"id-100/internal/utils"
+)
+
+// loadRecap fetches the recap of the :key parameter with the player name
+// pseudonymised, since recap links are meant to be shared. It returns nil
+// after writing an error response.
+func loadRecap(c *echo.Context) (*models.SessionRecap, error) {
+	key := c.Param("key")
+	if !recap.ValidKey(key) {
+		return nil, c.String(http.StatusNotFound, "Rückblick nicht gefunden")
+	}
+	r, err := repository.GetSessionRecap(c.Request().Context(), key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, c.String(http.StatusNotFound, "Rückblick nicht gefunden")
+	}
+	if err != nil {
+		log.Printf("Failed to fetch recap: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return nil, c.String(http.StatusInternalServerError, "Datenbankfehler")
+	}
+	r.PlayerName = journey.Pseudonym(r.PlayerName)
+	return r, nil
+}
+
+// RecapHandler shows what a player made in an ended session
+func RecapHandler(c *echo.Context) error {
+	r, err := loadRecap(c)
+	if r == nil {
+		return err
+	}
+	for i := range r.Contributions {
+		r.Contributions[i].ImageURL = utils.EnsureFullImageURL(r.Contributions[i].ImageURL)
+	}
+	_, signedIn := middleware.PlayerID(c)
+	ids := recap.CompletedIDs(r.Contributions)
+	points := recap.Points(r.Contributions)
+
+	baseURL := seo.GetBaseURLFromRequest(c.Scheme(), c.Request().Host, c.Request().Header.Get("X-Forwarded-Host"))
+	builder := seo.NewBuilder(baseURL)
+	image := builder.Default().ImageURL
+	if len(r.Contributions) > 0 {
+		image = r.Contributions[0].ImageURL
+	}
+	seoMeta := builder.Custom(
+		fmt.Sprintf("%s: %d IDs, %d Punkte | Innenstadt ID-100", r.PlayerName, len(ids), points),
+		fmt.Sprintf("Rückblick auf Session #%d mit %s bei der urbanen Stadtrallye Innenstadt ID - 100.", r.SessionNumber, r.BagName),
+		image,
+		baseURL+recap.Path(r.Key),
+		"article",
+	)
+
+	return c.Render(http.StatusOK, "layout", templates.MergeTemplateData(map[string]interface{}{
+		"Title":           seoMeta.Title,
+		"SEO":             seoMeta,
+		"NoIndex":         true,
+		"ContentTemplate": "recap.content",
+		"Recap":           r,
+		"CompletedIDs":    ids,
+		"Points":          points,
+		"PlayerSignedIn":  signedIn,
+		"CurrentPath":     c.Request().URL.Path,
+		"CurrentYear":     time.Now().Year(),
+		"FooterStats":     utils.GetFooterStats(),
+	}))
+}
+
+// RecapArchiveHandler streams a ZIP with the images and comments of a recap
+func RecapArchiveHandler(c *echo.Context) error {
+	r, err := loadRecap(c)
+	if r == nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	c.Response().Header().Set("Content-Type", "application/zip")
+	c.Response().Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": recap.ArchiveName(r)}))
+	c.Response().WriteHeader(http.StatusOK)
+
+	err = recap.WriteArchive(c.Response(), r, func(imageURL string) (io.ReadCloser, error) {
+		body, err := utils.DownloadImageFromS3(ctx, imageURL)
+		if err != nil {
+			log.Printf("Recap archive: %v", err)
+			sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+		}
+		return body, err
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		// Headers are sent already; the client gets a truncated archive
+		log.Printf("Failed to write recap archive: %v", err)
+		sentryhelper.CaptureException(c, err)
+	}
+	return nil
+}
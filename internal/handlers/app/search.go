@@ -0,0 +1,121 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/models"
+	"id-100/internal/repository"
+	"id-100/internal/search"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/seo"
+	"id-100/internal/templates"
+	"id-100/internal/utils"
+)
+
+const (
+	searchPageSize    = 20
+	searchAPIMaxLimit = 50
+)
+
+// prepareSearchResults normalizes image URLs and renders the highlighted snippets
+func prepareSearchResults(results []models.SearchResult) []models.SearchResult {
+	if results == nil {
+		return []models.SearchResult{}
+	}
+	for i := range results {
+		results[i].ImageUrl = utils.EnsureFullImageURL(results[i].ImageUrl)
+		results[i].SnippetHTML = search.SnippetHTML(results[i].Snippet)
+		results[i].Snippet = search.PlainSnippet(results[i].Snippet)
+	}
+	return results
+}
+
+// SearchHandler displays the full-text search page
+func SearchHandler(c *echo.Context) error {
+	stats := utils.GetFooterStats()
+
+	rawQuery := c.QueryParam("q")
+	query, ok := search.NormalizeQuery(rawQuery)
+
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	var results []models.SearchResult
+	total := 0
+	searchFailed := false
+	if ok {
+		var err error
+		results, total, err = repository.SearchContent(c.Request().Context(), query, searchPageSize, (page-1)*searchPageSize)
+		if err != nil {
+			log.Printf("Search query error: %v", err)
+			sentryhelper.CaptureException(c, err)
+			searchFailed = true
+		}
+	}
+	results = prepareSearchResults(results)
+	totalPages := (total + searchPageSize - 1) / searchPageSize
+
+	// Generate SEO metadata
+	baseURL := seo.GetBaseURLFromRequest(c.Scheme(), c.Request().Host, c.Request().Header.Get("X-Forwarded-Host"))
+	builder := seo.NewBuilder(baseURL)
+	seoMeta := builder.ForPage("search")
+
+	return c.Render(http.StatusOK, "layout", templates.MergeTemplateData(map[string]interface{}{
+		"Title":           seoMeta.Title,
+		"SEO":             seoMeta,
+		"Query":           rawQuery,
+		"QueryTooShort":   rawQuery != "" && !ok,
+		"Searched":        ok,
+		"SearchFailed":    searchFailed,
+		"Results":         results,
+		"TotalResults":    total,
+		"CurrentPage":     page,
+		"HasNext":         page < totalPages,
+		"HasPrev":         page > 1,
+		"NextPage":        page + 1,
+		"PrevPage":        page - 1,
+		"ContentTemplate": "search.content",
+		"CurrentPath":     c.Request().URL.Path,
+		"CurrentYear":     time.Now().Year(),
+		"FooterStats":     stats,
+	}))
+}
+
+// SearchAPIHandler returns full-text search results as JSON
+func SearchAPIHandler(c *echo.Context) error {
+	query, ok := search.NormalizeQuery(c.QueryParam("q"))
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Suchbegriff zu kurz"})
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 || limit > searchAPIMaxLimit {
+		limit = searchPageSize
+	}
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	results, total, err := repository.SearchContent(c.Request().Context(), query, limit, offset)
+	if err != nil {
+		log.Printf("Search query error: %v", err)
+		sentryhelper.CaptureException(c, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Suche fehlgeschlagen"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"query":   query,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+		"results": prepareSearchResults(results),
+	})
+}
@@ -1,32 +1,31 @@
 package app
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/chai2010/webp"
 	"github.com/getsentry/sentry-go"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v5"
 
-	"id-100/internal/imgutil"
+	"id-100/internal/attribution"
+	"id-100/internal/consent"
 	"id-100/internal/middleware"
+	"id-100/internal/recap"
 	"id-100/internal/repository"
 	"id-100/internal/sentryhelper"
 	"id-100/internal/seo"
 	"id-100/internal/templates"
+	"id-100/internal/uploads"
 	"id-100/internal/utils"
+	"id-100/internal/webhooks"
 )
 
 // UploadGetHandler displays the upload form
@@ -59,6 +58,26 @@ func UploadGetHandler(c *echo.Context) error {
 	token, _ := c.Get("token").(string)
 	currentPlayer, _ := c.Get("current_player").(string)
 
+	// Sessions that agreed to an older privacy policy are asked again first
+	if currentPlayer != "" {
+		consented, err := repository.HasConsent(c.Request().Context(), tokenID, sessionNumber, consent.PolicyVersion)
+		if err != nil {
+			log.Printf("Failed to check consent: %v", err)
+			sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+		} else if !consented {
+			return renderConsentPage(c, http.StatusOK, "")
+		}
+	}
+
+	// Sessions played while signed in to a player account count towards it
+	playerID, signedIn := middleware.PlayerID(c)
+	if signedIn && currentPlayer != "" {
+		if _, err := repository.LinkPlayerSession(c.Request().Context(), playerID, tokenID, sessionNumber); err != nil {
+			log.Printf("Failed to link session %d of token %d to player %d: %v", sessionNumber, tokenID, playerID, err)
+			sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+		}
+	}
+
 	// Build a map[string]bool of derive numbers that were uploaded in THIS session/token
 	uploadedNumbers := make(map[string]bool)
 	totalPoints := 0
@@ -75,6 +94,28 @@ func UploadGetHandler(c *echo.Context) error {
 		}
 	}
 
+	// How the player's name appears next to their contributions
+	var nameDisplay attribution.Style
+	publicName := ""
+	if currentPlayer != "" {
+		if nameDisplay, publicName, err = repository.GetSessionAttribution(c.Request().Context(), tokenID, sessionNumber); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to fetch name display: %v", err)
+			sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+		}
+	}
+
+	// Only offer the derives the bag's ruleset allows
+	ruleset := middleware.Ruleset(c)
+	if len(ruleset.AllowedDerives) > 0 {
+		allowed := list[:0]
+		for _, d := range list {
+			if ruleset.AllowsDerive(d.Number) {
+				allowed = append(allowed, d)
+			}
+		}
+		list = allowed
+	}
+
 	// Generate SEO metadata
 	baseURL := seo.GetBaseURLFromRequest(c.Scheme(), c.Request().Host, c.Request().Header.Get("X-Forwarded-Host"))
 	builder := seo.NewBuilder(baseURL)
@@ -92,8 +133,16 @@ func UploadGetHandler(c *echo.Context) error {
 		"SelectedNumber":  c.QueryParam("number"),
 		"Token":           token,
 		"CurrentPlayer":   currentPlayer,
+		"PlayerSignedIn":  signedIn,
+		"NameDisplay":     string(nameDisplay),
+		"NameStyles":      attribution.Styles,
+		"PublicName":      publicName,
 		"UploadedNumbers": uploadedNumbers,
 		"TotalPoints":     totalPoints,
+		"HandoverPending": c.Get("handover_pending"),
+		"LimitedDerives":  len(ruleset.AllowedDerives) > 0,
+		"SessionEndsAt":   c.Get("session_ends_at"),
+		"UploadsLeft":     c.Get("uploads_remaining"),
 	}))
 }
 
@@ -108,11 +157,13 @@ func UploadPostHandler(c *echo.Context) error {
 	currentPlayer, _ := c.Get("current_player").(string)
 	sessionNumber, _ := c.Get("session_number").(int)
 
-	deriveNumberStr := c.FormValue("derive_number")
-	deriveNumber, err := strconv.Atoi(deriveNumberStr)
+	deriveNumber, err := strconv.Atoi(c.FormValue("derive_number"))
 	if err != nil {
 		return c.String(http.StatusBadRequest, "Ungültige Aufgabennummer")
 	}
+	if !middleware.Ruleset(c).AllowsDerive(deriveNumber) {
+		return c.String(http.StatusForbidden, "Diese Aufgabe ist für dieses Werkzeug nicht freigegeben")
+	}
 
 	file, err := c.FormFile("image")
 	if err != nil {
@@ -124,98 +175,29 @@ func UploadPostHandler(c *echo.Context) error {
 		return c.String(http.StatusBadRequest, "Datei konnte nicht geöffnet werden")
 	}
 	defer src.Close()
-	// Decode and auto-orient based on EXIF so mobile uploads keep the correct rotation
-	img, err := imgutil.DecodeAutoOriented(src)
-	if err != nil {
-		return c.String(http.StatusBadRequest, "Ungültiges Bildformat oder Korrektur fehlgeschlagen")
-	}
-	var buf bytes.Buffer
-	if err := webp.Encode(&buf, img, &webp.Options{Lossless: false, Quality: 75}); err != nil {
-		return c.String(http.StatusInternalServerError, "WebP-Kodierung fehlgeschlagen")
-	}
 
-	cfg, err := config.LoadDefaultConfig(c.Request().Context(),
-		config.WithRegion(os.Getenv("S3_REGION")),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			os.Getenv("S3_ACCESS_KEY"),
-			os.Getenv("S3_SECRET_KEY"),
-			""),
-		),
-	)
-	if err != nil {
-		log.Printf("Failed to load AWS config: %v", err)
-		sentryhelper.CaptureException(c, err)
-		return c.String(http.StatusInternalServerError, "Upload fehlgeschlagen")
-	}
-	s3Client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" {
-			o.BaseEndpoint = aws.String(endpoint)
-		}
-		o.UsePathStyle = true
-	})
-
-	fileName := fmt.Sprintf("derive_%s_%d.webp", deriveNumberStr, time.Now().Unix())
-
-	_, err = s3Client.PutObject(c.Request().Context(), &s3.PutObjectInput{
-		Bucket:      aws.String(os.Getenv("S3_BUCKET")),
-		Key:         aws.String(fileName),
-		Body:        bytes.NewReader(buf.Bytes()),
-		ContentType: aws.String("image/webp"),
+	currentPlayerCity, _ := c.Get("current_player_city").(string)
+	result, err := uploads.Store(c.Request().Context(), src, uploads.Params{
+		TokenID:       tokenID,
+		SessionNumber: sessionNumber,
+		DeriveNumber:  deriveNumber,
+		PlayerName:    currentPlayer,
+		PlayerCity:    currentPlayerCity,
+		Comment:       c.FormValue("comment"),
 	})
-	if err != nil {
-		log.Printf("S3 upload error: %v", err)
-		sentryhelper.CaptureException(c, err)
-		return c.String(http.StatusInternalServerError, "Upload fehlgeschlagen")
-	}
-
-	// Store just the filename in DB
-	relativePath := fileName
-
-	// generate tiny LQIP (data-uri) and store it
-	lqip, lqipErr := utils.GenerateLQIP(img, 24)
-	if lqipErr != nil {
-		log.Printf("LQIP generation failed: %v", lqipErr)
-		sentryhelper.CaptureError(c, lqipErr, sentry.LevelWarning)
-		lqip = ""
-	}
-
-	// Get derive internal ID
-	internalID, err := repository.GetDeriveIDByNumber(context.Background(), deriveNumberStr)
-	if err != nil {
+	switch {
+	case errors.Is(err, uploads.ErrInvalidImage):
+		return c.String(http.StatusBadRequest, "Ungültiges Bildformat oder Korrektur fehlgeschlagen")
+	case errors.Is(err, uploads.ErrDeriveNotFound):
 		return c.String(http.StatusNotFound, "Aufgabe nicht gefunden")
-	}
-
-	// Get optional user comment (max 100 chars)
-	userComment := c.FormValue("comment")
-	runes := []rune(userComment)
-	if len(runes) > 100 {
-		userComment = string(runes[:100])
-	}
-
-	// Insert contribution
-	currentPlayerCity, _ := c.Get("current_player_city").(string)
-	contributionID, err := repository.InsertContribution(context.Background(),
-		internalID, relativePath, lqip, currentPlayer, currentPlayerCity, userComment)
-
-	if err != nil {
-		log.Printf("DB Error inserting contribution: %v", err)
+	case errors.Is(err, uploads.ErrConsentRequired):
+		return c.String(http.StatusConflict, "Bitte stimme zuerst der aktuellen Datenschutzerklärung zu")
+	case err != nil:
+		log.Printf("Upload failed: %v", err)
 		sentryhelper.CaptureException(c, err)
-		return c.String(http.StatusInternalServerError, "DB Error")
-	}
-
-	// Log upload
-	err = repository.InsertUploadLog(context.Background(), tokenID, deriveNumber, currentPlayer, sessionNumber, contributionID)
-	if err != nil {
-		log.Printf("Failed to log upload: %v", err)
-		sentryhelper.CaptureError(c, err, sentry.LevelWarning)
-	}
-
-	// Increment total_uploads counter
-	err = repository.IncrementTokenUploadCount(context.Background(), tokenID)
-	if err != nil {
-		log.Printf("Failed to increment upload counter: %v", err)
-		sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+		return c.String(http.StatusInternalServerError, "Upload fehlgeschlagen")
 	}
+	contributionID := result.ContributionID
 
 	// Emit a structured informational log to Sentry (requires EnableLogs=true)
 	// This will be correlated with the current request's trace/context.
@@ -249,16 +231,20 @@ func SetPlayerNameHandler(c *echo.Context) error {
 	}
 
 	playerName := c.FormValue("player_name")
-	token := c.FormValue("token")
+	// The plain token is only known when the request carried it; after a short link it lives hashed in the session
+	token, _ := c.Get("token").(string)
 
-	if playerName == "" || token == "" {
-		return c.String(http.StatusBadRequest, "Name und Token erforderlich")
+	if playerName == "" {
+		return c.String(http.StatusBadRequest, "Name erforderlich")
+	}
+	nameDisplay, ok := attribution.Parse(c.FormValue("name_display"))
+	if !ok {
+		return c.String(http.StatusBadRequest, "Ungültige Namensanzeige")
 	}
 
 	// Consent checkbox (required)
-	consent := c.FormValue("agree_privacy")
-	if consent == "" {
-		bagName, _ := repository.GetBagNameByToken(context.Background(), token)
+	if c.FormValue("agree_privacy") == "" {
+		bagName, _ := c.Get("bag_name").(string)
 
 		// Generate SEO metadata
 		baseURL := seo.GetBaseURLFromRequest(c.Scheme(), c.Request().Host, c.Request().Header.Get("X-Forwarded-Host"))
@@ -290,12 +276,110 @@ func SetPlayerNameHandler(c *echo.Context) error {
 	session.Save(c.Request(), c.Response())
 
 	// Update database
-	err := repository.UpdatePlayerNameAndCity(context.Background(), playerName, playerCity, token)
+	tokenID, _ := c.Get("token_id").(int)
+	deviceID, _ := c.Get("device_id").(string)
+	err := repository.UpdatePlayerNameAndCity(context.Background(), playerName, playerCity, tokenID, deviceID)
 	if err != nil {
 		log.Printf("Error setting player name: %v", err)
+	} else {
+		bagName, _ := c.Get("bag_name").(string)
+		sessionNumber, _ := c.Get("session_number").(int)
+		if _, err := repository.SetSessionNameDisplay(c.Request().Context(), tokenID, sessionNumber, nameDisplay); err != nil {
+			log.Printf("Error setting name display: %v", err)
+			sentryhelper.CaptureException(c, err)
+		}
+		// Without a record the upload page asks for the consent again
+		if err := repository.RecordConsent(c.Request().Context(), tokenID, sessionNumber,
+			consent.PolicyVersion, consent.Texts(), consent.SourceWeb, consent.HashIP(c.RealIP())); err != nil {
+			log.Printf("Error recording consent: %v", err)
+			sentryhelper.CaptureException(c, err)
+		}
+		if c.FormValue("hide_journey") != "" {
+			if err := repository.SetSessionJourneyHidden(c.Request().Context(), tokenID, sessionNumber, true); err != nil {
+				log.Printf("Error hiding session from journey: %v", err)
+				sentryhelper.CaptureException(c, err)
+			}
+		}
+		webhooks.Emit(c.Request().Context(), webhooks.EventSessionStarted, webhooks.Session{
+			TokenID:       tokenID,
+			BagName:       bagName,
+			SessionNumber: sessionNumber,
+			PlayerName:    webhooks.PublicPlayerName(c.Request().Context(), tokenID, sessionNumber),
+			PlayerCity:    playerCity,
+		})
 	}
 
-	// Redirect to upload page
+	// Redirect to upload page; when the browser keeps the session cookie the
+	// token stays out of the address bar (short links rely on that)
+	if _, err := c.Request().Cookie("id-100-session"); err == nil || token == "" {
+		return c.Redirect(http.StatusSeeOther, "/upload")
+	}
+	return c.Redirect(http.StatusSeeOther, "/upload?token="+url.QueryEscape(token))
+}
+
+// renderConsentPage asks a running session to agree to the current privacy policy
+func renderConsentPage(c *echo.Context, status int, formError string) error {
+	token, _ := c.Get("token").(string)
+	bagName, _ := c.Get("bag_name").(string)
+	return c.Render(status, "layout", templates.MergeTemplateData(map[string]interface{}{
+		"Title":           "Datenschutzerklärung aktualisiert | Innenstadt ID-100",
+		"NoIndex":         true,
+		"ContentTemplate": "consent.content",
+		"CurrentPath":     c.Request().URL.Path,
+		"CurrentYear":     time.Now().Year(),
+		"Token":           token,
+		"BagName":         bagName,
+		"FormError":       formError,
+	}))
+}
+
+// ConsentHandler records that the running session agrees to the current
+// privacy policy and continues to the upload page
+func ConsentHandler(c *echo.Context) error {
+	if c.FormValue("agree_privacy") == "" {
+		return renderConsentPage(c, http.StatusBadRequest, "Bitte stimme der Datenschutzerklärung zu, um weiter hochzuladen.")
+	}
+	tokenID, _ := c.Get("token_id").(int)
+	sessionNumber, _ := c.Get("session_number").(int)
+	if currentPlayer, _ := c.Get("current_player").(string); currentPlayer != "" {
+		if err := repository.RecordConsent(c.Request().Context(), tokenID, sessionNumber,
+			consent.PolicyVersion, consent.Texts(), consent.SourceWeb, consent.HashIP(c.RealIP())); err != nil {
+			log.Printf("Error recording consent: %v", err)
+			sentryhelper.CaptureException(c, err)
+			return c.String(http.StatusInternalServerError, "Datenbankfehler")
+		}
+	}
+
+	token, _ := c.Get("token").(string)
+	if _, err := c.Request().Cookie("id-100-session"); err == nil || token == "" {
+		return c.Redirect(http.StatusSeeOther, "/upload")
+	}
+	return c.Redirect(http.StatusSeeOther, "/upload?token="+url.QueryEscape(token))
+}
+
+// NameDisplayHandler changes how the player's name appears next to the
+// contributions of the running session, including earlier ones
+func NameDisplayHandler(c *echo.Context) error {
+	nameDisplay, ok := attribution.Parse(c.FormValue("name_display"))
+	if !ok {
+		return c.String(http.StatusBadRequest, "Ungültige Namensanzeige")
+	}
+	tokenID, _ := c.Get("token_id").(int)
+	sessionNumber, _ := c.Get("session_number").(int)
+	currentPlayer, _ := c.Get("current_player").(string)
+	if currentPlayer != "" {
+		_, err := repository.SetSessionNameDisplay(c.Request().Context(), tokenID, sessionNumber, nameDisplay)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Error setting name display: %v", err)
+			sentryhelper.CaptureException(c, err)
+			return c.String(http.StatusInternalServerError, "Datenbankfehler")
+		}
+	}
+
+	token, _ := c.Get("token").(string)
+	if _, err := c.Request().Cookie("id-100-session"); err == nil || token == "" {
+		return c.Redirect(http.StatusSeeOther, "/upload")
+	}
 	return c.Redirect(http.StatusSeeOther, "/upload?token="+url.QueryEscape(token))
 }
 
@@ -324,6 +408,28 @@ func EndSessionHandler(c *echo.Context) error {
 		})
 	}
 
+	bagName, _ := c.Get("bag_name").(string)
+	sessionNumber, _ := c.Get("session_number").(int)
+	webhooks.Emit(c.Request().Context(), webhooks.EventSessionEnded, webhooks.Session{
+		TokenID:       tokenID,
+		BagName:       bagName,
+		SessionNumber: sessionNumber,
+		PlayerName:    webhooks.PublicPlayerName(c.Request().Context(), tokenID, sessionNumber),
+		EndedBy:       "player",
+	})
+
+	// The recap keeps the player's contributions reachable after the bag is passed on
+	resp := map[string]string{
+		"status":  "success",
+		"message": "Session beendet. Das Werkzeug kann jetzt an den nächsten Spieler weitergegeben werden.",
+	}
+	if key, err := repository.CreateSessionRecap(c.Request().Context(), tokenID, sessionNumber); err == nil {
+		resp["recap_url"] = recap.Path(key)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("Failed to create recap for session %d of token %d: %v", sessionNumber, tokenID, err)
+		sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+	}
+
 	// Clear session data
 	session, err := middleware.Store.Get(c.Request(), "id-100-session")
 	if err != nil {
@@ -338,8 +444,5 @@ func EndSessionHandler(c *echo.Context) error {
 		}
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"status":  "success",
-		"message": "Session beendet. Das Werkzeug kann jetzt an den nächsten Spieler weitergegeben werden.",
-	})
+	return c.JSON(http.StatusOK, resp)
 }
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/citysearch"
+	"id-100/internal/sentryhelper"
+)
+
+// CitySearchHandler proxies city autocomplete queries so the search backend stays private
+func CitySearchHandler(c *echo.Context) error {
+	q, ok := citysearch.NormalizeQuery(c.QueryParam("q"))
+	if !ok {
+		return c.JSON(http.StatusOK, citysearch.Result{Hits: []citysearch.Hit{}})
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+
+	if citysearch.Default == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Ortssuche nicht verfügbar"})
+	}
+
+	result, err := citysearch.Default.Search(c.Request().Context(), q, limit)
+	if err != nil {
+		log.Printf("City search failed: %v", err)
+		sentryhelper.CaptureError(c, err, sentry.LevelWarning)
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Ortssuche nicht verfügbar"})
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, result)
+}
@@ -4,8 +4,10 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v5"
+	echoMiddleware "github.com/labstack/echo/v5/middleware"
 
 	"id-100/internal/handlers/admin"
+	"id-100/internal/handlers/api"
 	"id-100/internal/handlers/app"
 	"id-100/internal/middleware"
 )
@@ -20,6 +22,53 @@ func RegisterRoutes(e *echo.Echo, baseURL string) {
 	})
 
 	e.GET("/api/stats", StatsHandler)
+	e.GET("/api/cities", CitySearchHandler, middleware.RateLimitPerIP(5, 20))
+	e.GET("/api/search", app.SearchAPIHandler, middleware.RateLimitPerIP(2, 10))
+
+	// Public read-only API v1
+	v1 := e.Group("/api/v1", echoMiddleware.CORSWithConfig(echoMiddleware.CORSConfig{
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{http.MethodGet},
+		AllowHeaders:  []string{"If-None-Match"},
+		ExposeHeaders: []string{"ETag"},
+		MaxAge:        86400,
+	}), middleware.RateLimitPerIP(10, 40))
+	v1.File("/openapi.yaml", "web/api/openapi.yaml")
+	v1.GET("/deriven", api.DerivenHandler, middleware.ETag)
+	v1.GET("/deriven/:number/contributions", api.DeriveContributionsHandler, middleware.ETag)
+	v1.GET("/contributions", api.ContributionsHandler, middleware.ETag)
+	v1.GET("/cities", api.CitiesHandler, middleware.ETag)
+	v1.GET("/contributions/stream", api.ContributionStreamHandler)
+
+	// Short bag links from QR codes and manual code entry. Codes are short, so
+	// lookups share one limiter: ten in a row, then one every six seconds.
+	bagCodeLimit := middleware.RateLimitPerIP(1.0/6, 10)
+
+	// Participant API: upload session cookie from the bag code, or bag token as bearer credential
+	e.POST("/api/v1/participant/auth", api.ParticipantAuthHandler, bagCodeLimit)
+	participant := e.Group("/api/v1/participant", middleware.BagToken)
+	participant.GET("/session", api.ParticipantSessionHandler)
+	participant.POST("/session", api.ParticipantStartSessionHandler)
+	participant.POST("/session/name-display", api.ParticipantNameDisplayHandler)
+	participant.POST("/consent", api.ParticipantConsentHandler)
+	participant.POST("/session/end", api.ParticipantEndSessionHandler)
+	participant.GET("/uploads", api.ParticipantUploadsHandler)
+	participant.POST("/uploads", api.ParticipantUploadHandler)
+	participant.DELETE("/uploads/:id", api.ParticipantDeleteUploadHandler)
+
+	// Resumable uploads (tus 1.0), authorised by bag token or upload session cookie
+	tusGroup := e.Group(api.TusBasePath, api.TusResumable)
+	tusGroup.OPTIONS("", api.TusOptionsHandler)
+	tusGroup.OPTIONS("/", api.TusOptionsHandler)
+	tusGroup.POST("", api.TusCreateHandler, middleware.BagToken)
+	tusGroup.POST("/", api.TusCreateHandler, middleware.BagToken)
+	tusGroup.HEAD("/:id", api.TusHeadHandler, middleware.BagToken)
+	tusGroup.PATCH("/:id", api.TusPatchHandler, middleware.BagToken)
+	tusGroup.DELETE("/:id", api.TusDeleteHandler, middleware.BagToken)
+
+	// Presigned direct-to-storage uploads
+	e.POST("/api/v1/direct-uploads", api.DirectUploadCreateHandler, middleware.BagToken)
+	e.POST("/api/v1/direct-uploads/:id/finalize", api.DirectUploadFinalizeHandler, middleware.BagToken)
 
 	// Sitemap for SEO
 	e.GET("/sitemap.xml", SitemapHandler)
@@ -28,19 +77,52 @@ func RegisterRoutes(e *echo.Echo, baseURL string) {
 
 	e.GET("/", app.DerivenHandler)
 	e.GET("/id/:number", app.DeriveHandler)
+	e.GET("/suche", app.SearchHandler)
+	e.GET("/ausstellung", app.ExhibitionHandler)
+
+	// Short bag links from QR codes and manual code entry, limited by bagCodeLimit
+	e.GET("/b", app.BagCodeFormHandler)
+	e.POST("/b", app.BagCodeSubmitHandler, bagCodeLimit)
+	e.GET("/b/:code", app.BagCodeHandler, bagCodeLimit)
+
+	// Public journey of a bag, linked from the label
+	e.GET("/werkzeug/:slug", app.JourneyHandler)
+
+	// Session recaps; archives fetch every image from storage, so they are rate-limited
+	e.GET("/rueckblick/:key", app.RecapHandler)
+	e.GET("/rueckblick/:key/archiv.zip", app.RecapArchiveHandler, middleware.RateLimitPerIP(1.0/30, 3))
+	e.POST("/rueckblick/:key/zuordnen", app.PlayerLinkRecapHandler)
+
+	// Optional player accounts; sign-ins share one limiter against guessing codes
+	playerLoginLimit := middleware.RateLimitPerIP(1.0/10, 5)
+	e.GET("/spieler", app.PlayerHandler)
+	e.POST("/spieler/neu", app.PlayerCreateHandler, middleware.RateLimitPerIP(1.0/60, 3))
+	e.POST("/spieler/anmelden", app.PlayerCodeLoginHandler, playerLoginLimit)
+	e.POST("/spieler/email", app.PlayerEmailLinkHandler, playerLoginLimit)
+	e.GET("/spieler/anmelden/:token", app.PlayerEmailLoginHandler, playerLoginLimit)
+	e.POST("/spieler/name", app.PlayerRenameHandler)
+	e.POST("/spieler/anzeige", app.PlayerNameDisplayHandler)
+	e.POST("/spieler/abmelden", app.PlayerLogoutHandler)
 
 	// Upload routes - protected by token middleware with session support
 	e.GET("/upload", app.UploadGetHandler, middleware.TokenWithSession)
 	e.POST("/upload", app.UploadPostHandler, middleware.TokenWithSession)
 	e.POST("/upload/set-name", app.SetPlayerNameHandler, middleware.TokenWithSession)
+	e.POST("/upload/name-display", app.NameDisplayHandler, middleware.TokenWithSession)
+	e.POST("/upload/consent", app.ConsentHandler, middleware.TokenWithSession)
 	e.POST("/upload/end-session", app.EndSessionHandler, middleware.TokenWithSession)
+	e.POST("/upload/handover", app.RequestHandoverHandler, middleware.RateLimitPerIP(1.0/10, 5))
+	e.POST("/upload/handover/approve", app.ApproveHandoverHandler, middleware.TokenWithSession)
+	e.POST("/upload/handover/decline", app.DeclineHandoverHandler, middleware.TokenWithSession)
 	e.POST("/upload/contributions/:id/delete", app.UserDeleteContributionHandler, middleware.TokenWithSession)
 
 	e.GET("/leitfaden", app.RulesHandler)
 	e.GET("/impressum", app.ImpressumHandler)
 	e.GET("/datenschutz", app.DatenschutzHandler)
 	e.GET("/werkzeug-anfordern", app.RequestBagHandler)
-	e.POST("/werkzeug-anfordern", app.RequestBagPostHandler)
+	// At most five requests per IP in a row, then one per minute
+	e.POST("/werkzeug-anfordern", app.RequestBagPostHandler, middleware.RateLimitPerIP(1.0/60, 5))
+	e.GET("/werkzeug-anfordern/bestaetigen", app.RequestBagConfirmHandler)
 
 	// Admin routes for token management
 	adminGroup := e.Group("/admin", middleware.BasicAuth)
@@ -49,17 +131,67 @@ func RegisterRoutes(e *echo.Echo, baseURL string) {
 	adminGroup.POST("/tokens", func(c *echo.Context) error {
 		return admin.AdminCreateTokenHandler(c, baseURL)
 	})
+	adminGroup.POST("/tokens/batch", func(c *echo.Context) error {
+		return admin.AdminCreateTokenBatchHandler(c, baseURL)
+	})
+	adminGroup.GET("/tokens/export.csv", func(c *echo.Context) error {
+		return admin.AdminTokenExportHandler(c, baseURL, "csv")
+	})
+	adminGroup.GET("/tokens/export.json", func(c *echo.Context) error {
+		return admin.AdminTokenExportHandler(c, baseURL, "json")
+	})
+	adminGroup.GET("/tokens/labels.pdf", func(c *echo.Context) error {
+		return admin.AdminTokenLabelsHandler(c, baseURL)
+	})
 	adminGroup.POST("/tokens/:id/deactivate", admin.AdminTokenDeactivateHandler)
 	adminGroup.POST("/tokens/:id/reset", admin.AdminTokenResetHandler)
 	adminGroup.POST("/tokens/:id/assign", admin.AdminTokenAssignHandler)
 	adminGroup.POST("/tokens/:id/quota", admin.AdminUpdateQuotaHandler)
+	adminGroup.POST("/tokens/:id/expiry", admin.AdminUpdateExpiryHandler)
+	adminGroup.POST("/tokens/:id/release-device", admin.AdminTokenReleaseDeviceHandler)
+	adminGroup.POST("/tokens/:id/ruleset", admin.AdminTokenRulesetHandler)
+	adminGroup.GET("/tokens/:id", admin.AdminBagDetailHandler)
+	adminGroup.POST("/tokens/:id/sessions/:session/reopen", admin.AdminReopenSessionHandler)
+	adminGroup.POST("/tokens/:id/sessions/:session/delete-uploads", admin.AdminDeleteSessionUploadsHandler)
+	adminGroup.POST("/tokens/:id/rotate", func(c *echo.Context) error {
+		return admin.AdminTokenRotateHandler(c, baseURL)
+	})
 	adminGroup.GET("/tokens/:id/qr", func(c *echo.Context) error {
 		return admin.AdminDownloadQRHandler(c, baseURL)
 	})
 
 	// Werkzeug request management
-	adminGroup.POST("/werkzeug-anfragen/:id/complete", admin.AdminBagRequestCompleteHandler)
+	adminGroup.GET("/werkzeug-anfragen/export.csv", admin.AdminBagRequestExportHandler)
+	adminGroup.POST("/werkzeug-anfragen/:id/status", admin.AdminBagRequestStatusHandler)
+	adminGroup.POST("/werkzeug-anfragen/:id/token", func(c *echo.Context) error {
+		return admin.AdminBagRequestTokenHandler(c, baseURL)
+	})
+	adminGroup.POST("/werkzeug-anfragen/:id/notes", admin.AdminBagRequestNotesHandler)
+
+	// Consent records
+	adminGroup.GET("/consents/export.csv", func(c *echo.Context) error {
+		return admin.AdminConsentExportHandler(c, "csv")
+	})
+	adminGroup.GET("/consents/export.json", func(c *echo.Context) error {
+		return admin.AdminConsentExportHandler(c, "json")
+	})
+
+	// Data subject access and erasure requests
+	adminGroup.GET("/privacy/export.zip", admin.AdminPrivacyExportHandler)
+	adminGroup.POST("/privacy/erase", admin.AdminPrivacyEraseHandler)
 
 	// Contribution deletion
 	adminGroup.POST("/contributions/:id/delete", admin.AdminDeleteContributionHandler)
+
+	// Rulesets
+	adminGroup.POST("/rulesets", admin.AdminCreateRulesetHandler)
+	adminGroup.POST("/rulesets/:id", admin.AdminUpdateRulesetHandler)
+	adminGroup.POST("/rulesets/:id/delete", admin.AdminDeleteRulesetHandler)
+
+	// Outbound webhooks
+	adminGroup.POST("/webhooks", admin.AdminCreateWebhookHandler)
+	adminGroup.POST("/webhooks/:id/toggle", admin.AdminWebhookToggleHandler)
+	adminGroup.POST("/webhooks/:id/test", admin.AdminWebhookTestHandler)
+	adminGroup.POST("/webhooks/:id/delete", admin.AdminDeleteWebhookHandler)
+	adminGroup.POST("/webhook-deliveries/:id/retry", admin.AdminRetryWebhookDeliveryHandler)
 }
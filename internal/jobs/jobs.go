@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Every runs fn in a background goroutine at the given interval until ctx is done.
+// Errors are logged and reported to Sentry; the job keeps running.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := fn(ctx); err != nil {
+					log.Printf("Background job %s failed: %v", name, err)
+					sentry.CaptureException(err)
+				}
+			}
+		}
+	}()
+}
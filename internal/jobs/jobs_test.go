@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEveryRunsUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs int32
+	Every(ctx, "test", 5*time.Millisecond, func(context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return errors.New("keeps going")
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&runs) < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if got := atomic.LoadInt32(&runs); got < 3 {
+		t.Fatalf("expected job to keep running after errors, got %d runs", got)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	after := atomic.LoadInt32(&runs)
+	time.Sleep(30 * time.Millisecond)
+	if atomic.LoadInt32(&runs) != after {
+		t.Error("job kept running after context was cancelled")
+	}
+}
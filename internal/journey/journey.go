@@ -0,0 +1,56 @@
+package journey
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Public bag journeys live at /werkzeug/<slug>. The slug is generated by the
+// database (generate_journey_slug) and is separate from the bag code, which
+// grants upload access and must not be printed next to it.
+
+const (
+	// SlugLength is the number of characters of a journey slug
+	SlugLength = 10
+	// SlugAlphabet is lowercase Crockford base32
+	SlugAlphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+)
+
+// ValidSlug reports whether s is a well-formed journey slug
+func ValidSlug(s string) bool {
+	if len(s) != SlugLength {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune(SlugAlphabet, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Path is the public path of a bag journey
+func Path(slug string) string {
+	return "/werkzeug/" + slug
+}
+
+// URL is the absolute journey link printed on a bag
+func URL(baseURL, slug string) string {
+	return baseURL + Path(slug)
+}
+
+// Pseudonym shortens a player name for the public journey: the first name and
+// the initial of the last one, "Anna Maria Schmidt" becomes "Anna S."
+func Pseudonym(name string) string {
+	parts := strings.Fields(name)
+	switch len(parts) {
+	case 0:
+		return "Anonym"
+	case 1:
+		return parts[0]
+	}
+	last := parts[len(parts)-1]
+	r, _ := utf8.DecodeRuneInString(last)
+	return parts[0] + " " + string(unicode.ToUpper(r)) + "."
+}
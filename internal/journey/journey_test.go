@@ -0,0 +1,44 @@
+package journey
+
+import "testing"
+
+func TestPseudonym(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Anna Schmidt", "Anna S."},
+		{"  Anna   Maria  schmidt ", "Anna S."},
+		{"Jörg Özdemir", "Jörg Ö."},
+		{"Anna", "Anna"},
+		{"", "Anonym"},
+		{"   ", "Anonym"},
+	}
+	for _, tt := range tests {
+		if got := Pseudonym(tt.in); got != tt.want {
+			t.Errorf("Pseudonym(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidSlug(t *testing.T) {
+	tests := []struct {
+		in string
+		ok bool
+	}{
+		{"k7p4qx2m9a", true},
+		{"K7P4QX2M9A", false}, // slugs are lowercase
+		{"k7p4qx2m9u", false}, // u is not in the alphabet
+		{"k7p4qx", false},
+		{"", false},
+		{"../admin00", false},
+	}
+	for _, tt := range tests {
+		if got := ValidSlug(tt.in); got != tt.ok {
+			t.Errorf("ValidSlug(%q) = %v, want %v", tt.in, got, tt.ok)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	if got := URL("https://id-100.de", "k7p4qx2m9a"); got != "https://id-100.de/werkzeug/k7p4qx2m9a" {
+		t.Errorf("URL = %q", got)
+	}
+}
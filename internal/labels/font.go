@@ -0,0 +1,130 @@
+package labels
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// font is one of the standard 14 PDF fonts, which every viewer provides, with
+// the glyph widths needed to wrap text
+type font struct {
+	resource string
+	base     string
+	widths   [95]int // ASCII 32..126 in 1/1000 em
+}
+
+var helvetica = &font{
+	resource: "F1",
+	base:     "Helvetica",
+	widths: [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space../
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, // 0..9
+		278, 278, 584, 584, 584, 556, 1015, // :..@
+		667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, // A..M
+		722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, // N..Z
+		278, 278, 278, 469, 556, 333, // [..`
+		556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, // a..m
+		556, 556, 556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, // n..z
+		334, 260, 334, 584, // {..~
+	},
+}
+
+var helveticaBold = &font{
+	resource: "F2",
+	base:     "Helvetica-Bold",
+	widths: [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556,
+		333, 333, 584, 584, 584, 611, 975,
+		722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833,
+		722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611,
+		333, 278, 333, 584, 556, 333,
+		556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889,
+		611, 611, 611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500,
+		389, 280, 389, 584,
+	},
+}
+
+// latinBase maps accented Latin-1 letters to the ASCII letter of the same width
+var latinBase = strings.NewReplacer(
+	"À", "A", "Á", "A", "Â", "A", "Ã", "A", "Ä", "A", "Å", "A",
+	"Ç", "C", "È", "E", "É", "E", "Ê", "E", "Ë", "E",
+	"Ì", "I", "Í", "I", "Î", "I", "Ï", "I", "Ñ", "N",
+	"Ò", "O", "Ó", "O", "Ô", "O", "Õ", "O", "Ö", "O", "Ø", "O",
+	"Ù", "U", "Ú", "U", "Û", "U", "Ü", "U", "Ý", "Y",
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a",
+	"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y",
+	"ß", "F", // same width (611) in both fonts
+)
+
+// width returns the width of s in points at the given size
+func (f *font) width(s string, size float64) float64 {
+	total := 0
+	for _, r := range latinBase.Replace(s) {
+		switch {
+		case r >= 32 && r <= 126:
+			total += f.widths[r-32]
+		case r == '…' || r == '—':
+			total += 1000
+		default:
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// wrap breaks s into lines no wider than maxWidth; words longer than a line
+// are split. At most maxLines are returned, the last one shortened with "…".
+func (f *font) wrap(s string, size, maxWidth float64, maxLines int) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(s) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if f.width(candidate, size) <= maxWidth {
+			current = candidate
+			continue
+		}
+		if current != "" {
+			lines = append(lines, current)
+		}
+		// split words that do not fit on a line of their own
+		for f.width(word, size) > maxWidth && utf8.RuneCountInString(word) > 1 {
+			n := f.fit(word, size, maxWidth)
+			lines = append(lines, word[:n])
+			word = word[n:]
+		}
+		current = word
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+
+	if maxLines > 0 && len(lines) > maxLines {
+		last := lines[maxLines-1]
+		for last != "" && f.width(last+"…", size) > maxWidth {
+			_, n := utf8.DecodeLastRuneInString(last)
+			last = last[:len(last)-n]
+		}
+		lines = append(lines[:maxLines-1], strings.TrimRight(last, " ")+"…")
+	}
+	return lines
+}
+
+// fit returns the byte length of the longest prefix of word (at least one rune) within maxWidth
+func (f *font) fit(word string, size, maxWidth float64) int {
+	end := 0
+	for i, r := range word {
+		next := i + utf8.RuneLen(r)
+		if end > 0 && f.width(word[:next], size) > maxWidth {
+			break
+		}
+		end = next
+	}
+	return end
+}
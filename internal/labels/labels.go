@@ -0,0 +1,289 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Printable bag labels: a grid of labels per A4 page, each with the project
+// logo, the bag's QR code, its name and short instructions. Sizes are in
+// millimetres in the API and in points inside the PDF.
+
+const (
+	pageWidth  = 595.28 // A4 in points
+	pageHeight = 841.89
+	mm         = 72 / 25.4
+
+	// MaxLabels caps the labels in one sheet request
+	MaxLabels = 500
+
+	minLabelWidth  = 60.0 // mm
+	minLabelHeight = 40.0
+)
+
+// DefaultInstructions is printed on every label unless overridden
+const DefaultInstructions = "QR-Code scannen, Namen eintragen und Fotos zu den Aufgaben hochladen. Danach das Werkzeug weitergeben."
+
+var (
+	ErrNoLabels      = errors.New("no labels to print")
+	ErrTooManyLabels = fmt.Errorf("at most %d labels per sheet", MaxLabels)
+)
+
+// Layout describes the label grid on an A4 page
+type Layout struct {
+	Columns  int     `json:"columns"`
+	Rows     int     `json:"rows"`
+	MarginMM float64 `json:"margin_mm"` // page margin around the grid, cut marks are drawn here
+	BleedMM  float64 `json:"bleed_mm"`  // how far label backgrounds extend past the cut line
+	CutMarks bool    `json:"cut_marks"`
+}
+
+// DefaultLayout fits eight labels of roughly 93 x 67 mm on a page
+var DefaultLayout = Layout{Columns: 2, Rows: 4, MarginMM: 10, BleedMM: 2, CutMarks: true}
+
+// Validate checks that the grid fits on A4 with labels large enough for a scannable QR code
+func (l Layout) Validate() error {
+	if l.Columns < 1 || l.Rows < 1 {
+		return errors.New("columns and rows must be at least 1")
+	}
+	if l.MarginMM < 0 || l.MarginMM > 40 {
+		return errors.New("margin must be between 0 and 40 mm")
+	}
+	if l.BleedMM < 0 || l.BleedMM > 5 {
+		return errors.New("bleed must be between 0 and 5 mm")
+	}
+	w, h := l.labelSize()
+	if w < minLabelWidth*mm || h < minLabelHeight*mm {
+		return fmt.Errorf("labels would be %.0f x %.0f mm, at least %.0f x %.0f mm are needed",
+			w/mm, h/mm, minLabelWidth, minLabelHeight)
+	}
+	return nil
+}
+
+// PerPage is the number of labels on one page
+func (l Layout) PerPage() int {
+	return l.Columns * l.Rows
+}
+
+// gutter is the space between two labels; neighbouring bleeds meet in its middle
+func (l Layout) gutter() float64 {
+	return 2 * l.BleedMM * mm
+}
+
+// labelSize returns the trimmed label size in points
+func (l Layout) labelSize() (w, h float64) {
+	margin := l.MarginMM * mm
+	w = (pageWidth - 2*margin - float64(l.Columns-1)*l.gutter()) / float64(l.Columns)
+	h = (pageHeight - 2*margin - float64(l.Rows-1)*l.gutter()) / float64(l.Rows)
+	return w, h
+}
+
+// cell returns the bottom-left corner of the i-th label on its page
+func (l Layout) cell(i int) (x, y float64) {
+	w, h := l.labelSize()
+	i %= l.PerPage()
+	col, row := i%l.Columns, i/l.Columns
+	margin := l.MarginMM * mm
+	x = margin + float64(col)*(w+l.gutter())
+	y = pageHeight - margin - float64(row)*(h+l.gutter()) - h
+	return x, y
+}
+
+// Label is one bag to print
+type Label struct {
+	BagName string
+	Code    string // bag code printed for manual entry, e.g. "K7P-4QX"
+	URL     string // encoded in the QR code
+	Journey string // optional public journey link, printed at the bottom
+}
+
+// Options control the content of every label
+type Options struct {
+	Layout       Layout
+	Title        string      // printed next to the logo, e.g. "ID-100"
+	Instructions string      // short how-to below the bag name
+	Logo         image.Image // optional
+}
+
+// Render writes a PDF with all labels, filling pages row by row
+func Render(w io.Writer, labels []Label, opts Options) error {
+	if len(labels) == 0 {
+		return ErrNoLabels
+	}
+	if len(labels) > MaxLabels {
+		return ErrTooManyLabels
+	}
+	if err := opts.Layout.Validate(); err != nil {
+		return err
+	}
+	if opts.Instructions == "" {
+		opts.Instructions = DefaultInstructions
+	}
+
+	doc := &document{}
+	catalog := doc.reserve()
+	pages := doc.reserve()
+	regular := doc.add([]byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"))
+	bold := doc.add([]byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>"))
+	resources := fmt.Sprintf("/Font << /%s %d 0 R /%s %d 0 R >>", helvetica.resource, regular, helveticaBold.resource, bold)
+	if opts.Logo != nil {
+		resources += fmt.Sprintf(" /XObject << /Logo %d 0 R >>", doc.addImage(opts.Logo))
+	}
+
+	perPage := opts.Layout.PerPage()
+	var kids []string
+	for start := 0; start < len(labels); start += perPage {
+		end := min(start+perPage, len(labels))
+
+		var c canvas
+		for i, label := range labels[start:end] {
+			if err := drawLabel(&c, opts, i, label); err != nil {
+				return err
+			}
+		}
+		if opts.Layout.CutMarks {
+			drawCutMarks(&c, opts.Layout, end-start)
+		}
+
+		content := doc.add(stream("", c.Bytes()))
+		page := doc.add([]byte(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << %s >> /Contents %d 0 R >>",
+			pages, num(pageWidth), num(pageHeight), resources, content)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+
+	doc.set(catalog, []byte(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages)))
+	doc.set(pages, []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))))
+	return doc.writeTo(w)
+}
+
+// drawLabel draws the i-th label of a page: a dark header bar running into
+// the bleed, the QR code on the left and name plus instructions on the right
+func drawLabel(c *canvas, opts Options, i int, label Label) error {
+	l := opts.Layout
+	x, y := l.cell(i)
+	w, h := l.labelSize()
+	bleed := l.BleedMM * mm
+	pad := 4 * mm
+
+	barH := max(8*mm, min(14*mm, h*0.18))
+	c.fillGray(0.1)
+	c.rect(x-bleed, y+h-barH, w+2*bleed, barH+bleed)
+	c.fill()
+
+	textX := x + pad
+	if opts.Logo != nil {
+		logo := barH - 3*mm
+		c.image("Logo", x+pad, y+h-barH+1.5*mm, logo, logo)
+		textX += logo + 2*mm
+	}
+	if opts.Title != "" {
+		size := barH * 0.45
+		c.fillGray(1)
+		c.text(helveticaBold, size, textX, y+h-barH/2-size*0.35, opts.Title)
+	}
+
+	// QR code as black squares, one rectangle per horizontal run
+	qr, err := qrcode.New(label.URL, qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("qr code for %q: %w", label.BagName, err)
+	}
+	qr.DisableBorder = true
+	bitmap := qr.Bitmap()
+
+	bodyTop := y + h - barH - pad
+	size := min(bodyTop-(y+pad), w*0.45)
+	module := size / float64(len(bitmap))
+	qrY := bodyTop - size
+	c.fillGray(0)
+	for row, line := range bitmap {
+		for col := 0; col < len(line); {
+			if !line[col] {
+				col++
+				continue
+			}
+			run := col
+			for run < len(line) && line[run] {
+				run++
+			}
+			// overlap by a hair so viewers do not show seams between modules
+			c.rect(x+pad+float64(col)*module, qrY+size-float64(row+1)*module, float64(run-col)*module+0.05, module+0.05)
+			col = run
+		}
+	}
+	c.fill()
+
+	tx := x + pad + size + pad
+	tw := x + w - pad - tx
+	ty := bodyTop
+
+	const nameSize, codeSize, infoSize = 12.0, 10.0, 7.5
+	for _, line := range helveticaBold.wrap(label.BagName, nameSize, tw, 3) {
+		ty -= nameSize * 1.15
+		c.text(helveticaBold, nameSize, tx, ty, line)
+	}
+	if label.Code != "" {
+		ty -= codeSize * 1.5
+		c.text(helvetica, codeSize, tx, ty, "Code: ")
+		c.text(helveticaBold, codeSize, tx+helvetica.width("Code: ", codeSize), ty, label.Code)
+	}
+	// the journey link sits at the bottom, the instructions fill the space above
+	bottom := y + pad
+	if label.Journey != "" {
+		lines := helvetica.wrap(label.Journey, infoSize, tw, 2)
+		for k := len(lines) - 1; k >= 0; k-- {
+			c.text(helvetica, infoSize, tx, bottom, lines[k])
+			bottom += infoSize * 1.3
+		}
+		bottom += infoSize * 0.5
+	}
+	ty -= infoSize * 0.8
+	for _, line := range helvetica.wrap(opts.Instructions, infoSize, tw, 0) {
+		if ty-infoSize*1.3 < bottom {
+			break
+		}
+		ty -= infoSize * 1.3
+		c.text(helvetica, infoSize, tx, ty, line)
+	}
+	return nil
+}
+
+// drawCutMarks draws short lines in the page margin in line with every cut
+// edge of the used rows and columns. They stay clear of the bleed.
+func drawCutMarks(c *canvas, l Layout, used int) {
+	margin, bleed := l.MarginMM*mm, l.BleedMM*mm
+	gap := bleed + 1*mm
+	length := min(5*mm, margin-gap)
+	if length < 2*mm {
+		return
+	}
+
+	w, h := l.labelSize()
+	cols := min(used, l.Columns)
+	rows := (used + l.Columns - 1) / l.Columns
+	top := pageHeight - margin
+	bottom := top - float64(rows)*h - float64(rows-1)*l.gutter()
+	left := margin
+	right := left + float64(cols)*w + float64(cols-1)*l.gutter()
+
+	c.strokeGray(0)
+	c.lineWidth(0.25)
+	for col := 0; col < cols; col++ {
+		x0 := margin + float64(col)*(w+l.gutter())
+		for _, x := range []float64{x0, x0 + w} {
+			c.line(x, top+gap, x, top+gap+length)
+			c.line(x, bottom-gap, x, bottom-gap-length)
+		}
+	}
+	for row := 0; row < rows; row++ {
+		y0 := top - float64(row)*(h+l.gutter())
+		for _, y := range []float64{y0, y0 - h} {
+			c.line(left-gap, y, left-gap-length, y)
+			c.line(right+gap, y, right+gap+length, y)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package labels
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLayoutValidate(t *testing.T) {
+	if err := DefaultLayout.Validate(); err != nil {
+		t.Fatalf("default layout: %v", err)
+	}
+	invalid := []Layout{
+		{Columns: 0, Rows: 4, MarginMM: 10},
+		{Columns: 2, Rows: 4, MarginMM: -1},
+		{Columns: 2, Rows: 4, MarginMM: 10, BleedMM: 6},
+		{Columns: 4, Rows: 4, MarginMM: 10}, // 47 mm wide
+		{Columns: 2, Rows: 8, MarginMM: 10}, // 34 mm high
+	}
+	for _, l := range invalid {
+		if err := l.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", l)
+		}
+	}
+}
+
+func TestLayoutCells(t *testing.T) {
+	l := Layout{Columns: 2, Rows: 2, MarginMM: 10, BleedMM: 2}
+	w, h := l.labelSize()
+	// two labels plus one gutter fill the width inside the margins
+	if got := 2*w + 4*mm + 20*mm; abs(got-pageWidth) > 0.01 {
+		t.Errorf("row width = %.2f, want %.2f", got, pageWidth)
+	}
+
+	x0, y0 := l.cell(0)
+	x1, y1 := l.cell(1)
+	x2, y2 := l.cell(2)
+	if abs(x0-10*mm) > 0.01 || abs(y0+h-(pageHeight-10*mm)) > 0.01 {
+		t.Errorf("cell 0 at %.2f,%.2f", x0, y0)
+	}
+	if abs(x1-(x0+w+4*mm)) > 0.01 || y1 != y0 {
+		t.Errorf("cell 1 at %.2f,%.2f", x1, y1)
+	}
+	if x2 != x0 || abs(y2-(y0-h-4*mm)) > 0.01 {
+		t.Errorf("cell 2 at %.2f,%.2f", x2, y2)
+	}
+	// the fifth label starts a new page in the first cell
+	if x4, y4 := l.cell(4); x4 != x0 || y4 != y0 {
+		t.Errorf("cell 4 at %.2f,%.2f, want first cell", x4, y4)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := helvetica.wrap("QR-Code scannen und Fotos hochladen", 10, 80, 0)
+	if len(lines) < 2 {
+		t.Fatalf("wrap = %q, want several lines", lines)
+	}
+	for _, line := range lines {
+		if helvetica.width(line, 10) > 80 {
+			t.Errorf("line %q is wider than 80pt", line)
+		}
+	}
+	if got := strings.Join(lines, " "); got != "QR-Code scannen und Fotos hochladen" {
+		t.Errorf("joined lines = %q", got)
+	}
+
+	lines = helveticaBold.wrap("Donaudampfschifffahrtsgesellschaftskapitän", 12, 60, 2)
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], "…") {
+		t.Errorf("long word = %q, want two lines ending in an ellipsis", lines)
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	tests := map[string]string{
+		"Werkzeug (1)": `Werkzeug \(1\)`,
+		`a\b`:          `a\\b`,
+		"Tüte…":        `T\374te\205`,
+		"日本":           "??",
+	}
+	for in, want := range tests {
+		if got := escapeText(in); got != want {
+			t.Errorf("escapeText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	logo := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	logo.Set(1, 1, color.NRGBA{R: 255, A: 255})
+
+	var labels []Label
+	for i := 1; i <= 9; i++ {
+		labels = append(labels, Label{
+			BagName: fmt.Sprintf("Tüte %d (ß)", i),
+			Code:    fmt.Sprintf("K7P-4Q%d", i),
+			URL:     fmt.Sprintf("https://example.org/upload?token=t%d", i),
+			Journey: fmt.Sprintf("example.org/werkzeug/slug%d", i),
+		})
+	}
+
+	var buf bytes.Buffer
+	err := Render(&buf, labels, Options{Layout: DefaultLayout, Title: "ID-100", Logo: logo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.Bytes()
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	checkXref(t, pdf)
+
+	if !bytes.Contains(pdf, []byte("/Type /Pages /Kids [")) || !bytes.Contains(pdf, []byte("/Count 2 >>")) {
+		t.Error("9 labels at 8 per page should give 2 pages")
+	}
+	if !bytes.Contains(pdf, []byte("/SMask")) {
+		t.Error("logo image missing")
+	}
+
+	content := contentStreams(t, pdf)
+	for _, want := range []string{`(T\374te 1 \(\337\)) Tj`, `(T\374te 9 \(\337\)) Tj`, "(ID-100) Tj", "(K7P-4Q9) Tj", "(example.org/werkzeug/slug9) Tj", "/Logo Do", " re\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content streams do not contain %q", want)
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if err := Render(io.Discard, nil, Options{Layout: DefaultLayout}); err != ErrNoLabels {
+		t.Errorf("no labels: err = %v", err)
+	}
+	if err := Render(io.Discard, make([]Label, MaxLabels+1), Options{Layout: DefaultLayout}); err != ErrTooManyLabels {
+		t.Errorf("too many labels: err = %v", err)
+	}
+	if err := Render(io.Discard, []Label{{BagName: "A", URL: "x"}}, Options{}); err == nil {
+		t.Error("zero layout: expected error")
+	}
+}
+
+// checkXref verifies that every xref entry points at its object
+func checkXref(t *testing.T, pdf []byte) {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("startxref missing")
+	}
+	start, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(pdf[start:], []byte("xref\n0 ")) {
+		t.Fatalf("startxref %d does not point at the xref table", start)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[start:], -1)
+	if len(entries) == 0 {
+		t.Fatal("no xref entries")
+	}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, pdf[off:min(off+12, len(pdf))])
+		}
+	}
+}
+
+// contentStreams inflates all page content streams (those without a dictionary of their own)
+func contentStreams(t *testing.T, pdf []byte) string {
+	t.Helper()
+	var out strings.Builder
+	re := regexp.MustCompile(`<< /Filter /FlateDecode /Length (\d+) >>\nstream\n`)
+	for _, loc := range re.FindAllSubmatchIndex(pdf, -1) {
+		n, _ := strconv.Atoi(string(pdf[loc[2]:loc[3]]))
+		zr, err := zlib.NewReader(bytes.NewReader(pdf[loc[1] : loc[1]+n]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Write(data)
+	}
+	return out.String()
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
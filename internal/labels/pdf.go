@@ -0,0 +1,170 @@
+package labels
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A minimal PDF 1.4 writer: just enough for vector shapes, the two standard
+// Helvetica fonts and one RGBA image, so label sheets need no extra dependency.
+
+// document collects numbered objects and writes them with a cross-reference table
+type document struct {
+	objects [][]byte
+}
+
+// add appends an object and returns its number
+func (d *document) add(body []byte) int {
+	d.objects = append(d.objects, body)
+	return len(d.objects)
+}
+
+// reserve allocates an object number whose body is set later
+func (d *document) reserve() int {
+	return d.add(nil)
+}
+
+func (d *document) set(n int, body []byte) {
+	d.objects[n-1] = body
+}
+
+// writeTo writes the document with object 1 as the catalog
+func (d *document) writeTo(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(d.objects))
+	for i, body := range d.objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(body)
+		buf.WriteString("\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(d.objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.objects)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// stream builds a Flate-compressed stream object
+func stream(dict string, data []byte) []byte {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(data)
+	zw.Close()
+
+	if dict != "" {
+		dict += " "
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<< %s/Filter /FlateDecode /Length %d >>\nstream\n", dict, z.Len())
+	b.Write(z.Bytes())
+	b.WriteString("\nendstream")
+	return b.Bytes()
+}
+
+// addImage adds img as an RGB image XObject with its alpha channel as soft mask
+func (d *document) addImage(img image.Image) int {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	rgb := make([]byte, 0, w*h*3)
+	alpha := make([]byte, 0, w*h)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+		}
+	}
+
+	mask := d.add(stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8", w, h), alpha))
+	return d.add(stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /SMask %d 0 R", w, h, mask), rgb))
+}
+
+// canvas accumulates the content stream of one page. Coordinates are in
+// points with the origin at the bottom left, as in PDF.
+type canvas struct {
+	bytes.Buffer
+}
+
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "" || s == "-" {
+		return "0"
+	}
+	return s
+}
+
+func (c *canvas) op(format string, args ...float64) {
+	parts := make([]interface{}, len(args))
+	for i, a := range args {
+		parts[i] = num(a)
+	}
+	fmt.Fprintf(c, format+"\n", parts...)
+}
+
+func (c *canvas) fillGray(g float64)   { c.op("%s g", g) }
+func (c *canvas) strokeGray(g float64) { c.op("%s G", g) }
+func (c *canvas) lineWidth(w float64)  { c.op("%s w", w) }
+
+func (c *canvas) rect(x, y, w, h float64) { c.op("%s %s %s %s re", x, y, w, h) }
+func (c *canvas) fill()                   { c.WriteString("f\n") }
+
+func (c *canvas) line(x1, y1, x2, y2 float64) {
+	c.op("%s %s m %s %s l S", x1, y1, x2, y2)
+}
+
+// image draws the named image XObject into the given box
+func (c *canvas) image(name string, x, y, w, h float64) {
+	c.op("q %s 0 0 %s %s %s cm", w, h, x, y)
+	fmt.Fprintf(c, "/%s Do Q\n", name)
+}
+
+// text draws s with its baseline starting at x, y
+func (c *canvas) text(f *font, size, x, y float64, s string) {
+	fmt.Fprintf(c, "BT /%s %s Tf %s %s Td (%s) Tj ET\n", f.resource, num(size), num(x), num(y), escapeText(s))
+}
+
+// winAnsiExtra holds the WinAnsiEncoding codes outside Latin-1 that German text uses
+var winAnsiExtra = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '–': 0x96, '—': 0x97,
+}
+
+// escapeText encodes s in WinAnsiEncoding as a PDF string literal body.
+// Latin-1 covers German umlauts; unsupported characters become '?'.
+func escapeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		code, ok := winAnsiExtra[r]
+		switch {
+		case ok:
+		case r < 0x20 || r > 0xff || (r >= 0x7f && r < 0xa0):
+			code = '?'
+		default:
+			code = byte(r)
+		}
+		switch {
+		case code == '(' || code == ')' || code == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(code)
+		case code > 0x7e:
+			fmt.Fprintf(&b, "\\%03o", code)
+		default:
+			b.WriteByte(code)
+		}
+	}
+	return b.String()
+}
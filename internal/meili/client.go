@@ -0,0 +1,160 @@
+package meili
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client is a minimal Meilisearch HTTP client covering the endpoints used by the app
+type Client struct {
+	BaseURL      string
+	APIKey       string
+	HTTPClient   *http.Client
+	PollInterval time.Duration
+}
+
+// Task is the summary Meilisearch returns for asynchronous operations
+type Task struct {
+	TaskUID int64 `json:"taskUid"`
+}
+
+// TaskStatus is the state of an enqueued Meilisearch task
+type TaskStatus struct {
+	UID    int64      `json:"uid"`
+	Status string     `json:"status"`
+	Type   string     `json:"type"`
+	Error  *TaskError `json:"error"`
+}
+
+// TaskError describes why a task failed
+type TaskError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewClient creates a client for the Meilisearch instance at baseURL
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL:      strings.TrimRight(baseURL, "/"),
+		APIKey:       apiKey,
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		PollInterval: 500 * time.Millisecond,
+	}
+}
+
+// CreateIndex enqueues creation of an index and returns the task uid
+func (c *Client) CreateIndex(ctx context.Context, uid, primaryKey string) (int64, error) {
+	var t Task
+	err := c.do(ctx, http.MethodPost, "/indexes", map[string]string{"uid": uid, "primaryKey": primaryKey}, &t)
+	return t.TaskUID, err
+}
+
+// UpdateSettings enqueues a partial settings update for an index
+func (c *Client) UpdateSettings(ctx context.Context, index string, settings map[string]interface{}) (int64, error) {
+	var t Task
+	err := c.do(ctx, http.MethodPatch, "/indexes/"+index+"/settings", settings, &t)
+	return t.TaskUID, err
+}
+
+// AddDocuments enqueues adding (or replacing) documents in an index
+func (c *Client) AddDocuments(ctx context.Context, index string, docs interface{}) (int64, error) {
+	var t Task
+	err := c.do(ctx, http.MethodPost, "/indexes/"+index+"/documents", docs, &t)
+	return t.TaskUID, err
+}
+
+// GetTask fetches the current status of a task
+func (c *Client) GetTask(ctx context.Context, uid int64) (*TaskStatus, error) {
+	var ts TaskStatus
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/tasks/%d", uid), nil, &ts); err != nil {
+		return nil, err
+	}
+	return &ts, nil
+}
+
+// WaitForTask polls a task until it succeeded, failed or was canceled
+func (c *Client) WaitForTask(ctx context.Context, uid int64) (*TaskStatus, error) {
+	for {
+		ts, err := c.GetTask(ctx, uid)
+		if err != nil {
+			return nil, err
+		}
+		switch ts.Status {
+		case "succeeded", "failed", "canceled":
+			return ts, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.PollInterval):
+		}
+	}
+}
+
+// Health reports whether the Meilisearch instance is reachable
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/health", nil, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("meilisearch %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("meilisearch %s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// SearchRequest is the body of a Meilisearch search query
+type SearchRequest struct {
+	Q                    string   `json:"q"`
+	Limit                int      `json:"limit,omitempty"`
+	AttributesToRetrieve []string `json:"attributesToRetrieve,omitempty"`
+}
+
+// Search runs a query against an index and decodes the hits into out (a pointer to a slice)
+func (c *Client) Search(ctx context.Context, index string, req SearchRequest, out interface{}) error {
+	resp := struct {
+		Hits json.RawMessage `json:"hits"`
+	}{}
+	if err := c.do(ctx, http.MethodPost, "/indexes/"+index+"/search", req, &resp); err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.Hits, out)
+}
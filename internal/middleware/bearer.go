@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/database"
+	"id-100/internal/rules"
+	"id-100/internal/utils"
+)
+
+// bearerToken extracts the credential from an "Authorization: Bearer <token>" header
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// maxDeviceIDLength bounds the X-Device-ID header of API clients
+const maxDeviceIDLength = 128
+
+// deviceIDHeader validates the X-Device-ID header with which API clients bind the
+// sessions they start. An empty header is valid here and yields no device; it is
+// rejected by bound sessions and by starting a session.
+func deviceIDHeader(header string) (string, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", true
+	}
+	if len(header) < 16 || len(header) > maxDeviceIDLength {
+		return "", false
+	}
+	for _, r := range header {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return "", false
+		}
+	}
+	return header, true
+}
+
+// deviceConflict reports whether a request from deviceID may not use a session
+// bound to sessionDevice. Requests without a device never match a bound session.
+func deviceConflict(sessionDevice, deviceID string) bool {
+	return sessionDevice != "" && sessionDevice != deviceID
+}
+
+// BearerToken authenticates participant API requests with the bag token as bearer credential.
+// It sets the same context values as TokenWithSession plus max_uploads, total_uploads and
+// session_started_at, and answers with JSON errors instead of HTML pages. Like
+// TokenWithSession it ends sessions past the ruleset's time limits, but it does not
+// enforce the player name, quota, cooldown or allowed derives; the handlers do.
+// A session bound to a device only accepts requests with its X-Device-ID.
+func BearerToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		token, ok := bearerToken(c.Request().Header.Get("Authorization"))
+		if !ok {
+			c.Response().Header().Set("WWW-Authenticate", `Bearer realm="id-100"`)
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Token fehlt"})
+		}
+		deviceID, ok := deviceIDHeader(c.Request().Header.Get("X-Device-ID"))
+		if !ok {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Ungültige Geräte-ID"})
+		}
+		return authenticateToken(c, utils.HashToken(token), deviceID, next)
+	}
+}
+
+// BagToken works like BearerToken but falls back to the token stored in the
+// id-100-session cookie, so browser clients of the upload page can use JSON endpoints.
+// Browser sessions are subject to the device binding of TokenWithSession.
+func BagToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		var tokenHash, deviceID string
+		if token, ok := bearerToken(c.Request().Header.Get("Authorization")); ok {
+			tokenHash = utils.HashToken(token)
+			if deviceID, ok = deviceIDHeader(c.Request().Header.Get("X-Device-ID")); !ok {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Ungültige Geräte-ID"})
+			}
+		} else if Store != nil {
+			if session, err := Store.Get(c.Request(), "id-100-session"); err == nil {
+				tokenHash = SessionTokenHash(session)
+				deviceID = DeviceID(session)
+			}
+		}
+		if tokenHash == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Token fehlt"})
+		}
+		return authenticateToken(c, tokenHash, deviceID, next)
+	}
+}
+
+// authenticateToken validates a bag token by its hash and stores its state in the
+// context. A session bound to another device, or to any device when deviceID is
+// empty, is rejected; the deviceID is stored as device_id so that new sessions
+// are bound to it.
+func authenticateToken(c *echo.Context, tokenHash, deviceID string, next echo.HandlerFunc) error {
+	var tokenID int
+	var isActive bool
+	var maxUploads, totalUploads, totalSessions int
+	var currentPlayer, currentPlayerCity, bagName string
+	var sessionStartedAt time.Time
+	var expiresAt *time.Time
+	var sessionDevice string
+
+	err := database.DB.QueryRow(context.Background(),
+		`SELECT id, is_active, max_uploads, total_uploads, total_sessions,
+		 COALESCE(current_player, ''), COALESCE(current_player_city, ''), COALESCE(bag_name, ''), COALESCE(session_started_at, created_at), expires_at,
+		 COALESCE(session_device, '')
+		 FROM upload_tokens WHERE token_hash = $1`,
+		tokenHash).Scan(&tokenID, &isActive, &maxUploads, &totalUploads, &totalSessions, &currentPlayer, &currentPlayerCity, &bagName, &sessionStartedAt, &expiresAt,
+		&sessionDevice)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.Response().Header().Set("WWW-Authenticate", `Bearer realm="id-100", error="invalid_token"`)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Ungültiger Token"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Datenbankfehler"})
+	}
+	if tokenExpired(expiresAt) {
+		c.Response().Header().Set("WWW-Authenticate", `Bearer realm="id-100", error="invalid_token", error_description="expired"`)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Token abgelaufen"})
+	}
+
+	// The bag's ruleset ends sessions past their time limit or idle timeout
+	state, err := loadRuleState(c.Request().Context(), tokenID, totalSessions)
+	if err != nil {
+		log.Printf("Failed to load ruleset for token_id=%d: %v", tokenID, err)
+	}
+	if currentPlayer != "" {
+		if reason, newStart := endExpiredSession(c.Request().Context(), state, tokenID, totalSessions, sessionStartedAt, bagName, currentPlayer); reason != rules.EndNone {
+			totalSessions++
+			totalUploads = 0
+			currentPlayer, currentPlayerCity, sessionDevice = "", "", ""
+			sessionStartedAt = newStart
+			state.lastUpload = nil
+		}
+	}
+
+	if deviceConflict(sessionDevice, deviceID) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Werkzeug wird auf einem anderen Gerät verwendet"})
+	}
+
+	if !isActive {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Token deaktiviert"})
+	}
+
+	c.Set("token_id", tokenID)
+	c.Set("current_player", currentPlayer)
+	c.Set("bag_name", bagName)
+	c.Set("session_number", totalSessions)
+	c.Set("uploads_remaining", state.ruleset.Remaining(maxUploads, totalUploads, state.lifetimeUploads))
+	c.Set("current_player_city", currentPlayerCity)
+	c.Set("max_uploads", state.ruleset.SessionLimit(maxUploads))
+	c.Set("total_uploads", totalUploads)
+	c.Set("session_started_at", sessionStartedAt)
+	c.Set("device_id", deviceID)
+	setRuleContext(c, state, sessionStartedAt)
+
+	return next(c)
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+)
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		wantOK bool
+	}{
+		{"Bearer abc123", "abc123", true},
+		{"bearer  abc123 ", "abc123", true},
+		{"Basic dXNlcjpwYXNz", "", false},
+		{"Bearer", "", false},
+		{"Bearer   ", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := bearerToken(tt.header)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("bearerToken(%q) = %q, %v; want %q, %v", tt.header, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestBearerTokenMissingHeader(t *testing.T) {
+	e := echo.New()
+	e.GET("/", func(c *echo.Context) error { return c.NoContent(http.StatusOK) }, BearerToken)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec.Header().Get("WWW-Authenticate") == "" {
+		t.Error("expected WWW-Authenticate header")
+	}
+}
+
+func TestDeviceIDHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		wantOK bool
+	}{
+		{"", "", true},
+		{"  ", "", true},
+		{"0123456789abcdef0123456789abcdef", "0123456789abcdef0123456789abcdef", true},
+		{" app-Install_42-abcdefgh ", "app-Install_42-abcdefgh", true},
+		{"short", "", false},
+		{"0123456789abcdef 0123456789abcdef", "", false},
+		{strings.Repeat("a", maxDeviceIDLength+1), "", false},
+	}
+	for _, tt := range tests {
+		got, ok := deviceIDHeader(tt.header)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("deviceIDHeader(%q) = %q, %v; want %q, %v", tt.header, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestBearerTokenInvalidDeviceID(t *testing.T) {
+	e := echo.New()
+	e.GET("/", func(c *echo.Context) error { return c.NoContent(http.StatusOK) }, BearerToken)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer abc123")
+	req.Header.Set("X-Device-ID", "not a device")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestDeviceConflict(t *testing.T) {
+	const bound = "0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		sessionDevice, deviceID string
+		want                    bool
+	}{
+		{"", "", false},
+		{"", bound, false},
+		{bound, bound, false},
+		{bound, "", true},
+		{bound, "fedcba9876543210fedcba9876543210", true},
+	}
+	for _, tt := range tests {
+		if got := deviceConflict(tt.sessionDevice, tt.deviceID); got != tt.want {
+			t.Errorf("deviceConflict(%q, %q) = %v, want %v", tt.sessionDevice, tt.deviceID, got, tt.want)
+		}
+	}
+}
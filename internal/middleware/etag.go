@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
+
+// etagWriter buffers the response so its hash can be sent as ETag before the body
+type etagWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
+
+// ETag adds a weak ETag to successful GET responses and answers matching
+// If-None-Match requests with 304 Not Modified.
+func ETag(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		req := c.Request()
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			return next(c)
+		}
+
+		orig := c.Response()
+		w := &etagWriter{ResponseWriter: orig}
+		c.SetResponse(w)
+		err := next(c)
+		c.SetResponse(orig)
+		if err != nil {
+			return err
+		}
+
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		if w.status != http.StatusOK {
+			orig.WriteHeader(w.status)
+			_, werr := orig.Write(w.buf.Bytes())
+			return werr
+		}
+
+		sum := sha256.Sum256(w.buf.Bytes())
+		etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		orig.Header().Set("ETag", etag)
+
+		if etagMatches(req.Header.Get("If-None-Match"), etag) {
+			orig.Header().Del(echo.HeaderContentType)
+			orig.Header().Del(echo.HeaderContentLength)
+			orig.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+
+		orig.WriteHeader(http.StatusOK)
+		_, werr := orig.Write(w.buf.Bytes())
+		return werr
+	}
+}
+
+// etagMatches implements the weak comparison of If-None-Match
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+)
+
+func TestETag(t *testing.T) {
+	e := echo.New()
+	e.GET("/data", func(c *echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]int{"n": 1})
+	}, ETag)
+	e.GET("/missing", func(c *echo.Context) error {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "nicht gefunden"})
+	}, ETag)
+
+	req := httptest.NewRequest(http.MethodGet, "/data", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" || rec.Body.Len() == 0 {
+		t.Fatalf("first request: code=%d etag=%q body=%q", rec.Code, etag, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/data", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("conditional request: code=%d body=%q; want 304 with empty body", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/data", nil)
+	req.Header.Set("If-None-Match", `W/"stale"`)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("stale ETag: code=%d, want 200", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/missing", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound || rec.Header().Get("ETag") != "" {
+		t.Errorf("error response: code=%d etag=%q; want 404 without ETag", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	etag := `W/"abc"`
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{`W/"abc"`, true},
+		{`"abc"`, true},
+		{`"x", W/"abc"`, true},
+		{"*", true},
+		{`"abd"`, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, etag); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
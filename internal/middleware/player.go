@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v5"
+)
+
+// Player accounts live in their own cookie, separate from the bag session in
+// "id-100-session": ending or handing over a bag must not sign the player out.
+
+const (
+	// PlayerSessionName is the cookie of a signed-in player
+	PlayerSessionName = "id-100-player"
+	// SessionKeyPlayerID holds the ID of the signed-in player
+	SessionKeyPlayerID = "player_id"
+
+	playerSessionMaxAge = 86400 * 365 // one year
+)
+
+// PlayerID returns the ID of the signed-in player, if any
+func PlayerID(c *echo.Context) (int, bool) {
+	session, err := Store.Get(c.Request(), PlayerSessionName)
+	if err != nil {
+		return 0, false
+	}
+	id, ok := GetSessionNumber(session.Values[SessionKeyPlayerID])
+	return id, ok && id > 0
+}
+
+// SignInPlayer stores the player ID in the player cookie
+func SignInPlayer(c *echo.Context, playerID int) error {
+	session, _ := Store.Get(c.Request(), PlayerSessionName)
+	session.Options.MaxAge = playerSessionMaxAge
+	session.Values[SessionKeyPlayerID] = playerID
+	return session.Save(c.Request(), c.Response())
+}
+
+// SignOutPlayer deletes the player cookie
+func SignOutPlayer(c *echo.Context) error {
+	session, _ := Store.Get(c.Request(), PlayerSessionName)
+	session.Options.MaxAge = -1
+	delete(session.Values, SessionKeyPlayerID)
+	return session.Save(c.Request(), c.Response())
+}
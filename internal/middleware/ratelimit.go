@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	echoMiddleware "github.com/labstack/echo/v5/middleware"
+)
+
+// RateLimitPerIP limits requests per client IP to ratePerSecond with the given burst.
+// Denied requests receive a JSON 429 with a Retry-After hint.
+func RateLimitPerIP(ratePerSecond float64, burst int) echo.MiddlewareFunc {
+	store := echoMiddleware.NewRateLimiterMemoryStoreWithConfig(echoMiddleware.RateLimiterMemoryStoreConfig{
+		Rate:      ratePerSecond,
+		Burst:     burst,
+		ExpiresIn: 5 * time.Minute,
+	})
+
+	retryAfter := 1
+	if ratePerSecond > 0 && ratePerSecond < 1 {
+		retryAfter = int(1/ratePerSecond + 0.5)
+	}
+
+	return echoMiddleware.RateLimiterWithConfig(echoMiddleware.RateLimiterConfig{
+		Store: store,
+		IdentifierExtractor: func(c *echo.Context) (string, error) {
+			return c.RealIP(), nil
+		},
+		DenyHandler: func(c *echo.Context, identifier string, err error) error {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+				"error":             "Zu viele Anfragen, bitte warte kurz",
+				"remaining_seconds": retryAfter,
+			})
+		},
+	})
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/labstack/echo/v5"
+
+	"id-100/internal/repository"
+	"id-100/internal/rules"
+	"id-100/internal/webhooks"
+)
+
+// Ruleset enforcement shared by TokenWithSession and the bearer middlewares
+
+// ruleState is the ruleset of a bag plus the upload history its checks need
+type ruleState struct {
+	ruleset         rules.Ruleset
+	lifetimeUploads int
+	lastUpload      *time.Time
+}
+
+// loadRuleState fetches the ruleset of a token and its upload history. On
+// errors the defaults are returned together with the error, so callers can
+// log and carry on.
+func loadRuleState(ctx context.Context, tokenID, sessionNumber int) (ruleState, error) {
+	state := ruleState{ruleset: rules.Default}
+	rs, err := repository.GetTokenRuleset(ctx, tokenID)
+	if err != nil {
+		return state, err
+	}
+	state.ruleset = rs
+	if rs.LifetimeUploads > 0 {
+		if state.lifetimeUploads, err = repository.CountTokenUploads(ctx, tokenID); err != nil {
+			return state, err
+		}
+	}
+	state.lastUpload, err = repository.GetLastUploadTime(ctx, tokenID, sessionNumber)
+	return state, err
+}
+
+// endExpiredSession ends a running session whose time limit or idle timeout
+// has passed and returns the reason and the start of the next session. The
+// reason is rules.EndNone if the session may continue.
+func endExpiredSession(ctx context.Context, state ruleState, tokenID, sessionNumber int, startedAt time.Time, bagName, player string) (rules.EndReason, time.Time) {
+	reason := state.ruleset.SessionEnd(startedAt, state.lastUpload, time.Now())
+	if reason == rules.EndNone {
+		return reason, startedAt
+	}
+	newStart, ok, err := repository.EndSession(ctx, tokenID, sessionNumber)
+	if err != nil {
+		log.Printf("Failed to end session %d of token_id=%d (%s): %v", sessionNumber, tokenID, reason, err)
+		return rules.EndNone, startedAt
+	}
+	if !ok {
+		// Another request ended this session in the meantime
+		return rules.EndNone, startedAt
+	}
+	webhooks.Emit(ctx, webhooks.EventSessionEnded, webhooks.Session{
+		TokenID:       tokenID,
+		BagName:       bagName,
+		SessionNumber: sessionNumber,
+		PlayerName:    webhooks.PublicPlayerName(ctx, tokenID, sessionNumber),
+		EndedBy:       string(reason),
+	})
+	return reason, newStart
+}
+
+// sessionEndedNotice explains an automatically ended session on the name form
+func sessionEndedNotice(reason rules.EndReason) string {
+	switch reason {
+	case rules.EndTimeLimit:
+		return "Die letzte Session hat die maximale Spieldauer erreicht und wurde automatisch beendet."
+	case rules.EndIdle:
+		return "Die letzte Session wurde nach längerer Inaktivität automatisch beendet."
+	}
+	return ""
+}
+
+// setRuleContext stores the ruleset and the derived session values in the context
+func setRuleContext(c *echo.Context, state ruleState, startedAt time.Time) {
+	c.Set("ruleset", state.ruleset)
+	if deadline, _, ok := state.ruleset.Deadline(startedAt, state.lastUpload); ok {
+		c.Set("session_ends_at", deadline)
+	}
+}
+
+// Ruleset returns the ruleset stored in the context by the token middlewares
+func Ruleset(c *echo.Context) rules.Ruleset {
+	if rs, ok := c.Get("ruleset").(rules.Ruleset); ok {
+		return rs
+	}
+	return rules.Default
+}
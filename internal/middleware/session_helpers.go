@@ -4,13 +4,19 @@ import (
 	"math"
 	"strconv"
 	"time"
+
+	"github.com/gorilla/sessions"
+
+	"id-100/internal/utils"
 )
 
 // Session key constants
 const (
 	SessionKeyPlayerName   = "player_name"
 	SessionKeyPlayerCity   = "player_city"
-	SessionKeyToken        = "token"
+	SessionKeyToken        = "token" // plain token of sessions from before tokens were hashed
+	SessionKeyTokenHash    = "token_hash"
+	SessionKeyDeviceID     = "device_id"
 	SessionKeyTokenID      = "token_id"
 	SessionKeyBagName      = "bag_name"
 	SessionKeySessionNum   = "session_number"
@@ -94,3 +100,44 @@ func GetSessionTime(v interface{}) (time.Time, bool) {
 	}
 	return time.Time{}, false
 }
+
+// SessionTokenHash returns the hash of the bag token stored in the session.
+// A plain token left by an older session is replaced by its hash.
+func SessionTokenHash(session *sessions.Session) string {
+	if tokenHash, ok := session.Values[SessionKeyTokenHash].(string); ok && tokenHash != "" {
+		return tokenHash
+	}
+	if token, ok := session.Values[SessionKeyToken].(string); ok && token != "" {
+		delete(session.Values, SessionKeyToken)
+		tokenHash := utils.HashToken(token)
+		session.Values[SessionKeyTokenHash] = tokenHash
+		return tokenHash
+	}
+	return ""
+}
+
+// SetSessionBag stores the hash of a bag token in the session. Switching to a
+// different bag forgets the player of the previous one.
+func SetSessionBag(session *sessions.Session, tokenHash string) {
+	if SessionTokenHash(session) != tokenHash {
+		delete(session.Values, SessionKeyPlayerName)
+		delete(session.Values, SessionKeyPlayerCity)
+		delete(session.Values, SessionKeySessionNum)
+		delete(session.Values, SessionKeySessionStart)
+	}
+	session.Values[SessionKeyTokenHash] = tokenHash
+}
+
+// DeviceID returns the random ID that identifies this browser, creating one if
+// the session has none yet. The caller saves the session.
+func DeviceID(session *sessions.Session) string {
+	if id, ok := session.Values[SessionKeyDeviceID].(string); ok && id != "" {
+		return id
+	}
+	id, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return ""
+	}
+	session.Values[SessionKeyDeviceID] = id
+	return id
+}
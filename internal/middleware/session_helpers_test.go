@@ -5,6 +5,10 @@ import (
 	"strconv"
 	"testing"
 	"time"
+
+	"github.com/gorilla/sessions"
+
+	"id-100/internal/utils"
 )
 
 func TestGetSessionNumber(t *testing.T) {
@@ -105,3 +109,75 @@ func TestGetSessionTime(t *testing.T) {
 		t.Fatalf("out-of-range seconds should fail: %s", strconv.FormatInt(out, 10))
 	}
 }
+
+func TestSessionTokenHash(t *testing.T) {
+	session := sessions.NewSession(nil, "id-100-session")
+	if got := SessionTokenHash(session); got != "" {
+		t.Fatalf("empty session returned %q", got)
+	}
+
+	// a plain token from an older session is replaced by its hash
+	session.Values[SessionKeyToken] = "abc"
+	want := utils.HashToken("abc")
+	if got := SessionTokenHash(session); got != want {
+		t.Fatalf("legacy session: got %q, want %q", got, want)
+	}
+	if _, ok := session.Values[SessionKeyToken]; ok {
+		t.Fatalf("plain token should be removed from the session")
+	}
+	if got := SessionTokenHash(session); got != want {
+		t.Fatalf("hashed session: got %q, want %q", got, want)
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	if tokenExpired(nil) {
+		t.Fatalf("token without expiry should not expire")
+	}
+	if !tokenExpired(&past) {
+		t.Fatalf("token in the past should be expired")
+	}
+	if tokenExpired(&future) {
+		t.Fatalf("token in the future should not be expired")
+	}
+}
+
+func TestDeviceID(t *testing.T) {
+	session := sessions.NewSession(nil, "id-100-session")
+	id := DeviceID(session)
+	if len(id) != 32 {
+		t.Fatalf("device ID %q should have 32 characters", id)
+	}
+	if again := DeviceID(session); again != id {
+		t.Fatalf("device ID changed from %q to %q", id, again)
+	}
+	if other := DeviceID(sessions.NewSession(nil, "id-100-session")); other == id {
+		t.Fatalf("two sessions got the same device ID")
+	}
+}
+
+func TestSetSessionBag(t *testing.T) {
+	session := sessions.NewSession(nil, "id-100-session")
+	SetSessionBag(session, "hash-a")
+	session.Values[SessionKeyPlayerName] = "Anna"
+	session.Values[SessionKeySessionNum] = 3
+
+	// the same bag keeps the player
+	SetSessionBag(session, "hash-a")
+	if session.Values[SessionKeyPlayerName] != "Anna" {
+		t.Fatalf("player of the same bag should be kept")
+	}
+
+	// another bag forgets it
+	SetSessionBag(session, "hash-b")
+	if got := SessionTokenHash(session); got != "hash-b" {
+		t.Fatalf("token hash = %q, want hash-b", got)
+	}
+	if _, ok := session.Values[SessionKeyPlayerName]; ok {
+		t.Fatalf("player of the previous bag should be removed")
+	}
+	if _, ok := session.Values[SessionKeySessionNum]; ok {
+		t.Fatalf("session number of the previous bag should be removed")
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
@@ -9,12 +10,17 @@ import (
 
 	"github.com/labstack/echo/v5"
 	"id-100/internal/database"
+	"id-100/internal/rules"
+	"id-100/internal/utils"
 )
 
-const (
-	// UploadCooldownDuration is the minimum time between uploads for the same token
-	UploadCooldownDuration = 5 * time.Second
-)
+// HandoverRequestTTL is how long a handover request waits for the current player
+const HandoverRequestTTL = 30 * time.Minute
+
+// tokenExpired reports whether a token with the given expiry may no longer be used
+func tokenExpired(expiresAt *time.Time) bool {
+	return expiresAt != nil && !expiresAt.After(time.Now())
+}
 
 // TokenWithSession is a middleware with session support for token validation
 func TokenWithSession(next echo.HandlerFunc) echo.HandlerFunc {
@@ -44,14 +50,15 @@ func TokenWithSession(next echo.HandlerFunc) echo.HandlerFunc {
 				}
 			}
 		}
-		if token == "" {
-			// Try to get from session
-			if sessToken, ok := session.Values["token"].(string); ok {
-				token = sessToken
-			}
+		// Only the hash is kept in the session and looked up in the database
+		tokenHash := ""
+		if token != "" {
+			tokenHash = utils.HashToken(token)
+		} else {
+			tokenHash = SessionTokenHash(session)
 		}
 
-		if token == "" {
+		if tokenHash == "" {
 			return c.Render(http.StatusForbidden, "layout", mergeTemplateData(map[string]interface{}{
 				"Title":           "Zugang verweigert",
 				"ContentTemplate": "access_denied.content",
@@ -66,12 +73,19 @@ func TokenWithSession(next echo.HandlerFunc) echo.HandlerFunc {
 		var maxUploads, totalUploads, totalSessions int
 		var currentPlayer, currentPlayerCity, bagName string
 		var sessionStartedAt time.Time
+		var expiresAt, handoverRequestedAt *time.Time
+		var sessionDevice, handoverDevice string
 
 		err = database.DB.QueryRow(context.Background(),
 			`SELECT id, is_active, max_uploads, total_uploads, total_sessions,
-			 COALESCE(current_player, ''), COALESCE(current_player_city, ''), COALESCE(bag_name, ''), COALESCE(session_started_at, created_at)
-			 FROM upload_tokens WHERE token = $1`,
-			token).Scan(&tokenID, &isActive, &maxUploads, &totalUploads, &totalSessions, &currentPlayer, &currentPlayerCity, &bagName, &sessionStartedAt)
+			 COALESCE(current_player, ''), COALESCE(current_player_city, ''), COALESCE(bag_name, ''), COALESCE(session_started_at, created_at), expires_at,
+			 COALESCE(session_device, ''), COALESCE(handover_device, ''), handover_requested_at
+			 FROM upload_tokens WHERE token_hash = $1`,
+			tokenHash).Scan(&tokenID, &isActive, &maxUploads, &totalUploads, &totalSessions, &currentPlayer, &currentPlayerCity, &bagName, &sessionStartedAt, &expiresAt,
+			&sessionDevice, &handoverDevice, &handoverRequestedAt)
+		if err == nil && tokenExpired(expiresAt) {
+			err = fmt.Errorf("token %d expired at %s", tokenID, expiresAt.Format(time.RFC3339))
+		}
 
 		if err != nil {
 			log.Printf("Token validation error: %v", err)
@@ -83,11 +97,52 @@ func TokenWithSession(next echo.HandlerFunc) echo.HandlerFunc {
 			}))
 		}
 
-		// Save token in session for subsequent requests
-		session.Values["token"] = token
+		// The bag's ruleset ends sessions past their time limit or idle timeout
+		state, err := loadRuleState(context.Background(), tokenID, totalSessions)
+		if err != nil {
+			log.Printf("Failed to load ruleset for token_id=%d: %v", tokenID, err)
+		}
+		notice := ""
+		if currentPlayer != "" {
+			if reason, newStart := endExpiredSession(context.Background(), state, tokenID, totalSessions, sessionStartedAt, bagName, currentPlayer); reason != rules.EndNone {
+				notice = sessionEndedNotice(reason)
+				totalSessions++
+				totalUploads = 0
+				currentPlayer, currentPlayerCity = "", ""
+				sessionDevice, handoverDevice, handoverRequestedAt = "", "", nil
+				sessionStartedAt = newStart
+				state.lastUpload = nil
+			}
+		}
+
+		// Save the token hash in session for subsequent requests
+		session.Values[SessionKeyTokenHash] = tokenHash
 		session.Values["token_id"] = tokenID
 		session.Values["bag_name"] = bagName
 
+		// A running session belongs to the device that started it; sessions
+		// without a device (older ones, or released by an admin) go to the next one
+		deviceID := DeviceID(session)
+		if sessionDevice == "" && currentPlayer != "" {
+			if err := database.DB.QueryRow(context.Background(),
+				"UPDATE upload_tokens SET session_device = COALESCE(session_device, $2) WHERE id = $1 RETURNING session_device",
+				tokenID, deviceID).Scan(&sessionDevice); err != nil {
+				log.Printf("Failed to bind token_id=%d to device: %v", tokenID, err)
+			}
+		}
+		handoverPending := handoverDevice != "" && handoverRequestedAt != nil && time.Since(*handoverRequestedAt) < HandoverRequestTTL
+		if sessionDevice != "" && sessionDevice != deviceID {
+			session.Save(c.Request(), c.Response())
+			return c.Render(http.StatusConflict, "layout", mergeTemplateData(map[string]interface{}{
+				"Title":             "Werkzeug in Benutzung",
+				"ContentTemplate":   "bag_in_use.content",
+				"CurrentPath":       c.Request().URL.Path,
+				"CurrentYear":       time.Now().Year(),
+				"BagName":           bagName,
+				"HandoverRequested": handoverPending && handoverDevice == deviceID,
+			}))
+		}
+
 		// session freshness: ensure session_number and session_started_at exist and match DB
 		sessNumVal := session.Values["session_number"]
 		if existing, ok := GetSessionNumber(sessNumVal); ok {
@@ -142,8 +197,8 @@ func TokenWithSession(next echo.HandlerFunc) echo.HandlerFunc {
 			if sessName, ok := session.Values["player_name"].(string); ok && sessName != "" {
 				// Update DB with name from session
 				result, err := database.DB.Exec(context.Background(),
-					"UPDATE upload_tokens SET current_player = $1, session_started_at = NOW() WHERE id = $2",
-					sessName, tokenID)
+					"UPDATE upload_tokens SET current_player = $1, session_started_at = NOW(), session_device = $3 WHERE id = $2",
+					sessName, tokenID, deviceID)
 
 				if err != nil {
 					log.Printf("Failed to update current_player for token_id=%d with name=%s: %v", tokenID, sessName, err)
@@ -155,6 +210,7 @@ func TokenWithSession(next echo.HandlerFunc) echo.HandlerFunc {
 						"CurrentYear":     time.Now().Year(),
 						"BagName":         bagName,
 						"Token":           token,
+						"Notice":          notice,
 					}))
 				}
 
@@ -174,6 +230,7 @@ func TokenWithSession(next echo.HandlerFunc) echo.HandlerFunc {
 					"CurrentYear":     time.Now().Year(),
 					"BagName":         bagName,
 					"Token":           token,
+					"Notice":          notice,
 				}))
 			}
 		} else {
@@ -189,8 +246,12 @@ func TokenWithSession(next echo.HandlerFunc) echo.HandlerFunc {
 		c.Set("current_player", currentPlayer)
 		c.Set("bag_name", bagName)
 		c.Set("session_number", totalSessions)
-		c.Set("uploads_remaining", maxUploads-totalUploads)
+		remaining := state.ruleset.Remaining(maxUploads, totalUploads, state.lifetimeUploads)
+		c.Set("uploads_remaining", remaining)
 		c.Set("current_player_city", currentPlayerCity)
+		c.Set("device_id", deviceID)
+		c.Set("handover_pending", handoverPending)
+		setRuleContext(c, state, sessionStartedAt)
 
 		// Check if token is active
 		if !isActive {
@@ -202,35 +263,32 @@ func TokenWithSession(next echo.HandlerFunc) echo.HandlerFunc {
 			}))
 		}
 
-		// Check upload limit
-		if totalUploads >= maxUploads {
-			return c.Render(http.StatusForbidden, "layout", mergeTemplateData(map[string]interface{}{
+		// Check upload limit (session quota and the bag's lifetime quota)
+		if remaining <= 0 {
+			lifetimeReached := state.ruleset.LifetimeUploads > 0 && state.lifetimeUploads >= state.ruleset.LifetimeUploads
+			data := map[string]interface{}{
 				"Title":           "Upload-Limit erreicht",
 				"ContentTemplate": "limit_reached.content",
 				"CurrentPath":     c.Request().URL.Path,
 				"CurrentYear":     time.Now().Year(),
 				"TotalUploads":    totalUploads,
-				"MaxUploads":      maxUploads,
-			}))
+				"MaxUploads":      state.ruleset.SessionLimit(maxUploads),
+				"LifetimeReached": lifetimeReached,
+			}
+			if lifetimeReached {
+				data["TotalUploads"] = state.lifetimeUploads
+				data["MaxUploads"] = state.ruleset.LifetimeUploads
+			}
+			return c.Render(http.StatusForbidden, "layout", mergeTemplateData(data))
 		}
 
-		// For POST requests: Check cooldown
-		if c.Request().Method == "POST" {
-			var lastUpload *time.Time
-			err = database.DB.QueryRow(context.Background(),
-				"SELECT MAX(uploaded_at) FROM upload_logs WHERE token_id = $1 AND session_number = $2",
-				tokenID, totalSessions).Scan(&lastUpload)
-
-			if err == nil && lastUpload != nil {
-				timeSince := time.Since(*lastUpload)
-
-				if timeSince < UploadCooldownDuration {
-					remainingSeconds := int(UploadCooldownDuration.Seconds() - timeSince.Seconds())
-					return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
-						"error":             "Bitte warte zwischen Uploads",
-						"remaining_seconds": remainingSeconds,
-					})
-				}
+		// For uploads: Check the ruleset's cooldown
+		if c.Request().Method == "POST" && c.Request().URL.Path == "/upload" {
+			if wait := state.ruleset.CooldownRemaining(state.lastUpload, time.Now()); wait > 0 {
+				return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+					"error":             "Bitte warte zwischen Uploads",
+					"remaining_seconds": int(wait.Seconds()) + 1,
+				})
 			}
 		}
 
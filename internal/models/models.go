@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"html/template"
+	"time"
+)
 
 // Derive represents a derive record
 type Derive struct {
@@ -38,18 +42,142 @@ type FooterStats struct {
 
 // TokenInfo holds information about an upload token
 type TokenInfo struct {
-	ID                int       `json:"id"`
-	Token             string    `json:"token"`
-	BagName           string    `json:"bag_name"`
-	CurrentPlayer     string    `json:"current_player"`
-	CurrentPlayerCity string    `json:"current_player_city"`
-	IsActive          bool      `json:"is_active"`
-	MaxUploads        int       `json:"max_uploads"`
-	TotalUploads      int       `json:"total_uploads"`
-	TotalSessions     int       `json:"total_sessions"`
-	SessionStartedAt  time.Time `json:"session_started_at"`
-	CreatedAt         time.Time `json:"created_at"`
-	Remaining         int       `json:"remaining"`
+	ID                int        `json:"id"`
+	Token             string     `json:"token,omitempty"` // plain token, only known right after creation or rotation
+	ShortCode         string     `json:"short_code"`
+	JourneySlug       string     `json:"journey_slug"` // public /werkzeug/<slug> page
+	BagName           string     `json:"bag_name"`
+	GroupLabel        string     `json:"group_label"`
+	CurrentPlayer     string     `json:"current_player"`
+	CurrentPlayerCity string     `json:"current_player_city"`
+	IsActive          bool       `json:"is_active"`
+	MaxUploads        int        `json:"max_uploads"`
+	TotalUploads      int        `json:"total_uploads"`
+	TotalSessions     int        `json:"total_sessions"`
+	SessionStartedAt  time.Time  `json:"session_started_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	Expired           bool       `json:"expired"`
+	DeviceBound       bool       `json:"device_bound"`         // the running session is bound to one device
+	RulesetID         int        `json:"ruleset_id,omitempty"` // 0: the default rules apply
+	Remaining         int        `json:"remaining"`
+}
+
+// BagSession is one session of a bag in the admin history
+type BagSession struct {
+	SessionNumber int
+	PlayerName    string
+	PublicName    string // the name on the session's contributions, if it differs from PlayerName
+	PlayerCity    string
+	StartedAt     time.Time
+	EndedAt       *time.Time // nil for the running session
+	Current       bool
+	JourneyHidden bool   // the player kept the session out of the public journey
+	RecapKey      string // set when the player ended the session
+	Points        int
+	Uploads       []BagSessionUpload
+}
+
+// BagSessionUpload is a contribution made in a bag session
+type BagSessionUpload struct {
+	ContributionID int
+	DeriveNumber   int
+	Points         int
+	ImageURL       string
+	ImageLqip      string
+	Comment        string
+	UploadedAt     time.Time
+}
+
+// JourneyStage is one session on the public journey of a bag. Hidden stages
+// (the player opted out) carry no name, city or contributions.
+type JourneyStage struct {
+	SessionNumber int
+	Pseudonym     string
+	City          string
+	StartedAt     time.Time
+	EndedAt       *time.Time
+	Hidden        bool
+	Points        int
+	Contributions []JourneyContribution
+}
+
+// JourneyContribution is a contribution shown on a bag journey
+type JourneyContribution struct {
+	ID           int
+	DeriveNumber int
+	ImageURL     string
+	ImageLqip    string
+}
+
+// SessionRecap is the permanent recap of an ended session
+type SessionRecap struct {
+	Key           string
+	BagName       string
+	SessionNumber int
+	PlayerName    string
+	PlayerCity    string
+	StartedAt     time.Time
+	EndedAt       *time.Time
+	Linked        bool // the session belongs to a player account
+	Contributions []RecapContribution
+}
+
+// RecapContribution is a contribution in a session recap
+type RecapContribution struct {
+	ID           int
+	DeriveNumber int
+	DeriveTitle  string
+	Points       int
+	ImageURL     string
+	ImageLqip    string
+	Comment      string
+	UploadedAt   time.Time
+}
+
+// Player is an optional account linking sessions across bags
+type Player struct {
+	ID         int       `json:"id"`
+	Email      string    `json:"email"`       // empty until an address was confirmed by magic link
+	PublicName string    `json:"public_name"` // empty keeps the names typed in at each bag
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PlayerSession is a bag session linked to a player account
+type PlayerSession struct {
+	TokenID       int
+	BagName       string
+	SessionNumber int
+	PlayerName    string
+	PlayerCity    string
+	StartedAt     time.Time
+	EndedAt       *time.Time
+	RecapKey      string
+	Uploads       int
+	Points        int
+}
+
+// PlayerContribution is a contribution from a linked session, as counted for progress
+type PlayerContribution struct {
+	DeriveNumber int
+	Points       int
+}
+
+// PlayerProgress is the combined progress of a player over all 100 IDs
+type PlayerProgress struct {
+	IDs           []PlayerProgressID
+	Completed     int
+	Contributions int
+	Points        int
+}
+
+// PlayerProgressID is one derive on the progress page; Count is the number of
+// contributions the player made to it
+type PlayerProgressID struct {
+	Number int
+	Title  string
+	Points int
+	Count  int
 }
 
 // RecentContrib represents a recent contribution for the admin dashboard
@@ -62,10 +190,25 @@ type RecentContrib struct {
 
 // BagRequest represents a bag request
 type BagRequest struct {
-	ID        int
-	Email     string
-	CreatedAt time.Time
-	Handled   bool
+	ID          int        `json:"id"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Status      string     `json:"status"`       // see bagrequest.Statuses
+	ConfirmedAt *time.Time `json:"confirmed_at"` // nil until the double opt-in link was opened
+	AdminNotes  string     `json:"admin_notes"`
+	// Optional postal address, only stored with consent
+	ShippingName       string     `json:"shipping_name"`
+	ShippingStreet     string     `json:"shipping_street"`
+	ShippingPostalCode string     `json:"shipping_postal_code"`
+	ShippingCity       string     `json:"shipping_city"`
+	AddressConsentAt   *time.Time `json:"address_consent_at"`
+	// Upload token created for the request
+	TokenID         *int       `json:"token_id"`
+	TokenBagName    string     `json:"token_bag_name"`
+	TokenAssignedAt *time.Time `json:"token_assigned_at"`
+	ShippedAt       *time.Time `json:"shipped_at"`
+	ReturnedAt      *time.Time `json:"returned_at"`
+	LostAt          *time.Time `json:"lost_at"`
 }
 
 // PageNumber represents pagination information
@@ -74,3 +217,281 @@ type PageNumber struct {
 	IsCurrent bool
 	IsDots    bool
 }
+
+// City represents a populated place imported from GeoNames
+type City struct {
+	ID          int64   `json:"id,string"`
+	Name        string  `json:"name"`
+	ASCIIName   string  `json:"ascii_name"`
+	CountryCode string  `json:"country_code"`
+	FeatureCode string  `json:"feature_code"`
+	Type        string  `json:"type"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+	Population  int64   `json:"population"`
+}
+
+// SearchResult is a single hit of the full-text search across deriven and contributions
+type SearchResult struct {
+	Kind           string        `json:"kind"` // "derive" or "contribution"
+	DeriveNumber   int           `json:"derive_number"`
+	DeriveTitle    string        `json:"derive_title"`
+	ContributionID int           `json:"contribution_id,omitempty"`
+	ImageUrl       string        `json:"image_url,omitempty"`
+	ImageLqip      string        `json:"-"`
+	UserCity       string        `json:"user_city,omitempty"`
+	Snippet        string        `json:"snippet"`
+	SnippetHTML    template.HTML `json:"snippet_html"`
+	Rank           float64       `json:"rank"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// APIDerive is the public v1 API representation of a derive
+type APIDerive struct {
+	Number            int    `json:"number"`
+	Title             string `json:"title"`
+	Description       string `json:"description"`
+	Points            int    `json:"points"`
+	ContributionCount int    `json:"contribution_count"`
+	LatestImageURL    string `json:"latest_image_url,omitempty"`
+}
+
+// APIContribution is the public v1 API representation of a contribution.
+// ID is the contributions primary key and stays stable across requests.
+type APIContribution struct {
+	ID           int       `json:"id"`
+	DeriveNumber int       `json:"derive_number"`
+	ImageURL     string    `json:"image_url"`
+	UserName     string    `json:"user_name"`
+	UserCity     string    `json:"user_city,omitempty"`
+	UserComment  string    `json:"user_comment,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// APICity is a city with contributions in the public v1 API
+type APICity struct {
+	Name              string    `json:"name"`
+	ContributionCount int       `json:"contribution_count"`
+	DeriveCount       int       `json:"derive_count"`
+	LastContribution  time.Time `json:"last_contribution_at"`
+}
+
+// ParticipantUpload is an upload of the current session in the participant API
+type ParticipantUpload struct {
+	ID           int       `json:"id"`
+	DeriveNumber int       `json:"derive_number"`
+	Points       int       `json:"points"`
+	ImageURL     string    `json:"image_url"`
+	ImageLqip    string    `json:"image_lqip,omitempty"`
+	Comment      string    `json:"comment,omitempty"`
+	UploadedAt   time.Time `json:"uploaded_at"`
+}
+
+// ParticipantSession is the session status returned by the participant API
+type ParticipantSession struct {
+	BagName          string     `json:"bag_name"`
+	PlayerName       string     `json:"player_name"`
+	PlayerCity       string     `json:"player_city"`
+	PublicName       string     `json:"public_name,omitempty"`  // the name shown next to contributions
+	NameDisplay      string     `json:"name_display,omitempty"` // attribution style of the session
+	NeedsPlayerName  bool       `json:"needs_player_name"`
+	NeedsConsent     bool       `json:"needs_consent"`  // the session has to agree to PolicyVersion before uploading
+	PolicyVersion    string     `json:"policy_version"` // current version of the privacy policy
+	ConsentText      string     `json:"consent_text"`   // text to show with the agreement
+	SessionNumber    int        `json:"session_number"`
+	SessionStartedAt time.Time  `json:"session_started_at"`
+	MaxUploads       int        `json:"max_uploads"`
+	TotalUploads     int        `json:"total_uploads"`
+	UploadsRemaining int        `json:"uploads_remaining"`
+	CooldownSeconds  int        `json:"cooldown_seconds"`
+	SessionUploads   int        `json:"session_uploads"`
+	Points           int        `json:"points"`
+	SessionEndsAt    *time.Time `json:"session_ends_at,omitempty"` // set when the ruleset limits the session time
+	AllowedDerives   []int      `json:"allowed_derives,omitempty"` // empty means all derives
+}
+
+// TusUpload is the state of a resumable upload
+type TusUpload struct {
+	ID             string
+	TokenID        int
+	SessionNumber  int
+	DeriveNumber   int
+	UserComment    string
+	Length         int64
+	Offset         int64
+	ContributionID *int
+	UpdatedAt      time.Time
+	CompletedAt    *time.Time
+}
+
+// DirectUpload is a presigned upload straight to object storage
+type DirectUpload struct {
+	ID             string
+	TokenID        int
+	SessionNumber  int
+	DeriveNumber   int
+	ObjectKey      string
+	ContentType    string
+	ExpiresAt      time.Time
+	ContributionID *int
+	FinalizedAt    *time.Time
+}
+
+// Webhook is an outbound webhook subscription
+type Webhook struct {
+	ID          int
+	URL         string
+	Secret      string
+	Events      []string
+	Description string
+	IsActive    bool
+	CreatedAt   time.Time
+}
+
+// WebhookDelivery is a queued or attempted webhook delivery
+type WebhookDelivery struct {
+	ID             int64
+	WebhookID      int
+	WebhookURL     string
+	Secret         string
+	Event          string
+	Payload        string
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastAttemptAt  *time.Time
+	ResponseStatus *int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+// OutboxEmail is a rendered email waiting in (or sent from) the outbox
+type OutboxEmail struct {
+	ID        int64
+	Template  string
+	To        string
+	Subject   string
+	TextBody  string
+	HTMLBody  string
+	Status    string
+	Attempts  int
+	LastError string
+	CreatedAt time.Time
+	SentAt    *time.Time
+}
+
+// Consent is a stored agreement to the privacy policy
+type Consent struct {
+	ID            int       `json:"id"`
+	TokenID       *int      `json:"token_id"` // nil once the bag was deleted
+	BagName       string    `json:"bag_name"`
+	SessionNumber int       `json:"session_number"`
+	PolicyVersion string    `json:"policy_version"`
+	Texts         []string  `json:"texts"`
+	Source        string    `json:"source"`
+	IPHash        string    `json:"ip_hash"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ConsentVersionCount summarises the consent records of one policy version
+type ConsentVersionCount struct {
+	PolicyVersion string
+	Count         int
+	LastAt        time.Time
+}
+
+// SubjectQuery identifies a data subject for an access or erasure request.
+// Set criteria are combined: data matching any of them belongs to the subject.
+type SubjectQuery struct {
+	Name          string `json:"name,omitempty"`     // player name as typed, case-insensitive
+	TokenID       int    `json:"token_id,omitempty"` // with SessionNumber: one bag session
+	SessionNumber int    `json:"session_number,omitempty"`
+	Email         string `json:"email,omitempty"`     // bag requests, player accounts and sent emails
+	PlayerID      int    `json:"player_id,omitempty"` // player account with all linked sessions
+}
+
+// SubjectData is everything stored about a data subject
+type SubjectData struct {
+	Sessions      []SubjectSession      `json:"sessions"`
+	Contributions []SubjectContribution `json:"contributions"`
+	UploadLogs    []SubjectUploadLog    `json:"upload_logs"`
+	BagRequests   []BagRequest          `json:"bag_requests"`
+	Players       []Player              `json:"players"`
+	Consents      []Consent             `json:"consents"`
+	Emails        []SubjectEmail        `json:"emails"`
+	// Webhook payloads naming the subject (sessions, contributions, bag requests)
+	WebhookDeliveries []SubjectWebhookDelivery `json:"webhook_deliveries"`
+	// Storage keys of unfinished uploads of the sessions, erased with them
+	StagedObjects []string `json:"-"`
+}
+
+// SubjectSession is a bag session of a data subject
+type SubjectSession struct {
+	TokenID       int        `json:"token_id"`
+	BagName       string     `json:"bag_name"`
+	SessionNumber int        `json:"session_number"`
+	PlayerName    string     `json:"player_name"`
+	PlayerCity    string     `json:"player_city"`
+	PublicName    string     `json:"public_name"`
+	NameDisplay   string     `json:"name_display"`
+	StartedAt     time.Time  `json:"started_at"`
+	EndedAt       *time.Time `json:"ended_at"` // nil for a running session
+	RecapKey      string     `json:"recap_key"`
+	PlayerID      *int       `json:"player_id"`
+}
+
+// SubjectContribution is a contribution of a data subject
+type SubjectContribution struct {
+	ID           int       `json:"id"`
+	DeriveNumber int       `json:"derive_number"`
+	ImageURL     string    `json:"image_url"`
+	UserName     string    `json:"user_name"`
+	UserCity     string    `json:"user_city"`
+	UserComment  string    `json:"user_comment"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SubjectUploadLog is an upload log entry of a data subject
+type SubjectUploadLog struct {
+	ID             int       `json:"id"`
+	TokenID        int       `json:"token_id"`
+	SessionNumber  int       `json:"session_number"`
+	DeriveNumber   int       `json:"derive_number"`
+	PlayerName     string    `json:"player_name"`
+	ContributionID *int      `json:"contribution_id"`
+	UploadedAt     time.Time `json:"uploaded_at"`
+}
+
+// SubjectEmail is an email sent to a data subject, without its body
+type SubjectEmail struct {
+	ID        int64     `json:"id"`
+	Template  string    `json:"template"`
+	To        string    `json:"to"`
+	Subject   string    `json:"subject"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SubjectWebhookDelivery is a queued or logged webhook delivery about a data subject
+type SubjectWebhookDelivery struct {
+	ID        int64           `json:"id"`
+	WebhookID int             `json:"webhook_id"`
+	Event     string          `json:"event"`
+	Status    string          `json:"status"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// PrivacyAudit records an access or erasure request without personal data
+type PrivacyAudit struct {
+	ID        int
+	Action    string   // "export" or "erase"
+	Actor     string   // e.g. "admin:alice" or "cli:root"
+	Reference string   // free text such as a ticket number
+	Criteria  []string // kinds of criteria used (name, session, email, player), never their values
+	Counts    map[string]int
+	Verified  bool // erasures only: a search afterwards found nothing left
+	Error     string
+	CreatedAt time.Time
+}
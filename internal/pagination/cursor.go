@@ -0,0 +1,58 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// DefaultLimit and MaxLimit bound the page size of list endpoints
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// ErrInvalidCursor is returned for cursors that were not produced by Encode
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last item of a page for keyset pagination.
+// Which fields are set depends on the sort order of the list.
+type Cursor struct {
+	Time time.Time `json:"t,omitempty"`
+	ID   int       `json:"i,omitempty"`
+	Key  string    `json:"k,omitempty"`
+}
+
+// Encode returns the opaque URL-safe form of the cursor
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode parses an opaque cursor; an empty string yields nil
+func Decode(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// ClampLimit applies DefaultLimit to unset values and caps at MaxLimit
+func ClampLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
+}
@@ -0,0 +1,48 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	ts := time.Date(2026, 5, 1, 14, 30, 0, 123456789, time.UTC)
+	in := Cursor{Time: ts, ID: 42, Key: "Kassel"}
+
+	out, err := Decode(in.Encode())
+	if err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	if !out.Time.Equal(ts) || out.ID != 42 || out.Key != "Kassel" {
+		t.Errorf("round trip mismatch: got %+v, want %+v", out, in)
+	}
+}
+
+func TestDecodeEmpty(t *testing.T) {
+	c, err := Decode("")
+	if err != nil || c != nil {
+		t.Errorf("Decode(\"\") = %v, %v; want nil, nil", c, err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, s := range []string{"!!!", "bm90LWpzb24"} {
+		if _, err := Decode(s); err != ErrInvalidCursor {
+			t.Errorf("Decode(%q) error = %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestClampLimit(t *testing.T) {
+	tests := []struct{ in, want int }{
+		{0, DefaultLimit},
+		{-5, DefaultLimit},
+		{10, 10},
+		{MaxLimit + 1, MaxLimit},
+	}
+	for _, tt := range tests {
+		if got := ClampLimit(tt.in); got != tt.want {
+			t.Errorf("ClampLimit(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package player
+
+import (
+	"crypto/rand"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"id-100/internal/models"
+	"id-100/internal/utils"
+)
+
+// Player accounts are optional and have no password. A player signs in with
+// the recovery code shown once at sign-up, or with a magic link sent to an
+// email address they added. Both are stored hashed only.
+
+const (
+	// CodeLength is the number of characters of a recovery code without hyphens
+	CodeLength = 16
+	// CodeAlphabet is Crockford's base32, like bag codes
+	CodeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+	// LinkTokenLength is the number of characters of a magic link token
+	LinkTokenLength = 32
+	// LinkTTL is how long a magic link can be used
+	LinkTTL = 30 * time.Minute
+	// MaxLinksPerAddress limits magic links per email address within LinkTTL
+	MaxLinksPerAddress = 3
+
+	// MaxNameLength is the maximum length of a public name in characters
+	MaxNameLength = 40
+)
+
+// typos maps characters that are easily confused when copying a code
+var typos = map[rune]rune{'O': '0', 'I': '1', 'L': '1'}
+
+// NewRecoveryCode returns a random recovery code in its stored form
+func NewRecoveryCode() (string, error) {
+	b := make([]byte, CodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = CodeAlphabet[int(b[i])%len(CodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// NormalizeCode turns user input such as "k7p4 qx2m-..." into the stored form
+// and reports whether it is a well-formed recovery code
+func NormalizeCode(input string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(input) {
+		switch {
+		case r == '-' || r == ' ' || r == '\t':
+			continue
+		case typos[r] != 0:
+			r = typos[r]
+		}
+		if !strings.ContainsRune(CodeAlphabet, r) {
+			return "", false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() != CodeLength {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// FormatCode groups a code in blocks of four for display
+func FormatCode(code string) string {
+	if len(code) != CodeLength {
+		return code
+	}
+	return code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:]
+}
+
+// HashCode is the stored hash of a normalized recovery code
+func HashCode(code string) string {
+	return utils.HashToken(code)
+}
+
+// NewLinkToken returns a random magic link token
+func NewLinkToken() (string, error) {
+	return utils.GenerateSecureToken(LinkTokenLength)
+}
+
+// ValidLinkToken reports whether s looks like a magic link token (URL-safe base64)
+func ValidLinkToken(s string) bool {
+	if len(s) != LinkTokenLength {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// LinkPath is the path of a magic link
+func LinkPath(token string) string {
+	return "/spieler/anmelden/" + token
+}
+
+// NormalizeName trims a public name and reports whether it is usable
+func NormalizeName(name string) (string, bool) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+		return "", false
+	}
+	return name, true
+}
+
+// Progress marks the derives a player completed across all linked sessions.
+// Points count every contribution, like a session recap does.
+func Progress(derives []models.Derive, contribs []models.PlayerContribution) models.PlayerProgress {
+	counts := map[int]int{}
+	p := models.PlayerProgress{Contributions: len(contribs)}
+	for _, c := range contribs {
+		counts[c.DeriveNumber]++
+		p.Points += c.Points
+	}
+	p.IDs = make([]models.PlayerProgressID, 0, len(derives))
+	for _, d := range derives {
+		n := counts[d.Number]
+		if n > 0 {
+			p.Completed++
+		}
+		p.IDs = append(p.IDs, models.PlayerProgressID{Number: d.Number, Title: d.Title, Points: d.Points, Count: n})
+	}
+	return p
+}
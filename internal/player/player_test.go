@@ -0,0 +1,107 @@
+package player
+
+import (
+	"strings"
+	"testing"
+
+	"id-100/internal/models"
+)
+
+func TestNewRecoveryCode(t *testing.T) {
+	code, err := NewRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := NormalizeCode(code); !ok || got != code {
+		t.Errorf("NormalizeCode(%q) = %q, %v; want the code unchanged", code, got, ok)
+	}
+	other, _ := NewRecoveryCode()
+	if other == code {
+		t.Errorf("two recovery codes are equal: %q", code)
+	}
+}
+
+func TestNormalizeCode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"K7P4-QX2M-9ABC-DEFG", "K7P4QX2M9ABCDEFG", true},
+		{" k7p4 qx2m 9abc defg ", "K7P4QX2M9ABCDEFG", true},
+		{"O7P4-QX2M-9ABC-DEFI", "07P4QX2M9ABCDEF1", true}, // typos
+		{"K7P4-QX2M-9ABC-DEFU", "", false},                // U is not in the alphabet
+		{"K7P4-QX2M-9ABC", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizeCode(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeCode(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormatCode(t *testing.T) {
+	if got := FormatCode("K7P4QX2M9ABCDEFG"); got != "K7P4-QX2M-9ABC-DEFG" {
+		t.Errorf("FormatCode = %q", got)
+	}
+	if got := FormatCode("K7P4"); got != "K7P4" {
+		t.Errorf("FormatCode of a short input = %q, want it unchanged", got)
+	}
+}
+
+func TestValidLinkToken(t *testing.T) {
+	token, err := NewLinkToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ValidLinkToken(token) {
+		t.Errorf("ValidLinkToken(%q) = false", token)
+	}
+	for _, s := range []string{"", "short", strings.Repeat("a", LinkTokenLength-1) + "/"} {
+		if ValidLinkToken(s) {
+			t.Errorf("ValidLinkToken(%q) = true", s)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"  Mia   K. ", "Mia K.", true},
+		{"", "", false},
+		{"   ", "", false},
+		{strings.Repeat("ä", MaxNameLength), strings.Repeat("ä", MaxNameLength), true},
+		{strings.Repeat("ä", MaxNameLength+1), "", false},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizeName(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeName(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	derives := []models.Derive{
+		{Number: 1, Title: "Eins", Points: 1},
+		{Number: 2, Title: "Zwei", Points: 2},
+		{Number: 3, Title: "Drei", Points: 3},
+	}
+	contribs := []models.PlayerContribution{
+		{DeriveNumber: 1, Points: 1},
+		{DeriveNumber: 3, Points: 3},
+		{DeriveNumber: 3, Points: 3}, // same ID with another bag
+	}
+	p := Progress(derives, contribs)
+	if p.Completed != 2 || p.Contributions != 3 || p.Points != 7 {
+		t.Errorf("Progress = %d completed, %d contributions, %d points; want 2, 3, 7", p.Completed, p.Contributions, p.Points)
+	}
+	if len(p.IDs) != 3 || p.IDs[0].Count != 1 || p.IDs[1].Count != 0 || p.IDs[2].Count != 2 {
+		t.Errorf("Progress IDs = %+v", p.IDs)
+	}
+}
@@ -0,0 +1,134 @@
+package recap
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"id-100/internal/models"
+	"id-100/internal/utils"
+)
+
+// Recaps keep what a player made in a session reachable after the bag was
+// passed on. The link is a random key; whoever has it can see the recap.
+
+// KeyLength is the number of characters of a recap key
+const KeyLength = 32
+
+// NewKey returns a random recap key
+func NewKey() (string, error) {
+	return utils.GenerateSecureToken(KeyLength)
+}
+
+// ValidKey reports whether s looks like a recap key (URL-safe base64)
+func ValidKey(s string) bool {
+	if len(s) != KeyLength {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Path is the public path of a recap
+func Path(key string) string {
+	return "/rueckblick/" + key
+}
+
+// CompletedIDs returns the distinct derive numbers of the contributions in order
+func CompletedIDs(contribs []models.RecapContribution) []int {
+	seen := map[int]bool{}
+	var ids []int
+	for _, c := range contribs {
+		if !seen[c.DeriveNumber] {
+			seen[c.DeriveNumber] = true
+			ids = append(ids, c.DeriveNumber)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Points sums the points of all contributions
+func Points(contribs []models.RecapContribution) int {
+	total := 0
+	for _, c := range contribs {
+		total += c.Points
+	}
+	return total
+}
+
+// OpenFunc opens the stored image of a contribution
+type OpenFunc func(imageURL string) (io.ReadCloser, error)
+
+// WriteArchive writes a ZIP with the images of a session and a text file with
+// the IDs and comments. Images that cannot be opened are noted in the text
+// file; errors writing to w abort.
+func WriteArchive(w io.Writer, r *models.SessionRecap, open OpenFunc) error {
+	zw := zip.NewWriter(w)
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "ID-100 – %s, Session #%d\n", r.BagName, r.SessionNumber)
+	if r.PlayerCity != "" {
+		fmt.Fprintf(&text, "%s, %s\n", r.PlayerName, r.PlayerCity)
+	} else {
+		fmt.Fprintf(&text, "%s\n", r.PlayerName)
+	}
+	fmt.Fprintf(&text, "%d Beiträge, %d Punkte\n", len(r.Contributions), Points(r.Contributions))
+
+	for i, c := range r.Contributions {
+		name := fmt.Sprintf("%02d_ID-%03d%s", i+1, c.DeriveNumber, ImageExt(c.ImageURL))
+		fmt.Fprintf(&text, "\n%s\nID %d: %s (%d Punkte), %s\n", name, c.DeriveNumber, c.DeriveTitle, c.Points,
+			c.UploadedAt.Format("02.01.2006 15:04"))
+		if c.Comment != "" {
+			fmt.Fprintf(&text, "Kommentar: %s\n", c.Comment)
+		}
+
+		body, err := open(c.ImageURL)
+		if err != nil {
+			fmt.Fprintf(&text, "(Bild nicht verfügbar)\n")
+			continue
+		}
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: c.UploadedAt})
+		if err == nil {
+			_, err = io.Copy(f, body)
+		}
+		body.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "beitraege.txt", Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, text.String()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ArchiveName is the download name of a recap archive
+func ArchiveName(r *models.SessionRecap) string {
+	return fmt.Sprintf("id-100_%s_session-%d.zip", utils.SanitizeFilename(r.BagName), r.SessionNumber)
+}
+
+// ImageExt returns the file extension of a stored image, ".webp" if unknown
+func ImageExt(imageURL string) string {
+	ext := strings.ToLower(path.Ext(strings.SplitN(imageURL, "?", 2)[0]))
+	switch ext {
+	case ".webp", ".jpg", ".jpeg", ".png", ".gif", ".avif":
+		return ext
+	}
+	return ".webp"
+}
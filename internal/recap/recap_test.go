@@ -0,0 +1,110 @@
+package recap
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"id-100/internal/models"
+)
+
+func TestNewKey(t *testing.T) {
+	a, err := NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewKey()
+	if !ValidKey(a) || !ValidKey(b) || a == b {
+		t.Errorf("NewKey = %q, %q", a, b)
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		in string
+		ok bool
+	}{
+		{"abcdefghijklmnopqrstuvwxyzAB-_09", true},
+		{"abcdefghijklmnopqrstuvwxyzAB-_0", false},
+		{"abcdefghijklmnopqrstuvwxyzAB-_0/", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidKey(tt.in); got != tt.ok {
+			t.Errorf("ValidKey(%q) = %v, want %v", tt.in, got, tt.ok)
+		}
+	}
+}
+
+func TestCompletedIDsAndPoints(t *testing.T) {
+	contribs := []models.RecapContribution{{DeriveNumber: 12, Points: 2}, {DeriveNumber: 3, Points: 1}, {DeriveNumber: 12, Points: 2}}
+	if got := CompletedIDs(contribs); !reflect.DeepEqual(got, []int{3, 12}) {
+		t.Errorf("CompletedIDs = %v", got)
+	}
+	if got := Points(contribs); got != 5 {
+		t.Errorf("Points = %d", got)
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	uploaded := time.Date(2026, 5, 1, 14, 3, 0, 0, time.UTC)
+	r := &models.SessionRecap{
+		BagName:       "Werkzeug 6",
+		SessionNumber: 4,
+		PlayerName:    "Anna S.",
+		PlayerCity:    "Kassel",
+		Contributions: []models.RecapContribution{
+			{DeriveNumber: 7, DeriveTitle: "Ein Zaun", Points: 2, ImageURL: "http://minio/b/derive_7_1.webp", Comment: "rostig", UploadedAt: uploaded},
+			{DeriveNumber: 9, DeriveTitle: "Eine Bank", Points: 1, ImageURL: "http://minio/b/gone.jpg", UploadedAt: uploaded},
+		},
+	}
+	open := func(url string) (io.ReadCloser, error) {
+		if strings.Contains(url, "gone") {
+			return nil, errors.New("not found")
+		}
+		return io.NopCloser(strings.NewReader("image:" + url)), nil
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, r, open); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	if len(files) != 2 {
+		t.Errorf("archive has %d files, want image and text: %v", len(files), files)
+	}
+	if files["01_ID-007.webp"] != "image:http://minio/b/derive_7_1.webp" {
+		t.Errorf("image missing or wrong: %q", files["01_ID-007.webp"])
+	}
+	text := files["beitraege.txt"]
+	for _, want := range []string{"Session #4", "Anna S., Kassel", "2 Beiträge, 3 Punkte", "ID 7: Ein Zaun", "Kommentar: rostig", "02_ID-009.jpg", "(Bild nicht verfügbar)"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("beitraege.txt does not contain %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestArchiveName(t *testing.T) {
+	if got := ArchiveName(&models.SessionRecap{BagName: `Werkzeug "6"`, SessionNumber: 2}); got != "id-100_Werkzeug _6__session-2.zip" {
+		t.Errorf("ArchiveName = %q", got)
+	}
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+	"id-100/internal/pagination"
+)
+
+// Queries for the public read-only v1 API. All lists use keyset pagination and
+// fetch one row more than requested so callers can tell whether a next page exists.
+
+// APIDerivenFilter narrows the v1 deriven list
+type APIDerivenFilter struct {
+	City        string
+	AfterNumber int
+	Limit       int
+}
+
+// ListAPIDeriven returns deriven ordered by number
+func ListAPIDeriven(ctx context.Context, f APIDerivenFilter) ([]models.APIDerive, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT d.number, d.title, d.description, d.points,
+		       COALESCE(stats.cnt, 0), COALESCE(latest.image_url, '')
+		FROM deriven d
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS cnt FROM contributions
+			WHERE derive_id = d.id AND ($1 = '' OR user_city = $1)
+		) stats ON true
+		LEFT JOIN LATERAL (
+			SELECT image_url FROM contributions
+			WHERE derive_id = d.id AND ($1 = '' OR user_city = $1)
+			ORDER BY created_at DESC, id DESC LIMIT 1
+		) latest ON true
+		WHERE d.number > $2 AND ($1 = '' OR stats.cnt > 0)
+		ORDER BY d.number ASC
+		LIMIT $3`,
+		f.City, f.AfterNumber, f.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.APIDerive
+	for rows.Next() {
+		var d models.APIDerive
+		if err := rows.Scan(&d.Number, &d.Title, &d.Description, &d.Points, &d.ContributionCount, &d.LatestImageURL); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// contributionCreatedAt is the creation time of a contribution in API lists.
+// Legacy rows without created_at sort as the oldest instead of failing the scan.
+const contributionCreatedAt = "COALESCE(c.created_at, 'epoch'::timestamptz)"
+
+// APIContributionFilter narrows the v1 contribution list
+type APIContributionFilter struct {
+	City         string
+	DeriveNumber int
+	Since        time.Time
+	Until        time.Time
+	After        *pagination.Cursor
+	Limit        int
+}
+
+// ListAPIContributions returns contributions newest first, ordered by (created_at, id)
+func ListAPIContributions(ctx context.Context, f APIContributionFilter) ([]models.APIContribution, error) {
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.City != "" {
+		where = append(where, "c.user_city = "+arg(f.City))
+	}
+	if f.DeriveNumber > 0 {
+		where = append(where, "d.number = "+arg(f.DeriveNumber))
+	}
+	if !f.Since.IsZero() {
+		where = append(where, contributionCreatedAt+" >= "+arg(f.Since))
+	}
+	if !f.Until.IsZero() {
+		where = append(where, contributionCreatedAt+" < "+arg(f.Until))
+	}
+	if f.After != nil {
+		where = append(where, fmt.Sprintf("(%s, c.id) < (%s, %s)", contributionCreatedAt, arg(f.After.Time), arg(f.After.ID)))
+	}
+
+	query := `
+		SELECT c.id, d.number, c.image_url, c.user_name, COALESCE(c.user_city, ''), COALESCE(c.user_comment, ''), ` + contributionCreatedAt + `
+		FROM contributions c
+		INNER JOIN deriven d ON d.id = c.derive_id`
+	if len(where) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(where, " AND ")
+	}
+	query += "\n\t\tORDER BY " + contributionCreatedAt + " DESC, c.id DESC\n\t\tLIMIT " + arg(f.Limit+1)
+
+	rows, err := database.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.APIContribution
+	for rows.Next() {
+		var ct models.APIContribution
+		if err := rows.Scan(&ct.ID, &ct.DeriveNumber, &ct.ImageURL, &ct.UserName, &ct.UserCity, &ct.UserComment, &ct.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, ct)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// DeriveExists reports whether a derive with the given number exists
+func DeriveExists(ctx context.Context, number int) (bool, error) {
+	var exists bool
+	err := database.DB.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM deriven WHERE number = $1)", number).Scan(&exists)
+	return exists, err
+}
+
+// ListAPICities returns cities with contributions ordered by name
+func ListAPICities(ctx context.Context, afterName string, limit int) ([]models.APICity, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT user_city, COUNT(*), COUNT(DISTINCT derive_id), COALESCE(MAX(created_at), NOW())
+		FROM contributions
+		WHERE user_city IS NOT NULL AND user_city != '' AND user_city > $1
+		GROUP BY user_city
+		ORDER BY user_city ASC
+		LIMIT $2`,
+		afterName, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.APICity
+	for rows.Next() {
+		var city models.APICity
+		if err := rows.Scan(&city.Name, &city.ContributionCount, &city.DeriveCount, &city.LastContribution); err != nil {
+			return nil, err
+		}
+		list = append(list, city)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
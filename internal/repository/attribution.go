@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"id-100/internal/attribution"
+	"id-100/internal/database"
+)
+
+// Queries for the public name of a session's contributions. The name shown
+// publicly is written to contributions.user_name; the typed name stays in
+// token_sessions.player_name and upload_logs.player_name for admins.
+
+// GetSessionAttribution returns the display preference of a session and the
+// public name its contributions carry; pgx.ErrNoRows if the session is not in
+// the history
+func GetSessionAttribution(ctx context.Context, tokenID, sessionNumber int) (attribution.Style, string, error) {
+	var typed, public, style string
+	err := database.DB.QueryRow(ctx, `
+		SELECT player_name, COALESCE(public_name, ''), name_display
+		FROM token_sessions WHERE token_id = $1 AND session_number = $2`,
+		tokenID, sessionNumber).Scan(&typed, &public, &style)
+	if err != nil {
+		return "", "", err
+	}
+	return attribution.Style(style), attribution.Name(typed, public, attribution.Style(style)), nil
+}
+
+// SetSessionNameDisplay stores the display preference of a session and
+// renames its existing contributions. It returns the number of renamed
+// contributions; pgx.ErrNoRows if the session is not in the history.
+func SetSessionNameDisplay(ctx context.Context, tokenID, sessionNumber int, style attribution.Style) (int64, error) {
+	tx, err := database.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var typed, public string
+	err = tx.QueryRow(ctx, `
+		UPDATE token_sessions SET name_display = $3
+		WHERE token_id = $1 AND session_number = $2
+		RETURNING player_name, COALESCE(public_name, '')`,
+		tokenID, sessionNumber, string(style)).Scan(&typed, &public)
+	if err != nil {
+		return 0, err
+	}
+	n, err := renameSessionContributions(ctx, tx, tokenID, sessionNumber, attribution.Name(typed, public, style))
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit(ctx)
+}
+
+// SetPlayerNameDisplay applies a display preference to all sessions linked to
+// a player. It replaces a public name chosen in the account and returns the
+// number of renamed contributions.
+func SetPlayerNameDisplay(ctx context.Context, playerID int, style attribution.Style) (int64, error) {
+	tx, err := database.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "UPDATE players SET public_name = NULL WHERE id = $1", playerID); err != nil {
+		return 0, err
+	}
+	rows, err := tx.Query(ctx, `
+		UPDATE token_sessions SET name_display = $2, public_name = NULL
+		WHERE player_id = $1
+		RETURNING token_id, session_number, player_name`, playerID, string(style))
+	if err != nil {
+		return 0, err
+	}
+	type session struct {
+		tokenID, number int
+		typed           string
+	}
+	var sessions []session
+	for rows.Next() {
+		var s session
+		if err := rows.Scan(&s.tokenID, &s.number, &s.typed); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		sessions = append(sessions, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, s := range sessions {
+		n, err := renameSessionContributions(ctx, tx, s.tokenID, s.number, style.Apply(s.typed))
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, tx.Commit(ctx)
+}
+
+// renameSessionContributions sets the public name of all contributions of a session
+func renameSessionContributions(ctx context.Context, tx pgx.Tx, tokenID, sessionNumber int, name string) (int64, error) {
+	res, err := tx.Exec(ctx, `
+		UPDATE contributions c
+		SET user_name = $3
+		FROM upload_logs ul
+		WHERE ul.contribution_id = c.id AND ul.token_id = $1 AND ul.session_number = $2`,
+		tokenID, sessionNumber, name)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
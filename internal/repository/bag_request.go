@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+	"id-100/internal/utils"
+)
+
+// ErrBagRequestStatusChanged is returned when a request is no longer in the status
+// an update expected, e.g. because another admin changed it in the meantime
+var ErrBagRequestStatusChanged = errors.New("bag request status changed")
+
+const bagRequestColumns = `
+		SELECT b.id, b.email, b.created_at, b.status, b.confirmed_at, b.admin_notes,
+		       b.shipping_name, b.shipping_street, b.shipping_postal_code, b.shipping_city, b.address_consent_at,
+		       b.token_id, COALESCE(t.bag_name, ''), b.token_assigned_at, b.shipped_at, b.returned_at, b.lost_at
+		FROM bag_requests b
+		LEFT JOIN upload_tokens t ON t.id = b.token_id`
+
+func scanBagRequest(row pgx.Row, br *models.BagRequest) error {
+	return row.Scan(&br.ID, &br.Email, &br.CreatedAt, &br.Status, &br.ConfirmedAt, &br.AdminNotes,
+		&br.ShippingName, &br.ShippingStreet, &br.ShippingPostalCode, &br.ShippingCity, &br.AddressConsentAt,
+		&br.TokenID, &br.TokenBagName, &br.TokenAssignedAt, &br.ShippedAt, &br.ReturnedAt, &br.LostAt)
+}
+
+func queryBagRequests(ctx context.Context, query string, args ...interface{}) ([]models.BagRequest, error) {
+	rows, err := database.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.BagRequest{}
+	for rows.Next() {
+		var br models.BagRequest
+		if err := scanBagRequest(rows, &br); err != nil {
+			return nil, err
+		}
+		list = append(list, br)
+	}
+	return list, rows.Err()
+}
+
+// InsertBagRequest inserts a new bag request and returns its ID and creation time.
+// It stays hidden until ConfirmBagRequest is called.
+// The postal address fields are only stored together with the consent timestamp.
+func InsertBagRequest(ctx context.Context, br models.BagRequest) (id int, createdAt time.Time, err error) {
+	err = database.DB.QueryRow(ctx, `
+		INSERT INTO bag_requests (email, status,
+		                          shipping_name, shipping_street, shipping_postal_code, shipping_city, address_consent_at)
+		VALUES ($1, 'new', $2, $3, $4, $5, CASE WHEN $2 <> '' THEN NOW() END)
+		RETURNING id, created_at`,
+		br.Email, br.ShippingName, br.ShippingStreet, br.ShippingPostalCode, br.ShippingCity).
+		Scan(&id, &createdAt)
+	return id, createdAt, err
+}
+
+// GetBagRequest returns a single bag request
+func GetBagRequest(ctx context.Context, id int) (*models.BagRequest, error) {
+	var br models.BagRequest
+	if err := scanBagRequest(database.DB.QueryRow(ctx, bagRequestColumns+` WHERE b.id = $1`, id), &br); err != nil {
+		return nil, err
+	}
+	return &br, nil
+}
+
+// GetBagRequestCounts returns the number of bag requests per status
+func GetBagRequestCounts(ctx context.Context) (map[string]int, error) {
+	rows, err := database.DB.Query(ctx, "SELECT status, COUNT(*) FROM bag_requests GROUP BY status")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+// GetBagRequests returns confirmed bag requests (any status after the opt-in),
+// optionally filtered by status, newest first. limit <= 0 returns all.
+func GetBagRequests(ctx context.Context, status string, limit int) ([]models.BagRequest, error) {
+	var limitArg *int
+	if limit > 0 {
+		limitArg = &limit
+	}
+	return queryBagRequests(ctx, bagRequestColumns+`
+		WHERE b.status <> 'new' AND ($1 = '' OR b.status = $1)
+		ORDER BY b.created_at DESC
+		LIMIT $2`, status, limitArg)
+}
+
+// GetBagRequestsConfirmedBetween returns bag requests confirmed in (from, to], oldest first
+func GetBagRequestsConfirmedBetween(ctx context.Context, from, to time.Time) ([]models.BagRequest, error) {
+	return queryBagRequests(ctx, bagRequestColumns+`
+		WHERE b.confirmed_at > $1 AND b.confirmed_at <= $2
+		ORDER BY b.confirmed_at ASC`, from, to)
+}
+
+// CountBagRequestsSince counts requests for an address (case-insensitive) created after since,
+// confirmed or not
+func CountBagRequestsSince(ctx context.Context, email string, since time.Time) (int, error) {
+	var n int
+	err := database.DB.QueryRow(ctx,
+		"SELECT COUNT(*) FROM bag_requests WHERE LOWER(email) = LOWER($1) AND created_at > $2",
+		email, since).Scan(&n)
+	return n, err
+}
+
+// ConfirmBagRequest confirms a new request and returns it. newlyConfirmed is
+// false if it had already been confirmed. Returns pgx.ErrNoRows if it does not exist
+// (e.g. it was purged).
+func ConfirmBagRequest(ctx context.Context, id int) (br *models.BagRequest, newlyConfirmed bool, err error) {
+	err = database.DB.QueryRow(ctx, `
+		WITH prev AS (SELECT id, status FROM bag_requests WHERE id = $1 FOR UPDATE)
+		UPDATE bag_requests b
+		SET status = CASE WHEN prev.status = 'new' THEN 'confirmed' ELSE b.status END,
+		    confirmed_at = COALESCE(b.confirmed_at, NOW())
+		FROM prev
+		WHERE b.id = prev.id
+		RETURNING prev.status = 'new'`, id).Scan(&newlyConfirmed)
+	if err != nil {
+		return nil, false, err
+	}
+	br, err = GetBagRequest(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	return br, newlyConfirmed, nil
+}
+
+// PurgeUnconfirmedBagRequests deletes requests that were not confirmed before the given time
+func PurgeUnconfirmedBagRequests(ctx context.Context, createdBefore time.Time) (int64, error) {
+	res, err := database.DB.Exec(ctx,
+		"DELETE FROM bag_requests WHERE status = 'new' AND created_at < $1", createdBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// bagRequestStatusColumns maps statuses set by admins to their timestamp column
+var bagRequestStatusColumns = map[string]string{
+	"shipped":  "shipped_at",
+	"returned": "returned_at",
+	"lost":     "lost_at",
+}
+
+// SetBagRequestStatus moves a request from status from to status to and records the time.
+// Returns ErrBagRequestStatusChanged if the request is not in status from (anymore).
+func SetBagRequestStatus(ctx context.Context, id int, from, to string) error {
+	column, ok := bagRequestStatusColumns[to]
+	if !ok {
+		return errors.New("unsupported bag request status " + to)
+	}
+	res, err := database.DB.Exec(ctx, `
+		UPDATE bag_requests SET status = $3, `+column+` = NOW()
+		WHERE id = $1 AND status = $2`, id, from, to)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrBagRequestStatusChanged
+	}
+	return nil
+}
+
+// CreateTokenForBagRequest creates an upload token for a confirmed request and links it,
+// returning the token ID and short bag code.
+// Returns ErrBagRequestStatusChanged if the request is not confirmed (anymore).
+func CreateTokenForBagRequest(ctx context.Context, id int, token, bagName string, maxUploads int) (int, string, error) {
+	tx, err := database.DB.Begin(ctx)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	if err := tx.QueryRow(ctx, "SELECT status FROM bag_requests WHERE id = $1 FOR UPDATE", id).Scan(&status); err != nil {
+		return 0, "", err
+	}
+	if status != "confirmed" {
+		return 0, "", ErrBagRequestStatusChanged
+	}
+
+	var tokenID int
+	var shortCode string
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO upload_tokens (token_hash, bag_name, max_uploads, total_sessions)
+		VALUES ($1, $2, $3, 1) RETURNING id, short_code`,
+		utils.HashToken(token), bagName, maxUploads).Scan(&tokenID, &shortCode); err != nil {
+		return 0, "", err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE bag_requests SET status = 'token_assigned', token_id = $2, token_assigned_at = NOW()
+		WHERE id = $1`, id, tokenID); err != nil {
+		return 0, "", err
+	}
+	return tokenID, shortCode, tx.Commit(ctx)
+}
+
+// UpdateBagRequestNotes replaces the admin notes of a request
+func UpdateBagRequestNotes(ctx context.Context, id int, notes string) (int64, error) {
+	res, err := database.DB.Exec(ctx, "UPDATE bag_requests SET admin_notes = $2 WHERE id = $1", id, notes)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+)
+
+// UpsertCities inserts or updates GeoNames cities keyed by geonameid
+func UpsertCities(ctx context.Context, cities []models.City) error {
+	batch := &pgx.Batch{}
+	for _, city := range cities {
+		batch.Queue(`
+			INSERT INTO cities (id, name, ascii_name, country_code, feature_code, type, lat, lon, population, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+			ON CONFLICT (id) DO UPDATE SET
+				name = EXCLUDED.name,
+				ascii_name = EXCLUDED.ascii_name,
+				country_code = EXCLUDED.country_code,
+				feature_code = EXCLUDED.feature_code,
+				type = EXCLUDED.type,
+				lat = EXCLUDED.lat,
+				lon = EXCLUDED.lon,
+				population = EXCLUDED.population,
+				updated_at = NOW()`,
+			city.ID, city.Name, city.ASCIIName, city.CountryCode, city.FeatureCode, city.Type, city.Lat, city.Lon, city.Population)
+	}
+	return database.DB.SendBatch(ctx, batch).Close()
+}
+
+// SearchCities finds cities by prefix or trigram similarity, larger places first
+func SearchCities(ctx context.Context, query string, limit int) ([]models.City, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT id, name, ascii_name, country_code, feature_code, type, lat, lon, population
+		FROM cities
+		WHERE lower(name) LIKE lower($3) OR lower(name) % lower($1)
+		ORDER BY (lower(name) LIKE lower($3)) DESC,
+		         similarity(lower(name), lower($1)) DESC,
+		         population DESC
+		LIMIT $2`,
+		query, limit, escapeLike(query)+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cities []models.City
+	for rows.Next() {
+		var c models.City
+		if err := rows.Scan(&c.ID, &c.Name, &c.ASCIIName, &c.CountryCode, &c.FeatureCode, &c.Type, &c.Lat, &c.Lon, &c.Population); err != nil {
+			return nil, err
+		}
+		cities = append(cities, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cities, nil
+}
+
+// escapeLike escapes LIKE wildcards so user input only matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+)
+
+// Queries for consent records (consents)
+
+// RecordConsent stores a session's agreement to a policy version
+func RecordConsent(ctx context.Context, tokenID, sessionNumber int, policyVersion string, texts []string, source, ipHash string) error {
+	_, err := database.DB.Exec(ctx, `
+		INSERT INTO consents (token_id, session_number, policy_version, texts, source, ip_hash)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		tokenID, sessionNumber, policyVersion, texts, source, ipHash)
+	return err
+}
+
+// HasConsent reports whether a session agreed to the given policy version
+func HasConsent(ctx context.Context, tokenID, sessionNumber int, policyVersion string) (bool, error) {
+	var ok bool
+	err := database.DB.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM consents
+			WHERE token_id = $1 AND session_number = $2 AND policy_version = $3
+		)`, tokenID, sessionNumber, policyVersion).Scan(&ok)
+	return ok, err
+}
+
+// ListConsents returns all consent records, oldest first, for the export
+func ListConsents(ctx context.Context) ([]models.Consent, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT c.id, c.token_id, COALESCE(t.bag_name, ''), c.session_number, c.policy_version,
+		       c.texts, c.source, c.ip_hash, c.created_at
+		FROM consents c
+		LEFT JOIN upload_tokens t ON t.id = c.token_id
+		ORDER BY c.created_at ASC, c.id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Consent{}
+	for rows.Next() {
+		var r models.Consent
+		if err := rows.Scan(&r.ID, &r.TokenID, &r.BagName, &r.SessionNumber, &r.PolicyVersion,
+			&r.Texts, &r.Source, &r.IPHash, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// CountConsentsByVersion returns the number of records per policy version, newest version first
+func CountConsentsByVersion(ctx context.Context) ([]models.ConsentVersionCount, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT policy_version, COUNT(*), MAX(created_at)
+		FROM consents
+		GROUP BY policy_version
+		ORDER BY MAX(created_at) DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.ConsentVersionCount{}
+	for rows.Next() {
+		var v models.ConsentVersionCount
+		if err := rows.Scan(&v.PolicyVersion, &v.Count, &v.LastAt); err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
@@ -2,12 +2,15 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"log"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 
 	"id-100/internal/database"
 	"id-100/internal/models"
+	"id-100/internal/utils"
 )
 
 // Repository layer for database queries - provides data access abstraction for handlers
@@ -263,24 +266,15 @@ func IncrementTokenUploadCount(ctx context.Context, tokenID int) error {
 	return err
 }
 
-// InsertBagRequest inserts a new bag request
-func InsertBagRequest(ctx context.Context, email string) error {
-	_, err := database.DB.Exec(ctx, "INSERT INTO bag_requests (email) VALUES ($1)", email)
-	return err
-}
-
-// GetBagNameByToken retrieves the bag name for a token
-func GetBagNameByToken(ctx context.Context, token string) (string, error) {
-	var bagName string
-	err := database.DB.QueryRow(ctx, "SELECT COALESCE(bag_name,'') FROM upload_tokens WHERE token = $1", token).Scan(&bagName)
-	return bagName, err
-}
-
-// UpdatePlayerNameAndCity updates the current player and city for a token
-func UpdatePlayerNameAndCity(ctx context.Context, playerName, playerCity, token string) error {
+// UpdatePlayerNameAndCity updates the current player and city for a token and
+// binds the session to deviceID; an empty deviceID leaves it unbound
+func UpdatePlayerNameAndCity(ctx context.Context, playerName, playerCity string, tokenID int, deviceID string) error {
 	_, err := database.DB.Exec(ctx,
-		"UPDATE upload_tokens SET current_player = $1, current_player_city = $2, session_started_at = NOW() WHERE token = $3",
-		playerName, playerCity, token)
+		`UPDATE upload_tokens
+		 SET current_player = $1, current_player_city = $2, session_started_at = NOW(),
+		     session_device = NULLIF($4, ''), handover_device = NULL, handover_requested_at = NULL
+		 WHERE id = $3`,
+		playerName, playerCity, tokenID, deviceID)
 	return err
 }
 
@@ -334,9 +328,7 @@ func DecrementTokenUploadCount(ctx context.Context, tokenID int) error {
 // GetAllTokens retrieves all upload tokens
 func GetAllTokens(ctx context.Context) ([]models.TokenInfo, error) {
 	rows, err := database.DB.Query(ctx, `
-		SELECT id, token, COALESCE(bag_name, ''), COALESCE(current_player, ''), COALESCE(current_player_city, ''),
-		       is_active, max_uploads, total_uploads, total_sessions,
-		       COALESCE(session_started_at, created_at), created_at
+		SELECT `+tokenInfoColumns+`
 		FROM upload_tokens
 		ORDER BY id ASC
 	`)
@@ -345,21 +337,7 @@ func GetAllTokens(ctx context.Context) ([]models.TokenInfo, error) {
 	}
 	defer rows.Close()
 
-	var tokens []models.TokenInfo
-	for rows.Next() {
-		var t models.TokenInfo
-		if err := rows.Scan(&t.ID, &t.Token, &t.BagName, &t.CurrentPlayer, &t.CurrentPlayerCity, &t.IsActive,
-			&t.MaxUploads, &t.TotalUploads, &t.TotalSessions, &t.SessionStartedAt, &t.CreatedAt); err != nil {
-			continue
-		}
-		t.Remaining = t.MaxUploads - t.TotalUploads
-		tokens = append(tokens, t)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return tokens, nil
+	return scanTokenInfos(rows)
 }
 
 // GetRecentContributions retrieves recent contributions for the admin dashboard
@@ -391,62 +369,6 @@ func GetRecentContributions(ctx context.Context, limit int) ([]models.RecentCont
 	return recentContribs, nil
 }
 
-// GetBagRequestCounts retrieves counts of open and handled bag requests
-func GetBagRequestCounts(ctx context.Context) (openCount, handledCount int, err error) {
-	err = database.DB.QueryRow(ctx, "SELECT COUNT(*) FROM bag_requests WHERE handled = FALSE").Scan(&openCount)
-	if err != nil {
-		return 0, 0, err
-	}
-
-	err = database.DB.QueryRow(ctx, "SELECT COUNT(*) FROM bag_requests WHERE handled = TRUE").Scan(&handledCount)
-	if err != nil {
-		return 0, 0, err
-	}
-
-	return openCount, handledCount, nil
-}
-
-// GetBagRequests retrieves bag requests optionally filtered by status
-func GetBagRequests(ctx context.Context, status string, limit int) ([]models.BagRequest, error) {
-	var query string
-	switch status {
-	case "open":
-		query = "SELECT id, email, created_at, handled FROM bag_requests WHERE handled = FALSE ORDER BY created_at DESC LIMIT $1"
-	case "handled":
-		query = "SELECT id, email, created_at, handled FROM bag_requests WHERE handled = TRUE ORDER BY created_at DESC LIMIT $1"
-	default:
-		query = "SELECT id, email, created_at, handled FROM bag_requests ORDER BY created_at DESC LIMIT $1"
-	}
-
-	reqRows, err := database.DB.Query(ctx, query, limit)
-	if err != nil {
-		return nil, err
-	}
-	defer reqRows.Close()
-
-	var bagRequests []models.BagRequest
-	for reqRows.Next() {
-		var br models.BagRequest
-		if err := reqRows.Scan(&br.ID, &br.Email, &br.CreatedAt, &br.Handled); err == nil {
-			bagRequests = append(bagRequests, br)
-		}
-	}
-	if err := reqRows.Err(); err != nil {
-		return nil, err
-	}
-
-	return bagRequests, nil
-}
-
-// MarkBagRequestHandled marks a bag request as handled
-func MarkBagRequestHandled(ctx context.Context, id int) (int64, error) {
-	res, err := database.DB.Exec(ctx, "UPDATE bag_requests SET handled = TRUE WHERE id = $1", id)
-	if err != nil {
-		return 0, err
-	}
-	return res.RowsAffected(), nil
-}
-
 // ResetToken resets a token for the next player
 func ResetToken(ctx context.Context, tokenID string) (int64, error) {
 	result, err := database.DB.Exec(ctx,
@@ -455,7 +377,10 @@ func ResetToken(ctx context.Context, tokenID string) (int64, error) {
 		     total_sessions = total_sessions + 1,
 		     session_started_at = NOW(),
 		     current_player = NULL,
-		     is_active = true
+		     is_active = true,
+		     session_device = NULL,
+		     handover_device = NULL,
+		     handover_requested_at = NULL
 		 WHERE id = $1`,
 		tokenID)
 	if err != nil {
@@ -464,6 +389,28 @@ func ResetToken(ctx context.Context, tokenID string) (int64, error) {
 	return result.RowsAffected(), nil
 }
 
+// EndSession ends session sessionNumber of a token like ResetToken, but leaves
+// is_active alone and does nothing if that session already ended. It returns
+// the start of the new session; ok is false if nothing was changed.
+func EndSession(ctx context.Context, tokenID, sessionNumber int) (startedAt time.Time, ok bool, err error) {
+	err = database.DB.QueryRow(ctx,
+		`UPDATE upload_tokens
+		 SET total_uploads = 0,
+		     total_sessions = total_sessions + 1,
+		     session_started_at = NOW(),
+		     current_player = NULL,
+		     session_device = NULL,
+		     handover_device = NULL,
+		     handover_requested_at = NULL
+		 WHERE id = $1 AND total_sessions = $2
+		 RETURNING session_started_at`,
+		tokenID, sessionNumber).Scan(&startedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return startedAt, false, nil
+	}
+	return startedAt, err == nil, err
+}
+
 // DeactivateToken deactivates a token
 func DeactivateToken(ctx context.Context, tokenID string) (int64, error) {
 	result, err := database.DB.Exec(ctx,
@@ -475,13 +422,17 @@ func DeactivateToken(ctx context.Context, tokenID string) (int64, error) {
 	return result.RowsAffected(), nil
 }
 
-// AssignTokenToPlayer assigns a token to a specific player
+// AssignTokenToPlayer assigns a token to a specific player; the next device
+// that opens the bag is bound to the session
 func AssignTokenToPlayer(ctx context.Context, tokenID, playerName string) (int64, error) {
 	result, err := database.DB.Exec(ctx,
 		`UPDATE upload_tokens 
 		 SET current_player = $1,
 		     session_started_at = NOW(),
-		     is_active = true
+		     is_active = true,
+		     session_device = NULL,
+		     handover_device = NULL,
+		     handover_requested_at = NULL
 		 WHERE id = $2`,
 		playerName, tokenID)
 	if err != nil {
@@ -490,22 +441,25 @@ func AssignTokenToPlayer(ctx context.Context, tokenID, playerName string) (int64
 	return result.RowsAffected(), nil
 }
 
-// CreateToken creates a new token/bag
-func CreateToken(ctx context.Context, token, bagName string, maxUploads int) (int, error) {
+// CreateToken creates a new token/bag and returns its ID and short bag code.
+// Only the hash of the token is stored.
+func CreateToken(ctx context.Context, token, bagName string, maxUploads int, expiresAt *time.Time) (int, string, error) {
 	var tokenID int
+	var shortCode string
 	err := database.DB.QueryRow(ctx,
-		`INSERT INTO upload_tokens (token, bag_name, max_uploads, total_sessions) 
-		 VALUES ($1, $2, $3, 1) RETURNING id`,
-		token, bagName, maxUploads).Scan(&tokenID)
-	return tokenID, err
+		`INSERT INTO upload_tokens (token_hash, bag_name, max_uploads, expires_at, total_sessions) 
+		 VALUES ($1, $2, $3, $4, 1) RETURNING id, short_code`,
+		utils.HashToken(token), bagName, maxUploads, expiresAt).Scan(&tokenID, &shortCode)
+	return tokenID, shortCode, err
 }
 
-// GetTokenByID retrieves a token and bag name by ID
-func GetTokenByID(ctx context.Context, tokenID string) (token, bagName string, err error) {
+// GetTokenSession retrieves the bag name, current session number and current
+// player of a token by ID; the player is empty if no session is running
+func GetTokenSession(ctx context.Context, tokenID string) (bagName string, sessionNumber int, player string, err error) {
 	err = database.DB.QueryRow(ctx,
-		"SELECT token, COALESCE(bag_name, '') FROM upload_tokens WHERE id = $1",
-		tokenID).Scan(&token, &bagName)
-	return token, bagName, err
+		"SELECT COALESCE(bag_name, ''), total_sessions, COALESCE(current_player, '') FROM upload_tokens WHERE id = $1",
+		tokenID).Scan(&bagName, &sessionNumber, &player)
+	return bagName, sessionNumber, player, err
 }
 
 // UpdateTokenQuota updates the max_uploads quota for a token
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+)
+
+// Queries for presigned direct-to-storage uploads
+
+// CreateDirectUpload stores a newly presigned upload
+func CreateDirectUpload(ctx context.Context, u models.DirectUpload) error {
+	_, err := database.DB.Exec(ctx, `
+		INSERT INTO direct_uploads (id, token_id, session_number, derive_number, object_key, content_type, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		u.ID, u.TokenID, u.SessionNumber, u.DeriveNumber, u.ObjectKey, u.ContentType, u.ExpiresAt)
+	return err
+}
+
+// CountPendingDirectUploads counts unexpired, unfinalised uploads of a token
+func CountPendingDirectUploads(ctx context.Context, tokenID int) (int, error) {
+	var n int
+	err := database.DB.QueryRow(ctx,
+		"SELECT COUNT(*) FROM direct_uploads WHERE token_id = $1 AND finalized_at IS NULL AND expires_at > NOW()",
+		tokenID).Scan(&n)
+	return n, err
+}
+
+// GetDirectUpload returns an upload owned by the given token
+func GetDirectUpload(ctx context.Context, id string, tokenID int) (*models.DirectUpload, error) {
+	var u models.DirectUpload
+	err := database.DB.QueryRow(ctx, `
+		SELECT id, token_id, session_number, derive_number, object_key, content_type, expires_at, contribution_id, finalized_at
+		FROM direct_uploads
+		WHERE id = $1 AND token_id = $2`,
+		id, tokenID).Scan(&u.ID, &u.TokenID, &u.SessionNumber, &u.DeriveNumber, &u.ObjectKey, &u.ContentType,
+		&u.ExpiresAt, &u.ContributionID, &u.FinalizedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// ClaimDirectUpload marks an upload as being finalised; false if another request got there first
+func ClaimDirectUpload(ctx context.Context, id string) (bool, error) {
+	result, err := database.DB.Exec(ctx,
+		"UPDATE direct_uploads SET finalized_at = NOW() WHERE id = $1 AND finalized_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// UnclaimDirectUpload reverts ClaimDirectUpload after a failed finalisation
+func UnclaimDirectUpload(ctx context.Context, id string) error {
+	_, err := database.DB.Exec(ctx,
+		"UPDATE direct_uploads SET finalized_at = NULL WHERE id = $1 AND contribution_id IS NULL", id)
+	return err
+}
+
+// CompleteDirectUpload links the contribution created from an upload
+func CompleteDirectUpload(ctx context.Context, id string, contributionID int) error {
+	_, err := database.DB.Exec(ctx,
+		"UPDATE direct_uploads SET contribution_id = $2 WHERE id = $1", id, contributionID)
+	return err
+}
+
+// DeleteDirectUpload removes an upload record
+func DeleteDirectUpload(ctx context.Context, id string) error {
+	_, err := database.DB.Exec(ctx, "DELETE FROM direct_uploads WHERE id = $1", id)
+	return err
+}
+
+// GetExpiredDirectUploads returns uploads without contribution that expired before the given time
+func GetExpiredDirectUploads(ctx context.Context, before time.Time) ([]models.DirectUpload, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT id, object_key FROM direct_uploads
+		WHERE contribution_id IS NULL AND expires_at < $1`, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.DirectUpload
+	for rows.Next() {
+		var u models.DirectUpload
+		if err := rows.Scan(&u.ID, &u.ObjectKey); err != nil {
+			return nil, err
+		}
+		list = append(list, u)
+	}
+	return list, rows.Err()
+}
+
+// PurgeFinalizedDirectUploads removes finalised upload records older than before
+func PurgeFinalizedDirectUploads(ctx context.Context, before time.Time) (int64, error) {
+	result, err := database.DB.Exec(ctx,
+		"DELETE FROM direct_uploads WHERE finalized_at IS NOT NULL AND contribution_id IS NOT NULL AND finalized_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
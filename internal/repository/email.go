@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+)
+
+// EnqueueEmail stores a rendered email in the outbox. created_at defaults to now
+// unless m.CreatedAt is set.
+func EnqueueEmail(ctx context.Context, m models.OutboxEmail) (int64, error) {
+	var createdAt *time.Time
+	if !m.CreatedAt.IsZero() {
+		createdAt = &m.CreatedAt
+	}
+	var id int64
+	err := database.DB.QueryRow(ctx, `
+		INSERT INTO email_outbox (template, to_address, subject, text_body, html_body, created_at)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6, NOW()))
+		RETURNING id`,
+		m.Template, m.To, m.Subject, m.TextBody, m.HTMLBody, createdAt).Scan(&id)
+	return id, err
+}
+
+// ClaimDueEmails returns up to limit pending emails that are due and pushes their
+// next attempt out by lease, so concurrent workers do not send the same email twice.
+func ClaimDueEmails(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEmail, error) {
+	rows, err := database.DB.Query(ctx, `
+		WITH due AS (
+			SELECT id FROM email_outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE email_outbox e
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM due
+		WHERE e.id = due.id
+		RETURNING e.id, e.template, e.to_address, e.subject, e.text_body, e.html_body, e.attempts`,
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.OutboxEmail
+	for rows.Next() {
+		var m models.OutboxEmail
+		if err := rows.Scan(&m.ID, &m.Template, &m.To, &m.Subject, &m.TextBody, &m.HTMLBody, &m.Attempts); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// MarkEmailSent records a successful send
+func MarkEmailSent(ctx context.Context, id int64) error {
+	_, err := database.DB.Exec(ctx, `
+		UPDATE email_outbox
+		SET status = 'sent', attempts = attempts + 1, last_error = '',
+		    last_attempt_at = NOW(), sent_at = NOW()
+		WHERE id = $1`, id)
+	return err
+}
+
+// MarkEmailAttemptFailed records a failed attempt. A nil nextAttempt marks the email failed for good.
+func MarkEmailAttemptFailed(ctx context.Context, id int64, lastError string, nextAttempt *time.Time) error {
+	_, err := database.DB.Exec(ctx, `
+		UPDATE email_outbox
+		SET status = CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+		    attempts = attempts + 1, last_error = $2,
+		    last_attempt_at = NOW(), next_attempt_at = COALESCE($3, next_attempt_at)
+		WHERE id = $1`,
+		id, lastError, nextAttempt)
+	return err
+}
+
+// GetLastEmailCreatedAt returns when the newest email of a template was queued, or nil if none was
+func GetLastEmailCreatedAt(ctx context.Context, template string) (*time.Time, error) {
+	var last *time.Time
+	err := database.DB.QueryRow(ctx,
+		"SELECT MAX(created_at) FROM email_outbox WHERE template = $1", template).Scan(&last)
+	return last, err
+}
+
+// PurgeEmails deletes sent and failed emails created before the given time
+func PurgeEmails(ctx context.Context, before time.Time) (int64, error) {
+	res, err := database.DB.Exec(ctx,
+		"DELETE FROM email_outbox WHERE status <> 'pending' AND created_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+)
+
+// Queries for the live contribution feed and the exhibition kiosk
+
+const feedContributionColumns = `
+		SELECT c.id, d.number, c.image_url, c.user_name, COALESCE(c.user_city, ''), COALESCE(c.user_comment, ''), ` + contributionCreatedAt + `
+		FROM contributions c
+		INNER JOIN deriven d ON d.id = c.derive_id`
+
+// GetAPIContribution returns a single contribution in its public API form
+func GetAPIContribution(ctx context.Context, id int) (*models.APIContribution, error) {
+	var ct models.APIContribution
+	err := database.DB.QueryRow(ctx, feedContributionColumns+`
+		WHERE c.id = $1`, id).
+		Scan(&ct.ID, &ct.DeriveNumber, &ct.ImageURL, &ct.UserName, &ct.UserCity, &ct.UserComment, &ct.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &ct, nil
+}
+
+// ListAPIContributionsAfterID returns contributions with an ID above afterID, oldest first.
+// Used to replay events a reconnecting feed client missed (Last-Event-ID).
+func ListAPIContributionsAfterID(ctx context.Context, afterID int, city string, deriveNumber, limit int) ([]models.APIContribution, error) {
+	return queryAPIContributions(ctx, feedContributionColumns+`
+		WHERE c.id > $1
+		  AND ($2 = '' OR LOWER(c.user_city) = LOWER($2))
+		  AND ($3 = 0 OR d.number = $3)
+		ORDER BY c.id ASC
+		LIMIT $4`, afterID, city, deriveNumber, limit)
+}
+
+// ListRecentAPIContributions returns the newest contributions
+func ListRecentAPIContributions(ctx context.Context, city string, deriveNumber, limit int) ([]models.APIContribution, error) {
+	return queryAPIContributions(ctx, feedContributionColumns+`
+		WHERE ($1 = '' OR LOWER(c.user_city) = LOWER($1))
+		  AND ($2 = 0 OR d.number = $2)
+		ORDER BY `+contributionCreatedAt+` DESC, c.id DESC
+		LIMIT $3`, city, deriveNumber, limit)
+}
+
+// ListRandomAPIContributions returns a random sample of contributions, excluding the IDs given
+func ListRandomAPIContributions(ctx context.Context, city string, deriveNumber, limit int, exclude []int) ([]models.APIContribution, error) {
+	if exclude == nil {
+		// A NULL array would make NOT (id = ANY(...)) filter out every row
+		exclude = []int{}
+	}
+	return queryAPIContributions(ctx, feedContributionColumns+`
+		WHERE ($1 = '' OR LOWER(c.user_city) = LOWER($1))
+		  AND ($2 = 0 OR d.number = $2)
+		  AND NOT (c.id = ANY($4))
+		ORDER BY RANDOM()
+		LIMIT $3`, city, deriveNumber, limit, exclude)
+}
+
+func queryAPIContributions(ctx context.Context, query string, args ...interface{}) ([]models.APIContribution, error) {
+	rows, err := database.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.APIContribution{}
+	for rows.Next() {
+		var ct models.APIContribution
+		if err := rows.Scan(&ct.ID, &ct.DeriveNumber, &ct.ImageURL, &ct.UserName, &ct.UserCity, &ct.UserComment, &ct.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, ct)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,438 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+)
+
+// Queries for data subject access and erasure requests. A subject is found by
+// the name typed at a bag, a single bag session, an email address or a player
+// account; FindSubjectData follows the links between them (sessions of a
+// player, upload logs of a session, contributions of an upload log).
+
+// FindSubjectData returns all data stored about the subject of q
+func FindSubjectData(ctx context.Context, q models.SubjectQuery) (*models.SubjectData, error) {
+	d := &models.SubjectData{}
+	var err error
+
+	if d.Players, err = findSubjectPlayers(ctx, q); err != nil {
+		return nil, err
+	}
+	playerIDs := []int{}
+	emails := []string{}
+	if q.Email != "" {
+		emails = append(emails, strings.ToLower(strings.TrimSpace(q.Email)))
+	}
+	for _, p := range d.Players {
+		playerIDs = append(playerIDs, p.ID)
+		if p.Email != "" {
+			emails = append(emails, strings.ToLower(p.Email))
+		}
+	}
+
+	if d.Sessions, err = findSubjectSessions(ctx, q, playerIDs); err != nil {
+		return nil, err
+	}
+	tokenIDs, numbers := sessionKeys(d.Sessions)
+
+	if d.UploadLogs, err = findSubjectUploadLogs(ctx, q.Name, tokenIDs, numbers); err != nil {
+		return nil, err
+	}
+	contribIDs := []int{}
+	for _, l := range d.UploadLogs {
+		if l.ContributionID != nil {
+			contribIDs = append(contribIDs, *l.ContributionID)
+		}
+	}
+	if d.Contributions, err = findSubjectContributions(ctx, q.Name, contribIDs); err != nil {
+		return nil, err
+	}
+
+	if d.BagRequests, err = queryBagRequests(ctx, bagRequestColumns+`
+		WHERE LOWER(b.email) = ANY($1)
+		   OR ($2 <> '' AND LOWER(TRIM(b.shipping_name)) = LOWER(TRIM($2)))
+		ORDER BY b.created_at ASC`, emails, q.Name); err != nil {
+		return nil, err
+	}
+	for _, br := range d.BagRequests {
+		emails = append(emails, strings.ToLower(br.Email))
+	}
+
+	if d.Consents, err = findSubjectConsents(ctx, tokenIDs, numbers); err != nil {
+		return nil, err
+	}
+	if d.Emails, err = findSubjectEmails(ctx, emails); err != nil {
+		return nil, err
+	}
+	if d.StagedObjects, err = findStagedObjects(ctx, tokenIDs, numbers); err != nil {
+		return nil, err
+	}
+
+	requestIDs := []int{}
+	for _, br := range d.BagRequests {
+		requestIDs = append(requestIDs, br.ID)
+	}
+	if d.WebhookDeliveries, err = findSubjectWebhookDeliveries(ctx, q.Name, tokenIDs, numbers, contribIDsOf(d.Contributions), requestIDs, emails); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func findSubjectPlayers(ctx context.Context, q models.SubjectQuery) ([]models.Player, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT id, COALESCE(email, ''), COALESCE(public_name, ''), created_at
+		FROM players
+		WHERE id = $1 OR ($2 <> '' AND LOWER(email) = LOWER(TRIM($2)))
+		ORDER BY id`, q.PlayerID, q.Email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Player{}
+	for rows.Next() {
+		var p models.Player
+		if err := rows.Scan(&p.ID, &p.Email, &p.PublicName, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+func findSubjectSessions(ctx context.Context, q models.SubjectQuery, playerIDs []int) ([]models.SubjectSession, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT s.token_id, t.bag_name, s.session_number, s.player_name, s.player_city,
+		       COALESCE(s.public_name, ''), s.name_display, s.started_at, s.ended_at,
+		       COALESCE(s.recap_key, ''), s.player_id
+		FROM token_sessions s
+		JOIN upload_tokens t ON t.id = s.token_id
+		WHERE ($1 <> '' AND LOWER(TRIM(s.player_name)) = LOWER(TRIM($1)))
+		   OR (s.token_id = $2 AND s.session_number = $3)
+		   OR s.player_id = ANY($4)
+		ORDER BY s.started_at ASC, s.token_id, s.session_number`,
+		q.Name, q.TokenID, q.SessionNumber, playerIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.SubjectSession{}
+	for rows.Next() {
+		var s models.SubjectSession
+		if err := rows.Scan(&s.TokenID, &s.BagName, &s.SessionNumber, &s.PlayerName, &s.PlayerCity,
+			&s.PublicName, &s.NameDisplay, &s.StartedAt, &s.EndedAt, &s.RecapKey, &s.PlayerID); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+func findSubjectUploadLogs(ctx context.Context, name string, tokenIDs, numbers []int) ([]models.SubjectUploadLog, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT l.id, l.token_id, l.session_number, l.derive_number, l.player_name, l.contribution_id, l.uploaded_at
+		FROM upload_logs l
+		WHERE (l.token_id, l.session_number) IN (SELECT * FROM unnest($1::int[], $2::int[]))
+		   OR ($3 <> '' AND LOWER(TRIM(l.player_name)) = LOWER(TRIM($3)))
+		ORDER BY l.uploaded_at ASC, l.id`, tokenIDs, numbers, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.SubjectUploadLog{}
+	for rows.Next() {
+		var l models.SubjectUploadLog
+		if err := rows.Scan(&l.ID, &l.TokenID, &l.SessionNumber, &l.DeriveNumber, &l.PlayerName,
+			&l.ContributionID, &l.UploadedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+// findSubjectContributions also matches the public name, which covers
+// contributions from before upload logs were written
+func findSubjectContributions(ctx context.Context, name string, ids []int) ([]models.SubjectContribution, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT c.id, d.number, c.image_url, c.user_name, COALESCE(c.user_city, ''),
+		       COALESCE(c.user_comment, ''), c.created_at
+		FROM contributions c
+		JOIN deriven d ON d.id = c.derive_id
+		WHERE c.id = ANY($1)
+		   OR ($2 <> '' AND LOWER(TRIM(c.user_name)) = LOWER(TRIM($2)))
+		ORDER BY c.created_at ASC, c.id`, ids, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.SubjectContribution{}
+	for rows.Next() {
+		var c models.SubjectContribution
+		if err := rows.Scan(&c.ID, &c.DeriveNumber, &c.ImageURL, &c.UserName, &c.UserCity,
+			&c.UserComment, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+func findSubjectConsents(ctx context.Context, tokenIDs, numbers []int) ([]models.Consent, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT c.id, c.token_id, COALESCE(t.bag_name, ''), c.session_number, c.policy_version,
+		       c.texts, c.source, c.ip_hash, c.created_at
+		FROM consents c
+		LEFT JOIN upload_tokens t ON t.id = c.token_id
+		WHERE (c.token_id, c.session_number) IN (SELECT * FROM unnest($1::int[], $2::int[]))
+		ORDER BY c.created_at ASC, c.id ASC`, tokenIDs, numbers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Consent{}
+	for rows.Next() {
+		var r models.Consent
+		if err := rows.Scan(&r.ID, &r.TokenID, &r.BagName, &r.SessionNumber, &r.PolicyVersion,
+			&r.Texts, &r.Source, &r.IPHash, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+func findSubjectEmails(ctx context.Context, addresses []string) ([]models.SubjectEmail, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT id, template, to_address, subject, status, created_at
+		FROM email_outbox
+		WHERE LOWER(to_address) = ANY($1)
+		ORDER BY created_at ASC, id`, addresses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.SubjectEmail{}
+	for rows.Next() {
+		var e models.SubjectEmail
+		if err := rows.Scan(&e.ID, &e.Template, &e.To, &e.Subject, &e.Status, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// findStagedObjects returns the storage keys of unfinished tus and direct uploads of the sessions
+func findStagedObjects(ctx context.Context, tokenIDs, numbers []int) ([]string, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT ch.object_key
+		FROM tus_upload_chunks ch
+		JOIN tus_uploads u ON u.id = ch.upload_id
+		WHERE (u.token_id, u.session_number) IN (SELECT * FROM unnest($1::int[], $2::int[]))
+		UNION ALL
+		SELECT object_key
+		FROM direct_uploads
+		WHERE finalized_at IS NULL
+		  AND (token_id, session_number) IN (SELECT * FROM unnest($1::int[], $2::int[]))`,
+		tokenIDs, numbers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// findSubjectWebhookDeliveries returns deliveries whose payload belongs to the
+// subject: session and contribution events of their sessions, their
+// contributions and bag requests, and events carrying the typed name, which
+// session events did before they sent the public name. contribution.deleted
+// only holds ids and is not matched, so the events of the erasure itself do
+// not count as remaining.
+func findSubjectWebhookDeliveries(ctx context.Context, name string, tokenIDs, numbers, contribIDs, requestIDs []int, emails []string) ([]models.SubjectWebhookDelivery, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT d.id, d.webhook_id, d.event, d.status, d.payload, d.created_at
+		FROM webhook_deliveries d, LATERAL (SELECT d.payload::jsonb -> 'data' AS data) p
+		WHERE (d.event IN ('session.started', 'session.ended', 'contribution.created')
+		       AND (p.data ->> 'token_id', p.data ->> 'session_number') IN
+		           (SELECT t::text, n::text FROM unnest($1::int[], $2::int[]) AS s(t, n)))
+		   OR (d.event = 'contribution.created' AND p.data ->> 'id' = ANY($3::int[]::text[]))
+		   OR (d.event = 'bag_request.created'
+		       AND (p.data ->> 'id' = ANY($4::int[]::text[]) OR LOWER(p.data ->> 'email') = ANY($5)))
+		   OR ($6 <> '' AND LOWER(TRIM(COALESCE(p.data ->> 'player_name', p.data ->> 'user_name'))) = LOWER(TRIM($6)))
+		ORDER BY d.created_at ASC, d.id`, tokenIDs, numbers, contribIDs, requestIDs, emails, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.SubjectWebhookDelivery{}
+	for rows.Next() {
+		var w models.SubjectWebhookDelivery
+		var payload string
+		if err := rows.Scan(&w.ID, &w.WebhookID, &w.Event, &w.Status, &payload, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		w.Payload = json.RawMessage(payload)
+		list = append(list, w)
+	}
+	return list, rows.Err()
+}
+
+// contribIDsOf returns the ids of contributions
+func contribIDsOf(contributions []models.SubjectContribution) []int {
+	ids := []int{}
+	for _, c := range contributions {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+// sessionKeys splits sessions into parallel token id and session number arrays for unnest
+func sessionKeys(sessions []models.SubjectSession) (tokenIDs, numbers []int) {
+	tokenIDs, numbers = []int{}, []int{}
+	for _, s := range sessions {
+		tokenIDs = append(tokenIDs, s.TokenID)
+		numbers = append(numbers, s.SessionNumber)
+	}
+	return tokenIDs, numbers
+}
+
+// EraseSubjectData deletes the rows of d in one transaction and returns the
+// number of deleted rows per kind. A running session is ended first, like a
+// bag reset, so the bag can be handed on. Webhook deliveries about the subject
+// are deleted, pending ones included. Consent records are kept: they hold no
+// name and are the proof of consent.
+func EraseSubjectData(ctx context.Context, d *models.SubjectData) (map[string]int, error) {
+	tokenIDs, numbers := sessionKeys(d.Sessions)
+	var logIDs, contribIDs, requestIDs, playerIDs []int
+	var emailIDs, deliveryIDs []int64
+	addresses := []string{}
+	for _, l := range d.UploadLogs {
+		logIDs = append(logIDs, l.ID)
+	}
+	for _, c := range d.Contributions {
+		contribIDs = append(contribIDs, c.ID)
+	}
+	for _, br := range d.BagRequests {
+		requestIDs = append(requestIDs, br.ID)
+	}
+	for _, p := range d.Players {
+		playerIDs = append(playerIDs, p.ID)
+		if p.Email != "" {
+			addresses = append(addresses, strings.ToLower(p.Email))
+		}
+	}
+	for _, e := range d.Emails {
+		emailIDs = append(emailIDs, e.ID)
+	}
+	for _, w := range d.WebhookDeliveries {
+		deliveryIDs = append(deliveryIDs, w.ID)
+	}
+
+	tx, err := database.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE upload_tokens t
+		SET total_uploads = 0,
+		    total_sessions = total_sessions + 1,
+		    session_started_at = NOW(),
+		    current_player = NULL,
+		    current_player_city = NULL,
+		    session_device = NULL,
+		    handover_device = NULL,
+		    handover_requested_at = NULL
+		FROM unnest($1::int[], $2::int[]) AS s(token_id, session_number)
+		WHERE t.id = s.token_id AND t.total_sessions = s.session_number`,
+		tokenIDs, numbers); err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	steps := []struct {
+		kind  string
+		query string
+		args  []any
+	}{
+		{"upload_logs", "DELETE FROM upload_logs WHERE id = ANY($1)", []any{logIDs}},
+		{"contributions", "DELETE FROM contributions WHERE id = ANY($1)", []any{contribIDs}},
+		{"staged_uploads", `
+			DELETE FROM tus_uploads
+			WHERE (token_id, session_number) IN (SELECT * FROM unnest($1::int[], $2::int[]))`, []any{tokenIDs, numbers}},
+		{"staged_uploads", `
+			DELETE FROM direct_uploads
+			WHERE (token_id, session_number) IN (SELECT * FROM unnest($1::int[], $2::int[]))`, []any{tokenIDs, numbers}},
+		{"sessions", `
+			DELETE FROM token_sessions
+			WHERE (token_id, session_number) IN (SELECT * FROM unnest($1::int[], $2::int[]))`, []any{tokenIDs, numbers}},
+		{"bag_requests", "DELETE FROM bag_requests WHERE id = ANY($1)", []any{requestIDs}},
+		{"login_links", "DELETE FROM player_login_links WHERE player_id = ANY($1) OR LOWER(email) = ANY($2)", []any{playerIDs, addresses}},
+		{"players", "DELETE FROM players WHERE id = ANY($1)", []any{playerIDs}},
+		{"emails", "DELETE FROM email_outbox WHERE id = ANY($1)", []any{emailIDs}},
+		{"webhook_deliveries", "DELETE FROM webhook_deliveries WHERE id = ANY($1)", []any{deliveryIDs}},
+	}
+	for _, s := range steps {
+		res, err := tx.Exec(ctx, s.query, s.args...)
+		if err != nil {
+			return nil, err
+		}
+		counts[s.kind] += int(res.RowsAffected())
+	}
+	return counts, tx.Commit(ctx)
+}
+
+// RecordPrivacyAudit stores an audit record of an access or erasure request;
+// verified stays NULL for exports
+func RecordPrivacyAudit(ctx context.Context, a *models.PrivacyAudit) error {
+	return database.DB.QueryRow(ctx, `
+		INSERT INTO privacy_audit (action, actor, reference, criteria, counts, verified, error)
+		VALUES ($1, $2, $3, $4, $5, CASE WHEN $1 = 'erase' THEN $6::boolean END, $7)
+		RETURNING id, created_at`,
+		a.Action, a.Actor, a.Reference, a.Criteria, a.Counts, a.Verified, a.Error).Scan(&a.ID, &a.CreatedAt)
+}
+
+// ListPrivacyAudits returns the latest audit records, newest first
+func ListPrivacyAudits(ctx context.Context, limit int) ([]models.PrivacyAudit, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT id, action, actor, reference, criteria, counts, COALESCE(verified, false), error, created_at
+		FROM privacy_audit
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.PrivacyAudit{}
+	for rows.Next() {
+		var a models.PrivacyAudit
+		if err := rows.Scan(&a.ID, &a.Action, &a.Actor, &a.Reference, &a.Criteria, &a.Counts,
+			&a.Verified, &a.Error, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"id-100/internal/attribution"
+	"id-100/internal/database"
+	"id-100/internal/journey"
+	"id-100/internal/models"
+)
+
+// Queries for the public bag journey. Player names only leave this file as
+// pseudonyms, and sessions whose player opted out carry no personal data.
+
+// GetTokenByJourneySlug returns the bag with the given journey slug
+func GetTokenByJourneySlug(ctx context.Context, slug string) (*models.TokenInfo, error) {
+	rows, err := database.DB.Query(ctx, `SELECT `+tokenInfoColumns+` FROM upload_tokens WHERE journey_slug = $1`, slug)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := scanTokenInfos(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return &tokens[0], nil
+}
+
+// ListJourneyStages returns the sessions of a bag in order, with pseudonymised
+// players and their contributions
+func ListJourneyStages(ctx context.Context, tokenID int) ([]models.JourneyStage, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT session_number, player_name, COALESCE(public_name, ''), name_display, player_city, started_at, ended_at, journey_hidden
+		FROM token_sessions
+		WHERE token_id = $1
+		ORDER BY session_number ASC`, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stages := []models.JourneyStage{}
+	index := map[int]int{}
+	for rows.Next() {
+		var s models.JourneyStage
+		var name, public, style, city string
+		if err := rows.Scan(&s.SessionNumber, &name, &public, &style, &city, &s.StartedAt, &s.EndedAt, &s.Hidden); err != nil {
+			return nil, err
+		}
+		if !s.Hidden {
+			s.Pseudonym = journey.Pseudonym(attribution.Name(name, public, attribution.Style(style)))
+			s.City = city
+		}
+		index[s.SessionNumber] = len(stages)
+		stages = append(stages, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cRows, err := database.DB.Query(ctx, `
+		SELECT ul.session_number, c.id, d.number, d.points, c.image_url, COALESCE(c.image_lqip, '')
+		FROM upload_logs ul
+		JOIN token_sessions s ON s.token_id = ul.token_id AND s.session_number = ul.session_number
+		JOIN contributions c ON c.id = ul.contribution_id
+		JOIN deriven d ON d.id = c.derive_id
+		WHERE ul.token_id = $1 AND NOT s.journey_hidden
+		ORDER BY ul.uploaded_at ASC`, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer cRows.Close()
+
+	for cRows.Next() {
+		var sessionNumber, points int
+		var jc models.JourneyContribution
+		if err := cRows.Scan(&sessionNumber, &jc.ID, &jc.DeriveNumber, &points, &jc.ImageURL, &jc.ImageLqip); err != nil {
+			return nil, err
+		}
+		i, ok := index[sessionNumber]
+		if !ok {
+			continue
+		}
+		stages[i].Contributions = append(stages[i].Contributions, jc)
+		stages[i].Points += points
+	}
+	return stages, cRows.Err()
+}
+
+// SetSessionJourneyHidden keeps a session out of the public journey (or shows it again)
+func SetSessionJourneyHidden(ctx context.Context, tokenID, sessionNumber int, hidden bool) error {
+	_, err := database.DB.Exec(ctx,
+		"UPDATE token_sessions SET journey_hidden = $3 WHERE token_id = $1 AND session_number = $2",
+		tokenID, sessionNumber, hidden)
+	return err
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+)
+
+// Queries for the participant upload API
+
+// IdempotencyKeyTTL is how long a completed upload can be replayed by key
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// idempotencyClaimTimeout frees keys whose upload never completed (e.g. crashed request)
+const idempotencyClaimTimeout = 5 * time.Minute
+
+// ListSessionUploads returns the uploads of a token session, newest first
+func ListSessionUploads(ctx context.Context, tokenID, sessionNumber int) ([]models.ParticipantUpload, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT c.id, d.number, d.points, c.image_url, COALESCE(c.image_lqip, ''), COALESCE(c.user_comment, ''), ul.uploaded_at
+		FROM upload_logs ul
+		JOIN contributions c ON c.id = ul.contribution_id
+		JOIN deriven d ON d.id = c.derive_id
+		WHERE ul.token_id = $1 AND ul.session_number = $2
+		ORDER BY ul.uploaded_at DESC`,
+		tokenID, sessionNumber)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.ParticipantUpload
+	for rows.Next() {
+		var u models.ParticipantUpload
+		if err := rows.Scan(&u.ID, &u.DeriveNumber, &u.Points, &u.ImageURL, &u.ImageLqip, &u.Comment, &u.UploadedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetSessionUpload returns a single upload of a token session
+func GetSessionUpload(ctx context.Context, contributionID, tokenID, sessionNumber int) (*models.ParticipantUpload, error) {
+	var u models.ParticipantUpload
+	err := database.DB.QueryRow(ctx, `
+		SELECT c.id, d.number, d.points, c.image_url, COALESCE(c.image_lqip, ''), COALESCE(c.user_comment, ''), ul.uploaded_at
+		FROM upload_logs ul
+		JOIN contributions c ON c.id = ul.contribution_id
+		JOIN deriven d ON d.id = c.derive_id
+		WHERE c.id = $1 AND ul.token_id = $2 AND ul.session_number = $3`,
+		contributionID, tokenID, sessionNumber).Scan(&u.ID, &u.DeriveNumber, &u.Points, &u.ImageURL, &u.ImageLqip, &u.Comment, &u.UploadedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetLastUploadTime returns the time of the latest upload in a token session, or nil
+func GetLastUploadTime(ctx context.Context, tokenID, sessionNumber int) (*time.Time, error) {
+	var lastUpload *time.Time
+	err := database.DB.QueryRow(ctx,
+		"SELECT MAX(uploaded_at) FROM upload_logs WHERE token_id = $1 AND session_number = $2",
+		tokenID, sessionNumber).Scan(&lastUpload)
+	return lastUpload, err
+}
+
+// ClaimIdempotencyKey reserves an idempotency key for a token.
+// If the key was already used for a completed upload, its contribution ID is returned
+// and claimed is false. claimed false with a nil ID means the upload is still in progress.
+func ClaimIdempotencyKey(ctx context.Context, tokenID int, key string) (contributionID *int, claimed bool, err error) {
+	var discard *int
+	err = database.DB.QueryRow(ctx, `
+		INSERT INTO upload_idempotency_keys (token_id, idempotency_key)
+		VALUES ($1, $2)
+		ON CONFLICT (token_id, idempotency_key) DO UPDATE
+			SET contribution_id = NULL, created_at = NOW()
+			WHERE upload_idempotency_keys.created_at < NOW() - make_interval(secs => $3)
+			   OR (upload_idempotency_keys.contribution_id IS NULL
+			       AND upload_idempotency_keys.created_at < NOW() - make_interval(secs => $4))
+		RETURNING contribution_id`,
+		tokenID, key, IdempotencyKeyTTL.Seconds(), idempotencyClaimTimeout.Seconds()).Scan(&discard)
+	if err == nil {
+		return nil, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, err
+	}
+
+	err = database.DB.QueryRow(ctx,
+		"SELECT contribution_id FROM upload_idempotency_keys WHERE token_id = $1 AND idempotency_key = $2",
+		tokenID, key).Scan(&contributionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Row vanished in between (contribution deleted); let the caller retry
+		return nil, false, nil
+	}
+	return contributionID, false, err
+}
+
+// CompleteIdempotencyKey records the contribution created for a claimed key
+func CompleteIdempotencyKey(ctx context.Context, tokenID int, key string, contributionID int) error {
+	_, err := database.DB.Exec(ctx,
+		"UPDATE upload_idempotency_keys SET contribution_id = $3 WHERE token_id = $1 AND idempotency_key = $2",
+		tokenID, key, contributionID)
+	return err
+}
+
+// ReleaseIdempotencyKey frees a claimed key after a failed upload so it can be retried
+func ReleaseIdempotencyKey(ctx context.Context, tokenID int, key string) error {
+	_, err := database.DB.Exec(ctx,
+		"DELETE FROM upload_idempotency_keys WHERE token_id = $1 AND idempotency_key = $2 AND contribution_id IS NULL",
+		tokenID, key)
+	return err
+}
+
+// PurgeIdempotencyKeys removes keys that can no longer be replayed
+func PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	result, err := database.DB.Exec(ctx,
+		"DELETE FROM upload_idempotency_keys WHERE created_at < NOW() - make_interval(secs => $1)",
+		IdempotencyKeyTTL.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
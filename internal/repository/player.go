@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+)
+
+// Queries for optional player accounts
+
+// CreatePlayer stores a new player with the hash of their recovery code and,
+// when signing up by magic link, their email address
+func CreatePlayer(ctx context.Context, codeHash, email string) (int, error) {
+	var id int
+	err := database.DB.QueryRow(ctx,
+		"INSERT INTO players (recovery_code_hash, email) VALUES ($1, NULLIF($2, '')) RETURNING id",
+		codeHash, email).Scan(&id)
+	return id, err
+}
+
+// GetPlayer returns a player by ID; pgx.ErrNoRows if there is none
+func GetPlayer(ctx context.Context, id int) (*models.Player, error) {
+	p := &models.Player{}
+	err := database.DB.QueryRow(ctx, `
+		SELECT id, COALESCE(email, ''), COALESCE(public_name, ''), created_at
+		FROM players WHERE id = $1`, id).Scan(&p.ID, &p.Email, &p.PublicName, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// FindPlayerByCode returns the ID of the player with the given recovery code
+// hash and records the sign-in; pgx.ErrNoRows if there is none
+func FindPlayerByCode(ctx context.Context, codeHash string) (int, error) {
+	var id int
+	err := database.DB.QueryRow(ctx,
+		"UPDATE players SET last_seen_at = NOW() WHERE recovery_code_hash = $1 RETURNING id",
+		codeHash).Scan(&id)
+	return id, err
+}
+
+// FindPlayerByEmail returns the ID of the player with the given address
+// (case-insensitive) and records the sign-in; pgx.ErrNoRows if there is none
+func FindPlayerByEmail(ctx context.Context, email string) (int, error) {
+	var id int
+	err := database.DB.QueryRow(ctx,
+		"UPDATE players SET last_seen_at = NOW() WHERE LOWER(email) = LOWER($1) RETURNING id",
+		email).Scan(&id)
+	return id, err
+}
+
+// SetPlayerEmail adds a confirmed address to a player. An address used by
+// another player is a unique violation.
+func SetPlayerEmail(ctx context.Context, id int, email string) error {
+	_, err := database.DB.Exec(ctx, "UPDATE players SET email = $2 WHERE id = $1", id, email)
+	return err
+}
+
+// CreatePlayerLoginLink stores the hash of a magic link token. playerID is set
+// when a signed-in player adds their address.
+func CreatePlayerLoginLink(ctx context.Context, tokenHash, email string, playerID *int, expiresAt time.Time) error {
+	_, err := database.DB.Exec(ctx,
+		"INSERT INTO player_login_links (token_hash, email, player_id, expires_at) VALUES ($1, $2, $3, $4)",
+		tokenHash, email, playerID, expiresAt)
+	return err
+}
+
+// CountPlayerLoginLinksSince counts magic links sent to an address (case-insensitive) after since
+func CountPlayerLoginLinksSince(ctx context.Context, email string, since time.Time) (int, error) {
+	var n int
+	err := database.DB.QueryRow(ctx,
+		"SELECT COUNT(*) FROM player_login_links WHERE LOWER(email) = LOWER($1) AND created_at > $2",
+		email, since).Scan(&n)
+	return n, err
+}
+
+// UsePlayerLoginLink marks a magic link as used and returns its address and
+// player. Used, expired and unknown links return pgx.ErrNoRows.
+func UsePlayerLoginLink(ctx context.Context, tokenHash string) (email string, playerID *int, err error) {
+	err = database.DB.QueryRow(ctx, `
+		UPDATE player_login_links SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING email, player_id`, tokenHash).Scan(&email, &playerID)
+	return email, playerID, err
+}
+
+// PurgePlayerLoginLinks deletes magic links that expired before the given time
+func PurgePlayerLoginLinks(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	res, err := database.DB.Exec(ctx, "DELETE FROM player_login_links WHERE expires_at < $1", expiredBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// LinkPlayerSession links a bag session to a player unless it belongs to
+// someone already. The player's public name, if any, is applied to it.
+// It reports whether the session was linked.
+func LinkPlayerSession(ctx context.Context, playerID, tokenID, sessionNumber int) (bool, error) {
+	tx, err := database.DB.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(ctx, `
+		UPDATE token_sessions s
+		SET player_id = p.id, public_name = COALESCE(p.public_name, s.public_name)
+		FROM players p
+		WHERE p.id = $1 AND s.token_id = $2 AND s.session_number = $3 AND s.player_id IS NULL`,
+		playerID, tokenID, sessionNumber)
+	if err != nil {
+		return false, err
+	}
+	if res.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE contributions c
+		SET user_name = p.public_name
+		FROM upload_logs ul, players p
+		WHERE ul.contribution_id = c.id AND ul.token_id = $2 AND ul.session_number = $3
+		  AND p.id = $1 AND p.public_name IS NOT NULL`,
+		playerID, tokenID, sessionNumber); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// LinkPlayerSessionByRecap links the session of a recap key to a player, see
+// LinkPlayerSession. pgx.ErrNoRows if the key is unknown.
+func LinkPlayerSessionByRecap(ctx context.Context, playerID int, key string) (bool, error) {
+	var tokenID, sessionNumber int
+	err := database.DB.QueryRow(ctx,
+		"SELECT token_id, session_number FROM token_sessions WHERE recap_key = $1", key).Scan(&tokenID, &sessionNumber)
+	if err != nil {
+		return false, err
+	}
+	return LinkPlayerSession(ctx, playerID, tokenID, sessionNumber)
+}
+
+// ListPlayerSessions returns the sessions linked to a player, newest first,
+// with their upload count and points
+func ListPlayerSessions(ctx context.Context, playerID int) ([]models.PlayerSession, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT s.token_id, COALESCE(t.bag_name, ''), s.session_number, s.player_name, s.player_city,
+		       s.started_at, s.ended_at, COALESCE(s.recap_key, ''),
+		       COUNT(c.id), COALESCE(SUM(d.points), 0)
+		FROM token_sessions s
+		JOIN upload_tokens t ON t.id = s.token_id
+		LEFT JOIN upload_logs ul ON ul.token_id = s.token_id AND ul.session_number = s.session_number
+		LEFT JOIN contributions c ON c.id = ul.contribution_id
+		LEFT JOIN deriven d ON d.id = c.derive_id
+		WHERE s.player_id = $1
+		GROUP BY s.token_id, t.bag_name, s.session_number
+		ORDER BY s.started_at DESC`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.PlayerSession{}
+	for rows.Next() {
+		var s models.PlayerSession
+		if err := rows.Scan(&s.TokenID, &s.BagName, &s.SessionNumber, &s.PlayerName, &s.PlayerCity,
+			&s.StartedAt, &s.EndedAt, &s.RecapKey, &s.Uploads, &s.Points); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// ListPlayerContributions returns the derive and points of every contribution
+// in the sessions linked to a player
+func ListPlayerContributions(ctx context.Context, playerID int) ([]models.PlayerContribution, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT d.number, COALESCE(d.points, 0)
+		FROM token_sessions s
+		JOIN upload_logs ul ON ul.token_id = s.token_id AND ul.session_number = s.session_number
+		JOIN contributions c ON c.id = ul.contribution_id
+		JOIN deriven d ON d.id = c.derive_id
+		WHERE s.player_id = $1`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.PlayerContribution{}
+	for rows.Next() {
+		var c models.PlayerContribution
+		if err := rows.Scan(&c.DeriveNumber, &c.Points); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// RenamePlayer sets the public name of a player and applies it to the
+// sessions and contributions linked to them. It returns the number of renamed
+// contributions. The names typed in at the bags stay visible to admins.
+func RenamePlayer(ctx context.Context, playerID int, name string) (int64, error) {
+	tx, err := database.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "UPDATE players SET public_name = $2 WHERE id = $1", playerID, name); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, "UPDATE token_sessions SET public_name = $2 WHERE player_id = $1", playerID, name); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(ctx, `
+		UPDATE contributions c
+		SET user_name = $2
+		FROM upload_logs ul
+		JOIN token_sessions s ON s.token_id = ul.token_id AND s.session_number = ul.session_number
+		WHERE ul.contribution_id = c.id AND s.player_id = $1`, playerID, name)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), tx.Commit(ctx)
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"id-100/internal/attribution"
+	"id-100/internal/database"
+	"id-100/internal/models"
+	"id-100/internal/recap"
+)
+
+// Queries for session recaps
+
+// CreateSessionRecap gives a session a new recap key and returns it; a session
+// that already has one keeps it. It returns pgx.ErrNoRows if the session is
+// not in the history.
+func CreateSessionRecap(ctx context.Context, tokenID, sessionNumber int) (string, error) {
+	key, err := recap.NewKey()
+	if err != nil {
+		return "", err
+	}
+	var stored string
+	err = database.DB.QueryRow(ctx, `
+		UPDATE token_sessions SET recap_key = COALESCE(recap_key, $3)
+		WHERE token_id = $1 AND session_number = $2
+		RETURNING recap_key`,
+		tokenID, sessionNumber, key).Scan(&stored)
+	return stored, err
+}
+
+// GetSessionRecap returns the session with the given recap key and its
+// contributions, with the player's public name; pgx.ErrNoRows if there is none
+func GetSessionRecap(ctx context.Context, key string) (*models.SessionRecap, error) {
+	r := &models.SessionRecap{Key: key}
+	var tokenID int
+	var typed, public, style string
+	err := database.DB.QueryRow(ctx, `
+		SELECT s.token_id, COALESCE(t.bag_name, ''), s.session_number, s.player_name, COALESCE(s.public_name, ''), s.name_display,
+		       s.player_city, s.started_at, s.ended_at, s.player_id IS NOT NULL
+		FROM token_sessions s
+		JOIN upload_tokens t ON t.id = s.token_id
+		WHERE s.recap_key = $1`, key).Scan(&tokenID, &r.BagName, &r.SessionNumber, &typed, &public, &style,
+		&r.PlayerCity, &r.StartedAt, &r.EndedAt, &r.Linked)
+	if err != nil {
+		return nil, err
+	}
+	r.PlayerName = attribution.Name(typed, public, attribution.Style(style))
+
+	rows, err := database.DB.Query(ctx, `
+		SELECT c.id, d.number, d.title, d.points, c.image_url, COALESCE(c.image_lqip, ''), COALESCE(c.user_comment, ''), COALESCE(ul.uploaded_at, c.created_at)
+		FROM upload_logs ul
+		JOIN contributions c ON c.id = ul.contribution_id
+		JOIN deriven d ON d.id = c.derive_id
+		WHERE ul.token_id = $1 AND ul.session_number = $2
+		ORDER BY ul.uploaded_at ASC`, tokenID, r.SessionNumber)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.RecapContribution
+		if err := rows.Scan(&c.ID, &c.DeriveNumber, &c.DeriveTitle, &c.Points, &c.ImageURL, &c.ImageLqip, &c.Comment, &c.UploadedAt); err != nil {
+			return nil, err
+		}
+		r.Contributions = append(r.Contributions, c)
+	}
+	return r, rows.Err()
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+
+	"id-100/internal/database"
+	"id-100/internal/rules"
+)
+
+// Queries for per-bag rulesets
+
+const rulesetColumns = `id, name, cooldown_seconds, session_uploads, lifetime_uploads, session_minutes, idle_minutes, allowed_derives`
+
+// ListRulesets returns all rulesets by name with the number of bags using each
+func ListRulesets(ctx context.Context) ([]rules.Ruleset, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT `+rulesetColumns+`,
+		       (SELECT COUNT(*) FROM upload_tokens WHERE ruleset_id = rulesets.id)
+		FROM rulesets
+		ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []rules.Ruleset{}
+	for rows.Next() {
+		var r rules.Ruleset
+		if err := rows.Scan(&r.ID, &r.Name, &r.CooldownSeconds, &r.SessionUploads, &r.LifetimeUploads,
+			&r.SessionMinutes, &r.IdleMinutes, &r.AllowedDerives, &r.Bags); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// GetTokenRuleset returns the ruleset assigned to a token, or rules.Default
+func GetTokenRuleset(ctx context.Context, tokenID int) (rules.Ruleset, error) {
+	r := rules.Default
+	err := database.DB.QueryRow(ctx, `
+		SELECT COALESCE(r.id, 0), COALESCE(r.name, $2), COALESCE(r.cooldown_seconds, $3),
+		       COALESCE(r.session_uploads, 0), COALESCE(r.lifetime_uploads, 0),
+		       COALESCE(r.session_minutes, 0), COALESCE(r.idle_minutes, 0), r.allowed_derives
+		FROM upload_tokens t
+		LEFT JOIN rulesets r ON r.id = t.ruleset_id
+		WHERE t.id = $1`,
+		tokenID, rules.Default.Name, rules.Default.CooldownSeconds).Scan(&r.ID, &r.Name, &r.CooldownSeconds,
+		&r.SessionUploads, &r.LifetimeUploads, &r.SessionMinutes, &r.IdleMinutes, &r.AllowedDerives)
+	return r, err
+}
+
+// CountTokenUploads returns the number of uploads of a token over all sessions
+func CountTokenUploads(ctx context.Context, tokenID int) (int, error) {
+	var n int
+	err := database.DB.QueryRow(ctx, "SELECT COUNT(*) FROM upload_logs WHERE token_id = $1", tokenID).Scan(&n)
+	return n, err
+}
+
+// CreateRuleset stores a validated ruleset and returns its ID
+func CreateRuleset(ctx context.Context, r rules.Ruleset) (int, error) {
+	var id int
+	err := database.DB.QueryRow(ctx, `
+		INSERT INTO rulesets (name, cooldown_seconds, session_uploads, lifetime_uploads, session_minutes, idle_minutes, allowed_derives)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		r.Name, r.CooldownSeconds, r.SessionUploads, r.LifetimeUploads, r.SessionMinutes, r.IdleMinutes, derivesArray(r.AllowedDerives)).Scan(&id)
+	return id, err
+}
+
+// UpdateRuleset replaces all fields of a ruleset
+func UpdateRuleset(ctx context.Context, r rules.Ruleset) (int64, error) {
+	res, err := database.DB.Exec(ctx, `
+		UPDATE rulesets
+		SET name = $2, cooldown_seconds = $3, session_uploads = $4, lifetime_uploads = $5,
+		    session_minutes = $6, idle_minutes = $7, allowed_derives = $8, updated_at = NOW()
+		WHERE id = $1`,
+		r.ID, r.Name, r.CooldownSeconds, r.SessionUploads, r.LifetimeUploads, r.SessionMinutes, r.IdleMinutes, derivesArray(r.AllowedDerives))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// DeleteRuleset removes a ruleset; its bags fall back to the defaults
+func DeleteRuleset(ctx context.Context, id int) (int64, error) {
+	res, err := database.DB.Exec(ctx, "DELETE FROM rulesets WHERE id = $1", id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// SetTokenRuleset assigns a ruleset to a token; nil removes the assignment
+func SetTokenRuleset(ctx context.Context, tokenID int, rulesetID *int) (int64, error) {
+	res, err := database.DB.Exec(ctx, "UPDATE upload_tokens SET ruleset_id = $2 WHERE id = $1", tokenID, rulesetID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// derivesArray stores "all derives" as an empty array instead of NULL
+func derivesArray(nums []int) []int {
+	if nums == nil {
+		return []int{}
+	}
+	return nums
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+	"id-100/internal/search"
+)
+
+// SearchContent runs a ranked full-text search over deriven and contributions.
+// The query is matched both German-stemmed and verbatim so city names and
+// inflected words hit alike. Returns the page of results and the total hit count.
+func SearchContent(ctx context.Context, query string, limit, offset int) ([]models.SearchResult, int, error) {
+	rows, err := database.DB.Query(ctx, `
+		WITH q AS (
+			SELECT websearch_to_tsquery('german', $1) || websearch_to_tsquery('simple', $1) AS query
+		)
+		SELECT kind, number, title, contribution_id, image_url, image_lqip, user_city, snippet, rank, created_at,
+		       COUNT(*) OVER () AS total
+		FROM (
+			SELECT 'derive' AS kind, d.number, d.title, 0 AS contribution_id,
+			       '' AS image_url, '' AS image_lqip, '' AS user_city,
+			       ts_headline('german', translate(d.title || ': ' || d.description, chr(2) || chr(3), ''), q.query, $4) AS snippet,
+			       ts_rank(d.search_vector, q.query) AS rank,
+			       COALESCE(d.created_at, NOW()) AS created_at
+			FROM deriven d, q
+			WHERE d.search_vector @@ q.query
+			UNION ALL
+			SELECT 'contribution', d.number, d.title, c.id,
+			       c.image_url, COALESCE(c.image_lqip, ''), COALESCE(c.user_city, ''),
+			       ts_headline('german', translate(concat_ws(' · ', NULLIF(c.user_city, ''), NULLIF(c.user_comment, '')), chr(2) || chr(3), ''),
+			                   q.query, $4),
+			       ts_rank(c.search_vector, q.query),
+			       COALESCE(c.created_at, NOW())
+			FROM contributions c
+			INNER JOIN deriven d ON d.id = c.derive_id, q
+			WHERE c.search_vector @@ q.query
+		) hits
+		ORDER BY rank DESC, created_at DESC
+		LIMIT $2 OFFSET $3`,
+		query, limit, offset, search.HeadlineOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	total := 0
+	for rows.Next() {
+		var r models.SearchResult
+		var rank float32
+		if err := rows.Scan(&r.Kind, &r.DeriveNumber, &r.DeriveTitle, &r.ContributionID, &r.ImageUrl, &r.ImageLqip,
+			&r.UserCity, &r.Snippet, &rank, &r.CreatedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		r.Rank = float64(rank)
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+	"id-100/internal/utils"
+)
+
+// Upload tokens are stored as SHA-256 hashes (utils.HashToken). The plain
+// token exists only in the response that creates or rotates it.
+
+// tokenInfoColumns is the column list scanned by scanTokenInfos
+const tokenInfoColumns = `id, short_code, journey_slug, COALESCE(bag_name, ''), group_label, COALESCE(current_player, ''), COALESCE(current_player_city, ''),
+		       is_active, max_uploads, total_uploads, total_sessions,
+		       COALESCE(session_started_at, created_at), created_at, expires_at, session_device IS NOT NULL, COALESCE(ruleset_id, 0)`
+
+func scanTokenInfos(rows pgx.Rows) ([]models.TokenInfo, error) {
+	defer rows.Close()
+
+	var tokens []models.TokenInfo
+	for rows.Next() {
+		var t models.TokenInfo
+		if err := rows.Scan(&t.ID, &t.ShortCode, &t.JourneySlug, &t.BagName, &t.GroupLabel, &t.CurrentPlayer, &t.CurrentPlayerCity, &t.IsActive,
+			&t.MaxUploads, &t.TotalUploads, &t.TotalSessions, &t.SessionStartedAt, &t.CreatedAt, &t.ExpiresAt, &t.DeviceBound, &t.RulesetID); err != nil {
+			continue
+		}
+		t.Remaining = t.MaxUploads - t.TotalUploads
+		t.Expired = t.ExpiresAt != nil && !t.ExpiresAt.After(time.Now())
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// CreateTokenBatch inserts all tokens in one transaction, so a batch is created
+// completely or not at all. Token, BagName, MaxUploads, GroupLabel and ExpiresAt
+// are read from each element; ID, ShortCode and CreatedAt are filled in.
+func CreateTokenBatch(ctx context.Context, tokens []models.TokenInfo) error {
+	tx, err := database.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for i := range tokens {
+		t := &tokens[i]
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO upload_tokens (token_hash, bag_name, group_label, max_uploads, expires_at, total_sessions)
+			VALUES ($1, $2, $3, $4, $5, 1) RETURNING id, short_code, journey_slug, created_at`,
+			utils.HashToken(t.Token), t.BagName, t.GroupLabel, t.MaxUploads, t.ExpiresAt).Scan(&t.ID, &t.ShortCode, &t.JourneySlug, &t.CreatedAt); err != nil {
+			return err
+		}
+		t.IsActive = true
+		t.TotalSessions = 1
+		t.Remaining = t.MaxUploads
+	}
+	return tx.Commit(ctx)
+}
+
+// GetTokensByGroup retrieves the tokens of one group, or all tokens for an empty group
+func GetTokensByGroup(ctx context.Context, group string) ([]models.TokenInfo, error) {
+	if group == "" {
+		return GetAllTokens(ctx)
+	}
+	rows, err := database.DB.Query(ctx, `
+		SELECT `+tokenInfoColumns+`
+		FROM upload_tokens
+		WHERE group_label = $1
+		ORDER BY id ASC
+	`, group)
+	if err != nil {
+		return nil, err
+	}
+	return scanTokenInfos(rows)
+}
+
+// GetTokenGroups returns all non-empty group labels, newest group first
+func GetTokenGroups(ctx context.Context) ([]string, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT group_label FROM upload_tokens
+		WHERE group_label <> ''
+		GROUP BY group_label
+		ORDER BY MAX(created_at) DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []string
+	for rows.Next() {
+		var g string
+		if err := rows.Scan(&g); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// GetTokensByIDs retrieves the given tokens in id order; unknown ids are skipped
+func GetTokensByIDs(ctx context.Context, ids []int) ([]models.TokenInfo, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT `+tokenInfoColumns+`
+		FROM upload_tokens
+		WHERE id = ANY($1)
+		ORDER BY id ASC
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	return scanTokenInfos(rows)
+}
+
+// GetTokenHashByShortCode resolves a normalized bag code to the hash of its upload token
+func GetTokenHashByShortCode(ctx context.Context, code string) (string, error) {
+	var tokenHash string
+	err := database.DB.QueryRow(ctx, "SELECT token_hash FROM upload_tokens WHERE short_code = $1", code).Scan(&tokenHash)
+	return tokenHash, err
+}
+
+// GetTokenShortCode retrieves the bag code and name of a token by ID
+func GetTokenShortCode(ctx context.Context, tokenID string) (code, bagName string, err error) {
+	err = database.DB.QueryRow(ctx,
+		"SELECT short_code, COALESCE(bag_name, '') FROM upload_tokens WHERE id = $1",
+		tokenID).Scan(&code, &bagName)
+	return code, bagName, err
+}
+
+// RotateToken replaces the token and the bag code of a bag. Old QR codes,
+// short links and browser sessions stop working; the uploads stay.
+func RotateToken(ctx context.Context, tokenID, token string) (shortCode, bagName string, err error) {
+	err = database.DB.QueryRow(ctx, `
+		UPDATE upload_tokens
+		SET token_hash = $2, short_code = generate_bag_code(), token_rotated_at = NOW(),
+		    session_device = NULL, handover_device = NULL, handover_requested_at = NULL
+		WHERE id = $1
+		RETURNING short_code, COALESCE(bag_name, '')`,
+		tokenID, utils.HashToken(token)).Scan(&shortCode, &bagName)
+	return shortCode, bagName, err
+}
+
+// UpdateTokenExpiry sets or, with nil, clears the expiry of a token
+func UpdateTokenExpiry(ctx context.Context, tokenID string, expiresAt *time.Time) (int64, error) {
+	result, err := database.DB.Exec(ctx,
+		"UPDATE upload_tokens SET expires_at = $1 WHERE id = $2",
+		expiresAt, tokenID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// ReleaseTokenDevice lifts the device binding of a running session so another
+// device can continue it, e.g. when the player's phone died
+func ReleaseTokenDevice(ctx context.Context, tokenID string) (int64, error) {
+	result, err := database.DB.Exec(ctx,
+		"UPDATE upload_tokens SET session_device = NULL, handover_device = NULL, handover_requested_at = NULL WHERE id = $1",
+		tokenID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// RequestHandover records that deviceID wants to take over the running session
+// of a bag bound to another device. It reports false if there is no such session.
+func RequestHandover(ctx context.Context, tokenHash, deviceID string) (bool, error) {
+	result, err := database.DB.Exec(ctx, `
+		UPDATE upload_tokens SET handover_device = $2, handover_requested_at = NOW()
+		WHERE token_hash = $1 AND session_device IS NOT NULL AND session_device <> $2`,
+		tokenHash, deviceID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// ApproveHandover ends the running session like ResetToken and binds the bag
+// to the requesting device, so only it can start the next session. Requests
+// older than since are ignored. It reports false if no request was pending.
+func ApproveHandover(ctx context.Context, tokenID int, since time.Time) (bool, error) {
+	result, err := database.DB.Exec(ctx, `
+		UPDATE upload_tokens
+		SET total_uploads = 0,
+		    total_sessions = total_sessions + 1,
+		    session_started_at = NOW(),
+		    current_player = NULL,
+		    session_device = handover_device,
+		    handover_device = NULL,
+		    handover_requested_at = NULL
+		WHERE id = $1 AND handover_device IS NOT NULL AND handover_requested_at > $2`,
+		tokenID, since)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// DeclineHandover drops a pending handover request
+func DeclineHandover(ctx context.Context, tokenID int) error {
+	_, err := database.DB.Exec(ctx,
+		"UPDATE upload_tokens SET handover_device = NULL, handover_requested_at = NULL WHERE id = $1",
+		tokenID)
+	return err
+}
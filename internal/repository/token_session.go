@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"id-100/internal/attribution"
+	"id-100/internal/database"
+	"id-100/internal/models"
+)
+
+// Queries for the session history of a bag (token_sessions and upload_logs)
+
+// GetTokenInfo returns a single token by ID
+func GetTokenInfo(ctx context.Context, tokenID int) (*models.TokenInfo, error) {
+	rows, err := database.DB.Query(ctx, `SELECT `+tokenInfoColumns+` FROM upload_tokens WHERE id = $1`, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := scanTokenInfos(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return &tokens[0], nil
+}
+
+// ListBagSessions returns all sessions of a bag, newest first, with their
+// uploads. Sessions that only appear in the upload log are included too.
+func ListBagSessions(ctx context.Context, tokenID, currentSession int) ([]models.BagSession, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT session_number, player_name, COALESCE(public_name, ''), name_display, player_city, started_at, ended_at,
+		       journey_hidden, COALESCE(recap_key, '')
+		FROM token_sessions
+		WHERE token_id = $1
+		ORDER BY session_number DESC`, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.BagSession{}
+	index := map[int]int{}
+	for rows.Next() {
+		var s models.BagSession
+		var style string
+		if err := rows.Scan(&s.SessionNumber, &s.PlayerName, &s.PublicName, &style, &s.PlayerCity, &s.StartedAt, &s.EndedAt,
+			&s.JourneyHidden, &s.RecapKey); err != nil {
+			return nil, err
+		}
+		if s.PublicName = attribution.Name(s.PlayerName, s.PublicName, attribution.Style(style)); s.PublicName == s.PlayerName {
+			s.PublicName = ""
+		}
+		index[s.SessionNumber] = len(sessions)
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	uRows, err := database.DB.Query(ctx, `
+		SELECT ul.session_number, ul.player_name, COALESCE(ul.uploaded_at, c.created_at), c.id, d.number, d.points,
+		       c.image_url, COALESCE(c.image_lqip, ''), COALESCE(c.user_comment, '')
+		FROM upload_logs ul
+		JOIN contributions c ON c.id = ul.contribution_id
+		JOIN deriven d ON d.id = c.derive_id
+		WHERE ul.token_id = $1
+		ORDER BY ul.session_number DESC, ul.uploaded_at ASC`, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer uRows.Close()
+
+	for uRows.Next() {
+		var sessionNumber int
+		var playerName string
+		var u models.BagSessionUpload
+		if err := uRows.Scan(&sessionNumber, &playerName, &u.UploadedAt, &u.ContributionID, &u.DeriveNumber, &u.Points,
+			&u.ImageURL, &u.ImageLqip, &u.Comment); err != nil {
+			return nil, err
+		}
+		i, ok := index[sessionNumber]
+		if !ok {
+			i = len(sessions)
+			index[sessionNumber] = i
+			sessions = append(sessions, models.BagSession{SessionNumber: sessionNumber, PlayerName: playerName, StartedAt: u.UploadedAt})
+		}
+		s := &sessions[i]
+		s.Uploads = append(s.Uploads, u)
+		s.Points += u.Points
+	}
+	if err := uRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].SessionNumber == currentSession && sessions[i].EndedAt == nil
+	}
+	return sessions, nil
+}
+
+// ReopenSession makes the last ended session of a bag the running session
+// again, with its player, city, start time and upload count. This only works
+// while the session after it is still empty; it reports false otherwise.
+func ReopenSession(ctx context.Context, tokenID, sessionNumber int) (bool, error) {
+	result, err := database.DB.Exec(ctx, `
+		UPDATE upload_tokens t
+		SET total_sessions = s.session_number,
+		    current_player = s.player_name,
+		    current_player_city = s.player_city,
+		    session_started_at = s.started_at,
+		    total_uploads = (SELECT COUNT(*) FROM upload_logs WHERE token_id = t.id AND session_number = s.session_number),
+		    session_device = NULL,
+		    handover_device = NULL,
+		    handover_requested_at = NULL
+		FROM token_sessions s
+		WHERE t.id = $1 AND s.token_id = t.id AND s.session_number = $2
+		  AND t.total_sessions = $2 + 1
+		  AND COALESCE(t.current_player, '') = ''
+		  AND NOT EXISTS (SELECT 1 FROM upload_logs WHERE token_id = t.id AND session_number = t.total_sessions)`,
+		tokenID, sessionNumber)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// DeleteSessionContributions removes all contributions of a bag session with
+// their upload logs in one transaction and returns the deleted contributions,
+// so the caller can remove the images. The upload counter of a running
+// session is reset.
+func DeleteSessionContributions(ctx context.Context, tokenID, sessionNumber int) ([]models.BagSessionUpload, error) {
+	tx, err := database.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		DELETE FROM upload_logs
+		WHERE token_id = $1 AND session_number = $2 AND contribution_id IS NOT NULL
+		RETURNING contribution_id, derive_number, uploaded_at`,
+		tokenID, sessionNumber)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []models.BagSessionUpload
+	for rows.Next() {
+		var u models.BagSessionUpload
+		var uploadedAt *time.Time
+		if err := rows.Scan(&u.ContributionID, &u.DeriveNumber, &uploadedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if uploadedAt != nil {
+			u.UploadedAt = *uploadedAt
+		}
+		deleted = append(deleted, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range deleted {
+		if err := tx.QueryRow(ctx, "DELETE FROM contributions WHERE id = $1 RETURNING image_url",
+			deleted[i].ContributionID).Scan(&deleted[i].ImageURL); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE upload_tokens SET total_uploads = 0 WHERE id = $1 AND total_sessions = $2",
+		tokenID, sessionNumber); err != nil {
+		return nil, err
+	}
+
+	return deleted, tx.Commit(ctx)
+}
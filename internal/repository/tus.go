@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+)
+
+// Queries for resumable tus uploads
+
+// CreateTusUpload stores a new, empty resumable upload
+func CreateTusUpload(ctx context.Context, u models.TusUpload) error {
+	_, err := database.DB.Exec(ctx, `
+		INSERT INTO tus_uploads (id, token_id, session_number, derive_number, user_comment, upload_length)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		u.ID, u.TokenID, u.SessionNumber, u.DeriveNumber, u.UserComment, u.Length)
+	return err
+}
+
+// GetTusUpload returns an upload owned by the given token
+func GetTusUpload(ctx context.Context, id string, tokenID int) (*models.TusUpload, error) {
+	var u models.TusUpload
+	err := database.DB.QueryRow(ctx, `
+		SELECT id, token_id, session_number, derive_number, user_comment, upload_length, upload_offset,
+		       contribution_id, updated_at, completed_at
+		FROM tus_uploads
+		WHERE id = $1 AND token_id = $2`,
+		id, tokenID).Scan(&u.ID, &u.TokenID, &u.SessionNumber, &u.DeriveNumber, &u.UserComment, &u.Length, &u.Offset,
+		&u.ContributionID, &u.UpdatedAt, &u.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// AppendTusChunk records a stored chunk and advances the offset.
+// It returns false if the offset moved in the meantime (concurrent PATCH).
+func AppendTusChunk(ctx context.Context, id string, offset, size int64, objectKey string) (bool, error) {
+	tx, err := database.DB.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE tus_uploads SET upload_offset = upload_offset + $3, updated_at = NOW()
+		WHERE id = $1 AND upload_offset = $2 AND completed_at IS NULL`,
+		id, offset, size)
+	if err != nil {
+		return false, err
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO tus_upload_chunks (upload_id, chunk_offset, size, object_key) VALUES ($1, $2, $3, $4)",
+		id, offset, size, objectKey); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// GetTusChunkKeys returns the object keys of an upload in offset order
+func GetTusChunkKeys(ctx context.Context, id string) ([]string, error) {
+	rows, err := database.DB.Query(ctx,
+		"SELECT object_key FROM tus_upload_chunks WHERE upload_id = $1 ORDER BY chunk_offset ASC", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// CompleteTusUpload links the created contribution and drops the chunk records
+func CompleteTusUpload(ctx context.Context, id string, contributionID int) error {
+	tx, err := database.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE tus_uploads SET contribution_id = $2, completed_at = NOW(), updated_at = NOW() WHERE id = $1",
+		id, contributionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM tus_upload_chunks WHERE upload_id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteTusUpload removes an upload and its chunk records
+func DeleteTusUpload(ctx context.Context, id string) error {
+	_, err := database.DB.Exec(ctx, "DELETE FROM tus_uploads WHERE id = $1", id)
+	return err
+}
+
+// GetStaleTusUploads returns IDs of unfinished uploads without progress since before
+func GetStaleTusUploads(ctx context.Context, before time.Time) ([]string, error) {
+	rows, err := database.DB.Query(ctx,
+		"SELECT id FROM tus_uploads WHERE completed_at IS NULL AND updated_at < $1", before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// PurgeCompletedTusUploads removes finished upload records older than before
+func PurgeCompletedTusUploads(ctx context.Context, before time.Time) (int64, error) {
+	result, err := database.DB.Exec(ctx,
+		"DELETE FROM tus_uploads WHERE completed_at IS NOT NULL AND completed_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
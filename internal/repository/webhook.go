@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"id-100/internal/database"
+	"id-100/internal/models"
+)
+
+// Queries for outbound webhooks and their delivery queue
+
+// ListWebhooks returns all webhook subscriptions, newest first
+func ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT id, url, secret, events, description, is_active, created_at
+		FROM webhooks
+		ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Webhook{}
+	for rows.Next() {
+		var w models.Webhook
+		if err := rows.Scan(&w.ID, &w.URL, &w.Secret, &w.Events, &w.Description, &w.IsActive, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, w)
+	}
+	return list, rows.Err()
+}
+
+// CreateWebhook stores a new subscription and returns its ID
+func CreateWebhook(ctx context.Context, url, secret string, events []string, description string) (int, error) {
+	var id int
+	err := database.DB.QueryRow(ctx, `
+		INSERT INTO webhooks (url, secret, events, description)
+		VALUES ($1, $2, $3, $4) RETURNING id`,
+		url, secret, events, description).Scan(&id)
+	return id, err
+}
+
+// SetWebhookActive enables or pauses a subscription
+func SetWebhookActive(ctx context.Context, id int, active bool) (int64, error) {
+	res, err := database.DB.Exec(ctx, "UPDATE webhooks SET is_active = $1 WHERE id = $2", active, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// DeleteWebhook removes a subscription together with its deliveries
+func DeleteWebhook(ctx context.Context, id int) (int64, error) {
+	res, err := database.DB.Exec(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// EnqueueWebhookDeliveries queues payload for every active subscription of event
+func EnqueueWebhookDeliveries(ctx context.Context, event, payload string) (int64, error) {
+	res, err := database.DB.Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT id, $1, $2 FROM webhooks
+		WHERE is_active AND $1 = ANY(events)`,
+		event, payload)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// EnqueueWebhookDelivery queues payload for a single subscription regardless of its events (test pings)
+func EnqueueWebhookDelivery(ctx context.Context, webhookID int, event, payload string) (int64, error) {
+	res, err := database.DB.Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT id, $2, $3 FROM webhooks WHERE id = $1`,
+		webhookID, event, payload)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// ClaimDueWebhookDeliveries locks up to limit due deliveries for this worker by pushing
+// their next attempt lease into the future, so parallel instances skip them
+func ClaimDueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	rows, err := database.DB.Query(ctx, `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM due, webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, w.url, w.secret, d.event, d.payload, d.attempts`,
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.WebhookURL, &d.Secret, &d.Event, &d.Payload, &d.Attempts); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// MarkWebhookDelivered records a successful attempt
+func MarkWebhookDelivered(ctx context.Context, id int64, responseStatus int) error {
+	_, err := database.DB.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, response_status = $2,
+		    last_error = '', last_attempt_at = NOW(), delivered_at = NOW()
+		WHERE id = $1`,
+		id, responseStatus)
+	return err
+}
+
+// MarkWebhookAttemptFailed records a failed attempt; the delivery is retried at
+// nextAttempt, or marked failed for good when nextAttempt is nil
+func MarkWebhookAttemptFailed(ctx context.Context, id int64, responseStatus *int, lastError string, nextAttempt *time.Time) error {
+	_, err := database.DB.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+		    attempts = attempts + 1, response_status = $2, last_error = $3,
+		    last_attempt_at = NOW(), next_attempt_at = COALESCE($4, next_attempt_at)
+		WHERE id = $1`,
+		id, responseStatus, lastError, nextAttempt)
+	return err
+}
+
+// RetryWebhookDelivery puts a delivery back into the queue for an immediate attempt
+func RetryWebhookDelivery(ctx context.Context, id int64) (int64, error) {
+	res, err := database.DB.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1 AND status <> 'pending'`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// ListWebhookDeliveries returns the most recent deliveries for the admin log
+func ListWebhookDeliveries(ctx context.Context, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := database.DB.Query(ctx, `
+		SELECT d.id, d.webhook_id, w.url, d.event, d.status, d.attempts, d.next_attempt_at,
+		       d.last_attempt_at, d.response_status, d.last_error, d.created_at, d.delivered_at
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		ORDER BY d.created_at DESC, d.id DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.WebhookURL, &d.Event, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastAttemptAt, &d.ResponseStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// PurgeWebhookDeliveries removes finished deliveries older than before
+func PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	res, err := database.DB.Exec(ctx,
+		"DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
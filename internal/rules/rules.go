@@ -0,0 +1,215 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rulesets bundle the play rules of a bag: cooldown between uploads, upload
+// quotas per session and over the bag's lifetime, session time limits and the
+// derives that may be played. Limits of 0 and an empty derive list mean
+// "no restriction"; bags without a ruleset use Default.
+
+// DefaultCooldownSeconds is the cooldown of bags without a ruleset
+const DefaultCooldownSeconds = 5
+
+// maxDeriveRange bounds a single "a-b" range in ParseDerives
+const maxDeriveRange = 1000
+
+// EndReason tells why a session was ended by its ruleset; it is sent as
+// ended_by with the session.ended webhook
+type EndReason string
+
+const (
+	// EndNone means the session may continue
+	EndNone EndReason = ""
+	// EndTimeLimit means the maximum session duration has passed
+	EndTimeLimit EndReason = "time_limit"
+	// EndIdle means there was no upload for longer than the idle timeout
+	EndIdle EndReason = "idle_timeout"
+)
+
+var (
+	// ErrNameRequired is returned for rulesets without a name
+	ErrNameRequired = errors.New("name required")
+	// ErrNegativeLimit is returned when a limit is below zero
+	ErrNegativeLimit = errors.New("limits must not be negative")
+	// ErrInvalidDerives is returned for malformed derive lists
+	ErrInvalidDerives = errors.New("invalid derive list")
+)
+
+// Ruleset is a named set of play rules
+type Ruleset struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	CooldownSeconds int    `json:"cooldown_seconds"`
+	SessionUploads  int    `json:"session_uploads"`
+	LifetimeUploads int    `json:"lifetime_uploads"`
+	SessionMinutes  int    `json:"session_minutes"`
+	IdleMinutes     int    `json:"idle_minutes"`
+	AllowedDerives  []int  `json:"allowed_derives"`
+	Bags            int    `json:"bags"`
+}
+
+// Default applies to bags without a ruleset
+var Default = Ruleset{Name: "Standard", CooldownSeconds: DefaultCooldownSeconds}
+
+// Validate checks name and limits and normalizes the derive list
+func (r *Ruleset) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return ErrNameRequired
+	}
+	if r.CooldownSeconds < 0 || r.SessionUploads < 0 || r.LifetimeUploads < 0 || r.SessionMinutes < 0 || r.IdleMinutes < 0 {
+		return ErrNegativeLimit
+	}
+	for _, n := range r.AllowedDerives {
+		if n < 1 {
+			return fmt.Errorf("%w: %d", ErrInvalidDerives, n)
+		}
+	}
+	r.AllowedDerives = normalizeDerives(r.AllowedDerives)
+	return nil
+}
+
+// Cooldown is the minimum time between two uploads of a session
+func (r Ruleset) Cooldown() time.Duration {
+	return time.Duration(r.CooldownSeconds) * time.Second
+}
+
+// CooldownRemaining returns how long the next upload has to wait after lastUpload
+func (r Ruleset) CooldownRemaining(lastUpload *time.Time, now time.Time) time.Duration {
+	if lastUpload == nil {
+		return 0
+	}
+	if wait := r.Cooldown() - now.Sub(*lastUpload); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// AllowsDerive reports whether derive number n may be played
+func (r Ruleset) AllowsDerive(n int) bool {
+	if len(r.AllowedDerives) == 0 {
+		return true
+	}
+	for _, allowed := range r.AllowedDerives {
+		if allowed == n {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionLimit is the effective per-session quota given the bag's max_uploads
+func (r Ruleset) SessionLimit(maxUploads int) int {
+	if r.SessionUploads > 0 && r.SessionUploads < maxUploads {
+		return r.SessionUploads
+	}
+	return maxUploads
+}
+
+// Remaining returns the uploads left in the current session, taking both the
+// session quota and the bag's lifetime quota into account
+func (r Ruleset) Remaining(maxUploads, sessionUploads, lifetimeUploads int) int {
+	remaining := r.SessionLimit(maxUploads) - sessionUploads
+	if r.LifetimeUploads > 0 {
+		if left := r.LifetimeUploads - lifetimeUploads; left < remaining {
+			remaining = left
+		}
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Deadline returns when a session started at startedAt ends by time limit or
+// idle timeout, whichever comes first; ok is false if neither is set.
+// Idle time counts from the last upload, or from the start without one.
+func (r Ruleset) Deadline(startedAt time.Time, lastUpload *time.Time) (deadline time.Time, reason EndReason, ok bool) {
+	if r.SessionMinutes > 0 {
+		deadline, reason, ok = startedAt.Add(time.Duration(r.SessionMinutes)*time.Minute), EndTimeLimit, true
+	}
+	if r.IdleMinutes > 0 {
+		lastActivity := startedAt
+		if lastUpload != nil && lastUpload.After(startedAt) {
+			lastActivity = *lastUpload
+		}
+		idle := lastActivity.Add(time.Duration(r.IdleMinutes) * time.Minute)
+		if !ok || idle.Before(deadline) {
+			deadline, reason, ok = idle, EndIdle, true
+		}
+	}
+	return deadline, reason, ok
+}
+
+// SessionEnd reports whether a session has to be ended at now, and why
+func (r Ruleset) SessionEnd(startedAt time.Time, lastUpload *time.Time, now time.Time) EndReason {
+	if deadline, reason, ok := r.Deadline(startedAt, lastUpload); ok && !now.Before(deadline) {
+		return reason
+	}
+	return EndNone
+}
+
+// ParseDerives parses a list like "1-10, 15, 20" into sorted, unique derive
+// numbers; an empty string means all derives
+func ParseDerives(s string) ([]int, error) {
+	var nums []int
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		from, to, isRange := strings.Cut(part, "-")
+		a, err := strconv.Atoi(from)
+		if err != nil || a < 1 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidDerives, part)
+		}
+		b := a
+		if isRange {
+			if b, err = strconv.Atoi(to); err != nil || b < a || b-a >= maxDeriveRange {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidDerives, part)
+			}
+		}
+		for n := a; n <= b; n++ {
+			nums = append(nums, n)
+		}
+	}
+	return normalizeDerives(nums), nil
+}
+
+// FormatDerives is the inverse of ParseDerives and joins consecutive numbers to ranges
+func FormatDerives(nums []int) string {
+	nums = normalizeDerives(nums)
+	parts := make([]string, 0, len(nums))
+	for i := 0; i < len(nums); {
+		j := i
+		for j+1 < len(nums) && nums[j+1] == nums[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", nums[i], nums[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(nums[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// normalizeDerives sorts and de-duplicates derive numbers; nil stays nil
+func normalizeDerives(nums []int) []int {
+	if len(nums) == 0 {
+		return nil
+	}
+	sorted := append([]int(nil), nums...)
+	sort.Ints(sorted)
+	out := sorted[:1]
+	for _, n := range sorted[1:] {
+		if n != out[len(out)-1] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
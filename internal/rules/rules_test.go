@@ -0,0 +1,144 @@
+package rules
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDerives(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int
+		err  bool
+	}{
+		{"", nil, false},
+		{"  ", nil, false},
+		{"3", []int{3}, false},
+		{"1-4, 10", []int{1, 2, 3, 4, 10}, false},
+		{"10;2 2,1-2", []int{1, 2, 10}, false},
+		{"0", nil, true},
+		{"5-3", nil, true},
+		{"a", nil, true},
+		{"1-", nil, true},
+		{"1-5000", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDerives(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseDerives(%q) error = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrInvalidDerives) {
+			t.Errorf("ParseDerives(%q) error = %v, want ErrInvalidDerives", tt.in, err)
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseDerives(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDerives(t *testing.T) {
+	if got := FormatDerives([]int{10, 1, 2, 3, 5, 6, 2}); got != "1-3, 5-6, 10" {
+		t.Errorf("FormatDerives = %q", got)
+	}
+	if got := FormatDerives(nil); got != "" {
+		t.Errorf("FormatDerives(nil) = %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	r := Ruleset{Name: "  Schule  ", AllowedDerives: []int{3, 1, 3}}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if r.Name != "Schule" || !reflect.DeepEqual(r.AllowedDerives, []int{1, 3}) {
+		t.Errorf("Validate did not normalize: %+v", r)
+	}
+	if err := (&Ruleset{}).Validate(); !errors.Is(err, ErrNameRequired) {
+		t.Errorf("empty name: %v", err)
+	}
+	if err := (&Ruleset{Name: "x", IdleMinutes: -1}).Validate(); !errors.Is(err, ErrNegativeLimit) {
+		t.Errorf("negative limit: %v", err)
+	}
+	if err := (&Ruleset{Name: "x", AllowedDerives: []int{0}}).Validate(); !errors.Is(err, ErrInvalidDerives) {
+		t.Errorf("derive 0: %v", err)
+	}
+}
+
+func TestAllowsDerive(t *testing.T) {
+	if !Default.AllowsDerive(42) {
+		t.Error("Default must allow every derive")
+	}
+	r := Ruleset{AllowedDerives: []int{1, 2, 3}}
+	if !r.AllowsDerive(2) || r.AllowsDerive(4) {
+		t.Error("AllowsDerive does not respect the list")
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	tests := []struct {
+		name                      string
+		r                         Ruleset
+		max, session, lifetime, w int
+	}{
+		{"bag quota only", Default, 10, 3, 50, 7},
+		{"session limit below bag quota", Ruleset{SessionUploads: 5}, 10, 3, 50, 2},
+		{"session limit above bag quota", Ruleset{SessionUploads: 20}, 10, 3, 50, 7},
+		{"lifetime quota", Ruleset{LifetimeUploads: 52}, 10, 3, 50, 2},
+		{"lifetime quota used up", Ruleset{LifetimeUploads: 40}, 10, 3, 50, 0},
+		{"over quota", Default, 10, 12, 12, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.r.Remaining(tt.max, tt.session, tt.lifetime); got != tt.w {
+			t.Errorf("%s: Remaining = %d, want %d", tt.name, got, tt.w)
+		}
+	}
+}
+
+func TestCooldownRemaining(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := Ruleset{CooldownSeconds: 30}
+	last := now.Add(-10 * time.Second)
+	if got := r.CooldownRemaining(&last, now); got != 20*time.Second {
+		t.Errorf("CooldownRemaining = %v", got)
+	}
+	if got := r.CooldownRemaining(nil, now); got != 0 {
+		t.Errorf("CooldownRemaining without upload = %v", got)
+	}
+	old := now.Add(-time.Minute)
+	if got := r.CooldownRemaining(&old, now); got != 0 {
+		t.Errorf("CooldownRemaining after cooldown = %v", got)
+	}
+}
+
+func TestSessionEnd(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	lastUpload := start.Add(50 * time.Minute)
+	r := Ruleset{SessionMinutes: 90, IdleMinutes: 20}
+
+	tests := []struct {
+		name string
+		r    Ruleset
+		last *time.Time
+		now  time.Time
+		want EndReason
+	}{
+		{"no limits", Default, nil, start.Add(48 * time.Hour), EndNone},
+		{"running", r, &lastUpload, start.Add(60 * time.Minute), EndNone},
+		{"idle since start", r, nil, start.Add(20 * time.Minute), EndIdle},
+		{"idle since last upload", r, &lastUpload, start.Add(70 * time.Minute), EndIdle},
+		{"time limit before idle", Ruleset{SessionMinutes: 60, IdleMinutes: 20}, &lastUpload, start.Add(60 * time.Minute), EndTimeLimit},
+	}
+	for _, tt := range tests {
+		if got := tt.r.SessionEnd(start, tt.last, tt.now); got != tt.want {
+			t.Errorf("%s: SessionEnd = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	deadline, reason, ok := r.Deadline(start, &lastUpload)
+	if !ok || reason != EndIdle || !deadline.Equal(start.Add(70*time.Minute)) {
+		t.Errorf("Deadline = %v, %q, %v", deadline, reason, ok)
+	}
+}
@@ -0,0 +1,52 @@
+package search
+
+import (
+	"html"
+	"html/template"
+	"strings"
+)
+
+// Highlight markers passed to ts_headline. They are stripped from user text on
+// input (StripMarkers) and again before ts_headline, so snippets can be
+// HTML-escaped first and the markers swapped for <mark> afterwards without
+// letting user input through as markup.
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+// StripMarkers removes the highlight markers from user text
+func StripMarkers(s string) string {
+	return strings.NewReplacer(HighlightStart, "", HighlightStop, "").Replace(s)
+}
+
+// HeadlineOptions are the ts_headline options used for all search snippets
+const HeadlineOptions = "StartSel=\x02, StopSel=\x03, MaxWords=25, MinWords=8, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// MinQueryLength is the shortest query sent to the database
+const MinQueryLength = 2
+
+// MaxQueryLength caps search input
+const MaxQueryLength = 200
+
+// NormalizeQuery trims and length-limits a search query; ok is false when it is too short
+func NormalizeQuery(q string) (string, bool) {
+	q = strings.TrimSpace(q)
+	if runes := []rune(q); len(runes) > MaxQueryLength {
+		q = string(runes[:MaxQueryLength])
+	}
+	return q, len([]rune(q)) >= MinQueryLength
+}
+
+// SnippetHTML escapes a ts_headline result and turns the highlight markers into <mark> tags
+func SnippetHTML(raw string) template.HTML {
+	escaped := html.EscapeString(raw)
+	escaped = strings.ReplaceAll(escaped, HighlightStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, HighlightStop, "</mark>")
+	return template.HTML(escaped)
+}
+
+// PlainSnippet removes the highlight markers from a ts_headline result
+func PlainSnippet(raw string) string {
+	return StripMarkers(raw)
+}
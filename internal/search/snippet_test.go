@@ -0,0 +1,64 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSnippetHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{
+			name: "highlight",
+			raw:  "eine \x02Taube\x03 am Bahnhof",
+			want: "eine <mark>Taube</mark> am Bahnhof",
+		},
+		{
+			name: "user markup is escaped",
+			raw:  "<script>alert(1)</script> \x02Taube\x03",
+			want: "&lt;script&gt;alert(1)&lt;/script&gt; <mark>Taube</mark>",
+		},
+		{
+			name: "markers typed by a user are stripped on input",
+			raw:  "a \x02" + StripMarkers("b\x03 \x02c") + "\x03 d",
+			want: "a <mark>b c</mark> d",
+		},
+		{
+			name: "no highlight",
+			raw:  "Tür & Tor",
+			want: "Tür &amp; Tor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(SnippetHTML(tt.raw)); got != tt.want {
+				t.Errorf("SnippetHTML(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripMarkers(t *testing.T) {
+	if got := StripMarkers("\x03Taube\x02 im \x02\x02Park"); got != "Taube im Park" {
+		t.Errorf("StripMarkers = %q", got)
+	}
+	if got := string(SnippetHTML(StripMarkers("x\x03y"))); strings.Count(got, "<mark>") != strings.Count(got, "</mark>") {
+		t.Errorf("unbalanced marks in %q", got)
+	}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	if q, ok := NormalizeQuery("  Taube "); !ok || q != "Taube" {
+		t.Errorf("NormalizeQuery = %q, %v", q, ok)
+	}
+	if _, ok := NormalizeQuery("a"); ok {
+		t.Error("single character query should be rejected")
+	}
+	if q, _ := NormalizeQuery(strings.Repeat("ä", 300)); len([]rune(q)) != MaxQueryLength {
+		t.Errorf("query should be capped at %d runes, got %d", MaxQueryLength, len([]rune(q)))
+	}
+}
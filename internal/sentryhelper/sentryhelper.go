@@ -39,3 +39,14 @@ func Logger(c *echo.Context) sentry.Logger {
 	}
 	return sentry.NewLogger(c.Request().Context())
 }
+
+// CaptureContextError captures an error to the Sentry hub attached to ctx, if any.
+// Use it in code below the handler layer that only has the request context.
+func CaptureContextError(ctx context.Context, err error, level sentry.Level) {
+	if hub := sentry.GetHubFromContext(ctx); hub != nil {
+		hub.WithScope(func(scope *sentry.Scope) {
+			scope.SetLevel(level)
+			hub.CaptureException(err)
+		})
+	}
+}
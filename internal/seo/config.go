@@ -53,6 +53,18 @@ func GetConfig() *Config {
 				Description: "Lade deine Fotos zur urbanen Stadtrallye hoch und dokumentiere deine Wahrnehmung des Stadtraums.",
 				Type:        "website",
 			},
+			"search": {
+				Path:        "/suche",
+				Title:       "Suche | Innenstadt ID-100",
+				Description: "Durchsuche alle IDs und Beiträge der urbanen Stadtrallye nach Begriffen, Kommentaren und Orten.",
+				Type:        "website",
+			},
+			"exhibition": {
+				Path:        "/ausstellung",
+				Title:       "Ausstellung | Innenstadt ID-100",
+				Description: "Live-Ansicht der Ausstellung: neue Beiträge der urbanen Stadtrallye erscheinen in Echtzeit.",
+				Type:        "website",
+			},
 			"request_bag": {
 				Path:        "/werkzeug-anfordern",
 				Title:       "Werkzeug anfordern | Innenstadt ID-100",
@@ -2,22 +2,23 @@ package templates
 
 import (
 	"id-100/internal/config"
+	"id-100/internal/consent"
 	"id-100/internal/version"
 )
 
 // GetGlobalTemplateData returns global data that should be available in all templates
 func GetGlobalTemplateData() map[string]interface{} {
 	return map[string]interface{}{
-		"GeocodingURL":       config.GetGeocodingURL(),
-		"MeiliKey":           config.GetMeiliSearchKey(),
-		"SentryDSN":          config.GetSentryDSN(),
-		"Environment":        config.GetEnvironment(),
-		"UmamiScriptURL":    config.GetUmamiScriptURL(),
-		"UmamiWebsiteID":    config.GetUmamiWebsiteID(),
-		"AppVersion":         version.Version,
-		"BaseURL":            config.GetBaseURL(),
-		"AssetMainCSS":       GetAssetPath("main.css"),
-		"AssetMainJS":        GetAssetPath("main.js"),
+		"SentryDSN":      config.GetSentryDSN(),
+		"Environment":    config.GetEnvironment(),
+		"UmamiScriptURL": config.GetUmamiScriptURL(),
+		"UmamiWebsiteID": config.GetUmamiWebsiteID(),
+		"AppVersion":     version.Version,
+		"BaseURL":        config.GetBaseURL(),
+		"AssetMainCSS":   GetAssetPath("main.css"),
+		"AssetMainJS":    GetAssetPath("main.js"),
+		"PolicyVersion":  consent.PolicyVersion,
+		"ConsentText":    consent.Text,
 	}
 }
 
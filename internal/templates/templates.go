@@ -9,7 +9,9 @@ import (
 	"path/filepath"
 	"strings"
 
+	"id-100/internal/bagcode"
 	"id-100/internal/config"
+	"id-100/internal/rules"
 
 	"github.com/labstack/echo/v5"
 	"github.com/tdewolff/minify/v2"
@@ -90,6 +92,10 @@ func Load(cfg *config.Config) *Renderer {
 		"eq":        func(a, b string) bool { return a == b },
 		"or":        func(a, b bool) bool { return a || b },
 		"hasprefix": func(s, prefix string) bool { return strings.HasPrefix(s, prefix) },
+		// bagcode formats a stored bag code for display, e.g. K7P-4QX
+		"bagcode": bagcode.Format,
+		// derives shows a ruleset's allowed derives as ranges, e.g. 1-20, 35
+		"derives": rules.FormatDerives,
 		// urlParam encodes a query value for non-URL attributes (e.g. <option value>),
 		// which html/template does not autoescape. Uses %20 to match href encoding.
 		"urlParam": func(s string) string {
@@ -0,0 +1,59 @@
+package tokenbatch
+
+import (
+	"context"
+	"time"
+
+	"id-100/internal/bagcode"
+	"id-100/internal/models"
+	"id-100/internal/repository"
+	"id-100/internal/utils"
+	"id-100/internal/webhooks"
+)
+
+// Create generates a secure token for every entry, stores them in one
+// transaction and emits token.created for each. The result is the only place
+// the plain tokens appear.
+func Create(ctx context.Context, entries []Entry, group string, expiresAt *time.Time, baseURL string) ([]Token, error) {
+	infos := make([]models.TokenInfo, len(entries))
+	for i, e := range entries {
+		token, err := utils.GenerateSecureToken(40)
+		if err != nil {
+			return nil, err
+		}
+		infos[i] = models.TokenInfo{Token: token, BagName: e.BagName, GroupLabel: group, MaxUploads: e.MaxUploads, ExpiresAt: expiresAt}
+	}
+
+	if err := repository.CreateTokenBatch(ctx, infos); err != nil {
+		return nil, err
+	}
+
+	for _, t := range infos {
+		webhooks.Emit(ctx, webhooks.EventTokenCreated, webhooks.TokenCreated{
+			TokenID:    t.ID,
+			BagName:    t.BagName,
+			MaxUploads: t.MaxUploads,
+		})
+	}
+	return Export(infos, baseURL), nil
+}
+
+// Export converts stored tokens into their export form with short upload links and QR links
+func Export(infos []models.TokenInfo, baseURL string) []Token {
+	tokens := make([]Token, len(infos))
+	for i, t := range infos {
+		tokens[i] = Token{
+			ID:         t.ID,
+			BagName:    t.BagName,
+			Group:      t.GroupLabel,
+			MaxUploads: t.MaxUploads,
+			Code:       bagcode.Format(t.ShortCode),
+			Token:      t.Token,
+			UploadURL:  bagcode.URL(baseURL, t.ShortCode),
+			QRURL:      QRURL(baseURL, t.ID),
+			CreatedAt:  t.CreatedAt,
+			ExpiresAt:  t.ExpiresAt,
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,246 @@
+package tokenbatch
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"id-100/internal/utils"
+)
+
+// Batch creation of upload tokens for workshops: bag names come either from a
+// name pattern ("Werkzeug {n}") or from an imported CSV of names and quotas.
+
+const (
+	// MaxCount caps the number of tokens created in one batch
+	MaxCount = 500
+	// DefaultMaxUploads is the quota used when none is given
+	DefaultMaxUploads = 100
+	// MaxNameLength caps bag names and group labels
+	MaxNameLength = 100
+	// Placeholder is replaced by the running number in a name pattern
+	Placeholder = "{n}"
+)
+
+var (
+	ErrEmptyPattern = errors.New("name pattern is empty")
+	ErrInvalidCount = fmt.Errorf("count must be between 1 and %d", MaxCount)
+	ErrEmptyImport  = errors.New("import contains no bags")
+	ErrInvalidDate  = errors.New("expiry must be a date (YYYY-MM-DD) or RFC 3339 time")
+	ErrPastExpiry   = errors.New("expiry lies in the past")
+)
+
+// Entry is one bag to create
+type Entry struct {
+	BagName    string `json:"bag_name"`
+	MaxUploads int    `json:"max_uploads"`
+}
+
+// Names expands pattern into count bag names starting at start. The number is
+// zero-padded to the width of the largest one so names sort naturally; a
+// pattern without {n} gets the number appended.
+func Names(pattern string, start, count int) ([]string, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, ErrEmptyPattern
+	}
+	if count < 1 || count > MaxCount {
+		return nil, ErrInvalidCount
+	}
+	if start < 0 {
+		start = 0
+	}
+	if !strings.Contains(pattern, Placeholder) {
+		pattern += " " + Placeholder
+	}
+
+	width := len(strconv.Itoa(start + count - 1))
+	names := make([]string, count)
+	for i := range names {
+		n := fmt.Sprintf("%0*d", width, start+i)
+		names[i] = strings.ReplaceAll(pattern, Placeholder, n)
+		if utf8.RuneCountInString(names[i]) > MaxNameLength {
+			return nil, fmt.Errorf("bag name %q is longer than %d characters", names[i], MaxNameLength)
+		}
+	}
+	return names, nil
+}
+
+// FromPattern builds entries for Names with the same quota each
+func FromPattern(pattern string, start, count, maxUploads int) ([]Entry, error) {
+	names, err := Names(pattern, start, count)
+	if err != nil {
+		return nil, err
+	}
+	if maxUploads <= 0 {
+		maxUploads = DefaultMaxUploads
+	}
+	entries := make([]Entry, len(names))
+	for i, name := range names {
+		entries[i] = Entry{BagName: name, MaxUploads: maxUploads}
+	}
+	return entries, nil
+}
+
+// ParseCSV reads bag names and optional quotas, one bag per row. Comma and
+// semicolon separated files are accepted (spreadsheets in German locales use
+// the latter), a header row is skipped, and rows without a quota get
+// defaultMaxUploads.
+func ParseCSV(r io.Reader, defaultMaxUploads int) ([]Entry, error) {
+	if defaultMaxUploads <= 0 {
+		defaultMaxUploads = DefaultMaxUploads
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+
+	cr := csv.NewReader(strings.NewReader(text))
+	cr.Comma = detectComma(text)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var entries []Entry
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		name := strings.TrimSpace(rec[0])
+		if name == "" {
+			continue
+		}
+		if first && isHeader(name) {
+			continue
+		}
+		if utf8.RuneCountInString(name) > MaxNameLength {
+			return nil, fmt.Errorf("line %d: bag name is longer than %d characters", line, MaxNameLength)
+		}
+
+		maxUploads := defaultMaxUploads
+		if len(rec) > 1 && strings.TrimSpace(rec[1]) != "" {
+			maxUploads, err = strconv.Atoi(strings.TrimSpace(rec[1]))
+			if err != nil || maxUploads <= 0 {
+				return nil, fmt.Errorf("line %d: invalid quota %q", line, rec[1])
+			}
+		}
+
+		entries = append(entries, Entry{BagName: name, MaxUploads: maxUploads})
+		if len(entries) > MaxCount {
+			return nil, ErrInvalidCount
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrEmptyImport
+	}
+	return entries, nil
+}
+
+// detectComma picks ';' when the first line has more semicolons than commas
+func detectComma(text string) rune {
+	first, _, _ := strings.Cut(text, "\n")
+	if strings.Count(first, ";") > strings.Count(first, ",") {
+		return ';'
+	}
+	return ','
+}
+
+func isHeader(field string) bool {
+	switch strings.ToLower(field) {
+	case "bag_name", "name", "werkzeug", "werkzeugname":
+		return true
+	}
+	return false
+}
+
+// ParseExpiry reads an optional token expiry. A plain date keeps the token
+// valid through that whole day in loc; an empty string means no expiry.
+func ParseExpiry(s string, now time.Time, loc *time.Location) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		day, dayErr := time.ParseInLocation(time.DateOnly, s, loc)
+		if dayErr != nil {
+			return nil, ErrInvalidDate
+		}
+		t = day.AddDate(0, 0, 1)
+	}
+	if !t.After(now) {
+		return nil, ErrPastExpiry
+	}
+	return &t, nil
+}
+
+// NormalizeGroup trims a group label and checks its length
+func NormalizeGroup(group string) (string, error) {
+	group = strings.Join(strings.Fields(group), " ")
+	if utf8.RuneCountInString(group) > MaxNameLength {
+		return "", fmt.Errorf("group is longer than %d characters", MaxNameLength)
+	}
+	return group, nil
+}
+
+// Token is a created token as exported for printing labels or mail merges.
+// The plain token is only part of the export made right after creation.
+type Token struct {
+	ID         int        `json:"id"`
+	BagName    string     `json:"bag_name"`
+	Group      string     `json:"group,omitempty"`
+	MaxUploads int        `json:"max_uploads"`
+	Code       string     `json:"code"`
+	Token      string     `json:"token,omitempty"`
+	UploadURL  string     `json:"upload_url"`
+	QRURL      string     `json:"qr_url"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// QRURL is the admin download link for a bag's QR code
+func QRURL(baseURL string, id int) string {
+	return fmt.Sprintf("%s/admin/tokens/%d/qr", baseURL, id)
+}
+
+// WriteCSV writes tokens with a header row
+func WriteCSV(w io.Writer, tokens []Token) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "bag_name", "group", "max_uploads", "code", "token", "upload_url", "qr_url", "created_at", "expires_at"}); err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		expires := ""
+		if t.ExpiresAt != nil {
+			expires = t.ExpiresAt.Format(time.RFC3339)
+		}
+		if err := cw.Write([]string{
+			strconv.Itoa(t.ID),
+			utils.CSVSafe(t.BagName),
+			utils.CSVSafe(t.Group),
+			strconv.Itoa(t.MaxUploads),
+			t.Code,
+			t.Token,
+			t.UploadURL,
+			t.QRURL,
+			t.CreatedAt.Format(time.RFC3339),
+			expires,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,136 @@
+package tokenbatch
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		pattern      string
+		start, count int
+		want         []string
+	}{
+		{"Werkzeug {n}", 1, 3, []string{"Werkzeug 1", "Werkzeug 2", "Werkzeug 3"}},
+		{"Werkzeug {n}", 9, 2, []string{"Werkzeug 09", "Werkzeug 10"}},
+		{"WS-{n}-Tasche", 1, 1, []string{"WS-1-Tasche"}},
+		{"Workshop", 1, 2, []string{"Workshop 1", "Workshop 2"}},
+	}
+	for _, tt := range tests {
+		got, err := Names(tt.pattern, tt.start, tt.count)
+		if err != nil {
+			t.Fatalf("Names(%q, %d, %d): %v", tt.pattern, tt.start, tt.count, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Names(%q, %d, %d) = %q, want %q", tt.pattern, tt.start, tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestNamesInvalid(t *testing.T) {
+	if _, err := Names("  ", 1, 3); !errors.Is(err, ErrEmptyPattern) {
+		t.Errorf("empty pattern: err = %v", err)
+	}
+	if _, err := Names("W {n}", 1, 0); !errors.Is(err, ErrInvalidCount) {
+		t.Errorf("count 0: err = %v", err)
+	}
+	if _, err := Names("W {n}", 1, MaxCount+1); !errors.Is(err, ErrInvalidCount) {
+		t.Errorf("count too large: err = %v", err)
+	}
+	if _, err := Names(strings.Repeat("x", MaxNameLength), 1, 1); err == nil {
+		t.Error("expected error for overlong names")
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	in := "\ufeffName;Kontingent\nWerkzeug A;50\n\nWerkzeug B;\n  Werkzeug C ; 20\n"
+	got, err := ParseCSV(strings.NewReader(in), 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{BagName: "Werkzeug A", MaxUploads: 50},
+		{BagName: "Werkzeug B", MaxUploads: 80},
+		{BagName: "Werkzeug C", MaxUploads: 20},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCSV = %+v, want %+v", got, want)
+	}
+
+	got, err = ParseCSV(strings.NewReader("Eins,10\nZwei\n"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Entry{{BagName: "Eins", MaxUploads: 10}, {BagName: "Zwei", MaxUploads: DefaultMaxUploads}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCSV without header = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseCSVInvalid(t *testing.T) {
+	if _, err := ParseCSV(strings.NewReader("bag_name,max_uploads\n"), 0); !errors.Is(err, ErrEmptyImport) {
+		t.Errorf("header only: err = %v", err)
+	}
+	if _, err := ParseCSV(strings.NewReader("A,10\nB,viele\n"), 0); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("invalid quota: err = %v", err)
+	}
+	if _, err := ParseCSV(strings.NewReader(strings.Repeat("A\n", MaxCount+1)), 0); !errors.Is(err, ErrInvalidCount) {
+		t.Errorf("too many rows: err = %v", err)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	berlin := time.FixedZone("CET", 3600)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, berlin)
+
+	if got, err := ParseExpiry("", now, berlin); got != nil || err != nil {
+		t.Errorf("empty expiry = %v, %v", got, err)
+	}
+
+	got, err := ParseExpiry("2026-10-18", now, berlin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 10, 19, 0, 0, 0, 0, berlin); !got.Equal(want) {
+		t.Errorf("date expiry = %v, want end of day %v", got, want)
+	}
+
+	got, err = ParseExpiry("2026-10-18T18:30:00+02:00", now, berlin)
+	if err != nil || got.Hour() != 18 {
+		t.Errorf("RFC 3339 expiry = %v, %v", got, err)
+	}
+
+	if _, err := ParseExpiry("2026-10-17", now, berlin); !errors.Is(err, ErrPastExpiry) {
+		t.Errorf("past date: err = %v", err)
+	}
+	if _, err := ParseExpiry("18.10.2026", now, berlin); !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("invalid date: err = %v", err)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, []Token{{
+		ID:         7,
+		BagName:    "=Werkzeug",
+		Group:      "Workshop",
+		MaxUploads: 50,
+		Code:       "K7P-4QX",
+		Token:      "abc",
+		UploadURL:  "https://example.org/b/K7P-4QX",
+		QRURL:      QRURL("https://example.org", 7),
+		CreatedAt:  time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id,bag_name,group,max_uploads,code,token,upload_url,qr_url,created_at,expires_at\n" +
+		"7,'=Werkzeug,Workshop,50,K7P-4QX,abc,https://example.org/b/K7P-4QX,https://example.org/admin/tokens/7/qr,2025-03-01T12:00:00Z,\n"
+	if buf.String() != want {
+		t.Errorf("WriteCSV =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
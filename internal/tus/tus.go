@@ -0,0 +1,80 @@
+package tus
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Protocol pieces of the tus 1.0 resumable upload endpoint
+// (https://tus.io/protocols/resumable-upload) that do not touch the database or storage.
+
+const (
+	// Version is the only protocol version supported
+	Version = "1.0.0"
+	// Extensions lists the supported protocol extensions
+	Extensions = "creation,termination"
+	// MaxSize is the largest upload accepted (Tus-Max-Size)
+	MaxSize int64 = 30 << 20
+	// MaxChunkSize caps how many bytes a single PATCH stores; clients continue with the returned offset
+	MaxChunkSize int64 = 8 << 20
+	// OffsetContentType is the required Content-Type of PATCH requests
+	OffsetContentType = "application/offset+octet-stream"
+)
+
+var (
+	// ErrInvalidMetadata is returned for malformed Upload-Metadata headers
+	ErrInvalidMetadata = errors.New("invalid Upload-Metadata")
+	// ErrInvalidLength is returned for missing or malformed Upload-Length/Upload-Offset values
+	ErrInvalidLength = errors.New("invalid length")
+)
+
+// ParseMetadata decodes an Upload-Metadata header: comma-separated pairs of
+// a key and an optional base64 encoded value separated by a space.
+func ParseMetadata(header string) (map[string]string, error) {
+	meta := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		key, encoded, _ := strings.Cut(pair, " ")
+		if key == "" || strings.ContainsAny(key, " ,") {
+			return nil, ErrInvalidMetadata
+		}
+		if _, dup := meta[key]; dup {
+			return nil, ErrInvalidMetadata
+		}
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, ErrInvalidMetadata
+		}
+		meta[key] = string(value)
+	}
+	return meta, nil
+}
+
+// ParseLength parses a non-negative Upload-Length or Upload-Offset header value
+func ParseLength(s string) (int64, error) {
+	if s == "" {
+		return 0, ErrInvalidLength
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, ErrInvalidLength
+	}
+	return n, nil
+}
+
+// ChunkLimit returns how many bytes the next PATCH may store
+func ChunkLimit(length, offset int64) int64 {
+	remaining := length - offset
+	if remaining > MaxChunkSize {
+		return MaxChunkSize
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
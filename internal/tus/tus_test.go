@@ -0,0 +1,59 @@
+package tus
+
+import (
+	"testing"
+)
+
+func TestParseMetadata(t *testing.T) {
+	meta, err := ParseMetadata("derive_number NDI=, filename Zm90by5qcGc=,is_confidential")
+	if err != nil {
+		t.Fatalf("ParseMetadata returned error: %v", err)
+	}
+	if meta["derive_number"] != "42" || meta["filename"] != "foto.jpg" {
+		t.Errorf("unexpected values: %v", meta)
+	}
+	if v, ok := meta["is_confidential"]; !ok || v != "" {
+		t.Errorf("key without value should map to empty string, got %q, %v", v, ok)
+	}
+
+	empty, err := ParseMetadata("")
+	if err != nil || len(empty) != 0 {
+		t.Errorf("ParseMetadata(\"\") = %v, %v; want empty map", empty, err)
+	}
+}
+
+func TestParseMetadataInvalid(t *testing.T) {
+	for _, h := range []string{"derive_number !!!", " ,x", "a MQ==,a Mg=="} {
+		if _, err := ParseMetadata(h); err != ErrInvalidMetadata {
+			t.Errorf("ParseMetadata(%q) error = %v, want ErrInvalidMetadata", h, err)
+		}
+	}
+}
+
+func TestParseLength(t *testing.T) {
+	if n, err := ParseLength("1024"); err != nil || n != 1024 {
+		t.Errorf("ParseLength(1024) = %d, %v", n, err)
+	}
+	for _, s := range []string{"", "-1", "abc", "1.5"} {
+		if _, err := ParseLength(s); err != ErrInvalidLength {
+			t.Errorf("ParseLength(%q) error = %v, want ErrInvalidLength", s, err)
+		}
+	}
+}
+
+func TestChunkLimit(t *testing.T) {
+	tests := []struct {
+		length, offset, want int64
+	}{
+		{100, 0, 100},
+		{100, 60, 40},
+		{100, 100, 0},
+		{MaxChunkSize * 3, 0, MaxChunkSize},
+		{10, 20, 0},
+	}
+	for _, tt := range tests {
+		if got := ChunkLimit(tt.length, tt.offset); got != tt.want {
+			t.Errorf("ChunkLimit(%d, %d) = %d, want %d", tt.length, tt.offset, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package uploads
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"id-100/internal/models"
+	"id-100/internal/repository"
+	"id-100/internal/utils"
+)
+
+// Presigned direct uploads: the client puts the original file into a staging key,
+// finalisation pulls it through the normal pipeline and drops the staging object.
+
+// DirectUploadContentTypes maps accepted content types to the staging file extension
+var DirectUploadContentTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// DirectStagingKey returns the staging object key of a direct upload
+func DirectStagingKey(uploadID, contentType string) string {
+	return "staging/" + uploadID + DirectUploadContentTypes[contentType]
+}
+
+// FinishDirect runs a staged object through the upload pipeline
+func FinishDirect(ctx context.Context, u *models.DirectUpload, p Params) (*Result, error) {
+	body, err := utils.DownloadFromS3(ctx, u.ObjectKey)
+	if err != nil {
+		return nil, err
+	}
+	result, err := Store(ctx, body, p)
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repository.CompleteDirectUpload(ctx, u.ID, result.ContributionID); err != nil {
+		return nil, err
+	}
+	if err := utils.DeleteFromS3(ctx, u.ObjectKey); err != nil {
+		log.Printf("Failed to delete staging object %s: %v", u.ObjectKey, err)
+	}
+	return result, nil
+}
+
+// AbortDirect removes a direct upload and its staging object
+func AbortDirect(ctx context.Context, u *models.DirectUpload) error {
+	if err := utils.DeleteFromS3(ctx, u.ObjectKey); err != nil && !utils.IsS3NotFound(err) {
+		log.Printf("Failed to delete staging object %s: %v", u.ObjectKey, err)
+	}
+	return repository.DeleteDirectUpload(ctx, u.ID)
+}
+
+// CleanupExpiredDirect removes direct uploads that were never finalised within grace
+// after their presigned URLs expired, and forgets finalised ones older than a day
+func CleanupExpiredDirect(ctx context.Context, grace time.Duration) error {
+	expired, err := repository.GetExpiredDirectUploads(ctx, time.Now().Add(-grace))
+	if err != nil {
+		return err
+	}
+	for i := range expired {
+		if err := AbortDirect(ctx, &expired[i]); err != nil {
+			return err
+		}
+	}
+	if len(expired) > 0 {
+		log.Printf("Removed %d expired direct uploads", len(expired))
+	}
+
+	_, err = repository.PurgeFinalizedDirectUploads(ctx, time.Now().Add(-24*time.Hour))
+	return err
+}
@@ -0,0 +1,122 @@
+package uploads
+
+import (
+	"context"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"id-100/internal/models"
+	"id-100/internal/repository"
+	"id-100/internal/utils"
+)
+
+// TusChunkKey returns the storage key of a tus chunk starting at offset
+func TusChunkKey(uploadID string, offset int64) string {
+	return "tus/" + uploadID + "/" + strconv.FormatInt(offset, 10)
+}
+
+// chunkReader reads stored chunks one after another, opening each only when needed
+type chunkReader struct {
+	ctx  context.Context
+	keys []string
+	cur  io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			body, err := utils.DownloadFromS3(r.ctx, r.keys[0])
+			if err != nil {
+				return 0, err
+			}
+			r.cur = body
+			r.keys = r.keys[1:]
+		}
+		n, err := r.cur.Read(p)
+		if err == io.EOF {
+			r.cur.Close()
+			r.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.cur != nil {
+		return r.cur.Close()
+	}
+	return nil
+}
+
+// FinishTus runs a fully received tus upload through the upload pipeline and
+// removes its chunks from storage.
+func FinishTus(ctx context.Context, u *models.TusUpload, p Params) (*Result, error) {
+	keys, err := repository.GetTusChunkKeys(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &chunkReader{ctx: ctx, keys: keys}
+	result, err := Store(ctx, r, p)
+	r.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repository.CompleteTusUpload(ctx, u.ID, result.ContributionID); err != nil {
+		return nil, err
+	}
+	deleteChunks(ctx, keys)
+	return result, nil
+}
+
+// AbortTus deletes a tus upload and all of its stored chunks
+func AbortTus(ctx context.Context, uploadID string) error {
+	keys, err := repository.GetTusChunkKeys(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+	if err := repository.DeleteTusUpload(ctx, uploadID); err != nil {
+		return err
+	}
+	deleteChunks(ctx, keys)
+	return nil
+}
+
+// CleanupStaleTus aborts unfinished uploads without progress for maxAge and
+// forgets completed ones older than maxAge
+func CleanupStaleTus(ctx context.Context, maxAge time.Duration) error {
+	before := time.Now().Add(-maxAge)
+	ids, err := repository.GetStaleTusUploads(ctx, before)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := AbortTus(ctx, id); err != nil {
+			return err
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("Removed %d stale tus uploads", len(ids))
+	}
+
+	_, err = repository.PurgeCompletedTusUploads(ctx, before)
+	return err
+}
+
+func deleteChunks(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := utils.DeleteFromS3(ctx, key); err != nil {
+			log.Printf("Failed to delete tus chunk %s: %v", key, err)
+		}
+	}
+}
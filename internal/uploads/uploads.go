@@ -0,0 +1,240 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/chai2010/webp"
+	"github.com/getsentry/sentry-go"
+	"github.com/jackc/pgx/v5"
+
+	"id-100/internal/consent"
+	"id-100/internal/imgutil"
+	"id-100/internal/models"
+	"id-100/internal/repository"
+	"id-100/internal/search"
+	"id-100/internal/sentryhelper"
+	"id-100/internal/utils"
+	"id-100/internal/webhooks"
+)
+
+// Upload pipeline shared by the HTML upload form and the participant API:
+// decode + auto-orient, WebP encode, store in S3, LQIP, contribution row,
+// upload log and token counter.
+
+// MaxCommentLength is the maximum length of a user comment in runes
+const MaxCommentLength = 100
+
+var (
+	// ErrInvalidImage is returned when the upload cannot be decoded as an image
+	ErrInvalidImage = errors.New("invalid image")
+	// ErrDeriveNotFound is returned for unknown derive numbers
+	ErrDeriveNotFound = errors.New("derive not found")
+	// ErrNotOwned is returned when a contribution does not belong to the current session
+	ErrNotOwned = errors.New("contribution not owned by session")
+	// ErrConsentRequired is returned while the session has not agreed to the
+	// current privacy policy version
+	ErrConsentRequired = errors.New("consent to current privacy policy required")
+)
+
+// Params describe a single upload
+type Params struct {
+	TokenID       int
+	SessionNumber int
+	DeriveNumber  int
+	PlayerName    string
+	PlayerCity    string
+	Comment       string
+}
+
+// Result describes a stored contribution
+type Result struct {
+	ContributionID int
+	DeriveNumber   int
+	ImageKey       string
+	ImageLqip      string
+}
+
+// TrimComment removes search highlight markers from a comment and cuts it to
+// MaxCommentLength runes
+func TrimComment(comment string) string {
+	comment = search.StripMarkers(comment)
+	runes := []rune(comment)
+	if len(runes) > MaxCommentLength {
+		return string(runes[:MaxCommentLength])
+	}
+	return comment
+}
+
+// Store runs the full upload pipeline for an image read from r
+func Store(ctx context.Context, r io.Reader, p Params) (*Result, error) {
+	// Decode and auto-orient based on EXIF so mobile uploads keep the correct rotation
+	img, err := imgutil.DecodeAutoOriented(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	return StoreImage(ctx, img, p)
+}
+
+// StoreImage stores an already decoded image as a new contribution
+func StoreImage(ctx context.Context, img image.Image, p Params) (*Result, error) {
+	internalID, err := repository.GetDeriveIDByNumber(ctx, strconv.Itoa(p.DeriveNumber))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeriveNotFound
+		}
+		return nil, err
+	}
+
+	// Every upload path ends here, so this is where a missing consent stops it
+	consented, err := repository.HasConsent(ctx, p.TokenID, p.SessionNumber, consent.PolicyVersion)
+	if err != nil {
+		return nil, err
+	}
+	if !consented {
+		return nil, ErrConsentRequired
+	}
+
+	// Contributions carry the public name; the typed one goes to the upload log
+	userName := p.PlayerName
+	if _, name, err := repository.GetSessionAttribution(ctx, p.TokenID, p.SessionNumber); err == nil {
+		userName = name
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, img, &webp.Options{Lossless: false, Quality: 75}); err != nil {
+		return nil, fmt.Errorf("webp encoding failed: %w", err)
+	}
+
+	// Store just the filename in DB
+	fileName := fmt.Sprintf("derive_%d_%d.webp", p.DeriveNumber, time.Now().UnixNano())
+	if err := utils.UploadToS3(ctx, fileName, buf.Bytes(), "image/webp"); err != nil {
+		return nil, err
+	}
+
+	// generate tiny LQIP (data-uri) and store it
+	lqip, lqipErr := utils.GenerateLQIP(img, 24)
+	if lqipErr != nil {
+		log.Printf("LQIP generation failed: %v", lqipErr)
+		sentryhelper.CaptureContextError(ctx, lqipErr, sentry.LevelWarning)
+		lqip = ""
+	}
+
+	contributionID, err := repository.InsertContribution(ctx,
+		internalID, fileName, lqip, userName, p.PlayerCity, TrimComment(p.Comment))
+	if err != nil {
+		if delErr := utils.DeleteFromS3(ctx, fileName); delErr != nil {
+			log.Printf("Failed to remove orphaned upload %s: %v", fileName, delErr)
+		}
+		return nil, fmt.Errorf("insert contribution: %w", err)
+	}
+
+	if err := repository.InsertUploadLog(ctx, p.TokenID, p.DeriveNumber, p.PlayerName, p.SessionNumber, contributionID); err != nil {
+		log.Printf("Failed to log upload: %v", err)
+		sentryhelper.CaptureContextError(ctx, err, sentry.LevelWarning)
+	}
+
+	if err := repository.IncrementTokenUploadCount(ctx, p.TokenID); err != nil {
+		log.Printf("Failed to increment upload counter: %v", err)
+		sentryhelper.CaptureContextError(ctx, err, sentry.LevelWarning)
+	}
+
+	webhooks.Emit(ctx, webhooks.EventContributionCreated, webhooks.ContributionCreated{
+		APIContribution: models.APIContribution{
+			ID:           contributionID,
+			DeriveNumber: p.DeriveNumber,
+			ImageURL:     utils.EnsureFullImageURL(fileName),
+			UserName:     userName,
+			UserCity:     p.PlayerCity,
+			UserComment:  TrimComment(p.Comment),
+			CreatedAt:    time.Now(),
+		},
+		TokenID:       p.TokenID,
+		SessionNumber: p.SessionNumber,
+	})
+
+	return &Result{
+		ContributionID: contributionID,
+		DeriveNumber:   p.DeriveNumber,
+		ImageKey:       fileName,
+		ImageLqip:      lqip,
+	}, nil
+}
+
+// DeleteOwn removes a contribution uploaded in the given token session,
+// including its upload log, the token counter and the stored image.
+func DeleteOwn(ctx context.Context, contributionID, tokenID, sessionNumber int) error {
+	imageURL, err := repository.GetContributionForDeletion(ctx, contributionID, tokenID, sessionNumber)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotOwned
+		}
+		return err
+	}
+
+	if err := repository.DeleteUploadLog(ctx, contributionID); err != nil {
+		return fmt.Errorf("delete upload log: %w", err)
+	}
+
+	rowsAffected, err := repository.DeleteContribution(ctx, contributionID)
+	if err != nil {
+		return fmt.Errorf("delete contribution: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotOwned
+	}
+
+	if err := repository.DecrementTokenUploadCount(ctx, tokenID); err != nil {
+		log.Printf("Failed to decrement upload counter: %v", err)
+		sentryhelper.CaptureContextError(ctx, err, sentry.LevelWarning)
+	}
+
+	if imageURL != "" {
+		if err := utils.DeleteFromS3(ctx, imageURL); err != nil {
+			log.Printf("Failed to delete from S3 (continuing anyway): %v", err)
+			sentryhelper.CaptureContextError(ctx, err, sentry.LevelWarning)
+		}
+	}
+
+	webhooks.Emit(ctx, webhooks.EventContributionDeleted, webhooks.ContributionDeleted{
+		ID:        contributionID,
+		TokenID:   tokenID,
+		DeletedBy: "player",
+	})
+
+	return nil
+}
+
+// DeleteSession removes all contributions of a bag session (admin action),
+// including upload logs and stored images, and returns how many were deleted
+func DeleteSession(ctx context.Context, tokenID, sessionNumber int) (int, error) {
+	deleted, err := repository.DeleteSessionContributions(ctx, tokenID, sessionNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, u := range deleted {
+		if u.ImageURL != "" {
+			if err := utils.DeleteFromS3(ctx, u.ImageURL); err != nil {
+				log.Printf("Failed to delete from S3 (continuing anyway): %v", err)
+				sentryhelper.CaptureContextError(ctx, err, sentry.LevelWarning)
+			}
+		}
+		webhooks.Emit(ctx, webhooks.EventContributionDeleted, webhooks.ContributionDeleted{
+			ID:        u.ContributionID,
+			TokenID:   tokenID,
+			DeletedBy: "admin",
+		})
+	}
+
+	return len(deleted), nil
+}
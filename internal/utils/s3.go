@@ -1,16 +1,21 @@
 package utils
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 // extractFileNameFromURL extracts the filename from a MinIO/S3 storage URL
@@ -44,17 +49,8 @@ func extractFileNameFromURL(imageURL string) (string, error) {
 	return fileName, nil
 }
 
-// DeleteFromS3 extracts the file key from the image URL and deletes it from S3/MinIO
-func DeleteFromS3(ctx context.Context, imageURL string) error {
-	// Extract the filename from the URL path
-	// Example: http://minio:9000/id100-images/derive_1_1234567890.webp
-	// or: http://localhost:9000/id100-images/derive_1_1234567890.webp
-	fileName, err := extractFileNameFromURL(imageURL)
-	if err != nil {
-		return err
-	}
-
-	// Create S3 client
+// NewS3Client creates an S3 client for the configured MinIO/S3 endpoint
+func NewS3Client(ctx context.Context) (*s3.Client, error) {
 	cfg, err := config.LoadDefaultConfig(ctx,
 		config.WithRegion(os.Getenv("S3_REGION")),
 		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
@@ -64,15 +60,163 @@ func DeleteFromS3(ctx context.Context, imageURL string) error {
 		),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to load S3 config: %w", err)
+		return nil, fmt.Errorf("failed to load S3 config: %w", err)
 	}
 
-	s3Client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
 		if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" {
 			o.BaseEndpoint = aws.String(endpoint)
 		}
 		o.UsePathStyle = true
+	}), nil
+}
+
+// newPublicS3Client creates an S3 client for the browser-accessible endpoint (S3_PUBLIC_URL),
+// used for presigning so signatures match the host the client talks to
+func newPublicS3Client(ctx context.Context) (*s3.Client, error) {
+	client, err := NewS3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	publicURL := strings.TrimRight(os.Getenv("S3_PUBLIC_URL"), "/")
+	if publicURL == "" {
+		return client, nil
+	}
+	return s3.New(client.Options(), func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(publicURL)
+	}), nil
+}
+
+// PresignedUpload holds the credentials for a direct upload to object storage.
+// Clients either PUT the file to PutURL with the given Content-Type, or send a
+// multipart POST to PostURL with PostFields followed by the file field.
+type PresignedUpload struct {
+	PutURL     string            `json:"put_url"`
+	PostURL    string            `json:"post_url"`
+	PostFields map[string]string `json:"post_fields"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+}
+
+// PresignUpload creates presigned PUT and POST requests for key. The POST policy
+// limits the object size to maxSize; PUT uploads are checked on finalisation.
+func PresignUpload(ctx context.Context, key, contentType string, maxSize int64, expires time.Duration) (*PresignedUpload, error) {
+	client, err := newPublicS3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	presigner := s3.NewPresignClient(client)
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(os.Getenv("S3_BUCKET")),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	}
+
+	put, err := presigner.PresignPutObject(ctx, input, s3.WithPresignExpires(expires))
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign PUT: %w", err)
+	}
+
+	post, err := presigner.PresignPostObject(ctx, input, func(o *s3.PresignPostOptions) {
+		o.Expires = expires
+		o.Conditions = []any{
+			[]any{"content-length-range", 1, maxSize},
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign POST: %w", err)
+	}
+
+	return &PresignedUpload{
+		PutURL:     put.URL,
+		PostURL:    post.URL,
+		PostFields: post.Values,
+		ExpiresAt:  time.Now().Add(expires),
+	}, nil
+}
+
+// HeadS3Object returns size and content type of the object stored under key
+func HeadS3Object(ctx context.Context, key string) (int64, string, error) {
+	s3Client, err := NewS3Client(ctx)
+	if err != nil {
+		return 0, "", err
+	}
+
+	out, err := s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(os.Getenv("S3_BUCKET")),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	return aws.ToInt64(out.ContentLength), aws.ToString(out.ContentType), nil
+}
+
+// IsS3NotFound reports whether err means the object does not exist
+func IsS3NotFound(err error) bool {
+	var notFound *types.NotFound
+	var noSuchKey *types.NoSuchKey
+	return errors.As(err, &notFound) || errors.As(err, &noSuchKey)
+}
+
+// UploadToS3 stores an object under key in the configured bucket
+func UploadToS3(ctx context.Context, key string, body []byte, contentType string) error {
+	s3Client, err := NewS3Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(os.Getenv("S3_BUCKET")),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload to S3: %w", err)
+	}
+	return nil
+}
+
+// DownloadFromS3 opens the object stored under key; the caller closes the body
+func DownloadFromS3(ctx context.Context, key string) (io.ReadCloser, error) {
+	s3Client, err := NewS3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(os.Getenv("S3_BUCKET")),
+		Key:    aws.String(key),
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from S3: %w", err)
+	}
+	return out.Body, nil
+}
+
+// DownloadImageFromS3 opens the object behind a stored image URL; the caller closes the body
+func DownloadImageFromS3(ctx context.Context, imageURL string) (io.ReadCloser, error) {
+	fileName, err := extractFileNameFromURL(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	return DownloadFromS3(ctx, fileName)
+}
+
+// DeleteFromS3 extracts the file key from the image URL and deletes it from S3/MinIO
+func DeleteFromS3(ctx context.Context, imageURL string) error {
+	// Extract the filename from the URL path
+	// Example: http://minio:9000/id100-images/derive_1_1234567890.webp
+	// or: http://localhost:9000/id100-images/derive_1_1234567890.webp
+	fileName, err := extractFileNameFromURL(imageURL)
+	if err != nil {
+		return err
+	}
+
+	s3Client, err := NewS3Client(ctx)
+	if err != nil {
+		return err
+	}
 
 	// Delete the object from S3
 	_, err = s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
@@ -2,7 +2,9 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 )
 
@@ -21,3 +23,10 @@ func GenerateSecureToken(length int) (string, error) {
 	}
 	return encoded, nil
 }
+
+// HashToken returns the hex-encoded SHA-256 of an upload token. Only the hash
+// is stored; tokens carry enough entropy that a salt is not needed.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -77,3 +77,14 @@ func TestGenerateSecureTokenEdgeCases(t *testing.T) {
 		t.Errorf("GenerateSecureToken(0) length = %d, want 0", len(token))
 	}
 }
+
+func TestHashToken(t *testing.T) {
+	// Must match encode(sha256(convert_to(token, 'UTF8')), 'hex') in migration 016
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := HashToken("abc"); got != want {
+		t.Errorf("HashToken(abc) = %s, want %s", got, want)
+	}
+	if HashToken("abc") == HashToken("abd") {
+		t.Error("different tokens must have different hashes")
+	}
+}
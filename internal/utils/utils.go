@@ -79,3 +79,11 @@ func SanitizeFilename(name string) string {
 		return r
 	}, name)
 }
+
+// CSVSafe neutralises cell values that spreadsheet programs would evaluate as formulas
+func CSVSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -79,3 +79,25 @@ func TestSanitizeFilenameHeaderInjection(t *testing.T) {
 		}
 	}
 }
+
+func TestCSVSafe(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"Mia Muster", "Mia Muster"},
+		{"34117", "34117"},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+49 561", "'+49 561"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := CSVSafe(tt.input); got != tt.want {
+			t.Errorf("CSVSafe(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"id-100/internal/models"
+	"id-100/internal/repository"
+)
+
+// Payload data of the individual events
+
+// ContributionCreated is sent with contribution.created
+type ContributionCreated struct {
+	models.APIContribution
+	TokenID       int `json:"token_id"`
+	SessionNumber int `json:"session_number"`
+}
+
+// ContributionDeleted is sent with contribution.deleted; DeletedBy is "player" or "admin"
+type ContributionDeleted struct {
+	ID        int    `json:"id"`
+	TokenID   int    `json:"token_id"`
+	DeletedBy string `json:"deleted_by"`
+}
+
+// BagRequestCreated is sent with bag_request.created
+type BagRequestCreated struct {
+	ID        int       `json:"id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Session is sent with session.started and session.ended; EndedBy is "player", "admin",
+// "handover", or "time_limit" / "idle_timeout" when the bag's ruleset ended it
+type Session struct {
+	TokenID       int    `json:"token_id"`
+	BagName       string `json:"bag_name"`
+	SessionNumber int    `json:"session_number"`
+	PlayerName    string `json:"player_name,omitempty"`
+	PlayerCity    string `json:"player_city,omitempty"`
+	EndedBy       string `json:"ended_by,omitempty"`
+}
+
+// PublicPlayerName returns the name of a session as the gallery shows it, following
+// the player's name display choice, for the PlayerName of Session. The typed name is
+// never sent; sessions missing from the history send no name.
+func PublicPlayerName(ctx context.Context, tokenID, sessionNumber int) string {
+	_, name, err := repository.GetSessionAttribution(ctx, tokenID, sessionNumber)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to load attribution of session %d of token %d for webhook: %v", sessionNumber, tokenID, err)
+		}
+		return ""
+	}
+	return name
+}
+
+// TokenCreated is sent with token.created. The token secret itself is never sent.
+type TokenCreated struct {
+	TokenID    int    `json:"token_id"`
+	BagName    string `json:"bag_name"`
+	MaxUploads int    `json:"max_uploads"`
+}
@@ -0,0 +1,93 @@
+package webhooks
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+
+	"id-100/internal/repository"
+)
+
+const (
+	// batchSize is the number of deliveries one worker run attempts
+	batchSize = 20
+	// claimLease keeps a claimed delivery away from other instances while it is attempted
+	claimLease = 2 * time.Minute
+	// DeliveryRetention is how long finished deliveries stay in the log
+	DeliveryRetention = 30 * 24 * time.Hour
+)
+
+// client is shared by all deliveries; redirects are not followed so a
+// receiver cannot bounce signed payloads to another host
+var client = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Emit queues event for all active subscriptions. Failures are logged and reported
+// but never returned: a webhook problem must not fail the action that caused it.
+func Emit(ctx context.Context, event string, data interface{}) {
+	payload, err := NewPayload(event, data, time.Now())
+	if err != nil {
+		log.Printf("Failed to encode webhook %s: %v", event, err)
+		sentry.CaptureException(err)
+		return
+	}
+	if _, err := repository.EnqueueWebhookDeliveries(context.WithoutCancel(ctx), event, payload); err != nil {
+		log.Printf("Failed to queue webhook %s: %v", event, err)
+		sentry.CaptureException(err)
+	}
+}
+
+// Ping queues a test event for a single subscription
+func Ping(ctx context.Context, webhookID int) (bool, error) {
+	payload, err := NewPayload(EventPing, map[string]interface{}{"webhook_id": webhookID}, time.Now())
+	if err != nil {
+		return false, err
+	}
+	n, err := repository.EnqueueWebhookDelivery(ctx, webhookID, EventPing, payload)
+	return n > 0, err
+}
+
+// ProcessDue attempts all due deliveries, rescheduling failures with exponential backoff
+func ProcessDue(ctx context.Context) error {
+	for {
+		due, err := repository.ClaimDueWebhookDeliveries(ctx, batchSize, claimLease)
+		if err != nil {
+			return err
+		}
+		for _, d := range due {
+			status, sendErr := Send(ctx, client, Request{
+				URL:        d.WebhookURL,
+				Secret:     d.Secret,
+				Event:      d.Event,
+				DeliveryID: d.ID,
+				Payload:    d.Payload,
+			}, time.Now())
+			if sendErr == nil {
+				err = repository.MarkWebhookDelivered(ctx, d.ID, status)
+			} else {
+				var responseStatus *int
+				if status != 0 {
+					responseStatus = &status
+				}
+				var next *time.Time
+				if attempts := d.Attempts + 1; attempts < MaxAttempts {
+					t := time.Now().Add(Backoff(attempts))
+					next = &t
+				}
+				err = repository.MarkWebhookAttemptFailed(ctx, d.ID, responseStatus, truncate(sendErr.Error()), next)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if len(due) < batchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// signatureTolerance is the maximum clock skew accepted by the receiver
+const signatureTolerance = 5 * time.Minute
+
+// Receiver is a minimal webhook endpoint for local testing: it verifies the
+// signature and prints every delivery to Out
+type Receiver struct {
+	Secret string
+	Out    io.Writer
+	mu     sync.Mutex
+}
+
+// ServeHTTP implements http.Handler
+func (rc *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "read error", http.StatusBadRequest)
+		return
+	}
+
+	valid := rc.Secret == "" || Verify(rc.Secret, r.Header.Get(HeaderSignature), body, signatureTolerance, time.Now())
+
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, body, "", "  ") != nil {
+		pretty.Reset()
+		pretty.Write(body)
+	}
+
+	rc.mu.Lock()
+	status := "signature ok"
+	switch {
+	case rc.Secret == "":
+		status = "signature not checked"
+	case !valid:
+		status = "INVALID SIGNATURE"
+	}
+	fmt.Fprintf(rc.Out, "--- %s delivery=%s (%s)\n%s\n", r.Header.Get(HeaderEvent), r.Header.Get(HeaderDelivery), status, pretty.String())
+	rc.mu.Unlock()
+
+	if !valid {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
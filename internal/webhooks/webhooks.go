@@ -0,0 +1,203 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"id-100/internal/version"
+)
+
+// Outbound webhooks. Events are queued in webhook_deliveries and POSTed as JSON to
+// every subscription; receivers verify the X-ID100-Signature header with their secret.
+
+// Event names a subscription can listen to
+const (
+	EventContributionCreated = "contribution.created"
+	EventContributionDeleted = "contribution.deleted"
+	EventBagRequestCreated   = "bag_request.created"
+	EventSessionStarted      = "session.started"
+	EventSessionEnded        = "session.ended"
+	EventTokenCreated        = "token.created"
+	// EventPing is only sent by the admin test button
+	EventPing = "ping"
+)
+
+// Events lists all subscribable events in display order
+var Events = []string{
+	EventContributionCreated,
+	EventContributionDeleted,
+	EventBagRequestCreated,
+	EventSessionStarted,
+	EventSessionEnded,
+	EventTokenCreated,
+}
+
+// Request headers sent with every delivery
+const (
+	HeaderEvent     = "X-ID100-Event"
+	HeaderDelivery  = "X-ID100-Delivery"
+	HeaderSignature = "X-ID100-Signature"
+)
+
+const (
+	// MaxAttempts is how often a delivery is tried before it is marked failed
+	MaxAttempts = 10
+	// baseBackoff is the delay after the first failed attempt; it doubles per attempt
+	baseBackoff = 30 * time.Second
+	// maxBackoff caps the delay between attempts
+	maxBackoff = 6 * time.Hour
+	// requestTimeout bounds a single delivery attempt
+	requestTimeout = 10 * time.Second
+	// maxErrorLength keeps stored error messages and response excerpts short
+	maxErrorLength = 500
+)
+
+// IsValidEvent reports whether name is a subscribable event
+func IsValidEvent(name string) bool {
+	for _, e := range Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Envelope is the JSON body of every delivery
+type Envelope struct {
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// NewPayload encodes an event envelope
+func NewPayload(event string, data interface{}, occurredAt time.Time) (string, error) {
+	body, err := json.Marshal(Envelope{Event: event, OccurredAt: occurredAt.UTC(), Data: data})
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// Sign returns the signature header value for body sent at timestamp:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">"
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + computeSignature(secret, t, body)
+}
+
+func computeSignature(secret, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header against body. Signatures older than tolerance
+// are rejected to prevent replays; tolerance 0 disables the age check.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) bool {
+	var t, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			sig = v
+		}
+	}
+	if t == "" || sig == "" {
+		return false
+	}
+	if tolerance > 0 {
+		unix, err := strconv.ParseInt(t, 10, 64)
+		if err != nil {
+			return false
+		}
+		if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+			return false
+		}
+	}
+	return hmac.Equal([]byte(sig), []byte(computeSignature(secret, t, body)))
+}
+
+// Backoff returns the delay before the next attempt after attempts failed attempts
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+// Request describes a single delivery attempt
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID int64
+	Payload    string
+}
+
+// Send POSTs the payload to the receiver. Any 2xx response counts as delivered;
+// otherwise the returned error describes the failure and status is the response code (0 if none).
+func Send(ctx context.Context, client *http.Client, r Request, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	body := []byte(r.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "id-100-webhooks/"+version.Version)
+	req.Header.Set(HeaderEvent, r.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(r.DeliveryID, 10))
+	req.Header.Set(HeaderSignature, Sign(r.Secret, now, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return resp.StatusCode, nil
+	}
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+	return resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(excerpt)))
+}
+
+// truncate cuts s to maxErrorLength bytes at a rune boundary
+func truncate(s string) string {
+	if len(s) <= maxErrorLength {
+		return s
+	}
+	cut := maxErrorLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
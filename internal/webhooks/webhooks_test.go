@@ -0,0 +1,117 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"event":"ping"}`)
+	header := Sign("geheim", now, body)
+
+	if !strings.HasPrefix(header, "t=1780315200,v1=") {
+		t.Errorf("unexpected header format: %s", header)
+	}
+	if !Verify("geheim", header, body, time.Minute, now.Add(30*time.Second)) {
+		t.Error("valid signature was rejected")
+	}
+	if Verify("anders", header, body, time.Minute, now) {
+		t.Error("signature with wrong secret was accepted")
+	}
+	if Verify("geheim", header, []byte(`{"event":"pong"}`), time.Minute, now) {
+		t.Error("signature for modified body was accepted")
+	}
+	if Verify("geheim", header, body, time.Minute, now.Add(2*time.Minute)) {
+		t.Error("expired signature was accepted")
+	}
+	if !Verify("geheim", header, body, 0, now.Add(24*time.Hour)) {
+		t.Error("tolerance 0 should skip the age check")
+	}
+	if Verify("geheim", "v1=abc", body, 0, now) {
+		t.Error("header without timestamp was accepted")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{8, 64 * time.Minute},
+		{20, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestIsValidEvent(t *testing.T) {
+	if !IsValidEvent(EventContributionCreated) {
+		t.Error("contribution.created should be valid")
+	}
+	if IsValidEvent(EventPing) || IsValidEvent("contribution.updated") {
+		t.Error("ping and unknown events are not subscribable")
+	}
+}
+
+func TestSendToReceiver(t *testing.T) {
+	var out bytes.Buffer
+	srv := httptest.NewServer(&Receiver{Secret: "geheim", Out: &out})
+	defer srv.Close()
+
+	payload, err := NewPayload(EventTokenCreated, map[string]int{"token_id": 7}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := Send(context.Background(), srv.Client(), Request{
+		URL: srv.URL, Secret: "geheim", Event: EventTokenCreated, DeliveryID: 42, Payload: payload,
+	}, time.Now())
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("Send = %d, %v; want 204, nil", status, err)
+	}
+	if !strings.Contains(out.String(), "token.created delivery=42 (signature ok)") || !strings.Contains(out.String(), `"token_id": 7`) {
+		t.Errorf("unexpected receiver output:\n%s", out.String())
+	}
+
+	// Wrong secret: receiver rejects, Send reports the failure with status code
+	status, err = Send(context.Background(), srv.Client(), Request{
+		URL: srv.URL, Secret: "falsch", Event: EventTokenCreated, DeliveryID: 43, Payload: payload,
+	}, time.Now())
+	if err == nil || status != http.StatusUnauthorized {
+		t.Errorf("Send with wrong secret = %d, %v; want 401 and error", status, err)
+	}
+}
+
+func TestSendConnectionError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	status, err := Send(context.Background(), http.DefaultClient, Request{URL: url, Payload: "{}"}, time.Now())
+	if err == nil || status != 0 {
+		t.Errorf("Send to closed server = %d, %v; want 0 and error", status, err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	long := strings.Repeat("ä", maxErrorLength)
+	got := truncate(long)
+	if len(got) > maxErrorLength || !strings.HasPrefix(long, got) {
+		t.Errorf("truncate produced %d bytes, must be a prefix of at most %d", len(got), maxErrorLength)
+	}
+	if truncate("kurz") != "kurz" {
+		t.Error("short strings must be unchanged")
+	}
+}
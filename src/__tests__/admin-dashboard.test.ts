@@ -6,11 +6,26 @@ import {
   initAdminDashboard,
   resetToken,
   deactivateToken,
+  releaseDevice,
   updateQuota,
+  updateExpiry,
+  rotateToken,
+  assignRuleset,
+  rulesetPayload,
+  saveRuleset,
+  deleteRuleset,
+  reopenSession,
+  deleteSessionUploads,
   downloadQR,
+  printLabels,
   copyUploadURL,
-  markBagRequestDone,
+  setBagRequestStatus,
+  assignBagRequestToken,
+  saveBagRequestNotes,
   deleteContribution,
+  testWebhook,
+  deleteWebhook,
+  eraseSubjectData,
 } from "../lib/admin-dashboard";
 
 describe("initAdminDashboard", () => {
@@ -47,6 +62,7 @@ describe("initAdminDashboard", () => {
       <form id="createTokenForm">
         <input id="bagName" value="Werkzeug #1" />
         <input id="maxUploads" value="100" />
+        <input id="expiresAt" type="date" value="2026-12-31" />
         <button type="submit">Submit</button>
       </form>
       <div id="createResult"></div>
@@ -63,7 +79,11 @@ describe("initAdminDashboard", () => {
       expect(mockFetch).toHaveBeenCalledWith("/admin/tokens", {
         method: "POST",
         headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ bag_name: "Werkzeug #1", max_uploads: 100 }),
+        body: JSON.stringify({
+          bag_name: "Werkzeug #1",
+          max_uploads: 100,
+          expires_at: "2026-12-31",
+        }),
       });
     });
   });
@@ -133,6 +153,90 @@ describe("initAdminDashboard", () => {
   });
 });
 
+describe("token batch form", () => {
+  const batchForm = `
+    <form id="createTokenBatchForm">
+      <input id="batchPattern" value="Werkzeug {n}" />
+      <input id="batchCount" value="3" />
+      <input id="batchStart" value="1" />
+      <input id="batchMaxUploads" value="50" />
+      <input id="batchGroup" value="Workshop" />
+      <input id="batchFile" type="file" />
+      <button type="submit">Submit</button>
+    </form>
+    <div id="createBatchResult"></div>
+  `;
+
+  beforeEach(() => {
+    window.alert = vi.fn();
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it("should post the name pattern and render the created tokens", async () => {
+    const mockFetch = vi.fn().mockResolvedValue({
+      ok: true,
+      json: () =>
+        Promise.resolve({
+          group: "Workshop",
+          count: 1,
+          tokens: [
+            {
+              id: 7,
+              bag_name: "<b>Werkzeug 1</b>",
+              upload_url: "http://example.com/upload?token=abc",
+              qr_url: "http://example.com/admin/tokens/7/qr",
+            },
+          ],
+          export_csv: "/admin/tokens/export.csv?group=Workshop",
+          export_json: "/admin/tokens/export.json?group=Workshop",
+        }),
+    });
+    global.fetch = mockFetch;
+    document.body.innerHTML = batchForm;
+
+    initAdminDashboard();
+    const form = document.getElementById("createTokenBatchForm") as HTMLFormElement;
+    form.dispatchEvent(new Event("submit", { bubbles: true, cancelable: true }));
+
+    await vi.waitFor(() => {
+      expect(mockFetch).toHaveBeenCalledWith("/admin/tokens/batch", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          pattern: "Werkzeug {n}",
+          count: 3,
+          start: 1,
+          max_uploads: 50,
+          group: "Workshop",
+          expires_at: "",
+        }),
+      });
+      const result = document.getElementById("createBatchResult") as HTMLElement;
+      expect(result.style.display).toBe("block");
+      expect(result.querySelector("td")?.textContent).toBe("<b>Werkzeug 1</b>");
+      expect(result.querySelector("b")).toBeNull();
+    });
+  });
+
+  it("should require a pattern when no file is chosen", async () => {
+    global.fetch = vi.fn();
+    document.body.innerHTML = batchForm;
+    (document.getElementById("batchPattern") as HTMLInputElement).value = " ";
+
+    initAdminDashboard();
+    const form = document.getElementById("createTokenBatchForm") as HTMLFormElement;
+    form.dispatchEvent(new Event("submit", { bubbles: true, cancelable: true }));
+
+    await vi.waitFor(() => {
+      expect(window.alert).toHaveBeenCalled();
+    });
+    expect(global.fetch).not.toHaveBeenCalled();
+  });
+});
+
 describe("resetToken", () => {
   beforeEach(() => {
     global.fetch = vi.fn();
@@ -208,6 +312,201 @@ describe("deactivateToken", () => {
   });
 });
 
+describe("releaseDevice", () => {
+  beforeEach(() => {
+    window.alert = vi.fn();
+    window.location.reload = vi.fn() as any;
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it("should release the device after confirmation", async () => {
+    window.confirm = vi.fn(() => true);
+    const mockFetch = vi.fn().mockResolvedValue({
+      json: () => Promise.resolve({ message: "Gerät freigegeben." }),
+    });
+    global.fetch = mockFetch;
+
+    await releaseDevice(4, "Werkzeug 4");
+
+    expect(mockFetch).toHaveBeenCalledWith("/admin/tokens/4/release-device", { method: "POST" });
+    expect(window.alert).toHaveBeenCalledWith("Gerät freigegeben.");
+    expect(window.location.reload).toHaveBeenCalled();
+  });
+
+  it("should not release without confirmation", async () => {
+    window.confirm = vi.fn(() => false);
+    const mockFetch = vi.fn();
+    global.fetch = mockFetch;
+
+    await releaseDevice(4, "Werkzeug 4");
+
+    expect(mockFetch).not.toHaveBeenCalled();
+  });
+});
+
+describe("bag session history", () => {
+  beforeEach(() => {
+    window.alert = vi.fn();
+    window.confirm = vi.fn(() => true);
+    window.location.reload = vi.fn() as any;
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it("should reopen a session", async () => {
+    const mockFetch = vi.fn().mockResolvedValue({
+      ok: true,
+      json: () => Promise.resolve({ message: "Session #3 läuft wieder" }),
+    });
+    global.fetch = mockFetch;
+
+    await reopenSession(7, 3);
+
+    expect(mockFetch).toHaveBeenCalledWith("/admin/tokens/7/sessions/3/reopen", { method: "POST" });
+    expect(window.alert).toHaveBeenCalledWith("Session #3 läuft wieder");
+    expect(window.location.reload).toHaveBeenCalled();
+  });
+
+  it("should show the conflict message when a session cannot be reopened", async () => {
+    global.fetch = vi.fn().mockResolvedValue({
+      ok: false,
+      json: () => Promise.resolve({ error: "Nur die zuletzt beendete Session" }),
+    });
+
+    await reopenSession(7, 1);
+
+    expect(window.alert).toHaveBeenCalledWith("Fehler: Nur die zuletzt beendete Session");
+    expect(window.location.reload).not.toHaveBeenCalled();
+  });
+
+  it("should delete the uploads of a session", async () => {
+    const mockFetch = vi.fn().mockResolvedValue({
+      ok: true,
+      json: () => Promise.resolve({ deleted: 4, message: "4 Beiträge gelöscht" }),
+    });
+    global.fetch = mockFetch;
+
+    await deleteSessionUploads(7, 2, 4);
+
+    expect(window.confirm).toHaveBeenCalledWith(expect.stringContaining("4 Beiträge"));
+    expect(mockFetch).toHaveBeenCalledWith("/admin/tokens/7/sessions/2/delete-uploads", {
+      method: "POST",
+    });
+    expect(window.location.reload).toHaveBeenCalled();
+  });
+
+  it("should not delete without confirmation", async () => {
+    window.confirm = vi.fn(() => false);
+    const mockFetch = vi.fn();
+    global.fetch = mockFetch;
+
+    await deleteSessionUploads(7, 2, 4);
+
+    expect(mockFetch).not.toHaveBeenCalled();
+  });
+});
+
+describe("rulesets", () => {
+  const editor = `
+    <form id="rulesetForm">
+      <input name="name" value=" Schule " />
+      <input name="cooldown_seconds" value="30" />
+      <input name="session_uploads" value="" />
+      <input name="lifetime_uploads" value="500" />
+      <input name="session_minutes" value="90" />
+      <input name="idle_minutes" value="abc" />
+      <input name="allowed_derives" value="1-20, 35" />
+    </form>`;
+
+  beforeEach(() => {
+    window.alert = vi.fn();
+    window.location.reload = vi.fn() as any;
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it("should read the editor form with empty limits as 0", () => {
+    document.body.innerHTML = editor;
+    const form = document.getElementById("rulesetForm") as HTMLFormElement;
+
+    expect(rulesetPayload(form)).toEqual({
+      name: "Schule",
+      cooldown_seconds: 30,
+      session_uploads: 0,
+      lifetime_uploads: 500,
+      session_minutes: 90,
+      idle_minutes: 0,
+      allowed_derives: "1-20, 35",
+    });
+  });
+
+  it("should create a new ruleset for id 0 and update existing ones", async () => {
+    const mockFetch = vi.fn().mockResolvedValue({
+      ok: true,
+      json: () => Promise.resolve({ status: "success" }),
+    });
+    global.fetch = mockFetch;
+    document.body.innerHTML = editor;
+    const form = document.getElementById("rulesetForm") as HTMLFormElement;
+
+    await saveRuleset(0, form);
+    await saveRuleset(7, form);
+
+    expect(mockFetch.mock.calls[0][0]).toBe("/admin/rulesets");
+    expect(mockFetch.mock.calls[1][0]).toBe("/admin/rulesets/7");
+    expect(JSON.parse(mockFetch.mock.calls[1][1].body).lifetime_uploads).toBe(500);
+    expect(window.location.reload).toHaveBeenCalledTimes(2);
+  });
+
+  it("should show validation errors", async () => {
+    global.fetch = vi.fn().mockResolvedValue({
+      ok: false,
+      json: () => Promise.resolve({ error: "Name erforderlich" }),
+    });
+    document.body.innerHTML = editor;
+
+    await saveRuleset(0, document.getElementById("rulesetForm") as HTMLFormElement);
+
+    expect(window.alert).toHaveBeenCalledWith("Fehler: Name erforderlich");
+    expect(window.location.reload).not.toHaveBeenCalled();
+  });
+
+  it("should assign the selected ruleset to a token", async () => {
+    const mockFetch = vi.fn().mockResolvedValue({ ok: true, json: () => Promise.resolve({}) });
+    global.fetch = mockFetch;
+    document.body.innerHTML =
+      '<select id="ruleset-5"><option value="0">Standard</option><option value="2" selected>Schule</option></select>';
+
+    await assignRuleset(5);
+
+    expect(mockFetch).toHaveBeenCalledWith("/admin/tokens/5/ruleset", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ ruleset_id: 2 }),
+    });
+  });
+
+  it("should only delete after confirmation", async () => {
+    const mockFetch = vi.fn().mockResolvedValue({ ok: true, json: () => Promise.resolve({}) });
+    global.fetch = mockFetch;
+
+    window.confirm = vi.fn(() => false);
+    await deleteRuleset(2, "Schule");
+    expect(mockFetch).not.toHaveBeenCalled();
+
+    window.confirm = vi.fn(() => true);
+    await deleteRuleset(2, "Schule");
+    expect(mockFetch).toHaveBeenCalledWith("/admin/rulesets/2/delete", { method: "POST" });
+  });
+});
+
 describe("updateQuota", () => {
   beforeEach(() => {
     document.body.innerHTML = "";
@@ -264,6 +563,102 @@ describe("updateQuota", () => {
   });
 });
 
+describe("updateExpiry", () => {
+  beforeEach(() => {
+    window.alert = vi.fn();
+    window.location.reload = vi.fn() as any;
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it("should send the chosen date", async () => {
+    const mockFetch = vi.fn().mockResolvedValue({
+      ok: true,
+      json: () => Promise.resolve({ status: "success" }),
+    });
+    global.fetch = mockFetch;
+    document.body.innerHTML = '<input id="expiry-3" type="date" value="2026-12-31" />';
+
+    await updateExpiry(3);
+
+    expect(mockFetch).toHaveBeenCalledWith("/admin/tokens/3/expiry", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ expires_at: "2026-12-31" }),
+    });
+    expect(window.alert).toHaveBeenCalledWith("✅ Gültig bis 2026-12-31");
+    expect(window.location.reload).toHaveBeenCalled();
+  });
+
+  it("should clear the expiry for an empty date", async () => {
+    global.fetch = vi.fn().mockResolvedValue({
+      ok: true,
+      json: () => Promise.resolve({ status: "success" }),
+    });
+    document.body.innerHTML = '<input id="expiry-3" type="date" value="" />';
+
+    await updateExpiry(3);
+
+    expect(window.alert).toHaveBeenCalledWith("✅ Ablaufdatum entfernt");
+  });
+
+  it("should show the server error", async () => {
+    global.fetch = vi.fn().mockResolvedValue({
+      ok: false,
+      json: () => Promise.resolve({ error: "expiry lies in the past" }),
+    });
+    document.body.innerHTML = '<input id="expiry-3" type="date" value="2020-01-01" />';
+
+    await updateExpiry(3);
+
+    expect(window.alert).toHaveBeenCalledWith("Fehler: expiry lies in the past");
+    expect(window.location.reload).not.toHaveBeenCalled();
+  });
+});
+
+describe("rotateToken", () => {
+  beforeEach(() => {
+    window.alert = vi.fn();
+    window.prompt = vi.fn();
+    window.location.reload = vi.fn() as any;
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it("should rotate after confirmation and show the new link", async () => {
+    window.confirm = vi.fn(() => true);
+    const mockFetch = vi.fn().mockResolvedValue({
+      ok: true,
+      json: () =>
+        Promise.resolve({ code: "K7P-4QX", upload_url: "http://example.com/b/K7P-4QX" }),
+    });
+    global.fetch = mockFetch;
+
+    await rotateToken(5, "Werkzeug 5");
+
+    expect(mockFetch).toHaveBeenCalledWith("/admin/tokens/5/rotate", { method: "POST" });
+    expect(window.prompt).toHaveBeenCalledWith(
+      "Neuer Code K7P-4QX. Neuer Upload-Link:",
+      "http://example.com/b/K7P-4QX"
+    );
+    expect(window.location.reload).toHaveBeenCalled();
+  });
+
+  it("should do nothing without confirmation", async () => {
+    window.confirm = vi.fn(() => false);
+    const mockFetch = vi.fn();
+    global.fetch = mockFetch;
+
+    await rotateToken(5, "Werkzeug 5");
+
+    expect(mockFetch).not.toHaveBeenCalled();
+  });
+});
+
 describe("downloadQR", () => {
   beforeEach(() => {
     vi.spyOn(window, "open").mockImplementation(() => null);
@@ -284,6 +679,44 @@ describe("downloadQR", () => {
   });
 });
 
+describe("printLabels", () => {
+  beforeEach(() => {
+    vi.spyOn(window, "open").mockImplementation(() => null);
+    document.body.innerHTML = `
+      <form id="labelSheetForm">
+        <input id="labelColumns" value="3" />
+        <input id="labelRows" value="5" />
+        <input id="labelMargin" value="10" />
+        <input id="labelBleed" value="0" />
+        <input id="labelCutMarks" type="checkbox" />
+      </form>
+      <input type="checkbox" class="token-select" value="4" checked />
+      <input type="checkbox" class="token-select" value="5" />
+      <input type="checkbox" class="token-select" value="7" checked />
+    `;
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it("should open the label sheet for the selected tokens with the grid settings", () => {
+    printLabels();
+    expect(window.open).toHaveBeenCalledWith(
+      "/admin/tokens/labels.pdf?ids=4%2C7&columns=3&rows=5&margin=10&bleed=0&marks=0",
+      "_blank"
+    );
+  });
+
+  it("should open the label sheet for a group", () => {
+    printLabels("Workshop Kassel");
+    expect(window.open).toHaveBeenCalledWith(
+      "/admin/tokens/labels.pdf?group=Workshop+Kassel&columns=3&rows=5&margin=10&bleed=0&marks=0",
+      "_blank"
+    );
+  });
+});
+
 describe("copyUploadURL", () => {
   beforeEach(() => {
     window.alert = vi.fn();
@@ -302,9 +735,9 @@ describe("copyUploadURL", () => {
       configurable: true,
     });
 
-    await copyUploadURL("abc123", "Test Token");
+    await copyUploadURL("K7P-4QX", "Test Token");
 
-    const expectedUrl = `${location.origin}/upload?token=abc123`;
+    const expectedUrl = `${location.origin}/b/K7P-4QX`;
     expect(mockWriteText).toHaveBeenCalledWith(expectedUrl);
     expect(window.alert).toHaveBeenCalledWith("URL kopiert: " + expectedUrl);
   });
@@ -317,13 +750,13 @@ describe("copyUploadURL", () => {
       configurable: true,
     });
 
-    await copyUploadURL("abc123", "Test Token");
+    await copyUploadURL("K7P-4QX", "Test Token");
 
-    const expectedUrl = `${location.origin}/upload?token=abc123`;
+    const expectedUrl = `${location.origin}/b/K7P-4QX`;
     expect(window.prompt).toHaveBeenCalledWith("URL kopieren:", expectedUrl);
   });
 
-  it("should encode token in URL", async () => {
+  it("should encode code in URL", async () => {
     const mockWriteText = vi.fn().mockResolvedValue(undefined);
     Object.defineProperty(navigator, "clipboard", {
       value: { writeText: mockWriteText },
@@ -331,76 +764,135 @@ describe("copyUploadURL", () => {
       configurable: true,
     });
 
-    await copyUploadURL("token with spaces", "Test Token");
+    await copyUploadURL("K7P 4QX", "Test Token");
 
-    const expectedUrl = `${location.origin}/upload?token=token%20with%20spaces`;
+    const expectedUrl = `${location.origin}/b/K7P%204QX`;
     expect(mockWriteText).toHaveBeenCalledWith(expectedUrl);
   });
 });
 
-describe("markBagRequestDone", () => {
+describe("setBagRequestStatus", () => {
   beforeEach(() => {
     document.body.innerHTML = "";
     global.fetch = vi.fn();
     window.alert = vi.fn();
     window.confirm = vi.fn(() => true);
+    window.location.reload = vi.fn() as any;
   });
 
   afterEach(() => {
     vi.restoreAllMocks();
   });
 
-  it("should mark request as done when confirmed", async () => {
+  it("should post the new status and reload", async () => {
     const mockFetch = vi.fn().mockResolvedValue({
       ok: true,
-      json: () => Promise.resolve({ status: "ok" }),
+      json: () => Promise.resolve({ status: "shipped" }),
     });
     global.fetch = mockFetch;
 
-    document.body.innerHTML = `
-      <ul>
-        <li id="testLi">
-          <button id="testBtn">Mark Done</button>
-        </li>
-      </ul>
-    `;
-
-    const btn = document.getElementById("testBtn") as HTMLElement;
-
-    await markBagRequestDone(1, btn);
+    await setBagRequestStatus(1, "shipped");
 
-    expect(window.confirm).toHaveBeenCalledWith("Als erledigt markieren?");
-    expect(mockFetch).toHaveBeenCalledWith("/admin/werkzeug-anfragen/1/complete", {
+    expect(window.confirm).toHaveBeenCalledWith(expect.stringContaining("versendet"));
+    expect(mockFetch).toHaveBeenCalledWith("/admin/werkzeug-anfragen/1/status", {
       method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ status: "shipped" }),
     });
-
-    const li = document.getElementById("testLi") as HTMLLIElement;
-    expect(li.style.opacity).toBe("0.8");
-    expect(li.textContent).toContain("✅ Erledigt");
+    expect(window.location.reload).toHaveBeenCalled();
   });
 
-  it("should not mark request as done when cancelled", async () => {
+  it("should not change the status when cancelled", async () => {
     window.confirm = vi.fn(() => false);
     const mockFetch = vi.fn();
     global.fetch = mockFetch;
 
-    const btn = document.createElement("button");
-    await markBagRequestDone(1, btn);
+    await setBagRequestStatus(1, "lost");
 
     expect(mockFetch).not.toHaveBeenCalled();
   });
 
   it("should show error when request fails", async () => {
-    const mockFetch = vi.fn().mockResolvedValue({
+    global.fetch = vi.fn().mockResolvedValue({
       ok: false,
-      json: () => Promise.resolve({ error: "Server error" }),
+      json: () => Promise.resolve({ error: "Status wurde inzwischen geändert" }),
+    });
+
+    await setBagRequestStatus(1, "returned");
+
+    expect(window.alert).toHaveBeenCalledWith("Fehler: Status wurde inzwischen geändert");
+  });
+});
+
+describe("assignBagRequestToken", () => {
+  beforeEach(() => {
+    global.fetch = vi.fn();
+    window.alert = vi.fn();
+    window.location.reload = vi.fn() as any;
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it("should create a token with the entered name", async () => {
+    window.prompt = vi.fn(() => " Kassel 7 ");
+    const mockFetch = vi.fn().mockResolvedValue({
+      ok: true,
+      json: () => Promise.resolve({ token_id: 9 }),
     });
     global.fetch = mockFetch;
 
-    const btn = document.createElement("button");
-    await markBagRequestDone(1, btn);
+    await assignBagRequestToken(3);
 
-    expect(window.alert).toHaveBeenCalledWith("Fehler: Server error");
+    expect(window.prompt).toHaveBeenCalledWith("Name des Werkzeugs:", "Anfrage #3");
+    expect(mockFetch).toHaveBeenCalledWith("/admin/werkzeug-anfragen/3/token", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ bag_name: "Kassel 7" }),
+    });
+    expect(window.location.reload).toHaveBeenCalled();
+  });
+
+  it("should do nothing when the prompt is cancelled", async () => {
+    window.prompt = vi.fn(() => null);
+    const mockFetch = vi.fn();
+    global.fetch = mockFetch;
+
+    await assignBagRequestToken(3);
+
+    expect(mockFetch).not.toHaveBeenCalled();
+  });
+});
+
+describe("saveBagRequestNotes", () => {
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it("should save the textarea content", async () => {
+    const mockFetch = vi.fn().mockResolvedValue({
+      ok: true,
+      json: () => Promise.resolve({ status: "ok" }),
+    });
+    global.fetch = mockFetch;
+
+    document.body.innerHTML = `
+      <div>
+        <textarea>Paket bei Nachbarn</textarea>
+        <button id="notesBtn">Speichern</button>
+      </div>
+    `;
+    const btn = document.getElementById("notesBtn") as HTMLElement;
+
+    await saveBagRequestNotes(5, btn);
+
+    expect(mockFetch).toHaveBeenCalledWith("/admin/werkzeug-anfragen/5/notes", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ notes: "Paket bei Nachbarn" }),
+    });
+    expect(btn.textContent).toBe("✅ Gespeichert");
   });
 });
 
@@ -472,3 +964,132 @@ describe("deleteContribution", () => {
     expect(window.alert).toHaveBeenCalledWith("Fehler: Not found");
   });
 });
+
+describe("webhooks", () => {
+  beforeEach(() => {
+    document.body.innerHTML = "";
+    window.alert = vi.fn();
+    window.confirm = vi.fn(() => true);
+    window.location.reload = vi.fn() as any;
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it("should queue a test event", async () => {
+    const mockFetch = vi.fn().mockResolvedValue({
+      ok: true,
+      json: () => Promise.resolve({ message: "Test-Event wurde in die Warteschlange gestellt" }),
+    });
+    global.fetch = mockFetch;
+
+    await testWebhook(3);
+
+    expect(mockFetch).toHaveBeenCalledWith("/admin/webhooks/3/test", { method: "POST" });
+    expect(window.alert).toHaveBeenCalledWith("Test-Event wurde in die Warteschlange gestellt");
+  });
+
+  it("should not delete a webhook when cancelled", async () => {
+    window.confirm = vi.fn(() => false);
+    const mockFetch = vi.fn();
+    global.fetch = mockFetch;
+
+    await deleteWebhook(3);
+
+    expect(mockFetch).not.toHaveBeenCalled();
+  });
+
+  it("should require at least one event when creating", async () => {
+    const mockFetch = vi.fn();
+    global.fetch = mockFetch;
+
+    document.body.innerHTML = `
+      <form id="createWebhookForm">
+        <input id="webhookUrl" value="https://example.org/hook" />
+        <input id="webhookDescription" value="" />
+        <input type="checkbox" name="webhookEvents" value="contribution.created" />
+      </form>
+      <div id="createWebhookResult"></div>
+    `;
+
+    initAdminDashboard();
+    const form = document.getElementById("createWebhookForm") as HTMLFormElement;
+    form.dispatchEvent(new Event("submit", { bubbles: true, cancelable: true }));
+    await new Promise((resolve) => setTimeout(resolve, 0));
+
+    expect(window.alert).toHaveBeenCalledWith("Bitte mindestens ein Event auswählen");
+    expect(mockFetch).not.toHaveBeenCalled();
+  });
+});
+
+describe("eraseSubjectData", () => {
+  const eraseForm = () => {
+    document.body.innerHTML = `
+      <form id="subjectEraseForm">
+        <input type="hidden" name="name" value="Anna Schmidt" />
+        <input type="hidden" name="session" value="" />
+        <input type="hidden" name="email" value="anna@example.org" />
+        <input type="hidden" name="player" value="" />
+        <input type="hidden" name="reference" value="Anfrage 12" />
+      </form>
+    `;
+    return document.getElementById("subjectEraseForm") as HTMLFormElement;
+  };
+
+  beforeEach(() => {
+    window.alert = vi.fn();
+    window.confirm = vi.fn(() => true);
+    window.location.reload = vi.fn() as any;
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it("should post the search and report the verified erasure", async () => {
+    const mockFetch = vi.fn().mockResolvedValue({
+      ok: true,
+      json: () => Promise.resolve({ message: "Daten gelöscht und geprüft (Protokoll #4)" }),
+    });
+    global.fetch = mockFetch;
+
+    await eraseSubjectData(eraseForm());
+
+    expect(mockFetch).toHaveBeenCalledWith("/admin/privacy/erase", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        name: "Anna Schmidt",
+        session: "",
+        email: "anna@example.org",
+        player: "",
+        reference: "Anfrage 12",
+      }),
+    });
+    expect(window.alert).toHaveBeenCalledWith("Daten gelöscht und geprüft (Protokoll #4)");
+    expect(window.location.reload).toHaveBeenCalled();
+  });
+
+  it("should not erase without confirmation", async () => {
+    window.confirm = vi.fn(() => false);
+    const mockFetch = vi.fn();
+    global.fetch = mockFetch;
+
+    await eraseSubjectData(eraseForm());
+
+    expect(mockFetch).not.toHaveBeenCalled();
+  });
+
+  it("should show the error of a rejected search", async () => {
+    global.fetch = vi.fn().mockResolvedValue({
+      ok: false,
+      json: () => Promise.resolve({ error: "no search criteria given" }),
+    });
+
+    await eraseSubjectData(eraseForm());
+
+    expect(window.alert).toHaveBeenCalledWith("Fehler: no search criteria given");
+    expect(window.location.reload).not.toHaveBeenCalled();
+  });
+});
@@ -10,27 +10,22 @@ const mockCities = [
   { name: "Frankfurt am Main" },
 ];
 
-// Mock Meilisearch
-vi.mock("meilisearch", () => {
-  return {
-    Meilisearch: vi.fn().mockImplementation(function () {
-      return {
-        index: vi.fn(() => ({
-          search: vi.fn(async (query: string) => {
-            // Simulate search based on query
-            const filtered = mockCities.filter((city) =>
-              city.name.toLowerCase().includes(query.toLowerCase())
-            );
-            return {
-              hits: filtered,
-              estimatedTotalHits: filtered.length,
-            };
-          }),
-        })),
-      };
-    }),
-  };
-});
+// Mock the /api/cities endpoint
+vi.stubGlobal(
+  "fetch",
+  vi.fn(async (input: string) => {
+    const query = new URL(input, "http://localhost").searchParams.get("q") ?? "";
+    // Simulate search based on query
+    const filtered = mockCities.filter((city) =>
+      city.name.toLowerCase().includes(query.toLowerCase())
+    );
+    return {
+      ok: true,
+      status: 200,
+      json: async () => ({ hits: filtered, source: "meilisearch" }),
+    };
+  })
+);
 
 describe("City Autocomplete - Dropdown Rendering", () => {
   let container: HTMLDivElement;
@@ -57,9 +52,6 @@ describe("City Autocomplete - Dropdown Rendering", () => {
     `;
     document.body.appendChild(container);
 
-    // Set window.GEOCODING_API_URL for tests
-    (window as any).GEOCODING_API_URL = "http://localhost:8081";
-
     resetState();
   });
 
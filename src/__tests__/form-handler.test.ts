@@ -103,7 +103,7 @@ describe("initFormHandlers", () => {
     expect(mockFetch).toHaveBeenCalledWith("/werkzeug-anfordern", {
       method: "POST",
       headers: { "Content-Type": "application/json" },
-      body: JSON.stringify({ email: "test@example.com" }),
+      body: JSON.stringify({ email: "test@example.com", website: "" }),
     });
 
     // Wait for async operations
@@ -112,6 +112,110 @@ describe("initFormHandlers", () => {
     });
   });
 
+  it("should ask to confirm via email when opt-in is required", async () => {
+    global.fetch = vi.fn().mockResolvedValue({
+      json: () => Promise.resolve({ status: "ok", confirmation_required: true }),
+    });
+
+    document.body.innerHTML = `
+      <form id="requestBagForm">
+        <input name="email" value="test@example.com" />
+        <input name="website" value="" />
+        <button type="submit">anfragen</button>
+        <div id="requestResult"></div>
+      </form>
+    `;
+
+    initFormHandlers();
+
+    const form = document.getElementById("requestBagForm") as HTMLFormElement;
+    form.dispatchEvent(new Event("submit", { bubbles: true, cancelable: true }));
+
+    await vi.waitFor(() => {
+      expect(form.innerHTML).toContain("bestätige deine Anfrage");
+    });
+  });
+
+  it("should require consent when an address is entered", () => {
+    const mockFetch = vi.fn();
+    global.fetch = mockFetch;
+
+    document.body.innerHTML = `
+      <form id="requestBagForm">
+        <input name="email" value="test@example.com" />
+        <input name="name" value="Mia Muster" />
+        <input type="checkbox" name="address_consent" />
+        <button type="submit">anfragen</button>
+        <div id="requestResult"></div>
+      </form>
+    `;
+
+    initFormHandlers();
+
+    const form = document.getElementById("requestBagForm") as HTMLFormElement;
+    const resultDiv = document.getElementById("requestResult") as HTMLDivElement;
+    form.dispatchEvent(new Event("submit", { bubbles: true, cancelable: true }));
+
+    expect(resultDiv.innerText).toContain("Speicherung deiner Adresse");
+    expect(mockFetch).not.toHaveBeenCalled();
+  });
+
+  it("should send the address with consent", () => {
+    const mockFetch = vi.fn().mockReturnValue(new Promise(() => {}));
+    global.fetch = mockFetch;
+
+    document.body.innerHTML = `
+      <form id="requestBagForm">
+        <input name="email" value="test@example.com" />
+        <input name="name" value="Mia Muster" />
+        <input name="street" value="Hauptstr. 1" />
+        <input name="postal_code" value="34117" />
+        <input name="city" value="Kassel" />
+        <input type="checkbox" name="address_consent" checked />
+        <button type="submit">anfragen</button>
+        <div id="requestResult"></div>
+      </form>
+    `;
+
+    initFormHandlers();
+
+    const form = document.getElementById("requestBagForm") as HTMLFormElement;
+    form.dispatchEvent(new Event("submit", { bubbles: true, cancelable: true }));
+
+    expect(JSON.parse(mockFetch.mock.calls[0][1].body)).toEqual({
+      email: "test@example.com",
+      website: "",
+      name: "Mia Muster",
+      street: "Hauptstr. 1",
+      postal_code: "34117",
+      city: "Kassel",
+      address_consent: true,
+    });
+  });
+
+  it("should send the honeypot field", () => {
+    const mockFetch = vi.fn().mockReturnValue(new Promise(() => {}));
+    global.fetch = mockFetch;
+
+    document.body.innerHTML = `
+      <form id="requestBagForm">
+        <input name="email" value="test@example.com" />
+        <input name="website" value="https://spam.example" />
+        <button type="submit">anfragen</button>
+        <div id="requestResult"></div>
+      </form>
+    `;
+
+    initFormHandlers();
+
+    const form = document.getElementById("requestBagForm") as HTMLFormElement;
+    form.dispatchEvent(new Event("submit", { bubbles: true, cancelable: true }));
+
+    expect(mockFetch.mock.calls[0][1].body).toBe(
+      JSON.stringify({ email: "test@example.com", website: "https://spam.example" }),
+    );
+  });
+
   it("should handle server error response", async () => {
     const mockFetch = vi.fn().mockResolvedValue({
       json: () => Promise.resolve({ status: "error", error: "Server error" }),
@@ -0,0 +1,89 @@
+/**
+ * Tests for kiosk module
+ */
+import { describe, it, expect, beforeEach } from "vitest";
+import { KioskQueue, initKiosk, type KioskContribution } from "../lib/kiosk";
+
+const contribution = (id: number): KioskContribution => ({
+  id,
+  derive_number: id,
+  image_url: `/img/${id}.webp`,
+  user_name: `Spieler ${id}`,
+  created_at: "2026-05-01T12:00:00Z",
+});
+
+describe("KioskQueue", () => {
+  it("should cycle through the initial contributions", () => {
+    const queue = new KioskQueue([contribution(1), contribution(2)]);
+
+    expect(queue.next()?.contribution.id).toBe(1);
+    expect(queue.next()?.contribution.id).toBe(2);
+    expect(queue.next()?.contribution.id).toBe(1);
+  });
+
+  it("should drop duplicates from the initial data", () => {
+    const queue = new KioskQueue([contribution(1), contribution(1), contribution(2)]);
+    expect(queue.size).toBe(2);
+  });
+
+  it("should return null when empty", () => {
+    expect(new KioskQueue().next()).toBeNull();
+  });
+
+  it("should show live arrivals first and keep them in rotation", () => {
+    const queue = new KioskQueue([contribution(1), contribution(2)]);
+    queue.next(); // 1
+
+    queue.addLive(contribution(3));
+    const slide = queue.next();
+    expect(slide?.contribution.id).toBe(3);
+    expect(slide?.live).toBe(true);
+
+    expect(queue.next()?.contribution.id).toBe(2);
+    expect(queue.next()?.contribution.id).toBe(1);
+    const again = queue.next();
+    expect(again?.contribution.id).toBe(3);
+    expect(again?.live).toBe(false);
+  });
+
+  it("should ignore live events for contributions already shown", () => {
+    const queue = new KioskQueue([contribution(1)]);
+    queue.addLive(contribution(1));
+    expect(queue.next()?.live).toBe(false);
+  });
+
+  it("should remove deleted contributions", () => {
+    const queue = new KioskQueue([contribution(1), contribution(2), contribution(3)]);
+    queue.next(); // 1
+    queue.next(); // 2
+    queue.remove(1);
+
+    expect(queue.size).toBe(2);
+    expect(queue.next()?.contribution.id).toBe(3);
+    expect(queue.next()?.contribution.id).toBe(2);
+  });
+});
+
+describe("initKiosk", () => {
+  beforeEach(() => {
+    document.body.innerHTML = "";
+  });
+
+  it("should do nothing without a kiosk element", () => {
+    expect(() => initKiosk()).not.toThrow();
+  });
+
+  it("should show the empty hint without contributions", () => {
+    document.body.innerHTML = `
+      <main data-kiosk>
+        <img data-kiosk-image />
+        <p data-kiosk-empty hidden></p>
+      </main>
+      <script type="application/json" id="kiosk-data">{"recent": [], "random": null}</script>
+    `;
+
+    initKiosk();
+
+    expect(document.querySelector<HTMLElement>("[data-kiosk-empty]")?.hidden).toBe(false);
+  });
+});
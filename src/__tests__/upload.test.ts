@@ -348,6 +348,17 @@ describe("endSession", () => {
     expect(window.location.href).toBe("/upload?token=test123");
   });
 
+  it("should open the recap after ending the session", async () => {
+    global.fetch = vi.fn().mockResolvedValue({
+      ok: true,
+      json: () => Promise.resolve({ message: "Session ended", recap_url: "/rueckblick/abc" }),
+    });
+
+    await endSession();
+
+    expect(window.location.href).toBe("/rueckblick/abc");
+  });
+
   it("should not end session when cancelled", async () => {
     window.confirm = vi.fn(() => false);
     const mockFetch = vi.fn();
@@ -16,6 +16,8 @@ export function initAdminDashboard(): void {
       e.preventDefault();
 
       const bagName = (document.getElementById("bagName") as HTMLInputElement).value;
+      const expiresAt =
+        (document.getElementById("expiresAt") as HTMLInputElement | null)?.value ?? "";
       const maxUploads = parseInt(
         (document.getElementById("maxUploads") as HTMLInputElement).value,
         10
@@ -30,7 +32,11 @@ export function initAdminDashboard(): void {
         const response = await fetch("/admin/tokens", {
           method: "POST",
           headers: { "Content-Type": "application/json" },
-          body: JSON.stringify({ bag_name: bagName, max_uploads: maxUploads }),
+          body: JSON.stringify({
+            bag_name: bagName,
+            max_uploads: maxUploads,
+            expires_at: expiresAt,
+          }),
         });
 
         const data = await response.json();
@@ -46,7 +52,8 @@ export function initAdminDashboard(): void {
         <small>Token-ID: ${data.token_id}</small><br>
         <a href="${data.qr_url}?format=svg" target="_blank" style="color: #2196F3;">📥 QR-Code (SVG) herunterladen</a> | 
         <a href="${data.qr_url}?format=png" target="_blank" style="color: #2196F3;">📥 QR-Code (PNG) herunterladen</a><br>
-        <small style="word-break: break-all;">URL: ${data.upload_url} <button id="copyNewUrlBtn" style="margin-left:0.5rem; padding:0.2rem 0.4rem; border-radius:4px;">🔗 Kopieren</button></small>
+        <small style="word-break: break-all;">URL: ${data.upload_url} <button id="copyNewUrlBtn" style="margin-left:0.5rem; padding:0.2rem 0.4rem; border-radius:4px;">🔗 Kopieren</button></small><br>
+        <small style="word-break: break-all;">Token (wird nur jetzt angezeigt): <code>${data.token}</code></small>
       `;
 
           const copyBtn = document.getElementById("copyNewUrlBtn");
@@ -73,10 +80,176 @@ export function initAdminDashboard(): void {
     };
   }
 
+  initTokenBatchForm();
+
+  // Create webhook form submission
+  const createWebhookForm = document.getElementById("createWebhookForm") as HTMLFormElement | null;
+  if (createWebhookForm) {
+    createWebhookForm.onsubmit = async function (e: Event) {
+      e.preventDefault();
+
+      const url = (document.getElementById("webhookUrl") as HTMLInputElement).value;
+      const description = (document.getElementById("webhookDescription") as HTMLInputElement)
+        .value;
+      const events = Array.from(
+        createWebhookForm.querySelectorAll<HTMLInputElement>('input[name="webhookEvents"]:checked')
+      ).map((input) => input.value);
+
+      if (events.length === 0) {
+        alert("Bitte mindestens ein Event auswählen");
+        return;
+      }
+
+      try {
+        const response = await fetch("/admin/webhooks", {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ url, description, events }),
+        });
+        const data = await response.json();
+
+        if (response.ok) {
+          const resultDiv = document.getElementById("createWebhookResult") as HTMLElement;
+          resultDiv.style.display = "block";
+          resultDiv.style.background = "#e8f5e9";
+          resultDiv.style.padding = "1rem";
+          resultDiv.style.borderRadius = "4px";
+          resultDiv.innerHTML = `
+        <strong>✅ Webhook erstellt!</strong><br>
+        <small>Secret für die Signaturprüfung (X-ID100-Signature):</small><br>
+        <code style="word-break: break-all;"></code>
+      `;
+          const code = resultDiv.querySelector("code");
+          if (code) code.textContent = data.secret;
+        } else {
+          alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+        }
+      } catch (err) {
+        alert("Fehler: " + getErrorMessage(err));
+      }
+    };
+  }
+
   // Initialize AJAX filter for bag requests
   initBagRequestFilter();
 }
 
+interface BatchToken {
+  id: number;
+  bag_name: string;
+  upload_url: string;
+  qr_url: string;
+}
+
+/**
+ * Batch token creation: from a name pattern, or from a CSV file when one is chosen
+ */
+function initTokenBatchForm(): void {
+  const form = document.getElementById("createTokenBatchForm") as HTMLFormElement | null;
+  if (!form) return;
+
+  form.onsubmit = async function (e: Event) {
+    e.preventDefault();
+
+    const value = (id: string) => (document.getElementById(id) as HTMLInputElement).value;
+    const optionalValue = (id: string) =>
+      (document.getElementById(id) as HTMLInputElement | null)?.value ?? "";
+    const maxUploads = parseInt(value("batchMaxUploads"), 10);
+    if (isNaN(maxUploads) || maxUploads <= 0) {
+      alert("Kontingent muss eine gültige Zahl größer als 0 sein");
+      return;
+    }
+
+    const file = (document.getElementById("batchFile") as HTMLInputElement | null)?.files?.[0];
+    let init: RequestInit;
+    if (file) {
+      const body = new FormData();
+      body.append("file", file);
+      body.append("max_uploads", String(maxUploads));
+      body.append("group", value("batchGroup"));
+      body.append("expires_at", optionalValue("batchExpiresAt"));
+      init = { method: "POST", body };
+    } else {
+      const pattern = value("batchPattern").trim();
+      if (!pattern) {
+        alert("Bitte ein Namensmuster angeben oder eine CSV-Datei wählen");
+        return;
+      }
+      init = {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          pattern,
+          count: parseInt(value("batchCount"), 10),
+          start: parseInt(value("batchStart"), 10),
+          max_uploads: maxUploads,
+          group: value("batchGroup"),
+          expires_at: optionalValue("batchExpiresAt"),
+        }),
+      };
+    }
+
+    try {
+      const response = await fetch("/admin/tokens/batch", init);
+      const data = await response.json();
+      if (!response.ok) {
+        alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+        return;
+      }
+      renderBatchResult(data);
+    } catch (err) {
+      alert("Fehler: " + getErrorMessage(err));
+    }
+  };
+}
+
+/**
+ * Show the created batch with export links; names are user input and set as text
+ */
+function renderBatchResult(data: {
+  group: string;
+  count: number;
+  tokens: BatchToken[];
+  export_csv: string;
+  export_json: string;
+}): void {
+  const resultDiv = document.getElementById("createBatchResult");
+  if (!resultDiv) return;
+  resultDiv.style.display = "block";
+  resultDiv.innerHTML = `
+    <strong>✅ <span class="batch-count"></span> Tokens erstellt</strong> (<span class="batch-group"></span>)<br>
+    <a class="batch-export-csv" target="_blank">⬇️ CSV</a> |
+    <a class="batch-export-json" target="_blank">⬇️ JSON</a> |
+    <a class="batch-labels" href="#">🏷️ Etiketten (PDF)</a>
+    <table class="token-batch-result"><tbody></tbody></table>
+  `;
+  (resultDiv.querySelector(".batch-count") as HTMLElement).textContent = String(data.count);
+  (resultDiv.querySelector(".batch-group") as HTMLElement).textContent = data.group;
+  (resultDiv.querySelector(".batch-export-csv") as HTMLAnchorElement).href = data.export_csv;
+  (resultDiv.querySelector(".batch-export-json") as HTMLAnchorElement).href = data.export_json;
+  (resultDiv.querySelector(".batch-labels") as HTMLAnchorElement).onclick = (e) => {
+    e.preventDefault();
+    printLabels(data.group);
+  };
+
+  const tbody = resultDiv.querySelector("tbody") as HTMLElement;
+  for (const t of data.tokens) {
+    const row = document.createElement("tr");
+    const name = document.createElement("td");
+    name.textContent = t.bag_name;
+    const url = document.createElement("td");
+    url.textContent = t.upload_url;
+    const qr = document.createElement("td");
+    const link = document.createElement("a");
+    link.href = `${t.qr_url}?format=svg`;
+    link.target = "_blank";
+    link.textContent = "QR";
+    qr.appendChild(link);
+    row.append(name, url, qr);
+    tbody.appendChild(row);
+  }
+}
+
 /**
  * Reset a token
  */
@@ -109,6 +282,27 @@ export async function deactivateToken(id: number, name: string): Promise<void> {
   }
 }
 
+/**
+ * Lift the device binding of a running session, e.g. when the player's phone died
+ */
+export async function releaseDevice(id: number, name: string): Promise<void> {
+  if (
+    !confirm(
+      `${name}: Gerät freigeben? Das nächste Gerät, das den QR-Code öffnet, übernimmt die Session.`
+    )
+  )
+    return;
+
+  try {
+    const response = await fetch(`/admin/tokens/${id}/release-device`, { method: "POST" });
+    const data = await response.json();
+    alert(data.message || "Gerät freigegeben");
+    location.reload();
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
 /**
  * Update token quota
  */
@@ -136,6 +330,187 @@ export async function updateQuota(id: number): Promise<void> {
   }
 }
 
+/**
+ * Set or clear the expiry date of a token
+ */
+export async function updateExpiry(id: number): Promise<void> {
+  const input = document.getElementById(`expiry-${id}`) as HTMLInputElement;
+
+  try {
+    const response = await fetch(`/admin/tokens/${id}/expiry`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ expires_at: input.value }),
+    });
+    const data = await response.json();
+    if (!response.ok) {
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+      return;
+    }
+    alert(input.value ? `✅ Gültig bis ${input.value}` : "✅ Ablaufdatum entfernt");
+    location.reload();
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
+/**
+ * Replace token and bag code; printed QR codes of the bag stop working
+ */
+export async function rotateToken(id: number, name: string): Promise<void> {
+  if (
+    !confirm(
+      `${name}: neuen Code erzeugen? Gedruckte QR-Codes und Etiketten funktionieren danach nicht mehr.`
+    )
+  )
+    return;
+
+  try {
+    const response = await fetch(`/admin/tokens/${id}/rotate`, { method: "POST" });
+    const data = await response.json();
+    if (!response.ok) {
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+      return;
+    }
+    prompt(`Neuer Code ${data.code}. Neuer Upload-Link:`, data.upload_url);
+    location.reload();
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
+/**
+ * Assign the ruleset chosen in the token card; 0 switches back to the defaults
+ */
+export async function assignRuleset(id: number): Promise<void> {
+  const select = document.getElementById(`ruleset-${id}`) as HTMLSelectElement;
+
+  try {
+    const response = await fetch(`/admin/tokens/${id}/ruleset`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ ruleset_id: parseInt(select.value, 10) || 0 }),
+    });
+    if (response.ok) {
+      location.reload();
+    } else {
+      const data = await response.json();
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+    }
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
+/**
+ * Read a ruleset editor form; empty number fields count as 0 (no limit)
+ */
+export function rulesetPayload(form: HTMLFormElement): Record<string, string | number> {
+  const value = (name: string) =>
+    (form.elements.namedItem(name) as HTMLInputElement | null)?.value.trim() ?? "";
+  const number = (name: string) => parseInt(value(name), 10) || 0;
+
+  return {
+    name: value("name"),
+    cooldown_seconds: number("cooldown_seconds"),
+    session_uploads: number("session_uploads"),
+    lifetime_uploads: number("lifetime_uploads"),
+    session_minutes: number("session_minutes"),
+    idle_minutes: number("idle_minutes"),
+    allowed_derives: value("allowed_derives"),
+  };
+}
+
+/**
+ * Create (id 0) or update a ruleset from its editor form
+ */
+export async function saveRuleset(id: number, form: HTMLFormElement): Promise<void> {
+  try {
+    const response = await fetch(id ? `/admin/rulesets/${id}` : "/admin/rulesets", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(rulesetPayload(form)),
+    });
+    if (response.ok) {
+      location.reload();
+    } else {
+      const data = await response.json();
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+    }
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
+/**
+ * Delete a ruleset; its bags fall back to the default rules
+ */
+export async function deleteRuleset(id: number, name: string): Promise<void> {
+  if (!confirm(`Regelwerk "${name}" löschen? Zugeordnete Werkzeuge nutzen dann den Standard.`))
+    return;
+
+  try {
+    const response = await fetch(`/admin/rulesets/${id}/delete`, { method: "POST" });
+    if (response.ok) {
+      location.reload();
+    } else {
+      const data = await response.json();
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+    }
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
+/**
+ * Continue the last ended session of a bag from its history page
+ */
+export async function reopenSession(tokenId: number, session: number): Promise<void> {
+  if (!confirm(`Session #${session} wieder öffnen? Spieler:in und Uploads werden übernommen.`))
+    return;
+
+  try {
+    const response = await fetch(`/admin/tokens/${tokenId}/sessions/${session}/reopen`, {
+      method: "POST",
+    });
+    const data = await response.json();
+    if (!response.ok) {
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+      return;
+    }
+    alert(data.message || "Session wieder geöffnet");
+    location.reload();
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
+/**
+ * Delete all contributions of one bag session
+ */
+export async function deleteSessionUploads(
+  tokenId: number,
+  session: number,
+  count: number
+): Promise<void> {
+  if (!confirm(`Alle ${count} Beiträge aus Session #${session} endgültig löschen?`)) return;
+
+  try {
+    const response = await fetch(`/admin/tokens/${tokenId}/sessions/${session}/delete-uploads`, {
+      method: "POST",
+    });
+    const data = await response.json();
+    if (!response.ok) {
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+      return;
+    }
+    alert(data.message || "Beiträge gelöscht");
+    location.reload();
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
 /**
  * Download QR code
  */
@@ -145,11 +520,42 @@ export function downloadQR(id: number, _name: string, format: "svg" | "png"): vo
 }
 
 /**
- * Copy upload URL to clipboard
+ * Open the PDF label sheet for a group, or for the selected tokens (all when
+ * none are selected), using the grid settings of the label form
+ */
+export function printLabels(group?: string): void {
+  const params = new URLSearchParams();
+  if (group) {
+    params.set("group", group);
+  } else {
+    const ids = Array.from(
+      document.querySelectorAll<HTMLInputElement>(".token-select:checked")
+    ).map((input) => input.value);
+    if (ids.length > 0) params.set("ids", ids.join(","));
+  }
+
+  const fields: Record<string, string> = {
+    columns: "labelColumns",
+    rows: "labelRows",
+    margin: "labelMargin",
+    bleed: "labelBleed",
+  };
+  for (const [param, id] of Object.entries(fields)) {
+    const input = document.getElementById(id) as HTMLInputElement | null;
+    if (input?.value) params.set(param, input.value);
+  }
+  const marks = document.getElementById("labelCutMarks") as HTMLInputElement | null;
+  if (marks && !marks.checked) params.set("marks", "0");
+
+  const query = params.toString();
+  window.open(`/admin/tokens/labels.pdf${query ? "?" + query : ""}`, "_blank");
+}
+
+/**
+ * Copy the short upload link of a bag code to clipboard
  */
-export async function copyUploadURL(token: string, _name: string): Promise<void> {
-  const encodedToken = encodeURIComponent(token);
-  const url = `${location.origin}/upload?token=${encodedToken}`;
+export async function copyUploadURL(code: string, _name: string): Promise<void> {
+  const url = `${location.origin}/b/${encodeURIComponent(code)}`;
   try {
     await navigator.clipboard.writeText(url);
     alert("URL kopiert: " + url);
@@ -160,24 +566,75 @@ export async function copyUploadURL(token: string, _name: string): Promise<void>
 }
 
 /**
- * Mark bag request as done
+ * Statuses an admin can set on a bag request, with their confirmation prompts
+ */
+const bagStatusPrompts: Record<string, string> = {
+  shipped: "Als versendet markieren? Die anfragende Person bekommt eine E‑Mail.",
+  returned: "Werkzeug als zurück markieren?",
+  lost: "Werkzeug als verloren markieren?",
+};
+
+/**
+ * Move a bag request to the next status of the fulfilment pipeline
  */
-export async function markBagRequestDone(id: number, btn: HTMLElement): Promise<void> {
-  if (!confirm("Als erledigt markieren?")) return;
+export async function setBagRequestStatus(id: number, status: string): Promise<void> {
+  if (!confirm(bagStatusPrompts[status] ?? "Status ändern?")) return;
   try {
-    const res = await fetch(`/admin/werkzeug-anfragen/${id}/complete`, { method: "POST" });
-    const data = await res.json();
+    const res = await fetch(`/admin/werkzeug-anfragen/${id}/status`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ status }),
+    });
     if (res.ok) {
-      const li = btn.closest("li");
-      const span = document.createElement("span");
-      span.style.color = "#4CAF50";
-      span.style.fontWeight = "600";
-      span.textContent = "✅ Erledigt";
-      btn.replaceWith(span);
-      if (li) {
-        li.style.opacity = "0.8";
-      }
+      location.reload();
+    } else {
+      const data = await res.json();
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+    }
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
+/**
+ * Create the upload token for a confirmed bag request
+ */
+export async function assignBagRequestToken(id: number): Promise<void> {
+  const bagName = prompt("Name des Werkzeugs:", `Anfrage #${id}`);
+  if (bagName === null) return;
+  try {
+    const res = await fetch(`/admin/werkzeug-anfragen/${id}/token`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ bag_name: bagName.trim() }),
+    });
+    if (res.ok) {
+      location.reload();
     } else {
+      const data = await res.json();
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+    }
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
+/**
+ * Save the admin notes of a bag request from the textarea next to the button
+ */
+export async function saveBagRequestNotes(id: number, btn: HTMLElement): Promise<void> {
+  const textarea = btn.parentElement?.querySelector("textarea");
+  if (!textarea) return;
+  try {
+    const res = await fetch(`/admin/werkzeug-anfragen/${id}/notes`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ notes: textarea.value }),
+    });
+    if (res.ok) {
+      btn.textContent = "✅ Gespeichert";
+    } else {
+      const data = await res.json();
       alert("Fehler: " + (data.error || "Unbekannter Fehler"));
     }
   } catch (err) {
@@ -267,13 +724,134 @@ export async function deleteContribution(id: number, _btn: HTMLElement): Promise
   }
 }
 
+/**
+ * Send a test event to a webhook
+ */
+export async function testWebhook(id: number): Promise<void> {
+  try {
+    const response = await fetch(`/admin/webhooks/${id}/test`, { method: "POST" });
+    const data = await response.json();
+    alert(response.ok ? data.message : "Fehler: " + (data.error || "Unbekannter Fehler"));
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
+/**
+ * Pause or resume a webhook
+ */
+export async function toggleWebhook(id: number, active: boolean): Promise<void> {
+  try {
+    const response = await fetch(`/admin/webhooks/${id}/toggle`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ active }),
+    });
+    if (response.ok) {
+      location.reload();
+    } else {
+      const data = await response.json();
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+    }
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
+/**
+ * Delete a webhook including its delivery log
+ */
+export async function deleteWebhook(id: number): Promise<void> {
+  if (!confirm("Webhook wirklich löschen? Das Zustellungsprotokoll wird ebenfalls gelöscht.")) return;
+
+  try {
+    const response = await fetch(`/admin/webhooks/${id}/delete`, { method: "POST" });
+    if (response.ok) {
+      location.reload();
+    } else {
+      const data = await response.json();
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+    }
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
+/**
+ * Queue a finished webhook delivery again
+ */
+export async function retryWebhookDelivery(id: number): Promise<void> {
+  try {
+    const response = await fetch(`/admin/webhook-deliveries/${id}/retry`, { method: "POST" });
+    if (response.ok) {
+      location.reload();
+    } else {
+      const data = await response.json();
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+    }
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
+/**
+ * Erase everything found for a data subject on the privacy tab
+ */
+export async function eraseSubjectData(form: HTMLFormElement): Promise<void> {
+  if (
+    !confirm(
+      "Alle gefundenen Daten endgültig löschen? Beiträge, Bilder, Sessions, Anfragen und Spielerkonten werden entfernt."
+    )
+  )
+    return;
+
+  const fields = new FormData(form);
+  const payload: Record<string, string> = {};
+  for (const key of ["name", "session", "email", "player", "reference"]) {
+    payload[key] = String(fields.get(key) ?? "");
+  }
+
+  try {
+    const response = await fetch("/admin/privacy/erase", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(payload),
+    });
+    const data = await response.json();
+    if (!response.ok) {
+      alert("Fehler: " + (data.error || "Unbekannter Fehler"));
+      return;
+    }
+    alert(data.message || "Daten gelöscht");
+    location.reload();
+  } catch (err) {
+    alert("Fehler: " + getErrorMessage(err));
+  }
+}
+
 // Export functions to global window object for inline onclick handlers
 if (typeof window !== "undefined") {
   (window as any).resetToken = resetToken;
   (window as any).deactivateToken = deactivateToken;
+  (window as any).releaseDevice = releaseDevice;
   (window as any).updateQuota = updateQuota;
+  (window as any).updateExpiry = updateExpiry;
+  (window as any).rotateToken = rotateToken;
+  (window as any).assignRuleset = assignRuleset;
+  (window as any).saveRuleset = saveRuleset;
+  (window as any).deleteRuleset = deleteRuleset;
+  (window as any).reopenSession = reopenSession;
+  (window as any).deleteSessionUploads = deleteSessionUploads;
   (window as any).downloadQR = downloadQR;
+  (window as any).printLabels = printLabels;
   (window as any).copyUploadURL = copyUploadURL;
-  (window as any).markBagRequestDone = markBagRequestDone;
+  (window as any).setBagRequestStatus = setBagRequestStatus;
+  (window as any).assignBagRequestToken = assignBagRequestToken;
+  (window as any).saveBagRequestNotes = saveBagRequestNotes;
   (window as any).deleteContribution = deleteContribution;
+  (window as any).testWebhook = testWebhook;
+  (window as any).toggleWebhook = toggleWebhook;
+  (window as any).deleteWebhook = deleteWebhook;
+  (window as any).retryWebhookDelivery = retryWebhookDelivery;
+  (window as any).eraseSubjectData = eraseSubjectData;
 }
@@ -1,10 +1,9 @@
 /**
- * City autocomplete module using Meilisearch SDK with custom dropdown
+ * City autocomplete module backed by the server-side /api/cities endpoint with custom dropdown
  * Provides autocomplete functionality for city selection with styled dropdown
  * Includes Zod schema validation for form data
  */
 
-import { Meilisearch } from "meilisearch";
 import { z } from "zod";
 
 interface CityHit {
@@ -58,7 +57,6 @@ export function validateForm(data: unknown): ValidationResult {
 let debounceTimer: number | undefined;
 let validCities: Set<string> = new Set();
 let citySelected = false;
-let client: Meilisearch | null = null;
 let selectedIndex = -1;
 let currentResults: string[] = [];
 
@@ -73,21 +71,6 @@ export function resetState(): void {
   currentResults = [];
 }
 
-/**
- * Initialize Meilisearch client
- */
-function initMeilisearchClient(): Meilisearch {
-  if (!client) {
-    const meilisearchUrl = window.GEOCODING_API_URL || "http://localhost:8081";
-    const apiKey = window.MEILI_SEARCH_KEY || "";
-    client = new Meilisearch({
-      host: meilisearchUrl,
-      apiKey: apiKey,
-    });
-  }
-  return client;
-}
-
 /**
  * Create custom dropdown element
  */
@@ -212,9 +195,6 @@ export function initCityAutocomplete(): void {
     submitBtn.classList.add("disabled");
   }
 
-  // Initialize Meilisearch client
-  const meiliClient = initMeilisearchClient();
-
   // Create custom dropdown and insert after the input
   const dropdown = createDropdown();
   if (cityInput.parentNode) {
@@ -263,7 +243,7 @@ export function initCityAutocomplete(): void {
 
     // Debounce the API call
     debounceTimer = window.setTimeout(() => {
-      searchCities(query, cityInput, dropdown, meiliClient);
+      searchCities(query, cityInput, dropdown);
     }, 300);
   });
 
@@ -310,20 +290,21 @@ export function initCityAutocomplete(): void {
 }
 
 /**
- * Search cities using Meilisearch SDK
+ * Search cities via the app's /api/cities endpoint
  */
 async function searchCities(
   query: string,
   input: HTMLInputElement,
-  dropdown: HTMLDivElement,
-  meiliClient: Meilisearch
+  dropdown: HTMLDivElement
 ): Promise<void> {
   try {
-    // Search using Meilisearch SDK
-    const searchResults = await meiliClient.index("cities").search<CityHit>(query, {
-      limit: 10,
-      attributesToRetrieve: ["name"],
+    const response = await fetch(`/api/cities?q=${encodeURIComponent(query)}&limit=10`, {
+      headers: { Accept: "application/json" },
     });
+    if (!response.ok) {
+      throw new Error(`city search failed with status ${response.status}`);
+    }
+    const searchResults = (await response.json()) as { hits: CityHit[] };
 
     // Clear valid cities
     validCities.clear();
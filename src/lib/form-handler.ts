@@ -13,6 +13,19 @@ export function initFormHandlers(): void {
     e.preventDefault();
 
     const emailInput = form.querySelector<HTMLInputElement>('input[name="email"]');
+    // Honeypot: empty for humans, the server silently drops filled-in requests
+    const website = form.querySelector<HTMLInputElement>('input[name="website"]')?.value ?? "";
+    const field = (name: string): string =>
+      form.querySelector<HTMLInputElement>(`input[name="${name}"]`)?.value.trim() ?? "";
+    const address = {
+      name: field("name"),
+      street: field("street"),
+      postal_code: field("postal_code"),
+      city: field("city"),
+    };
+    const addressConsent =
+      form.querySelector<HTMLInputElement>('input[name="address_consent"]')?.checked ?? false;
+    const hasAddress = Object.values(address).some((v) => v !== "");
     const btn = form.querySelector<HTMLButtonElement>("button[type=submit]");
     const resultDiv = form.querySelector<HTMLDivElement>("#requestResult");
 
@@ -27,6 +40,13 @@ export function initFormHandlers(): void {
       return;
     }
 
+    if (hasAddress && !addressConsent) {
+      resultDiv.style.display = "block";
+      resultDiv.style.color = "#d32f2f";
+      resultDiv.innerText = "Bitte stimme der Speicherung deiner Adresse zu oder lass die Felder leer.";
+      return;
+    }
+
     btn.disabled = true;
     btn.innerText = "sende...";
 
@@ -35,11 +55,17 @@ export function initFormHandlers(): void {
     fetch("/werkzeug-anfordern", {
       method: "POST",
       headers: { "Content-Type": "application/json" },
-      body: JSON.stringify({ email }),
+      body: JSON.stringify(
+        hasAddress
+          ? { email, website, ...address, address_consent: addressConsent }
+          : { email, website },
+      ),
     })
       .then((r) => r.json())
-      .then((data: { status: string; error?: string }) => {
-        if (data.status === "ok") {
+      .then((data: { status: string; error?: string; confirmation_required?: boolean }) => {
+        if (data.status === "ok" && data.confirmation_required) {
+          form.innerHTML = `<p style="font-weight:500;">Fast geschafft! Wir haben dir eine E‑Mail geschickt. Bitte bestätige deine Anfrage über den Link darin.</p>`;
+        } else if (data.status === "ok") {
           form.innerHTML = `<p style="font-weight:500;">Danke! Wir benachrichtigen dich per E‑Mail, sobald ein Werkzeug verfügbar ist.</p>`;
         } else {
           resultDiv.style.display = "block";
@@ -0,0 +1,187 @@
+/**
+ * Exhibition kiosk module
+ * Cycles through recent and random contributions on /ausstellung and
+ * shows new arrivals from the live contribution stream first
+ */
+
+export interface KioskContribution {
+  id: number;
+  derive_number: number;
+  image_url: string;
+  user_name: string;
+  user_city?: string;
+  user_comment?: string;
+  created_at: string;
+}
+
+export interface KioskSlide {
+  contribution: KioskContribution;
+  live: boolean;
+}
+
+// Keep the rotation bounded during long exhibition days
+const MAX_POOL_SIZE = 200;
+
+/**
+ * Rotation order for the kiosk: live arrivals jump the queue once,
+ * afterwards they stay in the regular rotation
+ */
+export class KioskQueue {
+  private pool: KioskContribution[] = [];
+  private live: KioskContribution[] = [];
+  private index = 0;
+
+  constructor(initial: KioskContribution[] = []) {
+    initial.forEach((c) => {
+      if (!this.has(c.id)) this.pool.push(c);
+    });
+  }
+
+  get size(): number {
+    return this.pool.length;
+  }
+
+  has(id: number): boolean {
+    return this.pool.some((c) => c.id === id) || this.live.some((c) => c.id === id);
+  }
+
+  addLive(contribution: KioskContribution): void {
+    if (this.has(contribution.id)) return;
+    this.live.push(contribution);
+  }
+
+  remove(id: number): void {
+    const before = this.pool.findIndex((c) => c.id === id);
+    if (before !== -1) {
+      this.pool.splice(before, 1);
+      if (before < this.index) this.index--;
+    }
+    this.live = this.live.filter((c) => c.id !== id);
+  }
+
+  next(): KioskSlide | null {
+    const arrival = this.live.shift();
+    if (arrival) {
+      // Insert after the current position so it comes round again in the normal rotation
+      this.pool.splice(this.index, 0, arrival);
+      this.index++;
+      if (this.pool.length > MAX_POOL_SIZE) {
+        this.pool.pop();
+      }
+      return { contribution: arrival, live: true };
+    }
+
+    if (this.pool.length === 0) return null;
+    if (this.index >= this.pool.length) this.index = 0;
+    const contribution = this.pool[this.index];
+    this.index++;
+    return { contribution, live: false };
+  }
+}
+
+function readInitialData(): KioskContribution[] {
+  const el = document.getElementById("kiosk-data");
+  if (!el?.textContent) return [];
+  try {
+    const data = JSON.parse(el.textContent) as {
+      recent?: KioskContribution[] | null;
+      random?: KioskContribution[] | null;
+    };
+    return [...(data.recent || []), ...(data.random || [])];
+  } catch (e) {
+    console.warn("Kiosk: could not parse initial data", e);
+    return [];
+  }
+}
+
+export function initKiosk(): void {
+  const root = document.querySelector<HTMLElement>("[data-kiosk]");
+  if (!root) return;
+
+  const image = root.querySelector<HTMLImageElement>("[data-kiosk-image]");
+  const badge = root.querySelector<HTMLElement>("[data-kiosk-badge]");
+  const idLabel = root.querySelector<HTMLElement>("[data-kiosk-id]");
+  const meta = root.querySelector<HTMLElement>("[data-kiosk-meta]");
+  const comment = root.querySelector<HTMLElement>("[data-kiosk-comment]");
+  const empty = root.querySelector<HTMLElement>("[data-kiosk-empty]");
+  const status = root.querySelector<HTMLElement>("[data-kiosk-status]");
+  if (!image) return;
+
+  const queue = new KioskQueue(readInitialData());
+  const interval = parseInt(root.dataset.interval || "8000", 10) || 8000;
+  let timer: number | undefined;
+
+  const render = (slide: KioskSlide): void => {
+    const c = slide.contribution;
+    image.src = c.image_url;
+    image.alt = `Beitrag zu ID ${c.derive_number}`;
+    if (idLabel) idLabel.textContent = `ID ${c.derive_number}`;
+    if (meta) meta.textContent = [c.user_name, c.user_city].filter(Boolean).join(" · ");
+    if (comment) {
+      comment.textContent = c.user_comment || "";
+      comment.hidden = !c.user_comment;
+    }
+    if (badge) badge.hidden = !slide.live;
+    root.classList.toggle("kiosk-live", slide.live);
+  };
+
+  const show = (): void => {
+    const slide = queue.next();
+    if (empty) empty.hidden = slide !== null;
+    root.classList.toggle("kiosk-is-empty", slide === null);
+    if (!slide) return;
+
+    // Swap only once the next image is loaded so the screen never flashes empty
+    const preload = new Image();
+    preload.onload = () => render(slide);
+    preload.onerror = () => queue.remove(slide.contribution.id);
+    preload.src = slide.contribution.image_url;
+  };
+
+  const schedule = (): void => {
+    window.clearInterval(timer);
+    timer = window.setInterval(show, interval);
+  };
+
+  show();
+  schedule();
+
+  const streamURL = root.dataset.streamUrl;
+  if (streamURL && typeof EventSource !== "undefined") {
+    const source = new EventSource(streamURL);
+    source.addEventListener("open", () => {
+      if (status) status.textContent = "Live";
+      root.classList.remove("kiosk-offline");
+    });
+    source.addEventListener("error", () => {
+      // EventSource reconnects on its own and resumes from the last event ID
+      if (status) status.textContent = "Verbindung unterbrochen…";
+      root.classList.add("kiosk-offline");
+    });
+    source.addEventListener("contribution", (e) => {
+      try {
+        queue.addLive(JSON.parse((e as MessageEvent).data) as KioskContribution);
+        // Show arrivals right away and restart the rotation timer
+        show();
+        schedule();
+      } catch (err) {
+        console.warn("Kiosk: invalid contribution event", err);
+      }
+    });
+    source.addEventListener("removed", (e) => {
+      try {
+        const { id } = JSON.parse((e as MessageEvent).data) as { id: number };
+        queue.remove(id);
+      } catch (err) {
+        console.warn("Kiosk: invalid removed event", err);
+      }
+    });
+  }
+
+  // A click or tap switches the kiosk screen to full screen
+  root.addEventListener("click", () => {
+    if (!document.fullscreenElement) {
+      document.documentElement.requestFullscreen?.().catch(() => {});
+    }
+  });
+}
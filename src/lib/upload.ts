@@ -229,8 +229,9 @@ export async function endSession(): Promise<void> {
 
     if (response.ok) {
       alert(data.message || "Session beendet");
-      // Redirect to upload page without token to force re-entry
-      location.href = "/upload?token=" + encodeURIComponent(token);
+      // Show the recap of the ended session; without one go back to the
+      // upload page, which asks the next player for a name
+      location.href = data.recap_url || "/upload?token=" + encodeURIComponent(token);
     } else {
       alert("Fehler: " + (data.error || "Unbekannter Fehler"));
     }
@@ -13,6 +13,7 @@ import { initCityAutocomplete, initFormValidation } from "./lib/city-autocomplet
 import { initAdminDashboard } from "./lib/admin-dashboard";
 import { initUpload } from "./lib/upload";
 import { initProductSlideshow } from "./lib/product-slideshow";
+import { initKiosk } from "./lib/kiosk";
 
 // Initialize all modules when DOM is ready
 (() => {
@@ -48,4 +49,7 @@ import { initProductSlideshow } from "./lib/product-slideshow";
 
   // Upload page functionality
   initUpload();
+
+  // Exhibition kiosk (/ausstellung)
+  initKiosk();
 })();
@@ -3,8 +3,6 @@
  */
 
 interface Window {
-  GEOCODING_API_URL: string;
-  MEILI_SEARCH_KEY: string;
   SENTRY_DSN?: string;
   ENVIRONMENT?: string;
   APP_VERSION?: string;